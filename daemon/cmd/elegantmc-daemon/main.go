@@ -19,6 +19,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == mc.IsolationHelperArg {
+		mc.RunIsolationHelper(os.Args[2:])
+	}
+
 	cfg, err := config.LoadFromEnv()
 	if err != nil {
 		log.Fatalf("config: %v", err)
@@ -46,9 +50,25 @@ func main() {
 		ServersFS: rootFS,
 		Log:       logger,
 		JavaCandidates: cfg.JavaCandidates,
+		JavaDiscover: cfg.JavaDiscover,
 		JavaAutoDownload: cfg.JavaAutoDownload,
 		JavaCacheDir: cfg.JavaCacheDir,
 		JavaAdoptiumAPIBaseURL: cfg.JavaAdoptiumAPIBaseURL,
+		JavaZuluAPIBaseURL: cfg.JavaZuluAPIBaseURL,
+		JavaManifestURL: cfg.JavaManifestURL,
+		JavaVendor: cfg.JavaVendor,
+		RuntimeDir: cfg.RuntimeDir,
+		Detach: cfg.MCDetach,
+		Cgroups: cfg.Cgroups,
+		CgroupParent: cfg.CgroupParent,
+		Isolation: mc.IsolationConfig{
+			Enabled: cfg.MCIsolation,
+			UID:     cfg.MCUID,
+			GID:     cfg.MCGID,
+			// Daemon state (panel binding, frpc tokens, runtime files) and other instances.
+			HidePaths: append([]string{cfg.BaseDir, cfg.ServersRoot(), cfg.FRPWorkDir, cfg.JavaCacheDir, cfg.RuntimeDir}, cfg.MCIsolationHide...),
+			EnvAllow:  cfg.MCIsolationEnv,
+		},
 	})
 
 	exec := commands.NewExecutor(commands.ExecutorDeps{
@@ -60,6 +80,7 @@ func main() {
 		FRPC:   cfg.FRPCPath,
 		PreferredConnectAddrs: cfg.PreferredConnectAddrs,
 		ScheduleFile: cfg.ScheduleFile,
+		ClientModDenylist: cfg.ClientModDenylistFile,
 		Mojang: commands.MojangConfig{
 			MetaBaseURL: cfg.MojangMetaBaseURL,
 			DataBaseURL: cfg.MojangDataBaseURL,
@@ -67,8 +88,36 @@ func main() {
 		Paper: commands.PaperConfig{
 			APIBaseURL: cfg.PaperAPIBaseURL,
 		},
+		Purpur: commands.PurpurConfig{
+			APIBaseURL: cfg.PurpurAPIBaseURL,
+		},
+		Fabric: commands.FabricConfig{
+			MetaBaseURL: cfg.FabricMetaBaseURL,
+		},
+		Quilt: commands.QuiltConfig{
+			MetaBaseURL: cfg.QuiltMetaBaseURL,
+		},
+		Forge: commands.ForgeConfig{
+			MavenBaseURL: cfg.ForgeMavenBaseURL,
+		},
+		NeoForge: commands.ForgeConfig{
+			MavenBaseURL: cfg.NeoForgeMavenBaseURL,
+		},
+		CurseForge: commands.CurseForgeConfig{
+			APIBaseURL: cfg.CurseForgeAPIBaseURL,
+		},
+		Modrinth: commands.ModrinthConfig{
+			APIBaseURL: cfg.ModrinthAPIBaseURL,
+		},
+		VersionCache: commands.VersionCacheConfig{
+			Dir: cfg.VersionsCacheDir,
+			TTL: time.Duration(cfg.VersionsCacheTTLSec) * time.Second,
+		},
 	})
 
+	// Re-attach servers left running by a previous daemon process.
+	exec.AdoptInstances(ctx)
+
 	if cfg.ScheduleEnabled {
 		go scheduler.New(scheduler.Config{
 			Enabled:   true,
@@ -78,6 +127,7 @@ func main() {
 			ServersFS: rootFS,
 			MC:        mcMgr,
 			Log:       logger,
+			Progress:  exec.EmitInstall,
 		}).Run(ctx)
 	}
 
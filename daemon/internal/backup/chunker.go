@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"errors"
+	"io"
+)
+
+// Content-defined chunking (gear hash, FastCDC style): cut points depend on the data,
+// so an insert or a rewritten region only changes the chunks around it.
+const (
+	chunkMin     = 64 << 10
+	chunkMax     = 1 << 20
+	chunkAvgBits = 18 // cut chance 2^-18 per byte after chunkMin: ~320 KiB average
+)
+
+var gearTable = func() (t [256]uint64) {
+	// splitmix64 with a fixed seed: the table must never change, or dedup against
+	// existing repositories breaks.
+	seed := uint64(0x6a09e667f3bcc908)
+	for i := range t {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return t
+}()
+
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, 0, chunkMax)}
+}
+
+// Next returns the next chunk (a fresh slice) or io.EOF.
+func (c *chunker) Next() ([]byte, error) {
+	for len(c.buf) < chunkMax && !c.eof {
+		n, err := c.r.Read(c.buf[len(c.buf):chunkMax])
+		c.buf = c.buf[:len(c.buf)+n]
+		if errors.Is(err, io.EOF) {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+	cut := chunkCutPoint(c.buf)
+	out := append([]byte(nil), c.buf[:cut]...)
+	c.buf = c.buf[:copy(c.buf, c.buf[cut:])]
+	return out, nil
+}
+
+func chunkCutPoint(b []byte) int {
+	if len(b) <= chunkMin {
+		return len(b)
+	}
+	n := len(b)
+	if n > chunkMax {
+		n = chunkMax
+	}
+	var h uint64
+	for i := chunkMin; i < n; i++ {
+		h = (h << 1) + gearTable[b[i]]
+		if h>>(64-chunkAvgBits) == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func chunkAll(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	ch := newChunker(bytes.NewReader(data))
+	var out [][]byte
+	for {
+		c, err := ch.Next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("Next(): %v", err)
+		}
+		out = append(out, c)
+	}
+}
+
+func TestChunker_Boundaries(t *testing.T) {
+	data := make([]byte, 6<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	chunks := chunkAll(t, data)
+	if len(chunks) < 6 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	var joined []byte
+	for i, c := range chunks {
+		if len(c) > chunkMax {
+			t.Fatalf("chunk %d: %d bytes > max", i, len(c))
+		}
+		if len(c) < chunkMin && i != len(chunks)-1 {
+			t.Fatalf("chunk %d: %d bytes < min", i, len(c))
+		}
+		joined = append(joined, c...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Fatalf("chunks do not reassemble the input")
+	}
+}
+
+func TestChunker_SmallAndEmpty(t *testing.T) {
+	if got := chunkAll(t, nil); len(got) != 0 {
+		t.Fatalf("empty input: got %d chunks", len(got))
+	}
+	got := chunkAll(t, []byte("level.dat"))
+	if len(got) != 1 || string(got[0]) != "level.dat" {
+		t.Fatalf("small input: got %q", got)
+	}
+	// Data without cut points is split at chunkMax.
+	got = chunkAll(t, make([]byte, 2*chunkMax+10))
+	if len(got) != 3 || len(got[0]) != chunkMax || len(got[2]) != 10 {
+		t.Fatalf("zero input: got %d chunks", len(got))
+	}
+}
+
+func TestChunker_InsertOnlyChangesNearbyChunks(t *testing.T) {
+	data := make([]byte, 8<<20)
+	rand.New(rand.NewSource(2)).Read(data)
+	shifted := append([]byte("inserted at the front"), data...)
+
+	ids := func(chunks [][]byte) map[[32]byte]bool {
+		out := make(map[[32]byte]bool)
+		for _, c := range chunks {
+			out[sha256.Sum256(c)] = true
+		}
+		return out
+	}
+	before := ids(chunkAll(t, data))
+	after := chunkAll(t, shifted)
+	changed := 0
+	for _, c := range after {
+		if !before[sha256.Sum256(c)] {
+			changed++
+		}
+	}
+	if changed > 2 {
+		t.Fatalf("insert changed %d of %d chunks", changed, len(after))
+	}
+}
@@ -0,0 +1,685 @@
+package backup
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SnapshotExt is the file suffix of snapshot manifests in a repository directory.
+const SnapshotExt = ".snapshot"
+
+const (
+	repoVersion     = 1
+	repoDataDir     = "repo"
+	repoRefsName    = "refs.json"
+	repoConfigName  = "config.json"
+	chunkEncRaw     = 0
+	chunkEncDeflate = 1
+)
+
+// Repo is a content-addressed, deduplicating backup store (restic-like). Layout of dir:
+//
+//	<name>.snapshot            snapshot manifest (files -> chunk ids)
+//	repo/config.json           repository version and chunker parameters
+//	repo/chunks/<ab>/<sha256>  chunk data (1 byte encoding + raw or deflate bytes)
+//	repo/refs.json             chunk id -> number of snapshots referencing it
+//
+// Chunks are written first, then the incremented reference counts, then the manifest:
+// an interrupted backup leaves unreferenced chunks or over-counted references (both only
+// cost space until GC), never a manifest whose chunks are not counted. Forget also checks
+// the remaining manifests before deleting a chunk, so stale counts cannot lose data.
+type Repo struct {
+	dir string
+	mu  *sync.Mutex
+}
+
+// Snapshot is the manifest of one backup.
+type Snapshot struct {
+	Version       int            `json:"version"`
+	ID            string         `json:"id"`
+	CreatedAtUnix int64          `json:"created_at_unix"`
+	Files         []SnapshotFile `json:"files"`
+	Dirs          []string       `json:"dirs,omitempty"`
+	Bytes         int64          `json:"bytes"`
+}
+
+type SnapshotFile struct {
+	Path      string   `json:"path"`
+	Size      int64    `json:"size"`
+	Mode      uint32   `json:"mode"`
+	MTimeUnix int64    `json:"mtime_unix"`
+	Chunks    []string `json:"chunks"`
+}
+
+// SnapshotStats describes a finished backup.
+type SnapshotStats struct {
+	ID          string
+	Files       int
+	Bytes       int64 // logical size of the backed up files
+	Chunks      int   // distinct chunks referenced by the snapshot
+	NewChunks   int   // chunks that were not in the repository yet
+	StoredBytes int64 // on-disk size of the new chunks
+}
+
+type repoConfig struct {
+	Version      int `json:"version"`
+	ChunkMin     int `json:"chunk_min"`
+	ChunkMax     int `json:"chunk_max"`
+	ChunkAvgBits int `json:"chunk_avg_bits"`
+}
+
+type repoRefs struct {
+	Version int            `json:"version"`
+	Refs    map[string]int `json:"refs"`
+}
+
+var repoLocksMu sync.Mutex
+var repoLocks = make(map[string]*sync.Mutex)
+
+// OpenRepo opens (and creates if needed) the repository in dir.
+func OpenRepo(dir string) (*Repo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := filepath.Join(abs, repoDataDir)
+	if err := os.MkdirAll(filepath.Join(data, "chunks"), 0o755); err != nil {
+		return nil, err
+	}
+	cfgPath := filepath.Join(data, repoConfigName)
+	var cfg repoConfig
+	if b, err := os.ReadFile(cfgPath); err == nil {
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid backup repo config: %w", err)
+		}
+		if cfg.Version != repoVersion || cfg.ChunkMin != chunkMin || cfg.ChunkMax != chunkMax || cfg.ChunkAvgBits != chunkAvgBits {
+			return nil, fmt.Errorf("unsupported backup repo (version %d)", cfg.Version)
+		}
+	} else if os.IsNotExist(err) {
+		cfg = repoConfig{Version: repoVersion, ChunkMin: chunkMin, ChunkMax: chunkMax, ChunkAvgBits: chunkAvgBits}
+		if err := writeJSONAtomic(cfgPath, cfg); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	repoLocksMu.Lock()
+	mu := repoLocks[abs]
+	if mu == nil {
+		mu = &sync.Mutex{}
+		repoLocks[abs] = mu
+	}
+	repoLocksMu.Unlock()
+	return &Repo{dir: abs, mu: mu}, nil
+}
+
+// IsSnapshotPath reports whether p names a snapshot manifest.
+func IsSnapshotPath(p string) bool {
+	return strings.HasSuffix(strings.ToLower(p), SnapshotExt)
+}
+
+// Dir returns the repository directory.
+func (r *Repo) Dir() string { return r.dir }
+
+// Snapshots lists the snapshot manifest names, oldest first.
+func (r *Repo) Snapshots() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, ent := range entries {
+		if ent.Type().IsRegular() && IsSnapshotPath(ent.Name()) {
+			out = append(out, ent.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (r *Repo) snapshotPath(name string) (string, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if !IsSnapshotPath(name) || name == SnapshotExt {
+		return "", fmt.Errorf("invalid snapshot name: %q", name)
+	}
+	return filepath.Join(r.dir, name), nil
+}
+
+func (r *Repo) chunkPath(id string) string {
+	return filepath.Join(r.dir, repoDataDir, "chunks", id[:2], id)
+}
+
+// Backup stores srcDir as snapshot name (<name>.snapshot). Files whose chunks are
+// already in the repository only cost their manifest entry.
+func (r *Repo) Backup(srcDir string, name string, onProgress ArchiveProgressFunc) (SnapshotStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	manifestPath, err := r.snapshotPath(name)
+	if err != nil {
+		return SnapshotStats{}, err
+	}
+	if _, err := os.Stat(manifestPath); err == nil {
+		return SnapshotStats{}, fmt.Errorf("snapshot already exists: %s", filepath.Base(manifestPath))
+	}
+	srcAbs, err := filepath.Abs(srcDir)
+	if err != nil {
+		return SnapshotStats{}, err
+	}
+	info, err := os.Stat(srcAbs)
+	if err != nil {
+		return SnapshotStats{}, err
+	}
+	if !info.IsDir() {
+		return SnapshotStats{}, errors.New("srcDir is not a directory")
+	}
+
+	snap := Snapshot{
+		Version:       repoVersion,
+		ID:            strings.TrimSuffix(filepath.Base(manifestPath), SnapshotExt),
+		CreatedAtUnix: time.Now().Unix(),
+	}
+	stats := SnapshotStats{ID: snap.ID}
+	seen := make(map[string]bool)
+	lastEmit := time.Now()
+
+	walkErr := filepath.WalkDir(srcAbs, func(p string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(srcAbs, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			return errors.New("refuse to back up symlink")
+		}
+		if d.IsDir() {
+			snap.Dirs = append(snap.Dirs, rel)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := SnapshotFile{Path: rel, Mode: uint32(fi.Mode().Perm()), MTimeUnix: fi.ModTime().Unix()}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		ch := newChunker(f)
+		for {
+			data, err := ch.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(data)
+			id := hex.EncodeToString(sum[:])
+			entry.Chunks = append(entry.Chunks, id)
+			entry.Size += int64(len(data))
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			written, err := r.writeChunk(id, data)
+			if err != nil {
+				return err
+			}
+			if written > 0 {
+				stats.NewChunks++
+				stats.StoredBytes += written
+			}
+		}
+		snap.Files = append(snap.Files, entry)
+		snap.Bytes += entry.Size
+		stats.Files++
+		if onProgress != nil && time.Since(lastEmit) >= 1*time.Second {
+			onProgress(ArchiveProgress{Files: stats.Files, Bytes: snap.Bytes})
+			lastEmit = time.Now()
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return SnapshotStats{}, walkErr
+	}
+	if onProgress != nil {
+		onProgress(ArchiveProgress{Files: stats.Files, Bytes: snap.Bytes})
+	}
+	stats.Bytes = snap.Bytes
+	stats.Chunks = len(seen)
+
+	refs, err := r.loadRefs()
+	if err != nil {
+		// First snapshot, or the counts are unreadable: write the manifest and rebuild the
+		// counts from all manifests. Until that finishes refs.json stays missing or damaged,
+		// so the next operation rebuilds them as well.
+		if err := writeJSONAtomic(manifestPath, snap); err != nil {
+			return SnapshotStats{}, err
+		}
+		if _, _, err := r.gcLocked(); err != nil {
+			return SnapshotStats{}, err
+		}
+		return stats, nil
+	}
+	for id := range seen {
+		refs.Refs[id]++
+	}
+	if err := writeJSONAtomic(r.refsPath(), refs); err != nil {
+		return SnapshotStats{}, err
+	}
+	if err := writeJSONAtomic(manifestPath, snap); err != nil {
+		return SnapshotStats{}, err
+	}
+	return stats, nil
+}
+
+// writeChunk stores a chunk unless it exists and returns the bytes written (0 if it
+// was already there). Chunks that compress are stored deflated.
+func (r *Repo) writeChunk(id string, data []byte) (int64, error) {
+	p := r.chunkPath(id)
+	if _, err := os.Stat(p); err == nil {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(chunkEncDeflate)
+	fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := fw.Close(); err != nil {
+		return 0, err
+	}
+	enc := buf.Bytes()
+	if len(enc) >= len(data)+1 {
+		enc = append([]byte{chunkEncRaw}, data...)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".chunk-*")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Write(enc); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	return int64(len(enc)), nil
+}
+
+// readChunk loads a chunk and verifies it against its id.
+func (r *Repo) readChunk(id string) ([]byte, error) {
+	enc, err := os.ReadFile(r.chunkPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", id, err)
+	}
+	if len(enc) == 0 {
+		return nil, fmt.Errorf("chunk %s: empty", id)
+	}
+	var data []byte
+	switch enc[0] {
+	case chunkEncRaw:
+		data = enc[1:]
+	case chunkEncDeflate:
+		fr := flate.NewReader(bytes.NewReader(enc[1:]))
+		data, err = io.ReadAll(io.LimitReader(fr, chunkMax+1))
+		_ = fr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s: %w", id, err)
+		}
+	default:
+		return nil, fmt.Errorf("chunk %s: unknown encoding %d", id, enc[0])
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != id {
+		return nil, fmt.Errorf("chunk %s: checksum mismatch", id)
+	}
+	return data, nil
+}
+
+// ReadSnapshot loads a snapshot manifest.
+func (r *Repo) ReadSnapshot(name string) (Snapshot, error) {
+	p, err := r.snapshotPath(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return readSnapshotFile(p)
+}
+
+func readSnapshotFile(p string) (Snapshot, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("invalid snapshot %s: %w", filepath.Base(p), err)
+	}
+	if snap.Version != repoVersion {
+		return Snapshot{}, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	return snap, nil
+}
+
+// Restore writes snapshot name into destDir and returns the number of files.
+// Every chunk is verified; entries that would escape destDir are rejected.
+func (r *Repo) Restore(name string, destDir string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snap, err := r.ReadSnapshot(name)
+	if err != nil {
+		return 0, err
+	}
+	destAbs, err := filepath.Abs(destDir)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(destAbs, 0o755); err != nil {
+		return 0, err
+	}
+	target := func(rel string) (string, error) {
+		clean := path.Clean(strings.TrimPrefix(strings.ReplaceAll(rel, "\\", "/"), "/"))
+		if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return "", errors.New("snapshot entry escapes destination")
+		}
+		out := filepath.Clean(filepath.Join(destAbs, filepath.FromSlash(clean)))
+		if !hasPathPrefix(out, destAbs) {
+			return "", errors.New("snapshot entry escapes destination")
+		}
+		return out, nil
+	}
+
+	for _, d := range snap.Dirs {
+		out, err := target(d)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.MkdirAll(out, 0o755); err != nil {
+			return 0, err
+		}
+	}
+	files := 0
+	for _, f := range snap.Files {
+		out, err := target(f.Path)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.restoreFile(f, out); err != nil {
+			return 0, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		files++
+	}
+	return files, nil
+}
+
+func (r *Repo) restoreFile(f SnapshotFile, out string) error {
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(f.Mode).Perm()
+	if mode == 0 {
+		mode = 0o644
+	}
+	dst, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	var written int64
+	for _, id := range f.Chunks {
+		data, err := r.readChunk(id)
+		if err != nil {
+			_ = dst.Close()
+			return err
+		}
+		n, err := dst.Write(data)
+		written += int64(n)
+		if err != nil {
+			_ = dst.Close()
+			return err
+		}
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if written != f.Size {
+		return fmt.Errorf("size mismatch: want=%d got=%d", f.Size, written)
+	}
+	_ = os.Chmod(out, mode)
+	if f.MTimeUnix > 0 {
+		mt := time.Unix(f.MTimeUnix, 0)
+		_ = os.Chtimes(out, mt, mt)
+	}
+	return nil
+}
+
+// Forget deletes snapshot name and every chunk no other snapshot references.
+// It returns the number of chunks removed and their on-disk size.
+func (r *Repo) Forget(name string) (int, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, err := r.snapshotPath(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	snap, readErr := readSnapshotFile(p)
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+	refs, err := r.loadRefs()
+	if readErr != nil || err != nil {
+		// Without the manifest or the counts, fall back to a full mark and sweep.
+		return r.gcLocked()
+	}
+
+	var unused []string
+	done := make(map[string]bool)
+	for _, f := range snap.Files {
+		for _, id := range f.Chunks {
+			if done[id] {
+				continue
+			}
+			done[id] = true
+			if refs.Refs[id]--; refs.Refs[id] > 0 {
+				continue
+			}
+			delete(refs.Refs, id)
+			unused = append(unused, id)
+		}
+	}
+	if len(unused) > 0 {
+		// The counts say these chunks are free; make sure no remaining manifest disagrees
+		// (e.g. refs.json restored from an older copy) before deleting anything.
+		live, err := r.referencedLocked(unused)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(live) > 0 {
+			return r.gcLocked()
+		}
+	}
+
+	removed := 0
+	var freed int64
+	for _, id := range unused {
+		if n, ok := removeChunk(r.chunkPath(id)); ok {
+			removed++
+			freed += n
+		}
+	}
+	if err := writeJSONAtomic(r.refsPath(), refs); err != nil {
+		return removed, freed, err
+	}
+	return removed, freed, nil
+}
+
+// GC rebuilds the reference counts from the snapshot manifests and removes chunks that
+// no snapshot references (e.g. after an interrupted backup or a deleted manifest).
+func (r *Repo) GC() (int, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.gcLocked()
+}
+
+func (r *Repo) gcLocked() (int, int64, error) {
+	names, err := r.Snapshots()
+	if err != nil {
+		return 0, 0, err
+	}
+	refs := repoRefs{Version: repoVersion, Refs: make(map[string]int)}
+	for _, name := range names {
+		snap, err := readSnapshotFile(filepath.Join(r.dir, name))
+		if err != nil {
+			// Keep everything rather than delete chunks a damaged manifest may need.
+			return 0, 0, err
+		}
+		seen := make(map[string]bool)
+		for _, f := range snap.Files {
+			for _, id := range f.Chunks {
+				if !seen[id] {
+					seen[id] = true
+					refs.Refs[id]++
+				}
+			}
+		}
+	}
+
+	removed := 0
+	var freed int64
+	chunksDir := filepath.Join(r.dir, repoDataDir, "chunks")
+	walkErr := filepath.WalkDir(chunksDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if refs.Refs[d.Name()] > 0 {
+			return nil
+		}
+		if n, ok := removeChunk(p); ok {
+			removed++
+			freed += n
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return removed, freed, walkErr
+	}
+	if err := writeJSONAtomic(r.refsPath(), refs); err != nil {
+		return removed, freed, err
+	}
+	return removed, freed, nil
+}
+
+// referencedLocked returns the ids among candidates that some snapshot manifest uses.
+func (r *Repo) referencedLocked(candidates []string) (map[string]bool, error) {
+	want := make(map[string]bool, len(candidates))
+	for _, id := range candidates {
+		want[id] = true
+	}
+	names, err := r.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool)
+	for _, name := range names {
+		snap, err := readSnapshotFile(filepath.Join(r.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range snap.Files {
+			for _, id := range f.Chunks {
+				if want[id] {
+					live[id] = true
+				}
+			}
+		}
+	}
+	return live, nil
+}
+
+func (r *Repo) refsPath() string {
+	return filepath.Join(r.dir, repoDataDir, repoRefsName)
+}
+
+// loadRefs reads the reference counts. Callers rebuild them with gcLocked when the
+// file is missing or damaged.
+func (r *Repo) loadRefs() (repoRefs, error) {
+	b, err := os.ReadFile(r.refsPath())
+	if err != nil {
+		return repoRefs{}, err
+	}
+	var refs repoRefs
+	if err := json.Unmarshal(b, &refs); err != nil || refs.Refs == nil {
+		return repoRefs{}, errors.New("invalid backup repo refs")
+	}
+	return refs, nil
+}
+
+func removeChunk(p string) (int64, bool) {
+	st, err := os.Stat(p)
+	if err != nil {
+		return 0, false
+	}
+	if err := os.Remove(p); err != nil {
+		return 0, false
+	}
+	return st.Size(), true
+}
+
+func writeJSONAtomic(p string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".partial"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
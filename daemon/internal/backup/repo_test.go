@@ -0,0 +1,209 @@
+package backup
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, p string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatalf("write %s: %v", p, err)
+	}
+}
+
+// newTestRepo returns a repository and an instance dir holding a 4 MiB region file.
+func newTestRepo(t *testing.T) (*Repo, string, []byte) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "server1")
+	region := make([]byte, 4<<20)
+	rand.New(rand.NewSource(1)).Read(region)
+	writeTestFile(t, filepath.Join(src, "world", "region", "r.0.0.mca"), region)
+	writeTestFile(t, filepath.Join(src, "server.properties"), []byte("server-port=25565\n"))
+	if err := os.MkdirAll(filepath.Join(src, "plugins"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	r, err := OpenRepo(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenRepo(): %v", err)
+	}
+	return r, src, region
+}
+
+func countChunks(t *testing.T, r *Repo) int {
+	t.Helper()
+	n := 0
+	_ = filepath.WalkDir(filepath.Join(r.Dir(), repoDataDir, "chunks"), func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+func assertRestores(t *testing.T, r *Repo, name string, region []byte) {
+	t.Helper()
+	dest := t.TempDir()
+	if _, err := r.Restore(name, dest); err != nil {
+		t.Fatalf("Restore(%s): %v", name, err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "world", "region", "r.0.0.mca"))
+	if err != nil || !bytes.Equal(got, region) {
+		t.Fatalf("Restore(%s): region differs (err=%v)", name, err)
+	}
+}
+
+func TestRepo_BackupDedupRestore(t *testing.T) {
+	r, src, region := newTestRepo(t)
+
+	s1, err := r.Backup(src, "s1.snapshot", nil)
+	if err != nil {
+		t.Fatalf("Backup(s1): %v", err)
+	}
+	if s1.Files != 2 || s1.NewChunks != s1.Chunks || s1.Bytes != int64(len(region))+18 {
+		t.Fatalf("unexpected s1 stats: %+v", s1)
+	}
+
+	edited := append([]byte(nil), region...)
+	copy(edited[2<<20:], "a block changed")
+	writeTestFile(t, filepath.Join(src, "world", "region", "r.0.0.mca"), edited)
+	s2, err := r.Backup(src, "s2.snapshot", nil)
+	if err != nil {
+		t.Fatalf("Backup(s2): %v", err)
+	}
+	if s2.NewChunks != 1 {
+		t.Fatalf("expected 1 new chunk, got %+v", s2)
+	}
+	if _, err := r.Backup(src, "s2.snapshot", nil); err == nil {
+		t.Fatalf("expected error for existing snapshot")
+	}
+
+	assertRestores(t, r, "s1.snapshot", region)
+	assertRestores(t, r, "s2.snapshot", edited)
+
+	dest := t.TempDir()
+	if _, err := r.Restore("s1.snapshot", dest); err != nil {
+		t.Fatalf("Restore(): %v", err)
+	}
+	if st, err := os.Stat(filepath.Join(dest, "plugins")); err != nil || !st.IsDir() {
+		t.Fatalf("empty dir not restored: %v", err)
+	}
+}
+
+func TestRepo_ForgetAndGC(t *testing.T) {
+	r, src, region := newTestRepo(t)
+	if _, err := r.Backup(src, "s1.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s1): %v", err)
+	}
+	writeTestFile(t, filepath.Join(src, "server.properties"), []byte("server-port=25566\n"))
+	if _, err := r.Backup(src, "s2.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s2): %v", err)
+	}
+	total := countChunks(t, r)
+
+	removed, freed, err := r.Forget("s1.snapshot")
+	if err != nil {
+		t.Fatalf("Forget(s1): %v", err)
+	}
+	if removed != 1 || freed <= 0 || countChunks(t, r) != total-1 {
+		t.Fatalf("Forget(s1): removed=%d freed=%d", removed, freed)
+	}
+	assertRestores(t, r, "s2.snapshot", region)
+
+	if removed, _, err := r.GC(); err != nil || removed != 0 {
+		t.Fatalf("GC(): removed=%d err=%v", removed, err)
+	}
+	if _, _, err := r.Forget("s2.snapshot"); err != nil {
+		t.Fatalf("Forget(s2): %v", err)
+	}
+	if n := countChunks(t, r); n != 0 {
+		t.Fatalf("%d chunks left after forgetting every snapshot", n)
+	}
+}
+
+func TestRepo_InterruptedBackupRecovery(t *testing.T) {
+	r, src, region := newTestRepo(t)
+	if _, err := r.Backup(src, "s1.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s1): %v", err)
+	}
+	refsAfterS1, err := os.ReadFile(r.refsPath())
+	if err != nil {
+		t.Fatalf("read refs: %v", err)
+	}
+	if _, err := r.Backup(src, "s2.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s2): %v", err)
+	}
+
+	// Counts from before s2 (an old copy of refs.json): forgetting s1 must not delete
+	// the chunks s2 still uses.
+	if err := os.WriteFile(r.refsPath(), refsAfterS1, 0o644); err != nil {
+		t.Fatalf("write refs: %v", err)
+	}
+	if _, _, err := r.Forget("s1.snapshot"); err != nil {
+		t.Fatalf("Forget(s1): %v", err)
+	}
+	assertRestores(t, r, "s2.snapshot", region)
+
+	// Crash after the counts were written but before the manifest: the counts are too
+	// high and the new chunk is orphaned. Nothing is lost and GC reclaims the space.
+	writeTestFile(t, filepath.Join(src, "world", "extra.dat"), []byte("only in s3"))
+	if _, err := r.Backup(src, "s3.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s3): %v", err)
+	}
+	if err := os.Remove(filepath.Join(r.Dir(), "s3.snapshot")); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	writeTestFile(t, filepath.Join(r.Dir(), repoDataDir, "chunks", "ab", ".chunk-123"), []byte("partial"))
+	if _, _, err := r.Forget("s2.snapshot"); err != nil {
+		t.Fatalf("Forget(s2): %v", err)
+	}
+	if countChunks(t, r) == 0 {
+		t.Fatalf("over-counted chunks should survive Forget")
+	}
+	if _, _, err := r.GC(); err != nil {
+		t.Fatalf("GC(): %v", err)
+	}
+	if n := countChunks(t, r); n != 0 {
+		t.Fatalf("%d chunks left after GC", n)
+	}
+
+	// Missing counts are rebuilt from the manifests.
+	if _, err := r.Backup(src, "s4.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s4): %v", err)
+	}
+	if err := os.Remove(r.refsPath()); err != nil {
+		t.Fatalf("remove refs: %v", err)
+	}
+	if _, err := r.Backup(src, "s5.snapshot", nil); err != nil {
+		t.Fatalf("Backup(s5): %v", err)
+	}
+	if _, _, err := r.Forget("s4.snapshot"); err != nil {
+		t.Fatalf("Forget(s4): %v", err)
+	}
+	assertRestores(t, r, "s5.snapshot", region)
+}
+
+func TestRepo_RestoreRejectsEscape(t *testing.T) {
+	r, _, _ := newTestRepo(t)
+	for _, p := range []string{"../evil.txt", "world/../../evil.txt", ".."} {
+		snap := Snapshot{Version: repoVersion, ID: "evil", Files: []SnapshotFile{{Path: p}}}
+		if err := writeJSONAtomic(filepath.Join(r.Dir(), "evil.snapshot"), snap); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+		base := t.TempDir()
+		dest := filepath.Join(base, "server1")
+		if _, err := r.Restore("evil.snapshot", dest); err == nil {
+			t.Fatalf("%q: expected escape error", p)
+		}
+		if _, err := os.Stat(filepath.Join(base, "evil.txt")); err == nil {
+			t.Fatalf("%q: file written outside destination", p)
+		}
+	}
+}
@@ -38,6 +38,36 @@ type PaperConfig struct {
 	APIBaseURL string
 }
 
+type PurpurConfig struct {
+	APIBaseURL string
+}
+
+type FabricConfig struct {
+	MetaBaseURL string
+}
+
+type QuiltConfig struct {
+	MetaBaseURL string
+}
+
+type ForgeConfig struct {
+	MavenBaseURL string
+}
+
+type CurseForgeConfig struct {
+	APIBaseURL string
+}
+
+type ModrinthConfig struct {
+	APIBaseURL string
+}
+
+// VersionCacheConfig configures the on-disk cache of mc_versions results.
+type VersionCacheConfig struct {
+	Dir string // empty disables the cache
+	TTL time.Duration
+}
+
 type ExecutorDeps struct {
 	Log                   *log.Logger
 	FS                    *sandbox.FS
@@ -47,9 +77,22 @@ type ExecutorDeps struct {
 	FRPC                  string
 	PreferredConnectAddrs []string
 	ScheduleFile          string
+	// ClientModDenylist is a local file extending the built-in client-only mod denylist.
+	ClientModDenylist string
 
 	Mojang MojangConfig
 	Paper  PaperConfig
+	Purpur PurpurConfig
+	Fabric FabricConfig
+	Quilt  QuiltConfig
+
+	Forge    ForgeConfig
+	NeoForge ForgeConfig
+
+	CurseForge CurseForgeConfig
+	Modrinth   ModrinthConfig
+
+	VersionCache VersionCacheConfig
 }
 
 type Executor struct {
@@ -65,9 +108,18 @@ type Executor struct {
 	duMu    sync.Mutex
 	duCache map[string]duCacheEntry
 
+	logs *logBuffer
+
 	procMu        sync.Mutex
-	procPrevTotal uint64
-	procPrevByPID map[int]uint64
+	procPrevTotal uint64 // system CPU time (usec)
+	procPrevByPID map[int]cpuSample
+
+	// Last "Done (...)!" console line per instance (used by mc_upgrade).
+	doneMu     sync.Mutex
+	serverDone map[string]time.Time
+
+	// upgradeMC replaces deps.MC for mc_upgrade when set (tests).
+	upgradeMC upgradeServers
 }
 
 var instanceIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
@@ -79,54 +131,114 @@ func NewExecutor(deps ExecutorDeps) *Executor {
 		ex.uploads = newUploadManager(deps.FS)
 	}
 	ex.duCache = make(map[string]duCacheEntry)
-	ex.procPrevByPID = make(map[int]uint64)
+	ex.procPrevByPID = make(map[int]cpuSample)
+	ex.serverDone = make(map[string]time.Time)
+	ex.logs = newLogBuffer()
+	if deps.MC != nil {
+		deps.MC.SetEventSink(ex.emitMCEvent)
+	}
 	return ex
 }
 
 func (e *Executor) mcTemplates() protocol.CommandResult {
-	return ok(map[string]any{
-		"templates": []any{
-			map[string]any{
-				"id":          "vanilla",
-				"name":        "Vanilla",
-				"supported":   true,
-				"install_cmd": "mc_install_vanilla",
-				"presets": map[string]any{
-					"jar_name":        "server.jar",
-					"xms":             "1G",
-					"xmx":             "2G",
-					"accept_eula":     true,
-					"enable_frp":      true,
-					"frp_remote_port": 0,
-				},
+	templates := []any{
+		map[string]any{
+			"id":          "vanilla",
+			"name":        "Vanilla",
+			"supported":   true,
+			"install_cmd": "mc_install_vanilla",
+			"presets": map[string]any{
+				"jar_name":        "server.jar",
+				"xms":             "1G",
+				"xmx":             "2G",
+				"accept_eula":     true,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
+			},
+		},
+	}
+	// Single-jar software comes from the installer registry and installs via mc_install.
+	for _, sw := range mcinstall.Softwares() {
+		xms, xmx := "1G", "2G"
+		if sw.Proxy {
+			xms, xmx = "512M", "1G"
+		}
+		templates = append(templates, map[string]any{
+			"id":          sw.ID,
+			"name":        sw.Name,
+			"supported":   true,
+			"proxy":       sw.Proxy,
+			"install_cmd": "mc_install",
+			"presets": map[string]any{
+				"software":        sw.ID,
+				"jar_name":        "server.jar",
+				"xms":             xms,
+				"xmx":             xmx,
+				"accept_eula":     !sw.Proxy,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
+			},
+		})
+	}
+	templates = append(templates,
+		map[string]any{
+			"id":          "fabric",
+			"name":        "Fabric",
+			"supported":   true,
+			"install_cmd": "mc_install_fabric",
+			"presets": map[string]any{
+				"jar_name":        "fabric-server-launch.jar",
+				"xms":             "1G",
+				"xmx":             "2G",
+				"accept_eula":     true,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
 			},
-			map[string]any{
-				"id":          "paper",
-				"name":        "Paper",
-				"supported":   true,
-				"install_cmd": "mc_install_paper",
-				"presets": map[string]any{
-					"jar_name":        "server.jar",
-					"xms":             "1G",
-					"xmx":             "2G",
-					"accept_eula":     true,
-					"enable_frp":      true,
-					"frp_remote_port": 0,
-				},
+		},
+		map[string]any{
+			"id":          "forge",
+			"name":        "Forge",
+			"supported":   true,
+			"install_cmd": "mc_install_forge",
+			"presets": map[string]any{
+				"jar_name":        mc.LaunchDescriptorName,
+				"xms":             "2G",
+				"xmx":             "4G",
+				"accept_eula":     true,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
 			},
-			map[string]any{
-				"id":        "fabric",
-				"name":      "Fabric",
-				"supported": false,
-				"note":      "placeholder (not built-in yet). Use Modpack ZIP / upload your server jar.",
-				"presets": map[string]any{
-					"jar_name": "server.jar",
-					"xms":      "1G",
-					"xmx":      "2G",
-				},
+		},
+		map[string]any{
+			"id":          "neoforge",
+			"name":        "NeoForge",
+			"supported":   true,
+			"install_cmd": "mc_install_neoforge",
+			"presets": map[string]any{
+				"jar_name":        mc.LaunchDescriptorName,
+				"xms":             "2G",
+				"xmx":             "4G",
+				"accept_eula":     true,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
 			},
 		},
-	})
+		map[string]any{
+			"id":          "quilt",
+			"name":        "Quilt",
+			"supported":   true,
+			"install_cmd": "mc_install_quilt",
+			"presets": map[string]any{
+				"jar_name":        "quilt-server-launch.jar",
+				"xms":             "1G",
+				"xmx":             "2G",
+				"accept_eula":     true,
+				"enable_frp":      true,
+				"frp_remote_port": 0,
+			},
+		},
+	)
+	return ok(map[string]any{"templates": templates})
 }
 
 func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
@@ -143,8 +255,8 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 
 	format, _ := asString(cmd.Args["format"])
 	format = strings.TrimSpace(strings.ToLower(format))
-	if format != "" && format != "zip" && format != "tar.gz" && format != "tgz" {
-		return fail("format must be zip or tar.gz")
+	if format != "" && format != "zip" && format != "tar.gz" && format != "tgz" && format != "repo" {
+		return fail("format must be zip, tar.gz or repo")
 	}
 
 	backupName, _ := asString(cmd.Args["backup_name"])
@@ -158,6 +270,8 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 		if format == "tar.gz" || format == "tgz" {
 			backupName = fmt.Sprintf("%s-%d.tar.gz", instanceID, timeNowUnix())
 			format = "tar.gz"
+		} else if format == "repo" {
+			backupName = fmt.Sprintf("%s-%d%s", instanceID, timeNowUnix(), backup.SnapshotExt)
 		} else {
 			backupName = fmt.Sprintf("%s-%d.zip", instanceID, timeNowUnix())
 			if format == "" {
@@ -176,12 +290,18 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 		lower := strings.ToLower(backupName)
 		if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
 			format = "tar.gz"
+		} else if backup.IsSnapshotPath(lower) {
+			format = "repo"
 		} else {
 			format = "zip"
 		}
 	}
 	useTarGz := format == "tar.gz" || format == "tgz"
-	if useTarGz {
+	if format == "repo" {
+		if !backup.IsSnapshotPath(backupName) {
+			backupName += backup.SnapshotExt
+		}
+	} else if useTarGz {
 		lower := strings.ToLower(backupName)
 		if !strings.HasSuffix(lower, ".tar.gz") && !strings.HasSuffix(lower, ".tgz") {
 			backupName += ".tar.gz"
@@ -223,8 +343,30 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 
 	files := 0
 	var bytes int64
+	var snap backup.SnapshotStats
 	createdAtUnix := timeNowUnix()
-	if useTarGz {
+	if format == "repo" {
+		repo, err := backup.OpenRepo(filepath.Dir(destAbs))
+		if err != nil {
+			return fail(err.Error())
+		}
+		last := time.Now()
+		e.emitInstall(instanceID, fmt.Sprintf("backup: snapshot %s -> %s", instanceID, destRel))
+		snap, err = repo.Backup(srcAbs, backupName, func(p backup.ArchiveProgress) {
+			if time.Since(last) < 1*time.Second {
+				return
+			}
+			last = time.Now()
+			e.emitInstall(instanceID, fmt.Sprintf("backup progress: files=%d bytes=%d", p.Files, p.Bytes))
+		})
+		if err != nil {
+			return fail(err.Error())
+		}
+		files = snap.Files
+		bytes = snap.Bytes
+		e.emitInstall(instanceID, fmt.Sprintf("backup done: %d files (%d bytes), %d/%d chunks new (%d bytes stored) -> %s",
+			files, bytes, snap.NewChunks, snap.Chunks, snap.StoredBytes, destRel))
+	} else if useTarGz {
 		last := time.Now()
 		e.emitInstall(instanceID, fmt.Sprintf("backup: tar.gz %s -> %s", instanceID, destRel))
 		n, b, err := backup.TarGzDir(srcAbs, destAbs, func(p backup.ArchiveProgress) {
@@ -250,15 +392,15 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 		e.emitInstall(instanceID, fmt.Sprintf("backup done: %d files -> %s", files, destRel))
 	}
 
-	// Best-effort file size (zip doesn't report bytes).
-	if st, err := os.Stat(destAbs); err == nil && st != nil && st.Size() > 0 {
+	// Best-effort file size (zip doesn't report bytes). Snapshots keep the logical size.
+	if st, err := os.Stat(destAbs); err == nil && st != nil && st.Size() > 0 && format != "repo" {
 		bytes = st.Size()
 	}
 
 	// Best-effort metadata sidecar for panel restore points view.
 	{
 		meta := map[string]any{
-			"schema":          1,
+			"schema":          2,
 			"instance_id":     instanceID,
 			"path":            destRel,
 			"backup_name":     backupName,
@@ -268,6 +410,15 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 			"bytes":           bytes,
 			"comment":         comment,
 		}
+		if format == "repo" {
+			meta["repo"] = map[string]any{
+				"dir":          filepath.ToSlash(filepath.Dir(destRel)),
+				"snapshot_id":  snap.ID,
+				"chunks":       snap.Chunks,
+				"new_chunks":   snap.NewChunks,
+				"stored_bytes": snap.StoredBytes,
+			}
+		}
 		if b, err := json.MarshalIndent(meta, "", "  "); err == nil {
 			b = append(b, '\n')
 			_ = os.WriteFile(destAbs+".meta.json", b, 0o600)
@@ -287,6 +438,11 @@ func (e *Executor) mcBackup(ctx context.Context, cmd protocol.Command) protocol.
 	}
 	out := map[string]any{"instance_id": instanceID, "path": destRel, "files": files, "format": format}
 	out["bytes"] = bytes
+	if format == "repo" {
+		out["snapshot_id"] = snap.ID
+		out["new_chunks"] = snap.NewChunks
+		out["stored_bytes"] = snap.StoredBytes
+	}
 	return ok(out)
 }
 
@@ -310,6 +466,16 @@ func (e *Executor) mcRestore(ctx context.Context, cmd protocol.Command) protocol
 	if err != nil {
 		return fail(err.Error())
 	}
+	var repo *backup.Repo
+	if backup.IsSnapshotPath(zipRel) {
+		// Check the manifest before the instance dir is wiped.
+		if repo, err = backup.OpenRepo(filepath.Dir(zipAbs)); err != nil {
+			return fail(err.Error())
+		}
+		if _, err := repo.ReadSnapshot(filepath.Base(zipAbs)); err != nil {
+			return fail(err.Error())
+		}
+	}
 
 	// Stop instance (best-effort).
 	_ = e.deps.MC.Stop(ctx, instanceID)
@@ -330,7 +496,9 @@ func (e *Executor) mcRestore(ctx context.Context, cmd protocol.Command) protocol
 	e.emitInstall(instanceID, fmt.Sprintf("restore: %s -> %s", zipRel, instanceID))
 	var files int
 	lower := strings.ToLower(zipRel)
-	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+	if repo != nil {
+		files, err = repo.Restore(filepath.Base(zipAbs), instAbs)
+	} else if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
 		files, err = backup.UntarGzToDir(zipAbs, instAbs)
 	} else {
 		files, err = backup.UnzipToDir(zipAbs, instAbs)
@@ -342,6 +510,18 @@ func (e *Executor) mcRestore(ctx context.Context, cmd protocol.Command) protocol
 	return ok(map[string]any{"instance_id": instanceID, "restored": true, "files": files})
 }
 
+// clockTickUsec converts /proc clock ticks (USER_HZ, 100 on Linux) to microseconds, the
+// unit of cgroup cpu.stat.
+const clockTickUsec = 10000
+
+// cpuSample is the CPU time of an instance and where it came from: the cgroup (which
+// includes child processes) or /proc of the server PID. Samples from different sources
+// are not compared.
+type cpuSample struct {
+	usec   uint64
+	cgroup bool
+}
+
 func (e *Executor) HeartbeatSnapshot() protocol.Heartbeat {
 	var hb protocol.Heartbeat
 
@@ -416,26 +596,44 @@ func (e *Executor) HeartbeatSnapshot() protocol.Heartbeat {
 
 	// MC instances
 	instances := e.deps.MC.List()
-	ticksByPID := make(map[int]uint64)
+	cpuSamples := make(map[int]cpuSample)
 	memByPID := make(map[int]uint64)
+	memLimitByPID := make(map[int]uint64)
 	for _, st := range instances {
 		if !st.Running || st.PID <= 0 {
 			continue
 		}
-		if ticks, err := sysinfo.ReadProcCPUTicks(st.PID); err == nil {
-			ticksByPID[st.PID] = ticks
+		// Prefer cgroup accounting (covers child processes); fall back to /proc.
+		if st.CgroupPath != "" {
+			if usec, err := sysinfo.ReadCgroupCPUUsageUsec(st.CgroupPath); err == nil {
+				cpuSamples[st.PID] = cpuSample{usec: usec, cgroup: true}
+			}
+			if cur, err := sysinfo.ReadCgroupMemoryCurrent(st.CgroupPath); err == nil {
+				memByPID[st.PID] = cur
+			}
+			if max, err := sysinfo.ReadCgroupMemoryMax(st.CgroupPath); err == nil && max > 0 {
+				memLimitByPID[st.PID] = max
+			}
+		}
+		if _, ok := cpuSamples[st.PID]; !ok {
+			if ticks, err := sysinfo.ReadProcCPUTicks(st.PID); err == nil {
+				cpuSamples[st.PID] = cpuSample{usec: ticks * clockTickUsec}
+			}
 		}
-		if rss, err := sysinfo.ReadProcRSSBytes(st.PID); err == nil {
-			memByPID[st.PID] = rss
+		if _, ok := memByPID[st.PID]; !ok {
+			if rss, err := sysinfo.ReadProcRSSBytes(st.PID); err == nil {
+				memByPID[st.PID] = rss
+			}
 		}
 	}
 	cpuByPID := make(map[int]float64)
-	if total, _, err := sysinfo.ReadCPUTicks(); err == nil && total > 0 {
+	if totalTicks, _, err := sysinfo.ReadCPUTicks(); err == nil && totalTicks > 0 {
+		total := totalTicks * clockTickUsec
 		e.procMu.Lock()
 		prevTotal := e.procPrevTotal
 		if prevTotal == 0 {
 			e.procPrevTotal = total
-			for pid, cur := range ticksByPID {
+			for pid, cur := range cpuSamples {
 				e.procPrevByPID[pid] = cur
 			}
 		} else {
@@ -444,13 +642,13 @@ func (e *Executor) HeartbeatSnapshot() protocol.Heartbeat {
 				deltaTotal = total - prevTotal
 			}
 			e.procPrevTotal = total
-			for pid, cur := range ticksByPID {
+			for pid, cur := range cpuSamples {
 				prev, ok := e.procPrevByPID[pid]
 				e.procPrevByPID[pid] = cur
-				if !ok || deltaTotal == 0 || cur < prev {
+				if !ok || prev.cgroup != cur.cgroup || deltaTotal == 0 || cur.usec < prev.usec {
 					continue
 				}
-				cpu := float64(cur-prev) * 100 / float64(deltaTotal)
+				cpu := float64(cur.usec-prev.usec) * 100 / float64(deltaTotal)
 				if cpu < 0 {
 					cpu = 0
 				}
@@ -460,7 +658,7 @@ func (e *Executor) HeartbeatSnapshot() protocol.Heartbeat {
 				cpuByPID[pid] = cpu
 			}
 			for pid := range e.procPrevByPID {
-				if _, ok := ticksByPID[pid]; ok {
+				if _, ok := cpuSamples[pid]; ok {
 					continue
 				}
 				delete(e.procPrevByPID, pid)
@@ -485,18 +683,51 @@ func (e *Executor) HeartbeatSnapshot() protocol.Heartbeat {
 			val := v
 			memRSSBytes = &val
 		}
+		var memLimitBytes *uint64
+		if v, ok := memLimitByPID[st.PID]; ok {
+			val := v
+			memLimitBytes = &val
+		}
+		var ping *protocol.MCPing
+		if st.Ping != nil {
+			ping = &protocol.MCPing{
+				VersionName:   st.Ping.VersionName,
+				Protocol:      st.Ping.Protocol,
+				OnlinePlayers: st.Ping.Online,
+				MaxPlayers:    st.Ping.Max,
+				MOTD:          st.Ping.MOTD,
+				LatencyMs:     st.Ping.Latency.Milliseconds(),
+				Legacy:        st.Ping.Legacy,
+				CheckedUnix:   st.Ping.CheckedUnix,
+			}
+			for _, p := range st.Ping.Sample {
+				ping.PlayersSample = append(ping.PlayersSample, protocol.MCPingPlayer{Name: p.Name, ID: p.ID})
+			}
+		}
 		hb.Instances = append(hb.Instances, protocol.MCInstance{
 			ID:                id,
 			Running:           st.Running,
 			PID:               st.PID,
 			CPUPercent:        cpuPercent,
 			MemRSSBytes:       memRSSBytes,
+			MemLimitBytes:     memLimitBytes,
+			Cgroup:            st.CgroupPath != "",
 			Java:              st.Java,
 			JavaMajor:         st.JavaMajor,
 			RequiredJavaMajor: st.RequiredJavaMajor,
 			LastExitCode:      st.LastExitCode,
 			LastExitSignal:    st.LastExitSignal,
 			LastExitUnix:      st.LastExitUnix,
+			RestartPolicy:     st.RestartPolicy,
+			RestartCount:      st.RestartCount,
+			NextRestartUnix:   st.NextRestartUnix,
+			RestartGaveUp:     st.RestartGaveUp,
+			Adopted:           st.Adopted,
+			JvmProfile:        st.JvmProfile,
+			CommandLine:       st.CommandLine,
+			Ready:             st.Ready,
+			ReadyUnix:         st.ReadyUnix,
+			Ping:              ping,
 		})
 	}
 
@@ -524,12 +755,26 @@ func (e *Executor) Execute(ctx context.Context, cmd protocol.Command) protocol.C
 		return e.mcJavaCacheList(cmd)
 	case "mc_java_cache_remove":
 		return e.mcJavaCacheRemove(cmd)
+	case "java_list":
+		return e.javaList(ctx, cmd)
+	case "java_import":
+		return e.javaImport(ctx, cmd)
 	case "mc_backup":
 		return e.mcBackup(ctx, cmd)
 	case "mc_backup_prune":
 		return e.mcBackupPrune(cmd)
 	case "mc_restore":
 		return e.mcRestore(ctx, cmd)
+	case "mc_upgrade":
+		return e.mcUpgrade(ctx, cmd)
+	case "mc_mods_list":
+		return e.mcModsList(ctx, cmd)
+	case "mc_mods_set_enabled":
+		return e.mcModsSetEnabled(cmd)
+	case "mc_mods_update":
+		return e.mcModsUpdate(ctx, cmd)
+	case "mc_mods_client_only":
+		return e.mcModsClientOnly(ctx, cmd)
 	case "schedule_get":
 		return e.scheduleGet(cmd)
 	case "schedule_set":
@@ -583,7 +828,23 @@ func (e *Executor) Execute(ctx context.Context, cmd protocol.Command) protocol.C
 	case "mc_install_vanilla":
 		return e.mcInstallVanilla(ctx, cmd)
 	case "mc_install_paper":
-		return e.mcInstallPaper(ctx, cmd)
+		return e.mcInstallJar(ctx, cmd, "paper")
+	case "mc_install":
+		return e.mcInstall(ctx, cmd)
+	case "mc_versions":
+		return e.mcVersions(ctx, cmd)
+	case "mc_install_mrpack":
+		return e.mcInstallMrpack(ctx, cmd)
+	case "mc_install_curseforge":
+		return e.mcInstallCurseForge(ctx, cmd)
+	case "mc_install_fabric":
+		return e.mcInstallLoader(ctx, cmd, "fabric")
+	case "mc_install_quilt":
+		return e.mcInstallLoader(ctx, cmd, "quilt")
+	case "mc_install_forge":
+		return e.mcInstallForge(ctx, cmd, "forge")
+	case "mc_install_neoforge":
+		return e.mcInstallForge(ctx, cmd, "neoforge")
 	case "mc_start":
 		return e.mcStart(ctx, cmd)
 	case "mc_restart":
@@ -594,6 +855,10 @@ func (e *Executor) Execute(ctx context.Context, cmd protocol.Command) protocol.C
 		return e.mcDelete(ctx, cmd)
 	case "mc_console":
 		return e.mcConsole(ctx, cmd)
+	case "mc_rcon":
+		return e.mcRcon(ctx, cmd)
+	case "log_tail":
+		return e.logTail(ctx, cmd)
 	case "frp_start":
 		return e.frpStart(ctx, cmd)
 	case "frp_stop":
@@ -739,73 +1004,6 @@ func (e *Executor) mcInstallVanilla(ctx context.Context, cmd protocol.Command) p
 	})
 }
 
-func (e *Executor) mcInstallPaper(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
-	instanceID, _ := asString(cmd.Args["instance_id"])
-	version, _ := asString(cmd.Args["version"])
-	jarName, _ := asString(cmd.Args["jar_name"])
-	build, _ := asInt(cmd.Args["build"])
-	acceptEULA, _ := asBool(cmd.Args["accept_eula"])
-
-	if strings.TrimSpace(instanceID) == "" {
-		return fail("instance_id is required")
-	}
-	if err := validateInstanceID(instanceID); err != nil {
-		return fail(err.Error())
-	}
-	if strings.TrimSpace(version) == "" {
-		return fail("version is required")
-	}
-	if strings.TrimSpace(jarName) == "" {
-		jarName = "server.jar"
-	}
-	if err := validateJarName(jarName); err != nil {
-		return fail(err.Error())
-	}
-
-	targetRel := filepath.Join(instanceID, jarName)
-	targetAbs, err := e.deps.FS.Resolve(targetRel)
-	if err != nil {
-		return fail(err.Error())
-	}
-
-	e.emitInstall(instanceID, fmt.Sprintf("resolve paper version=%s build=%d", version, build))
-	resolved, err := mcinstall.ResolvePaperJar(ctx, e.deps.Paper.APIBaseURL, version, build)
-	if err != nil {
-		return fail(err.Error())
-	}
-
-	e.emitInstall(instanceID, fmt.Sprintf("download paper jar -> %s", targetRel))
-	dl, err := download.DownloadFileWithChecksumsProgress(ctx, resolved.URL, targetAbs, resolved.SHA256, "", func(p download.Progress) {
-		if p.Total > 0 {
-			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
-		} else {
-			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
-		}
-	})
-	if err != nil {
-		return fail(err.Error())
-	}
-	e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha256=%s", dl.Bytes, dl.SHA256))
-
-	if acceptEULA {
-		if err := e.writeEULA(instanceID); err != nil {
-			return fail(err.Error())
-		}
-		e.emitInstall(instanceID, "wrote eula.txt (accepted)")
-	}
-
-	return ok(map[string]any{
-		"instance_id": instanceID,
-		"version":     resolved.Version,
-		"build":       resolved.Build,
-		"jar_path":    jarName,
-		"path":        targetRel,
-		"url":         resolved.URL,
-		"sha256":      resolved.SHA256,
-		"bytes":       dl.Bytes,
-	})
-}
-
 func (e *Executor) fsRead(cmd protocol.Command) protocol.CommandResult {
 	path, _ := asString(cmd.Args["path"])
 	if strings.TrimSpace(path) == "" {
@@ -989,6 +1187,10 @@ func (e *Executor) fsUnzip(ctx context.Context, cmd protocol.Command) protocol.C
 	if e.deps.FS == nil {
 		return fail("servers filesystem not configured")
 	}
+	clientMods, err := parseClientModsMode(cmd.Args["client_mods"], "report")
+	if err != nil {
+		return fail(err.Error())
+	}
 
 	zipAbs, err := e.deps.FS.Resolve(zipPath)
 	if err != nil {
@@ -1043,6 +1245,7 @@ func (e *Executor) fsUnzip(ctx context.Context, cmd protocol.Command) protocol.C
 	}
 
 	var files, dirs int
+	var modJars []string
 	for _, f := range zr.File {
 		select {
 		case <-ctx.Done():
@@ -1115,22 +1318,46 @@ func (e *Executor) fsUnzip(ctx context.Context, cmd protocol.Command) protocol.C
 			return fail(copyErr.Error())
 		}
 		files++
+		if enabled, isJar := modJarState(path.Base(clean)); isJar && enabled && path.Base(path.Dir(clean)) == "mods" {
+			modJars = append(modJars, clean)
+		}
 	}
 
 	e.emitInstall(instanceID, fmt.Sprintf("unzip done: files=%d dirs=%d", files, dirs))
-	return ok(map[string]any{"zip_path": zipPath, "dest_dir": destDir, "files": files, "dirs": dirs})
+	out := map[string]any{"zip_path": zipPath, "dest_dir": destDir, "files": files, "dirs": dirs}
+	if len(modJars) > 0 {
+		// The files are already extracted: a failed scan is reported, not fatal.
+		clientOnly, err := e.scanClientOnlyMods(ctx, instanceID, destDir, modJars, clientMods == "disable")
+		if err != nil {
+			e.emitInstall(instanceID, fmt.Sprintf("unzip: check client-only mods failed: %v", err))
+			out["client_only_mods_error"] = err.Error()
+		} else {
+			out["client_only_mods"] = clientOnly
+		}
+	}
+	return ok(out)
 }
 
 func (e *Executor) mcStart(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
 	instanceID, _ := asString(cmd.Args["instance_id"])
 	jarPath, _ := asString(cmd.Args["jar_path"])
 	javaPath, _ := asString(cmd.Args["java_path"])
+	javaVendor, _ := asString(cmd.Args["java_vendor"])
+	jvmProfile, _ := asString(cmd.Args["jvm_profile"])
 	xms, _ := asString(cmd.Args["xms"])
 	xmx, _ := asString(cmd.Args["xmx"])
 	jvmArgs, _ := asStringSlice(cmd.Args["jvm_args"])
 	if err := validateInstanceID(instanceID); err != nil {
 		return fail(err.Error())
 	}
+	var restart *mc.RestartPolicy
+	if raw, ok := cmd.Args["restart_policy"]; ok && raw != nil {
+		p, err := parseRestartPolicy(raw)
+		if err != nil {
+			return fail(err.Error())
+		}
+		restart = &p
+	}
 
 	err := e.deps.MC.Start(ctx, mc.StartOptions{
 		InstanceID: instanceID,
@@ -1139,18 +1366,55 @@ func (e *Executor) mcStart(ctx context.Context, cmd protocol.Command) protocol.C
 		Xms:        xms,
 		Xmx:        xmx,
 		JvmArgs:    jvmArgs,
-	}, func(instID, stream, line string) {
-		e.emitLog(protocol.LogLine{
-			Source:   "mc",
-			Stream:   stream,
-			Instance: instID,
-			Line:     line,
-		})
-	})
+		JavaVendor: javaVendor,
+		JvmProfile: jvmProfile,
+		Restart:    restart,
+	}, e.mcLogSink)
 	if err != nil {
 		return fail(err.Error())
 	}
-	return ok(map[string]any{"instance_id": instanceID})
+	st := e.deps.MC.List()[instanceID]
+	return ok(map[string]any{"instance_id": instanceID, "jvm_profile": st.JvmProfile, "command_line": st.CommandLine})
+}
+
+func (e *Executor) mcLogSink(instID, stream, line string) {
+	e.emitLog(protocol.LogLine{
+		Source:   "mc",
+		Stream:   stream,
+		Instance: instID,
+		Line:     line,
+	})
+}
+
+// AdoptInstances re-attaches Minecraft servers that survived a daemon restart.
+func (e *Executor) AdoptInstances(ctx context.Context) {
+	if e.deps.MC == nil {
+		return
+	}
+	ids := e.deps.MC.Adopt(ctx, e.mcLogSink)
+	if len(ids) > 0 && e.deps.Log != nil {
+		e.deps.Log.Printf("mc: adopted %d running instance(s): %s", len(ids), strings.Join(ids, ", "))
+	}
+}
+
+// parseRestartPolicy accepts either a mode string ("on-failure") or a full policy object.
+func parseRestartPolicy(v any) (mc.RestartPolicy, error) {
+	var p mc.RestartPolicy
+	switch t := v.(type) {
+	case string:
+		p.Mode = t
+	case map[string]any:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return mc.RestartPolicy{}, err
+		}
+		if err := json.Unmarshal(b, &p); err != nil {
+			return mc.RestartPolicy{}, errors.New("invalid restart_policy")
+		}
+	default:
+		return mc.RestartPolicy{}, errors.New("restart_policy must be a string or object")
+	}
+	return p.Normalize()
 }
 
 func (e *Executor) mcRestart(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
@@ -1162,8 +1426,24 @@ func (e *Executor) mcRestart(ctx context.Context, cmd protocol.Command) protocol
 		return fail(err.Error())
 	}
 
+	stopOpt, err := e.parseStopOptions(instanceID, cmd.Args)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if stopOpt.WarnSec > 0 {
+		// The countdown can take up to an hour: run it in the background and report the
+		// outcome through the install log.
+		go func() {
+			bg := context.WithoutCancel(ctx)
+			_ = e.deps.MC.StopWithOptions(bg, instanceID, stopOpt)
+			if res := e.mcStart(bg, cmd); !res.OK {
+				e.emitInstall(instanceID, "[stop] restart failed: "+res.Error)
+			}
+		}()
+		return ok(map[string]any{"instance_id": instanceID, "scheduled": true, "warn_sec": stopOpt.WarnSec})
+	}
 	// Best-effort stop.
-	_ = e.deps.MC.Stop(ctx, instanceID)
+	_ = e.deps.MC.StopWithOptions(ctx, instanceID, stopOpt)
 	return e.mcStart(ctx, cmd)
 }
 
@@ -1175,12 +1455,75 @@ func (e *Executor) mcStop(ctx context.Context, cmd protocol.Command) protocol.Co
 	if err := validateInstanceID(instanceID); err != nil {
 		return fail(err.Error())
 	}
-	if err := e.deps.MC.Stop(ctx, instanceID); err != nil {
+	stopOpt, err := e.parseStopOptions(instanceID, cmd.Args)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if stopOpt.WarnSec > 0 {
+		// Don't hold the command open for the countdown; progress goes to the install log.
+		go func() {
+			if err := e.deps.MC.StopWithOptions(context.WithoutCancel(ctx), instanceID, stopOpt); err != nil {
+				e.emitInstall(instanceID, "[stop] failed: "+err.Error())
+			}
+		}()
+		return ok(map[string]any{"instance_id": instanceID, "scheduled": true, "warn_sec": stopOpt.WarnSec})
+	}
+	if err := e.deps.MC.StopWithOptions(ctx, instanceID, stopOpt); err != nil {
 		return fail(err.Error())
 	}
 	return ok(map[string]any{"instance_id": instanceID})
 }
 
+// parseStopOptions reads graceful stop args shared by mc_stop and mc_restart.
+func (e *Executor) parseStopOptions(instanceID string, args map[string]any) (mc.StopOptions, error) {
+	opt := mc.DefaultStopOptions()
+	if v, ok := args["warn_sec"]; ok {
+		n, err := asInt(v)
+		if err != nil || n < 0 || n > 3600 {
+			return opt, errors.New("warn_sec must be 0-3600")
+		}
+		opt.WarnSec = n
+	}
+	if v, ok := args["warn_intervals"]; ok {
+		list, ok := v.([]any)
+		if !ok {
+			return opt, errors.New("warn_intervals must be an array of seconds")
+		}
+		for _, it := range list {
+			n, err := asInt(it)
+			if err != nil || n <= 0 {
+				return opt, errors.New("warn_intervals must be an array of seconds")
+			}
+			opt.WarnIntervals = append(opt.WarnIntervals, n)
+		}
+	}
+	if v, ok := asString(args["warn_message"]); ok {
+		opt.WarnMessage = v
+	}
+	if v, ok := asBool(args["warn_tellraw"]); ok {
+		opt.Tellraw = v
+	}
+	if v, ok := asBool(args["save_all"]); ok {
+		opt.SaveAll = v
+	}
+	if v, ok := args["timeout_sec"]; ok {
+		n, err := asInt(v)
+		if err != nil || n < 1 || n > 3600 {
+			return opt, errors.New("timeout_sec must be 1-3600")
+		}
+		opt.TimeoutSec = n
+	}
+	if v, ok := args["term_timeout_sec"]; ok {
+		n, err := asInt(v)
+		if err != nil || n < 1 || n > 600 {
+			return opt, errors.New("term_timeout_sec must be 1-600")
+		}
+		opt.TermTimeoutSec = n
+	}
+	opt.Progress = func(line string) { e.emitInstall(instanceID, "[stop] "+line) }
+	return opt, nil
+}
+
 func (e *Executor) mcDelete(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
 	instanceID, _ := asString(cmd.Args["instance_id"])
 	if strings.TrimSpace(instanceID) == "" {
@@ -1192,6 +1535,10 @@ func (e *Executor) mcDelete(ctx context.Context, cmd protocol.Command) protocol.
 	if err := e.deps.MC.Delete(ctx, instanceID); err != nil {
 		return fail(err.Error())
 	}
+	e.logs.dropInstance(instanceID)
+	e.doneMu.Lock()
+	delete(e.serverDone, instanceID)
+	e.doneMu.Unlock()
 	return ok(map[string]any{"instance_id": instanceID, "deleted": true})
 }
 
@@ -1213,6 +1560,39 @@ func (e *Executor) mcConsole(ctx context.Context, cmd protocol.Command) protocol
 	return ok(map[string]any{"instance_id": instanceID})
 }
 
+func (e *Executor) mcRcon(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	command, _ := asString(cmd.Args["command"])
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return fail("command is required")
+	}
+	if strings.ContainsAny(command, "\r\n") {
+		return fail("command must be single-line")
+	}
+	timeoutSec := 10
+	if n, err := asInt(cmd.Args["timeout_sec"]); err == nil && n > 0 {
+		if n > 120 {
+			n = 120
+		}
+		timeoutSec = n
+	}
+	rctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	resp, err := e.deps.MC.RCONCommand(rctx, instanceID, strings.TrimPrefix(command, "/"))
+	if err != nil {
+		return fail(err.Error())
+	}
+	return ok(map[string]any{"instance_id": instanceID, "command": command, "response": resp})
+}
+
 func (e *Executor) frpStart(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
 	var proxy frp.ProxyConfig
 	var err error
@@ -1275,6 +1655,8 @@ func (e *Executor) frpStop(ctx context.Context, cmd protocol.Command) protocol.C
 }
 
 func (e *Executor) emitLog(line protocol.LogLine) {
+	// Buffer first so lines are not lost while the panel is disconnected.
+	line = e.logs.add(line)
 	if e.send == nil {
 		return
 	}
@@ -1286,6 +1668,53 @@ func (e *Executor) emitLog(line protocol.LogLine) {
 	})
 }
 
+func (e *Executor) emitMCEvent(instanceID string, ev mc.ConsoleEvent) {
+	if ev.Kind == mc.EventServerDone {
+		e.doneMu.Lock()
+		e.serverDone[instanceID] = time.Now()
+		e.doneMu.Unlock()
+	}
+	if e.send == nil {
+		return
+	}
+	payload, _ := jsonMarshal(protocol.Event{
+		Source:       "mc",
+		Instance:     instanceID,
+		Kind:         ev.Kind,
+		Stream:       ev.Stream,
+		Line:         ev.Line,
+		Player:       ev.Player,
+		UUID:         ev.UUID,
+		Message:      ev.Message,
+		StartupMs:    ev.StartupMs,
+		BehindMs:     ev.BehindMs,
+		SkippedTicks: ev.SkippedTicks,
+		Exception:    ev.Exception,
+		StackTrace:   ev.StackTrace,
+	})
+	e.send(protocol.Message{
+		Type:    "event",
+		TSUnix:  timeNowUnix(),
+		Payload: payload,
+	})
+}
+
+// serverDoneSince returns when the instance logged "Done (...)!" if that happened after since.
+func (e *Executor) serverDoneSince(instanceID string, since time.Time) time.Time {
+	e.doneMu.Lock()
+	defer e.doneMu.Unlock()
+	if t := e.serverDone[instanceID]; t.After(since) {
+		return t
+	}
+	return time.Time{}
+}
+
+// EmitInstall publishes a progress line for an instance on the install log stream
+// (used by the scheduler).
+func (e *Executor) EmitInstall(instanceID string, line string) {
+	e.emitInstall(instanceID, line)
+}
+
 func (e *Executor) emitInstall(instanceID string, line string) {
 	e.emitLog(protocol.LogLine{
 		Source:   "install",
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/base64"
 	"os"
@@ -295,6 +296,67 @@ func TestExecutor_MCBackupRestore_Roundtrip(t *testing.T) {
 	}
 }
 
+func TestExecutor_MCBackupRepo_DedupPruneRestore(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	ctx := context.Background()
+
+	instDir := filepath.Join(serversRoot, "server1")
+	if err := os.MkdirAll(filepath.Join(instDir, "world"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, "world", "level.dat"), bytes.Repeat([]byte("chunk"), 100000), 0o644); err != nil {
+		t.Fatalf("write world: %v", err)
+	}
+
+	backup := func(name string) map[string]any {
+		t.Helper()
+		res := ex.Execute(ctx, protocol.Command{
+			Name: "mc_backup",
+			Args: map[string]any{"instance_id": "server1", "backup_name": name, "format": "repo", "stop": false},
+		})
+		if !res.OK {
+			t.Fatalf("mc_backup %s failed: %s", name, res.Error)
+		}
+		return res.Output
+	}
+	first := backup("s1")
+	if first["path"] != "_backups/server1/s1.snapshot" || first["format"] != "repo" {
+		t.Fatalf("unexpected backup output: %#v", first)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, "server.properties"), []byte("server-port=25565\n"), 0o644); err != nil {
+		t.Fatalf("write props: %v", err)
+	}
+	second := backup("s2")
+	// Only the new file is stored; the world is shared with s1.
+	if n, _ := second["new_chunks"].(int); n != 1 {
+		t.Fatalf("expected 1 new chunk, got %#v", second["new_chunks"])
+	}
+
+	pruneRes := ex.Execute(ctx, protocol.Command{
+		Name: "mc_backup_prune",
+		Args: map[string]any{"instance_id": "server1", "keep_last": 1},
+	})
+	if !pruneRes.OK || pruneRes.Output["removed"] != 1 {
+		t.Fatalf("mc_backup_prune: ok=%v err=%s out=%#v", pruneRes.OK, pruneRes.Error, pruneRes.Output)
+	}
+
+	_ = os.RemoveAll(instDir)
+	restoreRes := ex.Execute(ctx, protocol.Command{
+		Name: "mc_restore",
+		Args: map[string]any{"instance_id": "server1", "zip_path": "_backups/server1/s2.snapshot"},
+	})
+	if !restoreRes.OK {
+		t.Fatalf("mc_restore failed: %s", restoreRes.Error)
+	}
+	b, err := os.ReadFile(filepath.Join(instDir, "world", "level.dat"))
+	if err != nil || len(b) != 500000 {
+		t.Fatalf("restored world: len=%d err=%v", len(b), err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(instDir, "server.properties")); string(b) != "server-port=25565\n" {
+		t.Fatalf("unexpected restored props: %q", string(b))
+	}
+}
+
 func TestExecutor_MCTemplates(t *testing.T) {
 	ex, _, _ := newTestExecutor(t)
 	ctx := context.Background()
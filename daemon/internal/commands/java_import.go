@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// javaImport installs a JDK/JRE archive from the servers sandbox into the java cache,
+// for nodes that cannot reach the download providers.
+func (e *Executor) javaImport(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	rel, _ := asString(cmd.Args["path"])
+	sum, _ := asString(cmd.Args["sha256"])
+	vendor, _ := asString(cmd.Args["vendor"])
+	replace, _ := asBool(cmd.Args["replace"])
+	deleteArchive, _ := asBool(cmd.Args["delete_archive"])
+
+	rel = strings.TrimSpace(rel)
+	if rel == "" {
+		return fail("path is required")
+	}
+	sum = strings.TrimSpace(sum)
+	if sum != "" && !sha256HexPattern.MatchString(sum) {
+		return fail("sha256 must be 64 hex chars")
+	}
+	if e.deps.MC == nil {
+		return fail("mc manager not configured")
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	abs, err := e.deps.FS.Resolve(rel)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if st, err := os.Stat(abs); err != nil {
+		return fail(err.Error())
+	} else if !st.Mode().IsRegular() {
+		return fail("path is not a file")
+	}
+
+	entry, err := e.deps.MC.ImportJava(ctx, abs, mc.JavaImportOptions{
+		Vendor:  vendor,
+		SHA256:  sum,
+		Source:  filepath.ToSlash(rel),
+		Replace: replace,
+	})
+	if err != nil {
+		return fail(err.Error())
+	}
+	deleted := false
+	if deleteArchive {
+		deleted = os.Remove(abs) == nil
+	}
+	return ok(map[string]any{"runtime": entry, "archive_deleted": deleted})
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"context"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+// javaList returns the Java inventory used for automatic runtime selection.
+func (e *Executor) javaList(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	refresh, _ := asBool(cmd.Args["refresh"])
+	if e.deps.MC == nil {
+		return fail("mc manager not configured")
+	}
+	list, scannedAt := e.deps.MC.JavaInventory(ctx, refresh)
+	return ok(map[string]any{
+		"java":         list,
+		"count":        len(list),
+		"scanned_unix": scannedAt.Unix(),
+	})
+}
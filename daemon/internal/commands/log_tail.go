@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+const (
+	logBufferLines   = 2000     // per (source, instance)
+	logBufferMaxLine = 8 * 1024 // longer lines are truncated in the buffer
+	logTailMaxLimit  = 5000
+)
+
+// logBuffer keeps the most recent log lines per (source, instance) so the panel can
+// backfill a console after reconnecting. Sequence numbers are global and increasing.
+type logBuffer struct {
+	mu      sync.Mutex
+	seq     uint64
+	streams map[string]*logRing
+}
+
+type logRing struct {
+	lines   []protocol.LogLine // ring storage, len <= logBufferLines
+	start   int                // index of the oldest line
+	dropped uint64             // seq of the newest line evicted from the ring
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{streams: make(map[string]*logRing)}
+}
+
+func logBufferKey(source string, instance string) string {
+	return source + "\x00" + instance
+}
+
+// add assigns the next sequence number and timestamp to line and stores it.
+func (b *logBuffer) add(line protocol.LogLine) protocol.LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	line.Seq = b.seq
+	if line.TSUnixMs == 0 {
+		line.TSUnixMs = time.Now().UnixMilli()
+	}
+
+	stored := line
+	if len(stored.Line) > logBufferMaxLine {
+		stored.Line = stored.Line[:logBufferMaxLine]
+	}
+	key := logBufferKey(line.Source, line.Instance)
+	r := b.streams[key]
+	if r == nil {
+		r = &logRing{}
+		b.streams[key] = r
+	}
+	if len(r.lines) < logBufferLines {
+		r.lines = append(r.lines, stored)
+	} else {
+		r.dropped = r.lines[r.start].Seq
+		r.lines[r.start] = stored
+		r.start = (r.start + 1) % len(r.lines)
+	}
+	return line
+}
+
+// dropInstance forgets the mc and install lines of a deleted instance.
+func (b *logBuffer) dropInstance(instance string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, source := range []string{"mc", "install"} {
+		delete(b.streams, logBufferKey(source, instance))
+	}
+}
+
+// tail returns up to limit lines with seq > afterSeq (oldest first), the oldest seq
+// still buffered, the latest seq assigned overall and whether lines after afterSeq
+// were already evicted.
+func (b *logBuffer) tail(source string, instance string, afterSeq uint64, limit int) (lines []protocol.LogLine, oldest uint64, latest uint64, truncated bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	latest = b.seq
+	r := b.streams[logBufferKey(source, instance)]
+	if r == nil || len(r.lines) == 0 {
+		return nil, 0, latest, false
+	}
+	truncated = r.dropped > afterSeq
+	n := len(r.lines)
+	oldest = r.lines[r.start].Seq
+	for i := 0; i < n; i++ {
+		l := r.lines[(r.start+i)%n]
+		if l.Seq <= afterSeq {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	// With a limit, keep the lines right after afterSeq so repeated calls page forward;
+	// without afterSeq the panel wants the most recent lines.
+	if limit > 0 && len(lines) > limit {
+		if afterSeq == 0 {
+			lines = lines[len(lines)-limit:]
+		} else {
+			lines = lines[:limit]
+		}
+	}
+	return lines, oldest, latest, truncated
+}
+
+func (e *Executor) logTail(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	source, _ := asString(cmd.Args["source"])
+	source = strings.ToLower(strings.TrimSpace(source))
+	if source == "" {
+		source = "mc"
+	}
+	switch source {
+	case "mc", "frp", "install":
+	default:
+		return fail("source must be mc/frp/install")
+	}
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	instanceID = strings.TrimSpace(instanceID)
+	if instanceID == "" {
+		return fail("instance_id is required")
+	}
+	if source != "frp" {
+		if err := validateInstanceID(instanceID); err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	var afterSeq uint64
+	if v, ok := cmd.Args["after_seq"]; ok {
+		n, err := asInt(v)
+		if err != nil || n < 0 {
+			return fail("after_seq must be >= 0")
+		}
+		afterSeq = uint64(n)
+	}
+	limit := 500
+	if v, ok := cmd.Args["limit"]; ok {
+		n, err := asInt(v)
+		if err != nil || n < 1 {
+			return fail("limit must be >= 1")
+		}
+		limit = n
+	}
+	if limit > logTailMaxLimit {
+		limit = logTailMaxLimit
+	}
+
+	lines, oldest, latest, truncated := e.logs.tail(source, instanceID, afterSeq, limit)
+	out := make([]map[string]any, 0, len(lines))
+	var nextSeq uint64 = afterSeq
+	for _, l := range lines {
+		out = append(out, map[string]any{
+			"seq":        l.Seq,
+			"ts_unix_ms": l.TSUnixMs,
+			"stream":     l.Stream,
+			"line":       l.Line,
+		})
+		nextSeq = l.Seq
+	}
+	return ok(map[string]any{
+		"source":      source,
+		"instance_id": instanceID,
+		"lines":       out,
+		"next_seq":    nextSeq,
+		"oldest_seq":  oldest,
+		"latest_seq":  latest,
+		"truncated":   truncated, // some lines after after_seq were evicted
+	})
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+func fillLogBuffer(b *logBuffer, source, instance string, n int) {
+	for i := 0; i < n; i++ {
+		b.add(protocol.LogLine{Source: source, Instance: instance, Stream: "stdout", Line: fmt.Sprintf("line %d", i)})
+	}
+}
+
+func seqs(lines []protocol.LogLine) (first, last uint64) {
+	if len(lines) == 0 {
+		return 0, 0
+	}
+	return lines[0].Seq, lines[len(lines)-1].Seq
+}
+
+func TestLogBuffer_Wraparound(t *testing.T) {
+	b := newLogBuffer()
+	fillLogBuffer(b, "mc", "srv1", logBufferLines+10)
+
+	lines, oldest, latest, truncated := b.tail("mc", "srv1", 0, 0)
+	if len(lines) != logBufferLines {
+		t.Fatalf("got %d lines, want %d", len(lines), logBufferLines)
+	}
+	first, last := seqs(lines)
+	if first != 11 || last != logBufferLines+10 || oldest != 11 || latest != logBufferLines+10 {
+		t.Fatalf("first=%d last=%d oldest=%d latest=%d", first, last, oldest, latest)
+	}
+	if lines[0].Line != "line 10" {
+		t.Fatalf("oldest line = %q", lines[0].Line)
+	}
+	if !truncated {
+		t.Fatalf("lines 1-10 were evicted, expected truncated")
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i].Seq != lines[i-1].Seq+1 {
+			t.Fatalf("lines out of order at %d: %d after %d", i, lines[i].Seq, lines[i-1].Seq)
+		}
+	}
+}
+
+func TestLogBuffer_Cursor(t *testing.T) {
+	b := newLogBuffer()
+	fillLogBuffer(b, "mc", "srv1", logBufferLines+100)
+
+	// A cursor older than the ring: everything still buffered, flagged as truncated.
+	lines, oldest, _, truncated := b.tail("mc", "srv1", 50, 0)
+	if first, _ := seqs(lines); !truncated || first != oldest || oldest != 101 || len(lines) != logBufferLines {
+		t.Fatalf("stale cursor: truncated=%v first=%d oldest=%d n=%d", truncated, first, oldest, len(lines))
+	}
+	// The newest evicted line itself is not a gap.
+	if _, _, _, truncated := b.tail("mc", "srv1", 100, 0); truncated {
+		t.Fatalf("cursor at the newest evicted line should not be truncated")
+	}
+	// A cursor inside the ring.
+	lines, _, _, truncated = b.tail("mc", "srv1", 2000, 0)
+	if first, last := seqs(lines); truncated || len(lines) != 100 || first != 2001 || last != 2100 {
+		t.Fatalf("cursor in ring: truncated=%v n=%d first=%d last=%d", truncated, len(lines), first, last)
+	}
+	// A cursor at the head returns nothing.
+	if lines, _, latest, _ := b.tail("mc", "srv1", 2100, 0); len(lines) != 0 || latest != 2100 {
+		t.Fatalf("cursor at head: n=%d latest=%d", len(lines), latest)
+	}
+}
+
+func TestLogBuffer_Limit(t *testing.T) {
+	b := newLogBuffer()
+	// Interleave another stream so sequence numbers are not contiguous per stream.
+	for i := 0; i < 10; i++ {
+		fillLogBuffer(b, "mc", "srv1", 1)
+		fillLogBuffer(b, "mc", "srv2", 1)
+	}
+
+	cases := []struct {
+		name      string
+		afterSeq  uint64
+		limit     int
+		wantFirst uint64
+		wantLast  uint64
+		wantN     int
+	}{
+		{"no cursor keeps the newest", 0, 3, 15, 19, 3},
+		{"cursor pages forward", 4, 3, 5, 9, 3},
+		{"limit larger than available", 12, 100, 13, 19, 4},
+		{"limit exactly available", 12, 4, 13, 19, 4},
+		{"no limit", 0, 0, 1, 19, 10},
+	}
+	for _, tc := range cases {
+		lines, _, latest, _ := b.tail("mc", "srv1", tc.afterSeq, tc.limit)
+		first, last := seqs(lines)
+		if len(lines) != tc.wantN || first != tc.wantFirst || last != tc.wantLast || latest != 20 {
+			t.Errorf("%s: n=%d first=%d last=%d latest=%d", tc.name, len(lines), first, last, latest)
+		}
+	}
+
+	if lines, oldest, latest, truncated := b.tail("install", "srv1", 0, 10); lines != nil || oldest != 0 || latest != 20 || truncated {
+		t.Fatalf("unknown stream: %v %d %d %v", lines, oldest, latest, truncated)
+	}
+}
+
+func TestLogBuffer_LongLinesTruncatedInBuffer(t *testing.T) {
+	b := newLogBuffer()
+	long := strings.Repeat("x", logBufferMaxLine+10)
+	if got := b.add(protocol.LogLine{Source: "mc", Instance: "srv1", Line: long}); got.Line != long {
+		t.Fatalf("live line should be complete")
+	}
+	lines, _, _, _ := b.tail("mc", "srv1", 0, 0)
+	if len(lines) != 1 || len(lines[0].Line) != logBufferMaxLine {
+		t.Fatalf("buffered line length = %d", len(lines[0].Line))
+	}
+}
+
+func TestExecutor_LogTailArgs(t *testing.T) {
+	ex, _, _ := newTestExecutor(t)
+	ctx := context.Background()
+	fillLogBuffer(ex.logs, "mc", "srv1", 3)
+
+	res := ex.Execute(ctx, protocol.Command{Name: "log_tail", Args: map[string]any{"instance_id": "srv1", "limit": 0}})
+	if res.OK {
+		t.Fatalf("limit 0 should be rejected")
+	}
+	res = ex.Execute(ctx, protocol.Command{Name: "log_tail", Args: map[string]any{"instance_id": "srv1", "after_seq": 3}})
+	if !res.OK {
+		t.Fatalf("log_tail failed: %s", res.Error)
+	}
+	if res.Output["next_seq"] != uint64(3) || len(res.Output["lines"].([]map[string]any)) != 0 {
+		t.Fatalf("empty page should keep the cursor: %#v", res.Output)
+	}
+	res = ex.Execute(ctx, protocol.Command{Name: "log_tail", Args: map[string]any{"instance_id": "srv1", "limit": 1_000_000}})
+	if !res.OK || len(res.Output["lines"].([]map[string]any)) != 3 || res.Output["next_seq"] != uint64(3) {
+		t.Fatalf("large limit: ok=%v %#v", res.OK, res.Output)
+	}
+}
+
+func TestExecutor_MCDeleteDropsLogRings(t *testing.T) {
+	ex, _, _ := newTestExecutor(t)
+	ctx := context.Background()
+	fillLogBuffer(ex.logs, "mc", "srv1", 3)
+	fillLogBuffer(ex.logs, "install", "srv1", 3)
+	fillLogBuffer(ex.logs, "mc", "srv2", 3)
+	fillLogBuffer(ex.logs, "frp", "srv1", 3)
+
+	res := ex.Execute(ctx, protocol.Command{Name: "mc_delete", Args: map[string]any{"instance_id": "srv1"}})
+	if !res.OK {
+		t.Fatalf("mc_delete failed: %s", res.Error)
+	}
+	for _, source := range []string{"mc", "install"} {
+		if lines, _, _, _ := ex.logs.tail(source, "srv1", 0, 0); len(lines) != 0 {
+			t.Fatalf("%s ring of the deleted instance kept %d lines", source, len(lines))
+		}
+	}
+	if lines, _, _, _ := ex.logs.tail("mc", "srv2", 0, 0); len(lines) != 3 {
+		t.Fatalf("other instance lost lines: %d", len(lines))
+	}
+	// frp ring keys are proxy names, not instances.
+	if lines, _, _, _ := ex.logs.tail("frp", "srv1", 0, 0); len(lines) != 3 {
+		t.Fatalf("frp ring should be kept: %d", len(lines))
+	}
+}
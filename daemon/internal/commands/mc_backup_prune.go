@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"elegantmc/daemon/internal/backup"
 	"elegantmc/daemon/internal/protocol"
 )
 
@@ -28,7 +29,7 @@ func listBackupZips(dirAbs string) ([]backupFile, error) {
 		}
 		name := ent.Name()
 		lower := strings.ToLower(name)
-		if !strings.HasSuffix(lower, ".zip") && !strings.HasSuffix(lower, ".tar.gz") && !strings.HasSuffix(lower, ".tgz") {
+		if !strings.HasSuffix(lower, ".zip") && !strings.HasSuffix(lower, ".tar.gz") && !strings.HasSuffix(lower, ".tgz") && !backup.IsSnapshotPath(lower) {
 			continue
 		}
 		info, err := ent.Info()
@@ -62,8 +63,21 @@ func pruneBackupZips(dirAbs string, keepLast int) (removed int, kept int, total
 	if keepLast >= total {
 		return 0, total, total, nil
 	}
+	var repo *backup.Repo
 	for i := keepLast; i < total; i++ {
-		_ = os.Remove(files[i].abs)
+		if backup.IsSnapshotPath(files[i].name) {
+			// Snapshots share chunks: forgetting one drops only the chunks no other snapshot references.
+			if repo == nil {
+				if repo, err = backup.OpenRepo(dirAbs); err != nil {
+					return removed, total - removed, total, err
+				}
+			}
+			if _, _, err := repo.Forget(files[i].name); err != nil {
+				return removed, total - removed, total, err
+			}
+		} else {
+			_ = os.Remove(files[i].abs)
+		}
 		_ = os.Remove(files[i].abs + ".meta.json")
 		removed++
 	}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// mcInstall is the unified install command: it dispatches on "software" to the
+// dedicated installers or, for single-jar software, to the registry providers.
+func (e *Executor) mcInstall(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	software, _ := asString(cmd.Args["software"])
+	software = strings.ToLower(strings.TrimSpace(software))
+	switch software {
+	case "":
+		return fail("software is required")
+	case "vanilla":
+		return e.mcInstallVanilla(ctx, cmd)
+	case "fabric", "quilt":
+		return e.mcInstallLoader(ctx, cmd, software)
+	case "forge", "neoforge":
+		return e.mcInstallForge(ctx, cmd, software)
+	}
+	if _, ok := mcinstall.LookupSoftware(software); !ok {
+		return fail("unsupported software: " + software)
+	}
+	return e.mcInstallJar(ctx, cmd, software)
+}
+
+func (e *Executor) jarProvider(provider string) (mcinstall.JarProvider, error) {
+	switch provider {
+	case mcinstall.ProviderPaperMC:
+		return mcinstall.PaperMCProvider{APIBaseURL: e.deps.Paper.APIBaseURL}, nil
+	case mcinstall.ProviderPurpur:
+		return mcinstall.PurpurProvider{APIBaseURL: e.deps.Purpur.APIBaseURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+}
+
+// mcInstallJar installs software that ships as a single server jar (Paper, Folia,
+// Purpur, Velocity, Waterfall).
+func (e *Executor) mcInstallJar(ctx context.Context, cmd protocol.Command, software string) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	version, _ := asString(cmd.Args["version"])
+	jarName, _ := asString(cmd.Args["jar_name"])
+	build, _ := asInt(cmd.Args["build"])
+	acceptEULA, _ := asBool(cmd.Args["accept_eula"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if strings.TrimSpace(version) == "" {
+		return fail("version is required")
+	}
+	if build < 0 {
+		return fail("build must be >= 0")
+	}
+	if strings.TrimSpace(jarName) == "" {
+		jarName = "server.jar"
+	}
+	if err := validateJarName(jarName); err != nil {
+		return fail(err.Error())
+	}
+	sw, found := mcinstall.LookupSoftware(software)
+	if !found {
+		return fail("unsupported software: " + software)
+	}
+	provider, err := e.jarProvider(sw.Provider)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	targetRel := filepath.Join(instanceID, jarName)
+	targetAbs, err := e.deps.FS.Resolve(targetRel)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("resolve %s version=%s build=%d", sw.ID, version, build))
+	resolved, err := provider.ResolveJar(ctx, sw.ID, version, build)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("download %s jar (build %d) -> %s", sw.ID, resolved.Build, targetRel))
+	want := download.Expected{SHA256: resolved.SHA256}
+	if want.SHA256 == "" {
+		want.MD5 = resolved.MD5
+	}
+	dl, err := download.DownloadFileVerified(ctx, resolved.URL, targetAbs, want, func(p download.Progress) {
+		if p.Total > 0 {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
+		} else {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
+		}
+	})
+	if err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha256=%s", dl.Bytes, dl.SHA256))
+
+	// Proxies have no EULA.
+	if acceptEULA && !sw.Proxy {
+		if err := e.writeEULA(instanceID); err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, "wrote eula.txt (accepted)")
+	}
+
+	if err := e.updateInstanceConfig(instanceID, map[string]any{
+		"jar_path":       jarName,
+		"server_kind":    sw.ID,
+		"server_version": resolved.Version,
+		"server_build":   resolved.Build,
+	}); err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, "updated "+instanceConfigFileName)
+
+	return ok(map[string]any{
+		"instance_id": instanceID,
+		"software":    sw.ID,
+		"proxy":       sw.Proxy,
+		"version":     resolved.Version,
+		"build":       resolved.Build,
+		"jar_path":    jarName,
+		"path":        targetRel,
+		"url":         resolved.URL,
+		"sha256":      dl.SHA256,
+		"bytes":       dl.Bytes,
+	})
+}
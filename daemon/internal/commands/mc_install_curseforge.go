@@ -0,0 +1,267 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const curseForgeManifestName = "manifest.json"
+
+type curseForgeManifest struct {
+	Minecraft struct {
+		Version    string `json:"version"`
+		ModLoaders []struct {
+			ID      string `json:"id"`
+			Primary bool   `json:"primary"`
+		} `json:"modLoaders"`
+	} `json:"minecraft"`
+	ManifestType    string `json:"manifestType"`
+	ManifestVersion int    `json:"manifestVersion"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	Files           []struct {
+		ProjectID int   `json:"projectID"`
+		FileID    int   `json:"fileID"`
+		Required  *bool `json:"required"` // missing: required
+	} `json:"files"`
+	Overrides string `json:"overrides"`
+}
+
+// mcInstallCurseForge installs a CurseForge modpack (zip with manifest.json): mod files
+// are resolved through the CurseForge API and downloaded into mods/, the overrides
+// folder is applied and the loader from minecraft.modLoaders is installed.
+func (e *Executor) mcInstallCurseForge(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	packPath, _ := asString(cmd.Args["path"])
+	packURL, _ := asString(cmd.Args["url"])
+	packSHA1, _ := asString(cmd.Args["sha1"])
+	apiKey, _ := asString(cmd.Args["api_key"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	if strings.TrimSpace(apiKey) == "" {
+		return fail("api_key is required")
+	}
+	packPath = strings.TrimSpace(packPath)
+	packURL = strings.TrimSpace(packURL)
+	if (packPath == "") == (packURL == "") {
+		return fail("exactly one of path or url is required")
+	}
+	clientMods, err := parseClientModsMode(cmd.Args["client_mods"], "disable")
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	var packAbs string
+	if packPath != "" {
+		abs, err := e.deps.FS.Resolve(packPath)
+		if err != nil {
+			return fail(err.Error())
+		}
+		packAbs = abs
+	} else {
+		abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, ".elegantmc-modpack.zip"))
+		if err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, "download modpack: "+packURL)
+		dl, err := download.DownloadFileVerified(ctx, packURL, abs, download.Expected{SHA1: packSHA1}, func(p download.Progress) {
+			if p.Total > 0 {
+				e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
+			} else {
+				e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
+			}
+		})
+		if err != nil {
+			return fail(err.Error())
+		}
+		defer os.Remove(abs)
+		e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha1=%s", dl.Bytes, dl.SHA1))
+		packAbs = abs
+	}
+
+	zr, err := zip.OpenReader(packAbs)
+	if err != nil {
+		return fail(fmt.Sprintf("open modpack: %v", err))
+	}
+	defer zr.Close()
+
+	manifest, err := readCurseForgeManifest(&zr.Reader)
+	if err != nil {
+		return fail(err.Error())
+	}
+	gameVersion := strings.TrimSpace(manifest.Minecraft.Version)
+	if gameVersion == "" {
+		return fail(curseForgeManifestName + " has no minecraft.version")
+	}
+	loader, loaderVersion := "vanilla", ""
+	for _, ml := range manifest.Minecraft.ModLoaders {
+		l, v, err := mcinstall.ParseCurseForgeModLoader(ml.ID)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if loaderVersion == "" || ml.Primary {
+			loader, loaderVersion = l, v
+		}
+		if ml.Primary {
+			break
+		}
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("modpack %s %s: minecraft=%s loader=%s %s files=%d", manifest.Name, manifest.Version, gameVersion, loader, loaderVersion, len(manifest.Files)))
+
+	var fileIDs []int
+	projects := make(map[int]int) // file id -> project id
+	skipped := 0
+	for _, f := range manifest.Files {
+		if f.FileID <= 0 {
+			return fail(fmt.Sprintf("%s: invalid fileID for project %d", curseForgeManifestName, f.ProjectID))
+		}
+		if f.Required != nil && !*f.Required {
+			e.emitInstall(instanceID, fmt.Sprintf("skip (optional) project %d file %d", f.ProjectID, f.FileID))
+			skipped++
+			continue
+		}
+		fileIDs = append(fileIDs, f.FileID)
+		projects[f.FileID] = f.ProjectID
+	}
+	var files []modpackFile
+	manual := []map[string]any{}
+	if len(fileIDs) > 0 {
+		e.emitInstall(instanceID, fmt.Sprintf("resolve %d files via curseforge api", len(fileIDs)))
+		resolved, err := mcinstall.ResolveCurseForgeFiles(ctx, e.deps.CurseForge.APIBaseURL, apiKey, fileIDs)
+		if err != nil {
+			return fail(err.Error())
+		}
+		for _, id := range fileIDs {
+			rf := resolved[id]
+			// Only jars belong in mods/; resource packs and shaders are client content.
+			if !strings.HasSuffix(strings.ToLower(rf.FileName), ".jar") {
+				e.emitInstall(instanceID, "skip (not a mod jar) "+rf.FileName)
+				skipped++
+				continue
+			}
+			if rf.FileName != path.Base(filepath.ToSlash(rf.FileName)) {
+				return fail(fmt.Sprintf("invalid file name from curseforge api: %q", rf.FileName))
+			}
+			rel, err := modpackTargetRel(instanceID, "mods/"+rf.FileName)
+			if err != nil {
+				return fail(err.Error())
+			}
+			if rf.DownloadURL == "" {
+				// The author disabled third-party downloads: the file must be fetched by hand.
+				e.emitInstall(instanceID, fmt.Sprintf("manual download required: project %d file %d -> mods/%s", projects[id], id, rf.FileName))
+				manual = append(manual, map[string]any{
+					"project_id": projects[id],
+					"file_id":    id,
+					"file_name":  rf.FileName,
+					"path":       filepath.ToSlash(rel),
+				})
+				continue
+			}
+			want := download.Expected{SHA1: rf.SHA1}
+			if want.SHA1 == "" {
+				want.MD5 = rf.MD5
+			}
+			files = append(files, modpackFile{Rel: rel, URLs: []string{rf.DownloadURL}, Want: want})
+		}
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("download %d files", len(files)))
+	if err := e.downloadModpackFiles(ctx, instanceID, files); err != nil {
+		return fail(err.Error())
+	}
+
+	overridesDir := strings.Trim(strings.ReplaceAll(strings.TrimSpace(manifest.Overrides), "\\", "/"), "/")
+	if overridesDir == "" {
+		overridesDir = "overrides"
+	}
+	overrides, err := e.extractModpackDir(ctx, &zr.Reader, overridesDir+"/", instanceID)
+	if err != nil {
+		return fail(fmt.Sprintf("apply %s: %v", overridesDir, err))
+	}
+	if overrides > 0 {
+		e.emitInstall(instanceID, fmt.Sprintf("applied %s: files=%d", overridesDir, overrides))
+	}
+
+	res := e.installModpackLoader(ctx, cmd, instanceID, loader, gameVersion, loaderVersion)
+	if !res.OK {
+		return fail("install loader: " + res.Error)
+	}
+
+	clientOnly, err := e.scanInstanceClientMods(ctx, instanceID, clientMods == "disable")
+	if err != nil {
+		return fail(fmt.Sprintf("check client-only mods: %v", err))
+	}
+
+	if err := e.updateInstanceConfig(instanceID, map[string]any{
+		"modpack_provider": "curseforge",
+		"modpack_name":     manifest.Name,
+		"modpack_version":  manifest.Version,
+	}); err != nil {
+		return fail(err.Error())
+	}
+	if len(manual) > 0 {
+		e.emitInstall(instanceID, fmt.Sprintf("modpack install done; %d file(s) need a manual download (see manual_files)", len(manual)))
+	} else {
+		e.emitInstall(instanceID, "modpack install done")
+	}
+
+	return ok(map[string]any{
+		"instance_id":      instanceID,
+		"name":             manifest.Name,
+		"version":          manifest.Version,
+		"minecraft":        gameVersion,
+		"loader":           loader,
+		"loader_version":   res.Output["loader_version"],
+		"jar_path":         res.Output["jar_path"],
+		"files":            len(files),
+		"skipped_files":    skipped,
+		"override_files":   overrides,
+		"manual_files":     manual,
+		"client_only_mods": clientOnly,
+	})
+}
+
+func readCurseForgeManifest(zr *zip.Reader) (curseForgeManifest, error) {
+	for _, f := range zr.File {
+		if f.Name != curseForgeManifestName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return curseForgeManifest{}, err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, 16*1024*1024))
+		rc.Close()
+		if err != nil {
+			return curseForgeManifest{}, err
+		}
+		var m curseForgeManifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			return curseForgeManifest{}, fmt.Errorf("invalid %s: %w", curseForgeManifestName, err)
+		}
+		if m.ManifestType != "" && m.ManifestType != "minecraftModpack" {
+			return curseForgeManifest{}, fmt.Errorf("unsupported manifestType: %s", m.ManifestType)
+		}
+		return m, nil
+	}
+	return curseForgeManifest{}, errors.New("not a curseforge modpack: missing " + curseForgeManifestName)
+}
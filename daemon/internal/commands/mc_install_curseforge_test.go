@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func sha1Hex(b []byte) string {
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// newCurseForgeStub serves the CurseForge files API, the mod jars and the Fabric/Mojang
+// metadata needed to install the loader.
+func newCurseForgeStub(t *testing.T, modA, modB []byte, flaky *int32) *httptest.Server {
+	t.Helper()
+	vanilla := []byte("vanilla server jar")
+	launcher := buildZip(t, map[string]string{"META-INF/MANIFEST.MF": "Main-Class: net.fabricmc.Launcher\n"})
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/mods/files":
+			if r.Method != http.MethodPost || r.Header.Get("x-api-key") != "test-key" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			var req struct {
+				FileIDs []int `json:"fileIds"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			// The optional file (1004) must not be resolved.
+			if len(req.FileIDs) != 4 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": []any{
+				map[string]any{"id": 1001, "modId": 1, "fileName": "mod-a.jar", "downloadUrl": srv.URL + "/files/mod-a.jar",
+					"hashes": []any{map[string]any{"value": sha1Hex(modA), "algo": 1}}},
+				map[string]any{"id": 1002, "modId": 2, "fileName": "mod-b.jar", "downloadUrl": srv.URL + "/files/mod-b.jar",
+					"hashes": []any{map[string]any{"value": sha1Hex(modB), "algo": 1}}},
+				map[string]any{"id": 1003, "modId": 3, "fileName": "textures.zip", "downloadUrl": srv.URL + "/files/textures.zip"},
+				// Third-party downloads disabled by the author.
+				map[string]any{"id": 1005, "modId": 5, "fileName": "manual.jar", "downloadUrl": nil,
+					"hashes": []any{map[string]any{"value": sha1Hex([]byte("manual")), "algo": 1}}},
+			}})
+		case "/files/mod-a.jar":
+			_, _ = w.Write(modA)
+		case "/files/mod-b.jar":
+			if atomic.AddInt32(flaky, 1) == 1 {
+				http.Error(w, "try again", http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write(modB)
+		case "/v2/versions/loader/1.20.1":
+			_, _ = w.Write([]byte(`[{"loader":{"version":"0.15.11","stable":true}}]`))
+		case "/v2/versions/installer":
+			_, _ = w.Write([]byte(`[{"version":"1.0.1","stable":true}]`))
+		case "/v2/versions/loader/1.20.1/0.15.11/1.0.1/server/jar":
+			_, _ = w.Write(launcher)
+		case "/mc/game/version_manifest_v2.json":
+			_, _ = w.Write([]byte(`{"versions":[{"id":"1.20.1","url":"` + srv.URL + `/v/1.20.1.json"}]}`))
+		case "/v/1.20.1.json":
+			_, _ = w.Write([]byte(`{"downloads":{"server":{"url":"` + srv.URL + `/server.jar","sha1":"` + sha1Hex(vanilla) + `"}}}`))
+		case "/server.jar":
+			_, _ = w.Write(vanilla)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExecutor_MCInstallCurseForge(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	ctx := context.Background()
+
+	modA, modB := []byte("mod a"), []byte("mod b")
+	var flaky int32
+	srv := newCurseForgeStub(t, modA, modB, &flaky)
+	ex.deps.CurseForge.APIBaseURL = srv.URL
+	ex.deps.Fabric.MetaBaseURL = srv.URL
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	manifest := `{
+		"minecraft": {"version": "1.20.1", "modLoaders": [{"id": "fabric-0.15.11", "primary": true}]},
+		"manifestType": "minecraftModpack", "manifestVersion": 1,
+		"name": "Test Pack", "version": "1.2.3",
+		"files": [
+			{"projectID": 1, "fileID": 1001, "required": true},
+			{"projectID": 2, "fileID": 1002, "required": true},
+			{"projectID": 3, "fileID": 1003, "required": true},
+			{"projectID": 4, "fileID": 1004, "required": false},
+			{"projectID": 5, "fileID": 1005}
+		],
+		"overrides": "overrides"
+	}`
+	pack := buildZip(t, map[string]string{
+		"manifest.json":               manifest,
+		"overrides/config/pack.toml":  "a = 1\n",
+		"overrides/server.properties": "motd=pack\n",
+		"unrelated/readme.txt":        "ignored",
+	})
+	if err := os.WriteFile(filepath.Join(serversRoot, "pack.zip"), pack, 0o644); err != nil {
+		t.Fatalf("write pack: %v", err)
+	}
+
+	res := ex.Execute(ctx, protocol.Command{Name: "mc_install_curseforge", Args: map[string]any{
+		"instance_id": "cf1",
+		"path":        "pack.zip",
+		"api_key":     "test-key",
+		"accept_eula": true,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_curseforge failed: %s", res.Error)
+	}
+	if res.Output["loader"] != "fabric" || res.Output["files"] != 2 || res.Output["skipped_files"] != 2 {
+		t.Fatalf("unexpected output: %#v", res.Output)
+	}
+	manual, _ := res.Output["manual_files"].([]map[string]any)
+	if len(manual) != 1 || manual[0]["file_id"] != 1005 || manual[0]["project_id"] != 5 || manual[0]["path"] != "cf1/mods/manual.jar" {
+		t.Fatalf("unexpected manual_files: %#v", res.Output["manual_files"])
+	}
+
+	instDir := filepath.Join(serversRoot, "cf1")
+	for name, want := range map[string]string{
+		"mods/mod-a.jar":    string(modA),
+		"mods/mod-b.jar":    string(modB),
+		"config/pack.toml":  "a = 1\n",
+		"server.properties": "motd=pack\n",
+		"eula.txt":          "",
+	} {
+		b, err := os.ReadFile(filepath.Join(instDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if want != "" && string(b) != want {
+			t.Fatalf("%s: got %q want %q", name, string(b), want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "mods", "manual.jar")); !os.IsNotExist(err) {
+		t.Fatalf("expected no download without a download url, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "mods", "textures.zip")); !os.IsNotExist(err) {
+		t.Fatalf("expected non-jar file to be skipped, stat err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "readme.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected files outside overrides to be ignored, stat err=%v", err)
+	}
+	if atomic.LoadInt32(&flaky) < 2 {
+		t.Fatalf("expected mod-b download to be retried")
+	}
+
+	b, err := os.ReadFile(filepath.Join(instDir, ".elegantmc.json"))
+	if err != nil {
+		t.Fatalf("read instance config: %v", err)
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		t.Fatalf("parse instance config: %v", err)
+	}
+	if cfg["jar_path"] != "fabric-server-launch.jar" || cfg["server_kind"] != "fabric" || cfg["modpack_provider"] != "curseforge" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+}
+
+func TestExecutor_MCInstallCurseForge_RejectsBadAPIKey(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	var flaky int32
+	srv := newCurseForgeStub(t, []byte("a"), []byte("b"), &flaky)
+	ex.deps.CurseForge.APIBaseURL = srv.URL
+
+	pack := buildZip(t, map[string]string{
+		"manifest.json": `{"minecraft":{"version":"1.20.1","modLoaders":[{"id":"forge-47.2.0","primary":true}]},"files":[{"projectID":1,"fileID":1001}]}`,
+	})
+	if err := os.WriteFile(filepath.Join(serversRoot, "pack.zip"), pack, 0o644); err != nil {
+		t.Fatalf("write pack: %v", err)
+	}
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_curseforge", Args: map[string]any{
+		"instance_id": "cf1",
+		"path":        "pack.zip",
+		"api_key":     "wrong",
+	}})
+	if res.OK {
+		t.Fatalf("expected failure with a rejected api key")
+	}
+	if _, err := os.Stat(filepath.Join(serversRoot, "cf1", "mods")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be downloaded, stat err=%v", err)
+	}
+}
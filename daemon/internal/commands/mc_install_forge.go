@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const forgeInstallTimeout = 30 * time.Minute
+
+// mcInstallForge runs the Forge/NeoForge installer headlessly (--installServer) in the
+// instance directory and records a launch descriptor for mc.Manager.
+func (e *Executor) mcInstallForge(ctx context.Context, cmd protocol.Command, loader string) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	version, _ := asString(cmd.Args["version"])
+	loaderVersion, _ := asString(cmd.Args["loader_version"])
+	javaPath, _ := asString(cmd.Args["java_path"])
+	acceptEULA, _ := asBool(cmd.Args["accept_eula"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fail("version is required")
+	}
+	if e.deps.MC == nil {
+		return fail("mc manager not configured")
+	}
+	instanceDir, err := e.deps.FS.Resolve(instanceID)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if err := os.MkdirAll(instanceDir, 0o755); err != nil {
+		return fail(err.Error())
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("resolve %s version=%s loader=%s", loader, version, loaderVersion))
+	var resolved mcinstall.ForgeInstaller
+	switch loader {
+	case "forge":
+		resolved, err = mcinstall.ResolveForgeInstaller(ctx, e.deps.Forge.MavenBaseURL, version, loaderVersion)
+	case "neoforge":
+		resolved, err = mcinstall.ResolveNeoForgeInstaller(ctx, e.deps.NeoForge.MavenBaseURL, version, loaderVersion)
+	default:
+		err = fmt.Errorf("unsupported loader: %s", loader)
+	}
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	installerName := fmt.Sprintf("%s-%s-installer.jar", loader, resolved.LoaderVersion)
+	installerAbs := filepath.Join(instanceDir, installerName)
+	e.emitInstall(instanceID, fmt.Sprintf("download %s installer -> %s", loader, filepath.Join(instanceID, installerName)))
+	dl, err := download.DownloadFileWithChecksumsProgress(ctx, resolved.URL, installerAbs, "", resolved.SHA1, func(p download.Progress) {
+		if p.Total > 0 {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
+		} else {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
+		}
+	})
+	if err != nil {
+		return fail(err.Error())
+	}
+	defer os.Remove(installerAbs)
+	e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha1=%s", dl.Bytes, dl.SHA1))
+
+	javaMajor, javaMax := forgeInstallerJava(version)
+	java := strings.TrimSpace(javaPath)
+	if java == "" {
+		if javaMax > 0 {
+			e.emitInstall(instanceID, fmt.Sprintf("select java %d..%d", javaMajor, javaMax))
+		} else {
+			e.emitInstall(instanceID, fmt.Sprintf("select java >= %d", javaMajor))
+		}
+		cfg, err := e.readInstanceConfig(instanceID)
+		if err != nil {
+			return fail(err.Error())
+		}
+		vendor, _ := asString(cfg["java_vendor"])
+		java, _, err = e.deps.MC.JavaForMajorRange(ctx, javaMajor, javaMax, vendor)
+		if err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("run installer: %s -jar %s --installServer", java, installerName))
+	if err := e.runForgeInstaller(ctx, instanceID, instanceDir, java, installerName); err != nil {
+		return fail(fmt.Sprintf("%s installer failed: %v", loader, err))
+	}
+
+	desc := mc.LaunchDescriptor{
+		Kind:          loader,
+		GameVersion:   resolved.GameVersion,
+		LoaderVersion: resolved.LoaderVersion,
+		JavaMajor:     javaMajor,
+	}
+	if argFiles := forgeArgFiles(instanceDir); len(argFiles) > 0 {
+		desc.ArgFiles = argFiles
+	} else if jar := findForgeServerJar(instanceDir, loader, resolved.LoaderVersion); jar != "" {
+		desc.Jar = jar
+	} else {
+		return fail(fmt.Sprintf("%s installer finished but no launch files were found (run.sh/unix_args.txt or %s-*.jar)", loader, loader))
+	}
+	if err := mc.WriteLaunchDescriptor(filepath.Join(instanceDir, mc.LaunchDescriptorName), desc); err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, "wrote "+mc.LaunchDescriptorName)
+
+	if acceptEULA {
+		if err := e.writeEULA(instanceID); err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, "wrote eula.txt (accepted)")
+	}
+
+	if err := e.updateInstanceConfig(instanceID, map[string]any{
+		"jar_path":       mc.LaunchDescriptorName,
+		"server_kind":    loader,
+		"server_version": resolved.GameVersion,
+		"loader_version": resolved.LoaderVersion,
+	}); err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, "updated "+instanceConfigFileName)
+
+	return ok(map[string]any{
+		"instance_id":    instanceID,
+		"loader":         loader,
+		"version":        resolved.GameVersion,
+		"loader_version": resolved.LoaderVersion,
+		"jar_path":       mc.LaunchDescriptorName,
+		"launch":         desc,
+		"installer_url":  resolved.URL,
+		"installer_sha1": dl.SHA1,
+		"java":           java,
+	})
+}
+
+// forgeInstallerJava returns the Java major range (max 0 = unbounded) the installer for
+// a Minecraft version runs on. Installers before 1.17 break on Java 9+, so they need
+// exactly Java 8 and auto-download kicks in on hosts that only have newer runtimes.
+func forgeInstallerJava(gameVersion string) (int, int) {
+	major := mc.RequiredJavaMajorForGameVersion(gameVersion)
+	if major <= 0 {
+		// Unknown formats (snapshots) keep the Java 8 floor without a cap.
+		return 8, 0
+	}
+	if major == 8 {
+		return 8, 8
+	}
+	return major, 0
+}
+
+func (e *Executor) runForgeInstaller(ctx context.Context, instanceID, instanceDir, java, installerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, forgeInstallTimeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, java, "-jar", installerName, "--installServer")
+	c.Dir = instanceDir
+	pr, pw := io.Pipe()
+	c.Stdout = pw
+	c.Stderr = pw
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	// The installer logs every library download; forward them so long installs show progress.
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		sc := bufio.NewScanner(pr)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			e.emitInstall(instanceID, "[installer] "+sc.Text())
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	err := c.Wait()
+	_ = pw.Close()
+	<-scanned
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// forgeArgFiles extracts the java @argument files from the run script generated by
+// Forge/NeoForge 1.17+ (e.g. "java @user_jvm_args.txt @libraries/.../unix_args.txt "$@"").
+func forgeArgFiles(instanceDir string) []string {
+	script := "run.sh"
+	if runtime.GOOS == "windows" {
+		script = "run.bat"
+	}
+	b, err := os.ReadFile(filepath.Join(instanceDir, script))
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(strings.TrimSpace(line))
+		if len(fields) == 0 || fields[0] != "java" {
+			continue
+		}
+		var files []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "@") && strings.HasSuffix(f, ".txt") {
+				files = append(files, filepath.ToSlash(strings.TrimPrefix(f, "@")))
+			}
+		}
+		if len(files) > 0 {
+			return files
+		}
+	}
+	return nil
+}
+
+// findForgeServerJar finds the runnable server jar of the legacy (pre-1.17) layout, e.g.
+// forge-1.12.2-14.23.5.2859.jar or forge-1.7.10-10.13.4.1614-1.7.10-universal.jar.
+func findForgeServerJar(instanceDir, loader, loaderVersion string) string {
+	entries, err := os.ReadDir(instanceDir)
+	if err != nil {
+		return ""
+	}
+	var candidates []string
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasPrefix(name, loader+"-") || !strings.HasSuffix(name, ".jar") || strings.HasSuffix(name, "-installer.jar") {
+			continue
+		}
+		candidates = append(candidates, name)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return strings.Contains(candidates[i], loaderVersion) && !strings.Contains(candidates[j], loaderVersion)
+	})
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// fakeForgeInstaller is a "java" that, when run with --installServer, lays out what the
+// Forge 1.17+ installer produces: run.sh, user_jvm_args.txt and the argument file.
+const fakeForgeInstaller = `#!/bin/sh
+[ "$3" = "--installServer" ] || exit 2
+echo "installing $2"
+mkdir -p libraries/net/minecraftforge/forge/1.20.1-47.2.0
+echo "-cp x" > libraries/net/minecraftforge/forge/1.20.1-47.2.0/unix_args.txt
+echo "# user args" > user_jvm_args.txt
+printf '#!/usr/bin/env sh\n# comment\njava @user_jvm_args.txt @libraries/net/minecraftforge/forge/1.20.1-47.2.0/unix_args.txt "$@"\n' > run.sh
+`
+
+func TestExecutor_MCInstallForge(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake installer is a shell script")
+	}
+	ex, _, serversRoot := newTestExecutor(t)
+	installer := []byte("forge installer")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/net/minecraftforge/forge/1.20.1-47.2.0/forge-1.20.1-47.2.0-installer.jar":
+			_, _ = w.Write(installer)
+		case "/net/minecraftforge/forge/1.20.1-47.2.0/forge-1.20.1-47.2.0-installer.jar.sha1":
+			_, _ = w.Write([]byte(sha1Hex(installer)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ex.deps.Forge.MavenBaseURL = srv.URL
+
+	java := filepath.Join(t.TempDir(), "java")
+	if err := os.WriteFile(java, []byte(fakeForgeInstaller), 0o755); err != nil {
+		t.Fatalf("write fake java: %v", err)
+	}
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_forge", Args: map[string]any{
+		"instance_id":    "f1",
+		"version":        "1.20.1",
+		"loader_version": "47.2.0",
+		"java_path":      java,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_forge failed: %s", res.Error)
+	}
+	instDir := filepath.Join(serversRoot, "f1")
+	d, err := mc.ReadLaunchDescriptor(filepath.Join(instDir, mc.LaunchDescriptorName))
+	if err != nil {
+		t.Fatalf("read descriptor: %v", err)
+	}
+	want := []string{"user_jvm_args.txt", "libraries/net/minecraftforge/forge/1.20.1-47.2.0/unix_args.txt"}
+	if d.Kind != "forge" || d.JavaMajor != 17 || !reflect.DeepEqual(d.ArgFiles, want) {
+		t.Fatalf("unexpected descriptor: %+v", d)
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "forge-47.2.0-installer.jar")); !os.IsNotExist(err) {
+		t.Fatalf("installer should be removed, stat err=%v", err)
+	}
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["jar_path"] != mc.LaunchDescriptorName || cfg["server_kind"] != "forge" || cfg["loader_version"] != "47.2.0" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+}
+
+func TestForgeInstallerJava(t *testing.T) {
+	cases := []struct {
+		version  string
+		min, max int
+	}{
+		{"1.12.2", 8, 8},
+		{"1.16.5", 8, 8},
+		{"1.17.1", 16, 0},
+		{"1.20.1", 17, 0},
+		{"1.21.1", 21, 0},
+		{"24w14a", 8, 0},
+	}
+	for _, tc := range cases {
+		if min, max := forgeInstallerJava(tc.version); min != tc.min || max != tc.max {
+			t.Errorf("forgeInstallerJava(%q) = %d..%d, want %d..%d", tc.version, min, max, tc.min, tc.max)
+		}
+	}
+}
+
+func TestFindForgeServerJar(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"forge-1.7.10-10.13.4.1614-1.7.10-installer.jar",
+		"forge-1.7.10-10.13.4.1558-1.7.10-universal.jar",
+		"forge-1.7.10-10.13.4.1614-1.7.10-universal.jar",
+		"minecraft_server.1.7.10.jar",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if got := findForgeServerJar(dir, "forge", "10.13.4.1614-1.7.10"); got != "forge-1.7.10-10.13.4.1614-1.7.10-universal.jar" {
+		t.Fatalf("findForgeServerJar() = %q", got)
+	}
+	if got := findForgeServerJar(dir, "neoforge", "21.1.77"); got != "" {
+		t.Fatalf("findForgeServerJar(neoforge) = %q, want none", got)
+	}
+}
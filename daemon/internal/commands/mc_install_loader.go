@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// vanillaJarName is where Fabric/Quilt server launchers look for the game jar by default.
+const vanillaJarName = "server.jar"
+
+// mcInstallLoader installs a Fabric or Quilt server: the loader's server launcher jar plus
+// the vanilla server jar (verified against Mojang's sha1) that the launcher loads.
+func (e *Executor) mcInstallLoader(ctx context.Context, cmd protocol.Command, loader string) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	version, _ := asString(cmd.Args["version"])
+	loaderVersion, _ := asString(cmd.Args["loader_version"])
+	installerVersion, _ := asString(cmd.Args["installer_version"])
+	jarName, _ := asString(cmd.Args["jar_name"])
+	acceptEULA, _ := asBool(cmd.Args["accept_eula"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if strings.TrimSpace(version) == "" {
+		return fail("version is required")
+	}
+	if strings.TrimSpace(jarName) == "" {
+		jarName = loader + "-server-launch.jar"
+	}
+	if err := validateJarName(jarName); err != nil {
+		return fail(err.Error())
+	}
+	if strings.EqualFold(jarName, vanillaJarName) {
+		return fail("jar_name " + vanillaJarName + " is reserved for the vanilla server jar")
+	}
+
+	targetRel := filepath.Join(instanceID, jarName)
+	targetAbs, err := e.deps.FS.Resolve(targetRel)
+	if err != nil {
+		return fail(err.Error())
+	}
+	vanillaRel := filepath.Join(instanceID, vanillaJarName)
+	vanillaAbs, err := e.deps.FS.Resolve(vanillaRel)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("resolve %s version=%s loader=%s installer=%s", loader, version, loaderVersion, installerVersion))
+	var resolved mcinstall.LoaderServerJar
+	switch loader {
+	case "fabric":
+		resolved, err = mcinstall.ResolveFabricServerJar(ctx, e.deps.Fabric.MetaBaseURL, version, loaderVersion, installerVersion)
+	case "quilt":
+		resolved, err = mcinstall.ResolveQuiltServerJar(ctx, e.deps.Quilt.MetaBaseURL, version, loaderVersion, installerVersion)
+	default:
+		err = fmt.Errorf("unsupported loader: %s", loader)
+	}
+	if err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("resolved %s loader=%s installer=%s", loader, resolved.LoaderVersion, resolved.InstallerVersion))
+
+	progress := func(p download.Progress) {
+		if p.Total > 0 {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
+		} else {
+			e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
+		}
+	}
+
+	var dl download.Result
+	var libraries int
+	sourceURL := resolved.URL
+	if resolved.ProfileURL != "" {
+		sourceURL = resolved.ProfileURL
+		dl, libraries, err = e.buildLauncherFromProfile(ctx, instanceID, resolved, targetAbs)
+		if err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, fmt.Sprintf("wrote %s server launcher -> %s (libraries=%d sha256=%s)", loader, targetRel, libraries, dl.SHA256))
+	} else {
+		// The meta API publishes no checksum for the generated launcher; check that it is a
+		// runnable jar and report its hashes instead.
+		e.emitInstall(instanceID, fmt.Sprintf("download %s server launcher -> %s", loader, targetRel))
+		dl, err = download.DownloadFileWithChecksumsProgress(ctx, resolved.URL, targetAbs, "", "", progress)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if err := checkRunnableJar(targetAbs); err != nil {
+			_ = os.Remove(targetAbs)
+			return fail(fmt.Sprintf("invalid %s server launcher: %v", loader, err))
+		}
+		e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha256=%s", dl.Bytes, dl.SHA256))
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("resolve vanilla version=%s", version))
+	vanilla, err := mcinstall.ResolveVanillaServerJar(ctx, e.deps.Mojang.MetaBaseURL, e.deps.Mojang.DataBaseURL, version)
+	if err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("download vanilla server jar -> %s", vanillaRel))
+	vdl, err := download.DownloadFileWithChecksumsProgress(ctx, vanilla.URL, vanillaAbs, "", vanilla.SHA1, progress)
+	if err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha1=%s", vdl.Bytes, vdl.SHA1))
+
+	if acceptEULA {
+		if err := e.writeEULA(instanceID); err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, "wrote eula.txt (accepted)")
+	}
+
+	if err := e.updateInstanceConfig(instanceID, map[string]any{
+		"jar_path":       jarName,
+		"server_kind":    loader,
+		"server_version": resolved.GameVersion,
+		"loader_version": resolved.LoaderVersion,
+	}); err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, "updated "+instanceConfigFileName)
+
+	out := map[string]any{
+		"instance_id":       instanceID,
+		"loader":            loader,
+		"version":           resolved.GameVersion,
+		"loader_version":    resolved.LoaderVersion,
+		"installer_version": resolved.InstallerVersion,
+		"jar_path":          jarName,
+		"path":              targetRel,
+		"url":               sourceURL,
+		"sha256":            dl.SHA256,
+		"bytes":             dl.Bytes,
+		"vanilla_jar_path":  vanillaJarName,
+		"vanilla_sha1":      vdl.SHA1,
+	}
+	if resolved.ProfileURL != "" {
+		out["libraries"] = libraries
+	}
+	return ok(out)
+}
+
+// buildLauncherFromProfile installs a loader whose meta API only serves the server launch
+// profile (Quilt): the profile's libraries go to <instance>/libraries/ and the launcher jar
+// at targetAbs references them through its manifest Class-Path.
+func (e *Executor) buildLauncherFromProfile(ctx context.Context, instanceID string, resolved mcinstall.LoaderServerJar, targetAbs string) (download.Result, int, error) {
+	e.emitInstall(instanceID, "fetch "+resolved.Loader+" server profile")
+	prof, err := mcinstall.FetchLoaderServerProfile(ctx, resolved.ProfileURL)
+	if err != nil {
+		return download.Result{}, 0, err
+	}
+
+	classPath := make([]string, 0, len(prof.Libraries))
+	for _, lib := range prof.Libraries {
+		p, err := lib.Path()
+		if err != nil {
+			return download.Result{}, 0, err
+		}
+		u, err := lib.DownloadURL()
+		if err != nil {
+			return download.Result{}, 0, err
+		}
+		rel := "libraries/" + p
+		abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, filepath.FromSlash(rel)))
+		if err != nil {
+			return download.Result{}, 0, err
+		}
+		e.emitInstall(instanceID, "download library "+lib.Name)
+		if _, err := download.DownloadFileWithChecksumsProgress(ctx, u, abs, "", strings.TrimSpace(lib.SHA1), nil); err != nil {
+			return download.Result{}, 0, fmt.Errorf("download library %s: %w", lib.Name, err)
+		}
+		classPath = append(classPath, rel)
+	}
+
+	tmp := targetAbs + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return download.Result{}, 0, err
+	}
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(f, h)}
+	if err := mcinstall.BuildQuiltServerLaunchJar(cw, prof.MainClass, classPath); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return download.Result{}, 0, err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return download.Result{}, 0, err
+	}
+	if err := os.Rename(tmp, targetAbs); err != nil {
+		_ = os.Remove(tmp)
+		return download.Result{}, 0, err
+	}
+	return download.Result{Bytes: cw.n, SHA256: hex.EncodeToString(h.Sum(nil))}, len(classPath), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// checkRunnableJar verifies that path is a jar with a Main-Class manifest entry.
+func checkRunnableJar(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != "META-INF/MANIFEST.MF" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, 256*1024))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(b), "Main-Class:") {
+			return errors.New("manifest has no Main-Class")
+		}
+		return nil
+	}
+	return errors.New("missing META-INF/MANIFEST.MF")
+}
+
+const instanceConfigFileName = ".elegantmc.json"
+
+// updateInstanceConfig merges fields into servers/<instance>/.elegantmc.json, keeping
+// the panel's other settings.
+func (e *Executor) updateInstanceConfig(instanceID string, fields map[string]any) error {
+	cfg, err := e.readInstanceConfig(instanceID)
+	if err != nil {
+		return err
+	}
+	abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, instanceConfigFileName))
+	if err != nil {
+		return err
+	}
+	for k, v := range fields {
+		cfg[k] = v
+	}
+	return writeJSONAtomic(abs, cfg)
+}
+
+// readInstanceConfig reads servers/<instance>/.elegantmc.json (empty if missing).
+func (e *Executor) readInstanceConfig(instanceID string) (map[string]any, error) {
+	abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, instanceConfigFileName))
+	if err != nil {
+		return nil, err
+	}
+	cfg := map[string]any{}
+	if b, err := os.ReadFile(abs); err == nil {
+		if err := json.Unmarshal(b, &cfg); err != nil || cfg == nil {
+			return nil, errors.New("invalid instance config (" + instanceConfigFileName + ")")
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return cfg, nil
+}
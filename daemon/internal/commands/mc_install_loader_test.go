@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+// newLoaderStub serves the Fabric (v2) and Quilt (v3) meta APIs, a maven repository with
+// the Quilt profile's libraries and the Mojang metadata for the vanilla server jar.
+func newLoaderStub(t *testing.T, loaderLib, hashedLib []byte) *httptest.Server {
+	t.Helper()
+	vanilla := []byte("vanilla server jar")
+	launcher := buildZip(t, map[string]string{"META-INF/MANIFEST.MF": "Main-Class: net.fabricmc.Launcher\n"})
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/versions/loader/1.20.1":
+			_, _ = w.Write([]byte(`[{"loader":{"version":"0.15.11","stable":true}}]`))
+		case "/v2/versions/installer":
+			_, _ = w.Write([]byte(`[{"version":"1.0.1","stable":true}]`))
+		case "/v2/versions/loader/1.20.1/0.15.11/1.0.1/server/jar":
+			_, _ = w.Write(launcher)
+		case "/v3/versions/loader/1.20.1":
+			_, _ = w.Write([]byte(`[{"loader":{"version":"0.26.4"}}]`))
+		case "/v3/versions/loader/1.20.1/0.26.4/server/jar":
+			http.Error(w, "not supported", http.StatusNotFound)
+		case "/v3/versions/loader/1.20.1/0.26.4/server/json":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":        "quilt-loader-0.26.4-1.20.1",
+				"mainClass": "org.quiltmc.loader.impl.launch.knot.KnotServer",
+				"libraries": []any{
+					map[string]any{"name": "org.quiltmc:quilt-loader:0.26.4", "url": srv.URL + "/maven/"},
+					map[string]any{"name": "org.quiltmc:hashed:1.20.1", "url": srv.URL + "/maven/", "sha1": sha1Hex(hashedLib)},
+				},
+			})
+		case "/maven/org/quiltmc/quilt-loader/0.26.4/quilt-loader-0.26.4.jar":
+			_, _ = w.Write(loaderLib)
+		case "/maven/org/quiltmc/hashed/1.20.1/hashed-1.20.1.jar":
+			_, _ = w.Write(hashedLib)
+		case "/mc/game/version_manifest_v2.json":
+			_, _ = w.Write([]byte(`{"versions":[{"id":"1.20.1","url":"` + srv.URL + `/v/1.20.1.json"}]}`))
+		case "/v/1.20.1.json":
+			_, _ = w.Write([]byte(`{"downloads":{"server":{"url":"` + srv.URL + `/server.jar","sha1":"` + sha1Hex(vanilla) + `"}}}`))
+		case "/server.jar":
+			_, _ = w.Write(vanilla)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func readInstanceConfigFile(t *testing.T, instDir string) map[string]any {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(instDir, ".elegantmc.json"))
+	if err != nil {
+		t.Fatalf("read instance config: %v", err)
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		t.Fatalf("parse instance config: %v", err)
+	}
+	return cfg
+}
+
+func TestExecutor_MCInstallFabric(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	srv := newLoaderStub(t, []byte("loader"), []byte("hashed"))
+	ex.deps.Fabric.MetaBaseURL = srv.URL
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_fabric", Args: map[string]any{
+		"instance_id": "fab1",
+		"version":     "1.20.1",
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_fabric failed: %s", res.Error)
+	}
+	if res.Output["loader_version"] != "0.15.11" || res.Output["installer_version"] != "1.0.1" || res.Output["jar_path"] != "fabric-server-launch.jar" {
+		t.Fatalf("unexpected output: %#v", res.Output)
+	}
+	instDir := filepath.Join(serversRoot, "fab1")
+	for _, name := range []string{"fabric-server-launch.jar", "server.jar"} {
+		if _, err := os.Stat(filepath.Join(instDir, name)); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["jar_path"] != "fabric-server-launch.jar" || cfg["server_kind"] != "fabric" || cfg["loader_version"] != "0.15.11" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+}
+
+func TestExecutor_MCInstallQuilt(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	loaderLib, hashedLib := []byte("quilt loader classes"), []byte("hashed mappings")
+	srv := newLoaderStub(t, loaderLib, hashedLib)
+	ex.deps.Quilt.MetaBaseURL = srv.URL
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_quilt", Args: map[string]any{
+		"instance_id": "q1",
+		"version":     "1.20.1",
+		"accept_eula": true,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_quilt failed: %s", res.Error)
+	}
+	if res.Output["loader_version"] != "0.26.4" || res.Output["libraries"] != 2 || res.Output["jar_path"] != "quilt-server-launch.jar" {
+		t.Fatalf("unexpected output: %#v", res.Output)
+	}
+
+	instDir := filepath.Join(serversRoot, "q1")
+	for name, want := range map[string][]byte{
+		"libraries/org/quiltmc/quilt-loader/0.26.4/quilt-loader-0.26.4.jar": loaderLib,
+		"libraries/org/quiltmc/hashed/1.20.1/hashed-1.20.1.jar":             hashedLib,
+	} {
+		b, err := os.ReadFile(filepath.Join(instDir, filepath.FromSlash(name)))
+		if err != nil || string(b) != string(want) {
+			t.Fatalf("%s: got %q, %v", name, b, err)
+		}
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(instDir, "quilt-server-launch.jar"))
+	if err != nil {
+		t.Fatalf("open launcher: %v", err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	mf := strings.ReplaceAll(files["META-INF/MANIFEST.MF"], "\r\n ", "")
+	if !strings.Contains(mf, "Main-Class: org.quiltmc.loader.impl.launch.server.QuiltServerLauncher\r\n") {
+		t.Fatalf("unexpected manifest: %q", mf)
+	}
+	if !strings.Contains(mf, "Class-Path: libraries/org/quiltmc/quilt-loader/0.26.4/quilt-loader-0.26.4.jar libraries/org/quiltmc/hashed/1.20.1/hashed-1.20.1.jar\r\n") {
+		t.Fatalf("unexpected class path: %q", mf)
+	}
+	if files["quilt-server-launch.properties"] != "launch.mainClass=org.quiltmc.loader.impl.launch.knot.KnotServer\n" {
+		t.Fatalf("unexpected launch properties: %q", files["quilt-server-launch.properties"])
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "server.jar")); err != nil {
+		t.Fatalf("expected vanilla server.jar: %v", err)
+	}
+
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["jar_path"] != "quilt-server-launch.jar" || cfg["server_kind"] != "quilt" || cfg["loader_version"] != "0.26.4" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+}
+
+func TestExecutor_MCInstallQuilt_LibraryChecksumMismatch(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	srv := newLoaderStub(t, []byte("loader"), []byte("hashed"))
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	// A profile whose sha1 does not match the library the maven stub serves.
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/server/json") {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"mainClass": "org.quiltmc.loader.impl.launch.knot.KnotServer",
+				"libraries": []any{map[string]any{"name": "org.quiltmc:hashed:1.20.1", "url": srv.URL + "/maven/", "sha1": sha1Hex([]byte("other"))}},
+			})
+			return
+		}
+		http.Redirect(w, r, srv.URL+r.URL.Path, http.StatusFound)
+	}))
+	t.Cleanup(bad.Close)
+	ex.deps.Quilt.MetaBaseURL = bad.URL
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_quilt", Args: map[string]any{
+		"instance_id": "q1",
+		"version":     "1.20.1",
+	}})
+	if res.OK {
+		t.Fatalf("expected failure on library checksum mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(serversRoot, "q1", "quilt-server-launch.jar")); !os.IsNotExist(err) {
+		t.Fatalf("launcher should not be written, stat err=%v", err)
+	}
+}
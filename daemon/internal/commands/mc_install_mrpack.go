@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const mrpackIndexName = "modrinth.index.json"
+
+type mrpackIndex struct {
+	FormatVersion int               `json:"formatVersion"`
+	Game          string            `json:"game"`
+	VersionID     string            `json:"versionId"`
+	Name          string            `json:"name"`
+	Files         []mrpackFile      `json:"files"`
+	Dependencies  map[string]string `json:"dependencies"`
+}
+
+type mrpackFile struct {
+	Path   string            `json:"path"`
+	Hashes map[string]string `json:"hashes"`
+	Env    *struct {
+		Client string `json:"client"`
+		Server string `json:"server"`
+	} `json:"env"`
+	Downloads []string `json:"downloads"`
+	FileSize  int64    `json:"fileSize"`
+}
+
+// mrpackLoaders maps modrinth.index.json dependency ids to installer loaders, in the
+// order they are checked.
+var mrpackLoaders = []struct{ dep, loader string }{
+	{"neoforge", "neoforge"},
+	{"forge", "forge"},
+	{"quilt-loader", "quilt"},
+	{"fabric-loader", "fabric"},
+}
+
+// mcInstallMrpack installs a Modrinth modpack (.mrpack) as a server: it downloads every
+// server-side file listed in modrinth.index.json (sha1/sha512 verified), applies
+// overrides/ and server-overrides/, then installs the pack's loader.
+func (e *Executor) mcInstallMrpack(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	packPath, _ := asString(cmd.Args["path"])
+	packURL, _ := asString(cmd.Args["url"])
+	packSHA1, _ := asString(cmd.Args["sha1"])
+	packSHA512, _ := asString(cmd.Args["sha512"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	packPath = strings.TrimSpace(packPath)
+	packURL = strings.TrimSpace(packURL)
+	if (packPath == "") == (packURL == "") {
+		return fail("exactly one of path or url is required")
+	}
+	clientMods, err := parseClientModsMode(cmd.Args["client_mods"], "disable")
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	var packAbs string
+	if packPath != "" {
+		abs, err := e.deps.FS.Resolve(packPath)
+		if err != nil {
+			return fail(err.Error())
+		}
+		packAbs = abs
+	} else {
+		abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, ".elegantmc-modpack.mrpack"))
+		if err != nil {
+			return fail(err.Error())
+		}
+		e.emitInstall(instanceID, "download modpack: "+packURL)
+		dl, err := download.DownloadFileVerified(ctx, packURL, abs, download.Expected{SHA1: packSHA1, SHA512: packSHA512}, func(p download.Progress) {
+			if p.Total > 0 {
+				e.emitInstall(instanceID, fmt.Sprintf("downloading... %d/%d bytes (%.1f%%)", p.Bytes, p.Total, float64(p.Bytes)*100/float64(p.Total)))
+			} else {
+				e.emitInstall(instanceID, fmt.Sprintf("downloading... %d bytes", p.Bytes))
+			}
+		})
+		if err != nil {
+			return fail(err.Error())
+		}
+		defer os.Remove(abs)
+		e.emitInstall(instanceID, fmt.Sprintf("download ok: bytes=%d sha1=%s", dl.Bytes, dl.SHA1))
+		packAbs = abs
+	}
+
+	zr, err := zip.OpenReader(packAbs)
+	if err != nil {
+		return fail(fmt.Sprintf("open mrpack: %v", err))
+	}
+	defer zr.Close()
+
+	index, err := readMrpackIndex(&zr.Reader)
+	if err != nil {
+		return fail(err.Error())
+	}
+	gameVersion := strings.TrimSpace(index.Dependencies["minecraft"])
+	if gameVersion == "" {
+		return fail(mrpackIndexName + " has no minecraft dependency")
+	}
+	loader, loaderVersion := "vanilla", ""
+	for _, l := range mrpackLoaders {
+		if v := strings.TrimSpace(index.Dependencies[l.dep]); v != "" {
+			loader, loaderVersion = l.loader, v
+			break
+		}
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("modpack %s %s: minecraft=%s loader=%s %s files=%d", index.Name, index.VersionID, gameVersion, loader, loaderVersion, len(index.Files)))
+
+	var files []modpackFile
+	skipped := 0
+	for _, f := range index.Files {
+		if f.Env != nil && strings.EqualFold(strings.TrimSpace(f.Env.Server), "unsupported") {
+			e.emitInstall(instanceID, "skip (client-only) "+f.Path)
+			skipped++
+			continue
+		}
+		rel, err := modpackTargetRel(instanceID, f.Path)
+		if err != nil {
+			return fail(err.Error())
+		}
+		want := download.Expected{SHA1: f.Hashes["sha1"], SHA512: f.Hashes["sha512"]}
+		if want.SHA1 == "" && want.SHA512 == "" {
+			return fail(fmt.Sprintf("%s: missing sha1/sha512 for %s", mrpackIndexName, f.Path))
+		}
+		if len(f.Downloads) == 0 {
+			return fail(fmt.Sprintf("%s: no downloads for %s", mrpackIndexName, f.Path))
+		}
+		files = append(files, modpackFile{Rel: rel, URLs: f.Downloads, Want: want})
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("download %d files", len(files)))
+	if err := e.downloadModpackFiles(ctx, instanceID, files); err != nil {
+		return fail(err.Error())
+	}
+
+	// server-overrides/ is applied last so it wins over overrides/.
+	overrides := 0
+	for _, prefix := range []string{"overrides/", "server-overrides/"} {
+		n, err := e.extractModpackDir(ctx, &zr.Reader, prefix, instanceID)
+		if err != nil {
+			return fail(fmt.Sprintf("apply %s: %v", strings.TrimSuffix(prefix, "/"), err))
+		}
+		if n > 0 {
+			e.emitInstall(instanceID, fmt.Sprintf("applied %s: files=%d", strings.TrimSuffix(prefix, "/"), n))
+		}
+		overrides += n
+	}
+
+	res := e.installModpackLoader(ctx, cmd, instanceID, loader, gameVersion, loaderVersion)
+	if !res.OK {
+		return fail("install loader: " + res.Error)
+	}
+
+	clientOnly, err := e.scanInstanceClientMods(ctx, instanceID, clientMods == "disable")
+	if err != nil {
+		return fail(fmt.Sprintf("check client-only mods: %v", err))
+	}
+
+	if err := e.updateInstanceConfig(instanceID, map[string]any{
+		"modpack_provider": "modrinth",
+		"modpack_name":     index.Name,
+		"modpack_version":  index.VersionID,
+	}); err != nil {
+		return fail(err.Error())
+	}
+	e.emitInstall(instanceID, "modpack install done")
+
+	return ok(map[string]any{
+		"instance_id":      instanceID,
+		"name":             index.Name,
+		"version_id":       index.VersionID,
+		"minecraft":        gameVersion,
+		"loader":           loader,
+		"loader_version":   res.Output["loader_version"],
+		"jar_path":         res.Output["jar_path"],
+		"files":            len(files),
+		"skipped_files":    skipped,
+		"override_files":   overrides,
+		"client_only_mods": clientOnly,
+	})
+}
+
+func readMrpackIndex(zr *zip.Reader) (mrpackIndex, error) {
+	for _, f := range zr.File {
+		if f.Name != mrpackIndexName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return mrpackIndex{}, err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, 32*1024*1024))
+		rc.Close()
+		if err != nil {
+			return mrpackIndex{}, err
+		}
+		var index mrpackIndex
+		if err := json.Unmarshal(b, &index); err != nil {
+			return mrpackIndex{}, fmt.Errorf("invalid %s: %w", mrpackIndexName, err)
+		}
+		if index.FormatVersion != 1 {
+			return mrpackIndex{}, fmt.Errorf("unsupported %s formatVersion: %d", mrpackIndexName, index.FormatVersion)
+		}
+		if index.Game != "" && index.Game != "minecraft" {
+			return mrpackIndex{}, fmt.Errorf("unsupported modpack game: %s", index.Game)
+		}
+		return index, nil
+	}
+	return mrpackIndex{}, errors.New("not a .mrpack: missing " + mrpackIndexName)
+}
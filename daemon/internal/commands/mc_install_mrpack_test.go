@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+func sha512Hex(b []byte) string {
+	sum := sha512.Sum512(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// newMrpackStub serves pack files under /files/ and hands everything else to the
+// Fabric/Mojang loader stub. Requests for /files/client.jar are counted.
+func newMrpackStub(t *testing.T, files map[string][]byte, clientHits *int32) *httptest.Server {
+	t.Helper()
+	loaders := newLoaderStub(t, []byte("loader"), []byte("hashed"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, isFile := strings.CutPrefix(r.URL.Path, "/files/")
+		if !isFile {
+			http.Redirect(w, r, loaders.URL+r.URL.Path, http.StatusFound)
+			return
+		}
+		if name == "client.jar" {
+			atomic.AddInt32(clientHits, 1)
+		}
+		b, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeMrpack(t *testing.T, serversRoot string, index map[string]any, extra map[string]string) {
+	t.Helper()
+	b, err := json.Marshal(index)
+	if err != nil {
+		t.Fatalf("marshal index: %v", err)
+	}
+	files := map[string]string{mrpackIndexName: string(b)}
+	for k, v := range extra {
+		files[k] = v
+	}
+	if err := os.WriteFile(filepath.Join(serversRoot, "pack.mrpack"), buildZip(t, files), 0o644); err != nil {
+		t.Fatalf("write pack: %v", err)
+	}
+}
+
+func mrpackIndexWith(files ...map[string]any) map[string]any {
+	return map[string]any{
+		"formatVersion": 1,
+		"game":          "minecraft",
+		"versionId":     "1.2.3",
+		"name":          "Test Pack",
+		"files":         files,
+		"dependencies":  map[string]string{"minecraft": "1.20.1", "fabric-loader": "0.15.11"},
+	}
+}
+
+func TestExecutor_MCInstallMrpack(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	modA, cfgFile := []byte("mod a"), []byte("option = true\n")
+	var clientHits int32
+	srv := newMrpackStub(t, map[string][]byte{"a.jar": modA, "client.jar": []byte("client"), "extra.toml": cfgFile}, &clientHits)
+	ex.deps.Fabric.MetaBaseURL = srv.URL
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	writeMrpack(t, serversRoot, mrpackIndexWith(
+		map[string]any{"path": "mods/a.jar", "downloads": []string{srv.URL + "/files/missing.jar", srv.URL + "/files/a.jar"},
+			"hashes": map[string]string{"sha1": sha1Hex(modA), "sha512": sha512Hex(modA)},
+			"env":    map[string]string{"client": "required", "server": "required"}},
+		map[string]any{"path": "mods/client.jar", "downloads": []string{srv.URL + "/files/client.jar"},
+			"hashes": map[string]string{"sha1": sha1Hex([]byte("client"))},
+			"env":    map[string]string{"client": "required", "server": "unsupported"}},
+		map[string]any{"path": "config/extra.toml", "downloads": []string{srv.URL + "/files/extra.toml"},
+			"hashes": map[string]string{"sha512": sha512Hex(cfgFile)}},
+	), map[string]string{
+		"overrides/config/pack.toml":        "source = \"overrides\"\n",
+		"overrides/server.properties":       "motd=pack\n",
+		"server-overrides/config/pack.toml": "source = \"server-overrides\"\n",
+		"client-overrides/options.txt":      "ignored",
+	})
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_mrpack", Args: map[string]any{
+		"instance_id": "mr1",
+		"path":        "pack.mrpack",
+		"jar_name":    "server.jar", // the panel's default, must not clash with the vanilla jar
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_mrpack failed: %s", res.Error)
+	}
+	if res.Output["loader"] != "fabric" || res.Output["jar_path"] != "fabric-server-launch.jar" ||
+		res.Output["files"] != 2 || res.Output["skipped_files"] != 1 || res.Output["override_files"] != 3 {
+		t.Fatalf("unexpected output: %#v", res.Output)
+	}
+	if n := atomic.LoadInt32(&clientHits); n != 0 {
+		t.Fatalf("server-unsupported file should not be downloaded, got %d requests", n)
+	}
+
+	instDir := filepath.Join(serversRoot, "mr1")
+	for name, want := range map[string]string{
+		"mods/a.jar":        string(modA),
+		"config/extra.toml": string(cfgFile),
+		"config/pack.toml":  "source = \"server-overrides\"\n",
+		"server.properties": "motd=pack\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(instDir, filepath.FromSlash(name)))
+		if err != nil || string(b) != want {
+			t.Fatalf("%s: got %q, %v; want %q", name, b, err, want)
+		}
+	}
+	for _, name := range []string{"mods/client.jar", "options.txt"} {
+		if _, err := os.Stat(filepath.Join(instDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Fatalf("%s should not be installed, stat err=%v", name, err)
+		}
+	}
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["jar_path"] != "fabric-server-launch.jar" || cfg["modpack_provider"] != "modrinth" || cfg["modpack_name"] != "Test Pack" || cfg["modpack_version"] != "1.2.3" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+}
+
+func TestExecutor_MCInstallMrpack_SHA512Mismatch(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	var clientHits int32
+	srv := newMrpackStub(t, map[string][]byte{"a.jar": []byte("tampered")}, &clientHits)
+
+	writeMrpack(t, serversRoot, mrpackIndexWith(
+		map[string]any{"path": "mods/a.jar", "downloads": []string{srv.URL + "/files/a.jar"},
+			"hashes": map[string]string{"sha512": sha512Hex([]byte("mod a"))}},
+	), map[string]string{"overrides/server.properties": "motd=pack\n"})
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_mrpack", Args: map[string]any{
+		"instance_id": "mr1",
+		"path":        "pack.mrpack",
+	}})
+	if res.OK || !strings.Contains(res.Error, "sha512 mismatch") {
+		t.Fatalf("expected sha512 mismatch, got ok=%v err=%s", res.OK, res.Error)
+	}
+	for _, name := range []string{"mods/a.jar", "server.properties"} {
+		if _, err := os.Stat(filepath.Join(serversRoot, "mr1", filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Fatalf("%s should not be written, stat err=%v", name, err)
+		}
+	}
+}
+
+func TestExecutor_MCInstallMrpack_RejectsEscapingPaths(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		file  string
+		extra map[string]string
+	}{
+		{"parent", "../evil.jar", nil},
+		{"nested parent", "mods/../../evil.jar", nil},
+		{"absolute", "/evil.jar", nil},
+		{"backslash parent", "mods\\..\\..\\evil.jar", nil},
+		{"override parent", "mods/ok.jar", map[string]string{"overrides/../evil.jar": "x"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ex, _, serversRoot := newTestExecutor(t)
+			var clientHits int32
+			srv := newMrpackStub(t, map[string][]byte{"evil.jar": []byte("evil")}, &clientHits)
+			writeMrpack(t, serversRoot, mrpackIndexWith(
+				map[string]any{"path": tc.file, "downloads": []string{srv.URL + "/files/evil.jar"},
+					"hashes": map[string]string{"sha1": sha1Hex([]byte("evil"))}},
+			), tc.extra)
+
+			res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_mrpack", Args: map[string]any{
+				"instance_id": "mr1",
+				"path":        "pack.mrpack",
+			}})
+			if res.OK || !strings.Contains(res.Error, "invalid modpack path") {
+				t.Fatalf("expected invalid path error, got ok=%v err=%s", res.OK, res.Error)
+			}
+			if _, err := os.Stat(filepath.Join(serversRoot, "evil.jar")); !os.IsNotExist(err) {
+				t.Fatalf("file escaped the instance dir, stat err=%v", err)
+			}
+		})
+	}
+}
+
+func TestModpackTargetRel(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"mods/a.jar", filepath.Join("mr1", "mods", "a.jar"), true},
+		{"config/./x/../pack.toml", filepath.Join("mr1", "config", "pack.toml"), true},
+		{"mods\\b.jar", filepath.Join("mr1", "mods", "b.jar"), true},
+		{"", "", false},
+		{".", "", false},
+		{"..", "", false},
+		{"../a.jar", "", false},
+		{"mods/../../a.jar", "", false},
+		{"/etc/passwd", "", false},
+		{"\\a.jar", "", false},
+		{"C:/a.jar", "", false},
+	}
+	for _, tc := range cases {
+		got, err := modpackTargetRel("mr1", tc.in)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("modpackTargetRel(%q) = %q, %v; want %q ok=%v", tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+// newJarAPIStub serves the PaperMC downloads API (paper and velocity) and the Purpur API,
+// each with a single 1.20.4 / 3.3.0 build.
+func newJarAPIStub(t *testing.T, jar []byte) *httptest.Server {
+	t.Helper()
+	sum256 := sha256.Sum256(jar)
+	sumMD5 := md5.Sum(jar)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/projects/paper/versions/1.20.4", "/v2/projects/velocity/versions/3.3.0":
+			_, _ = w.Write([]byte(`{"builds":[100,101]}`))
+		case "/v2/projects/paper/versions/1.20.4/builds/101", "/v2/projects/velocity/versions/3.3.0/builds/101":
+			_ = json.NewEncoder(w).Encode(map[string]any{"downloads": map[string]any{"application": map[string]any{
+				"name": "app-101.jar", "sha256": hex.EncodeToString(sum256[:]),
+			}}})
+		case "/v2/projects/paper/versions/1.20.4/builds/101/downloads/app-101.jar",
+			"/v2/projects/velocity/versions/3.3.0/builds/101/downloads/app-101.jar",
+			"/v2/purpur/1.20.4/2100/download":
+			_, _ = w.Write(jar)
+		case "/v2/purpur/1.20.4":
+			_, _ = w.Write([]byte(`{"builds":{"latest":"2100","all":["2099","2100"]}}`))
+		case "/v2/purpur/1.20.4/2100":
+			// Older Purpur builds only publish an md5.
+			_ = json.NewEncoder(w).Encode(map[string]any{"build": "2100", "result": "SUCCESS", "md5": hex.EncodeToString(sumMD5[:])})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExecutor_MCInstallPaper_MergesInstanceConfig(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	jar := []byte("paper jar")
+	ex.deps.Paper.APIBaseURL = newJarAPIStub(t, jar).URL
+
+	instDir := filepath.Join(serversRoot, "p1")
+	if err := os.MkdirAll(instDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, ".elegantmc.json"), []byte(`{"jar_path":"old.jar","xmx":"6G","server_kind":"vanilla"}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install_paper", Args: map[string]any{
+		"instance_id": "p1",
+		"version":     "1.20.4",
+		"accept_eula": true,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install_paper failed: %s", res.Error)
+	}
+	if res.Output["software"] != "paper" || res.Output["build"] != 101 || res.Output["jar_path"] != "server.jar" {
+		t.Fatalf("unexpected output: %#v", res.Output)
+	}
+	if b, _ := os.ReadFile(filepath.Join(instDir, "server.jar")); string(b) != string(jar) {
+		t.Fatalf("server.jar = %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "eula.txt")); err != nil {
+		t.Fatalf("expected eula.txt: %v", err)
+	}
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["jar_path"] != "server.jar" || cfg["server_kind"] != "paper" || cfg["server_version"] != "1.20.4" || cfg["server_build"] != float64(101) {
+		t.Fatalf("instance config not updated: %#v", cfg)
+	}
+	if cfg["xmx"] != "6G" {
+		t.Fatalf("unrelated config fields must be kept: %#v", cfg)
+	}
+}
+
+func TestExecutor_MCInstall_Registry(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	jar := []byte("registry jar")
+	srv := newJarAPIStub(t, jar)
+	ex.deps.Paper.APIBaseURL = srv.URL
+	ex.deps.Purpur.APIBaseURL = srv.URL
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install", Args: map[string]any{
+		"software":    "purpur",
+		"instance_id": "pp1",
+		"version":     "1.20.4",
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install purpur failed: %s", res.Error)
+	}
+	if res.Output["build"] != 2100 || res.Output["proxy"] != false {
+		t.Fatalf("unexpected purpur output: %#v", res.Output)
+	}
+
+	// Proxies never get an eula.txt.
+	res = ex.Execute(context.Background(), protocol.Command{Name: "mc_install", Args: map[string]any{
+		"software":    "velocity",
+		"instance_id": "v1",
+		"version":     "3.3.0",
+		"accept_eula": true,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_install velocity failed: %s", res.Error)
+	}
+	if res.Output["proxy"] != true {
+		t.Fatalf("velocity should be reported as a proxy: %#v", res.Output)
+	}
+	if _, err := os.Stat(filepath.Join(serversRoot, "v1", "eula.txt")); !os.IsNotExist(err) {
+		t.Fatalf("proxy install wrote eula.txt, stat err=%v", err)
+	}
+	if cfg := readInstanceConfigFile(t, filepath.Join(serversRoot, "v1")); cfg["server_kind"] != "velocity" {
+		t.Fatalf("unexpected instance config: %#v", cfg)
+	}
+
+	for _, software := range []string{"", "bukkit"} {
+		res := ex.Execute(context.Background(), protocol.Command{Name: "mc_install", Args: map[string]any{
+			"software":    software,
+			"instance_id": "x1",
+			"version":     "1.20.4",
+		}})
+		if res.OK {
+			t.Fatalf("expected software %q to be rejected", software)
+		}
+	}
+}
@@ -22,9 +22,11 @@ func (e *Executor) mcJavaCacheList(cmd protocol.Command) protocol.CommandResult
 		return fail(err.Error())
 	}
 	return ok(map[string]any{
-		"cache_dir": rt.CacheDir(),
-		"runtimes":  list,
-		"count":     len(list),
+		"cache_dir":      rt.CacheDir(),
+		"default_vendor": rt.DefaultVendor(),
+		"providers":      rt.Providers(),
+		"runtimes":       list,
+		"count":          len(list),
 	})
 }
 
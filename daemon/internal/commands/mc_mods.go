@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"elegantmc/daemon/internal/mods"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// disabledSuffix marks a mod or plugin jar that the server should not load.
+const disabledSuffix = ".disabled"
+
+// modDirs are the instance folders that hold mod and plugin jars.
+var modDirs = []string{"mods", "plugins"}
+
+type modFileInfo struct {
+	File         string     `json:"file"`
+	Dir          string     `json:"dir"`
+	Path         string     `json:"path"` // relative to the instance
+	Enabled      bool       `json:"enabled"`
+	Bytes        int64      `json:"bytes"`
+	ModifiedUnix int64      `json:"modified_unix"`
+	SHA1         string     `json:"sha1"`
+	SHA256       string     `json:"sha256"`
+	Mods         []mods.Mod `json:"mods"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// mcModsList lists the jars in mods/ and plugins/ with their metadata and hashes.
+func (e *Executor) mcModsList(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	dir, _ := asString(cmd.Args["dir"])
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	dirs := modDirs
+	if dir = strings.TrimSpace(dir); dir != "" {
+		if !isModDir(dir) {
+			return fail("dir must be mods or plugins")
+		}
+		dirs = []string{dir}
+	}
+
+	var files []modFileInfo
+	for _, d := range dirs {
+		list, err := e.listModFiles(ctx, instanceID, d)
+		if err != nil {
+			return fail(err.Error())
+		}
+		files = append(files, list...)
+	}
+	if files == nil {
+		files = []modFileInfo{}
+	}
+	return ok(map[string]any{"instance_id": instanceID, "files": files})
+}
+
+func (e *Executor) listModFiles(ctx context.Context, instanceID, dir string) ([]modFileInfo, error) {
+	dirAbs, err := e.deps.FS.Resolve(filepath.Join(instanceID, dir))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dirAbs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []modFileInfo
+	for _, ent := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := ent.Name()
+		enabled, isJar := modJarState(name)
+		if !isJar || !ent.Type().IsRegular() {
+			continue
+		}
+		info := modFileInfo{File: name, Dir: dir, Path: dir + "/" + name, Enabled: enabled, Mods: []mods.Mod{}}
+		abs := filepath.Join(dirAbs, name)
+		if st, err := ent.Info(); err == nil {
+			info.Bytes = st.Size()
+			info.ModifiedUnix = st.ModTime().Unix()
+		}
+		if info.SHA1, info.SHA256, err = hashModFile(abs); err != nil {
+			info.Error = err.Error()
+			out = append(out, info)
+			continue
+		}
+		if list, err := mods.ReadJar(abs); err != nil {
+			info.Error = err.Error()
+		} else if len(list) > 0 {
+			info.Mods = list
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].File) < strings.ToLower(out[j].File) })
+	return out, nil
+}
+
+// mcModsSetEnabled enables or disables a mod/plugin jar by removing or adding the
+// ".disabled" suffix.
+func (e *Executor) mcModsSetEnabled(cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	rel, _ := asString(cmd.Args["path"])
+	enabled, okEnabled := asBool(cmd.Args["enabled"])
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	if !okEnabled {
+		return fail("enabled is required")
+	}
+	dir, name, err := splitModPath(rel)
+	if err != nil {
+		return fail(err.Error())
+	}
+	target, err := e.setModEnabled(filepath.Join(instanceID, dir), name, enabled)
+	if err != nil {
+		return fail(err.Error())
+	}
+	return ok(map[string]any{"instance_id": instanceID, "path": dir + "/" + target, "enabled": enabled})
+}
+
+// setModEnabled renames the jar name in dirRel (relative to the servers root) to add or
+// remove the ".disabled" suffix and returns the new name.
+func (e *Executor) setModEnabled(dirRel, name string, enabled bool) (string, error) {
+	isEnabled, _ := modJarState(name)
+	target := name
+	if enabled && !isEnabled {
+		target = name[:len(name)-len(disabledSuffix)]
+	} else if !enabled && isEnabled {
+		target = name + disabledSuffix
+	}
+	if target == name {
+		return name, nil
+	}
+
+	srcAbs, err := e.deps.FS.Resolve(filepath.Join(dirRel, name))
+	if err != nil {
+		return "", err
+	}
+	dstAbs, err := e.deps.FS.Resolve(filepath.Join(dirRel, target))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(srcAbs); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dstAbs); err == nil {
+		return "", fmt.Errorf("%s already exists", filepath.ToSlash(filepath.Join(dirRel, target)))
+	}
+	if err := os.Rename(srcAbs, dstAbs); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// modJarState reports whether name is a mod/plugin jar and whether it is enabled.
+func modJarState(name string) (enabled bool, isJar bool) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".jar") {
+		return true, true
+	}
+	if strings.HasSuffix(lower, ".jar"+disabledSuffix) {
+		return false, true
+	}
+	return false, false
+}
+
+func isModDir(dir string) bool {
+	for _, d := range modDirs {
+		if dir == d {
+			return true
+		}
+	}
+	return false
+}
+
+// splitModPath validates "mods/<file>.jar[.disabled]" (relative to the instance).
+func splitModPath(rel string) (string, string, error) {
+	clean := path.Clean(strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(rel), "\\", "/"), "/"))
+	dir, name := path.Split(clean)
+	dir = strings.TrimSuffix(dir, "/")
+	if !isModDir(dir) || name == "" {
+		return "", "", errors.New("path must be a file in mods/ or plugins/")
+	}
+	if _, isJar := modJarState(name); !isJar {
+		return "", "", errors.New("path must be a .jar or .jar.disabled file")
+	}
+	return dir, name, nil
+}
+
+func hashModFile(abs string) (string, string, error) {
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h1, h256), f); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h1.Sum(nil)), hex.EncodeToString(h256.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"elegantmc/daemon/internal/mods"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// clientModReport describes a jar that should not be loaded on a dedicated server.
+type clientModReport struct {
+	Path     string   `json:"path"`
+	Reason   string   `json:"reason"`
+	ModIDs   []string `json:"mod_ids,omitempty"`
+	Disabled bool     `json:"disabled"`
+	NewPath  string   `json:"new_path,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// parseClientModsMode reads the client_mods argument of the install commands:
+// "disable" moves client-only jars aside, "report" only lists them.
+func parseClientModsMode(v any, def string) (string, error) {
+	mode, _ := asString(v)
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return def, nil
+	case "disable", "report":
+		return mode, nil
+	default:
+		return "", errors.New("client_mods must be disable or report")
+	}
+}
+
+// scanClientOnlyMods checks the given jars (relative to baseRel, which is relative to the
+// servers root) for client-only metadata or a denylist entry. With disable set the
+// matching jars get the ".disabled" suffix. instanceID is only used for install logs.
+func (e *Executor) scanClientOnlyMods(ctx context.Context, instanceID, baseRel string, rels []string, disable bool) ([]clientModReport, error) {
+	deny, err := mods.LoadDenylist(e.deps.ClientModDenylist)
+	if err != nil {
+		return nil, fmt.Errorf("client mod denylist: %w", err)
+	}
+	sort.Strings(rels)
+	reports := []clientModReport{}
+	for _, rel := range rels {
+		if err := ctx.Err(); err != nil {
+			return reports, err
+		}
+		abs, err := e.deps.FS.Resolve(filepath.Join(baseRel, filepath.FromSlash(rel)))
+		if err != nil {
+			return reports, err
+		}
+		// Jars without readable metadata can still match the denylist by file name.
+		found, _ := mods.ReadJar(abs)
+		reason := mods.ClientOnlyReason(path.Base(rel), found, deny)
+		if reason == "" {
+			continue
+		}
+		r := clientModReport{Path: rel, Reason: reason}
+		for _, m := range found {
+			r.ModIDs = append(r.ModIDs, m.ID)
+		}
+		if disable {
+			dir, name := path.Split(rel)
+			target, err := e.setModEnabled(filepath.Join(baseRel, filepath.FromSlash(dir)), name, false)
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				r.Disabled = true
+				r.NewPath = dir + target
+			}
+		}
+		switch {
+		case r.Error != "":
+			e.emitInstall(instanceID, fmt.Sprintf("client-only mod %s (%s): disable failed: %s", rel, reason, r.Error))
+		case r.Disabled:
+			e.emitInstall(instanceID, fmt.Sprintf("client-only mod %s (%s): disabled", rel, reason))
+		default:
+			e.emitInstall(instanceID, fmt.Sprintf("client-only mod %s (%s)", rel, reason))
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// scanInstanceClientMods runs scanClientOnlyMods over the enabled jars in <instance>/mods.
+func (e *Executor) scanInstanceClientMods(ctx context.Context, instanceID string, disable bool) ([]clientModReport, error) {
+	abs, err := e.deps.FS.Resolve(filepath.Join(instanceID, "mods"))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var rels []string
+	for _, ent := range entries {
+		if enabled, isJar := modJarState(ent.Name()); ent.Type().IsRegular() && isJar && enabled {
+			rels = append(rels, "mods/"+ent.Name())
+		}
+	}
+	return e.scanClientOnlyMods(ctx, instanceID, instanceID, rels, disable)
+}
+
+// mcModsClientOnly reports the client-only jars in mods/ and optionally disables them.
+func (e *Executor) mcModsClientOnly(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	disable, _ := asBool(cmd.Args["disable"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	reports, err := e.scanInstanceClientMods(ctx, instanceID, disable)
+	if err != nil {
+		return fail(err.Error())
+	}
+	return ok(map[string]any{"instance_id": instanceID, "client_only_mods": reports})
+}
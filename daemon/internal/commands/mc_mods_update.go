@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// modrinthLoaders maps an instance's server_kind to the Modrinth loaders accepted for
+// the jars in mods/ and plugins/.
+var modrinthLoaders = map[string]map[string][]string{
+	"mods": {
+		"fabric":   {"fabric"},
+		"quilt":    {"quilt", "fabric"},
+		"forge":    {"forge"},
+		"neoforge": {"neoforge"},
+	},
+	"plugins": {
+		"paper":     {"paper", "spigot", "bukkit"},
+		"purpur":    {"purpur", "paper", "spigot", "bukkit"},
+		"folia":     {"folia"},
+		"velocity":  {"velocity"},
+		"waterfall": {"waterfall", "bungeecord"},
+	},
+}
+
+// modrinthChannels lists the Modrinth version types at least as stable as channel, so an
+// update never moves a jar to a less stable channel than asked for.
+func modrinthChannels(channel string) []string {
+	switch channel {
+	case "alpha":
+		return []string{"release", "beta", "alpha"}
+	case "beta":
+		return []string{"release", "beta"}
+	default:
+		return []string{"release"}
+	}
+}
+
+type modUpdateLatest struct {
+	VersionID     string `json:"version_id"`
+	VersionNumber string `json:"version_number"`
+	VersionType   string `json:"version_type,omitempty"`
+	Filename      string `json:"filename"`
+	URL           string `json:"url"`
+}
+
+type modUpdateInfo struct {
+	Path            string           `json:"path"`
+	SHA1            string           `json:"sha1"`
+	ProjectID       string           `json:"project_id,omitempty"`
+	VersionID       string           `json:"version_id,omitempty"`
+	VersionNumber   string           `json:"version_number,omitempty"`
+	VersionType     string           `json:"version_type,omitempty"`
+	UpdateAvailable bool             `json:"update_available"`
+	Latest          *modUpdateLatest `json:"latest,omitempty"`
+	Applied         bool             `json:"applied,omitempty"`
+	NewPath         string           `json:"new_path,omitempty"`
+	TrashPath       string           `json:"trash_path,omitempty"`
+	Error           string           `json:"error,omitempty"`
+
+	latest mcinstall.ModrinthVersion
+}
+
+// mcModsUpdate checks the jars in mods/ and plugins/ for newer versions through the
+// Modrinth hash lookup API and optionally applies the updates. Old jars go to _trash.
+func (e *Executor) mcModsUpdate(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	dir, _ := asString(cmd.Args["dir"])
+	gameVersion, _ := asString(cmd.Args["game_version"])
+	loader, _ := asString(cmd.Args["loader"])
+	apply, _ := asBool(cmd.Args["apply"])
+	selected, _ := asStringSlice(cmd.Args["paths"])
+	versionType, _ := asString(cmd.Args["version_type"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	versionType = strings.ToLower(strings.TrimSpace(versionType))
+	switch versionType {
+	case "", "release", "beta", "alpha":
+	default:
+		return fail("version_type must be release, beta or alpha")
+	}
+	if apply && e.deps.MC != nil && e.deps.MC.List()[instanceID].Running {
+		return fail("instance is running; stop it before applying mod updates")
+	}
+	dirs := modDirs
+	if dir = strings.TrimSpace(dir); dir != "" {
+		if !isModDir(dir) {
+			return fail("dir must be mods or plugins")
+		}
+		dirs = []string{dir}
+	}
+	cfg, err := e.readInstanceConfig(instanceID)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if gameVersion = strings.TrimSpace(gameVersion); gameVersion == "" {
+		gameVersion, _ = asString(cfg["server_version"])
+	}
+	kind, _ := asString(cfg["server_kind"])
+	loader = strings.ToLower(strings.TrimSpace(loader))
+	only := map[string]bool{}
+	for _, p := range selected {
+		d, name, err := splitModPath(p)
+		if err != nil {
+			return fail(fmt.Sprintf("paths: %s: %v", p, err))
+		}
+		only[d+"/"+name] = true
+	}
+
+	var results []*modUpdateInfo
+	updates, applied := 0, 0
+	for _, d := range dirs {
+		files, err := e.listModFiles(ctx, instanceID, d)
+		if err != nil {
+			return fail(err.Error())
+		}
+		var infos []*modUpdateInfo
+		var hashes []string
+		for _, f := range files {
+			if !f.Enabled || f.SHA1 == "" {
+				continue
+			}
+			infos = append(infos, &modUpdateInfo{Path: f.Path, SHA1: f.SHA1})
+			hashes = append(hashes, f.SHA1)
+		}
+		if len(infos) == 0 {
+			continue
+		}
+		loaders := modrinthLoaders[d][strings.ToLower(kind)]
+		if loader != "" {
+			loaders = []string{loader}
+		}
+		var gameVersions []string
+		if gameVersion != "" {
+			gameVersions = []string{gameVersion}
+		}
+
+		e.emitInstall(instanceID, fmt.Sprintf("mods update: checking %d jars in %s (loaders=%s game_version=%s)", len(infos), d, strings.Join(loaders, ","), gameVersion))
+		current, err := mcinstall.ModrinthVersionsBySHA1(ctx, e.deps.Modrinth.APIBaseURL, hashes)
+		if err != nil {
+			return fail(err.Error())
+		}
+		// Without version_type every jar stays on its current channel or a more stable
+		// one: one update query per channel.
+		byChannel := map[string][]string{}
+		for _, h := range hashes {
+			cur, found := current[h]
+			if !found {
+				continue
+			}
+			channel := versionType
+			if channel == "" {
+				channel = strings.ToLower(cur.VersionType)
+			}
+			if channel != "beta" && channel != "alpha" {
+				channel = "release"
+			}
+			byChannel[channel] = append(byChannel[channel], h)
+		}
+		latest := map[string]mcinstall.ModrinthVersion{}
+		for channel, channelHashes := range byChannel {
+			found, err := mcinstall.ModrinthLatestVersionsBySHA1(ctx, e.deps.Modrinth.APIBaseURL, channelHashes, loaders, gameVersions, modrinthChannels(channel))
+			if err != nil {
+				return fail(err.Error())
+			}
+			for h, v := range found {
+				if slices.Contains(modrinthChannels(channel), strings.ToLower(v.VersionType)) {
+					latest[h] = v
+				}
+			}
+		}
+		for _, info := range infos {
+			results = append(results, info)
+			cur, found := current[info.SHA1]
+			if !found {
+				continue
+			}
+			info.ProjectID, info.VersionID, info.VersionNumber, info.VersionType = cur.ProjectID, cur.ID, cur.VersionNumber, cur.VersionType
+			next, found := latest[info.SHA1]
+			if !found || next.ID == cur.ID || next.HasFileHash(info.SHA1) {
+				continue
+			}
+			file, found := next.PrimaryFile()
+			if !found {
+				continue
+			}
+			info.UpdateAvailable = true
+			info.latest = next
+			info.Latest = &modUpdateLatest{
+				VersionID:     next.ID,
+				VersionNumber: next.VersionNumber,
+				VersionType:   next.VersionType,
+				Filename:      file.Filename,
+				URL:           file.URL,
+			}
+			updates++
+		}
+	}
+
+	if apply {
+		for _, info := range results {
+			if !info.UpdateAvailable || (len(only) > 0 && !only[info.Path]) {
+				continue
+			}
+			if err := e.applyModUpdate(ctx, instanceID, info); err != nil {
+				info.Error = err.Error()
+				e.emitInstall(instanceID, fmt.Sprintf("mods update: %s failed: %v", info.Path, err))
+				continue
+			}
+			applied++
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if results == nil {
+		results = []*modUpdateInfo{}
+	}
+	return ok(map[string]any{
+		"instance_id":  instanceID,
+		"game_version": gameVersion,
+		"files":        results,
+		"checked":      len(results),
+		"updates":      updates,
+		"applied":      applied,
+	})
+}
+
+// applyModUpdate downloads the new version next to the old jar (checksum verified),
+// moves the old jar to _trash and puts the new one in place.
+func (e *Executor) applyModUpdate(ctx context.Context, instanceID string, info *modUpdateInfo) error {
+	file, _ := info.latest.PrimaryFile()
+	name := file.Filename
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid file name from modrinth: %q", name)
+	}
+	if _, isJar := modJarState(name); !isJar || !strings.HasSuffix(strings.ToLower(name), ".jar") {
+		return fmt.Errorf("not a jar: %s", name)
+	}
+	if file.SHA512 == "" && file.SHA1 == "" {
+		return fmt.Errorf("no checksum for %s", name)
+	}
+	dir := path.Dir(info.Path)
+	newRel := dir + "/" + name
+	newAbs, err := e.deps.FS.Resolve(filepath.Join(instanceID, filepath.FromSlash(newRel)))
+	if err != nil {
+		return err
+	}
+	if newRel != info.Path {
+		if _, err := os.Stat(newAbs); err == nil {
+			return fmt.Errorf("%s already exists", newRel)
+		}
+	}
+	tmpAbs, err := e.deps.FS.Resolve(filepath.Join(instanceID, filepath.FromSlash(dir), ".elegantmc-update-"+name))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpAbs)
+
+	e.emitInstall(instanceID, fmt.Sprintf("mods update: %s %s -> %s (%s)", info.Path, info.VersionNumber, info.latest.VersionNumber, name))
+	if _, err := download.DownloadFileVerified(ctx, file.URL, tmpAbs, download.Expected{SHA512: file.SHA512, SHA1: file.SHA1}, nil); err != nil {
+		return err
+	}
+	trash := e.fsTrash(protocol.Command{Name: "fs_trash", Args: map[string]any{"path": filepath.Join(instanceID, filepath.FromSlash(info.Path))}})
+	if !trash.OK {
+		return fmt.Errorf("move old jar to trash: %s", trash.Error)
+	}
+	trashPath, _ := asString(trash.Output["trash_path"])
+	if err := os.Rename(tmpAbs, newAbs); err != nil {
+		return fmt.Errorf("install %s (old jar is in %s): %w", newRel, trashPath, err)
+	}
+	info.Applied = true
+	info.NewPath = newRel
+	info.TrashPath = trashPath
+	return nil
+}
@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// newModrinthStub serves version_files lookups for modA (outdated, newer version with
+// newA available) and modC (up to date).
+func newModrinthStub(t *testing.T, modA, newA, modC []byte, newSHA512 string) *httptest.Server {
+	t.Helper()
+	version := func(id, number, file, url string, content []byte, sha512Hex string) map[string]any {
+		return map[string]any{
+			"id": id, "project_id": "p-" + file[:1], "version_number": number, "version_type": "release",
+			"loaders": []string{"fabric"}, "game_versions": []string{"1.20.1"},
+			"files": []any{map[string]any{
+				"filename": file, "url": url, "primary": true, "size": len(content),
+				"hashes": map[string]string{"sha1": sha1Hex(content), "sha512": sha512Hex},
+			}},
+		}
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Hashes       []string `json:"hashes"`
+			Algorithm    string   `json:"algorithm"`
+			Loaders      []string `json:"loaders"`
+			GameVersions []string `json:"game_versions"`
+			VersionTypes []string `json:"version_types"`
+		}
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Algorithm != "sha1" {
+				http.Error(w, "bad algorithm", http.StatusBadRequest)
+				return
+			}
+		}
+		oldA := version("va1", "1.0.0", "mod-a-1.0.0.jar", srv.URL+"/old.jar", modA, "")
+		curC := version("vc1", "3.0.0", "mod-c.jar", srv.URL+"/c.jar", modC, "")
+		switch r.URL.Path {
+		case "/v2/version_files":
+			_ = json.NewEncoder(w).Encode(map[string]any{sha1Hex(modA): oldA, sha1Hex(modC): curC})
+		case "/v2/version_files/update":
+			if !reflect.DeepEqual(req.Loaders, []string{"fabric"}) || !reflect.DeepEqual(req.GameVersions, []string{"1.20.1"}) {
+				http.Error(w, "unexpected filters", http.StatusBadRequest)
+				return
+			}
+			nextA := version("va2", "1.1.0", "mod-a-1.1.0.jar", srv.URL+"/files/mod-a-1.1.0.jar", newA, newSHA512)
+			if slices.Contains(req.VersionTypes, "beta") {
+				nextA = version("va3", "1.2.0-beta.1", "mod-a-1.2.0-beta.1.jar", srv.URL+"/files/mod-a-1.2.0-beta.1.jar", []byte("beta"), "")
+				nextA["version_type"] = "beta"
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				sha1Hex(modA): nextA,
+				sha1Hex(modC): curC,
+			})
+		case "/files/mod-a-1.1.0.jar":
+			_, _ = w.Write(newA)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupModsInstance(t *testing.T, serversRoot string, files map[string][]byte) string {
+	t.Helper()
+	instDir := filepath.Join(serversRoot, "s1")
+	if err := os.MkdirAll(filepath.Join(instDir, "mods"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, ".elegantmc.json"), []byte(`{"server_kind":"fabric","server_version":"1.20.1"}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(instDir, "mods", name), b, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return instDir
+}
+
+func TestExecutor_MCModsUpdate(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	modA, newA, modB, modC := []byte("mod a 1.0"), []byte("mod a 1.1"), []byte("unknown mod"), []byte("mod c")
+	sum := sha512.Sum512(newA)
+	srv := newModrinthStub(t, modA, newA, modC, hex.EncodeToString(sum[:]))
+	ex.deps.Modrinth.APIBaseURL = srv.URL
+	instDir := setupModsInstance(t, serversRoot, map[string][]byte{"a.jar": modA, "b.jar": modB, "c.jar": modC})
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1"}})
+	if !res.OK {
+		t.Fatalf("check failed: %s", res.Error)
+	}
+	if res.Output["checked"] != 3 || res.Output["updates"] != 1 || res.Output["applied"] != 0 {
+		t.Fatalf("unexpected check output: %#v", res.Output)
+	}
+	files := res.Output["files"].([]*modUpdateInfo)
+	if files[0].Path != "mods/a.jar" || !files[0].UpdateAvailable || files[0].Latest.VersionNumber != "1.1.0" || files[0].VersionNumber != "1.0.0" {
+		t.Fatalf("unexpected mods/a.jar result: %#v", files[0])
+	}
+	if files[1].UpdateAvailable || files[1].ProjectID != "" || files[2].UpdateAvailable || files[2].VersionNumber != "3.0.0" {
+		t.Fatalf("unexpected results: %#v %#v", files[1], files[2])
+	}
+
+	res = ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{
+		"instance_id": "s1",
+		"apply":       true,
+		"paths":       []any{"mods/a.jar"},
+	}})
+	if !res.OK || res.Output["applied"] != 1 {
+		t.Fatalf("apply failed: %#v %s", res.Output, res.Error)
+	}
+	if b, err := os.ReadFile(filepath.Join(instDir, "mods", "mod-a-1.1.0.jar")); err != nil || string(b) != string(newA) {
+		t.Fatalf("new jar not installed: %q %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(instDir, "mods", "a.jar")); !os.IsNotExist(err) {
+		t.Fatalf("expected old jar to be moved, stat err=%v", err)
+	}
+	applied := res.Output["files"].([]*modUpdateInfo)[0]
+	if b, err := os.ReadFile(filepath.Join(serversRoot, filepath.FromSlash(applied.TrashPath), "a.jar")); err != nil || string(b) != string(modA) {
+		t.Fatalf("old jar not in trash (%s): %q %v", applied.TrashPath, b, err)
+	}
+}
+
+func TestExecutor_MCModsUpdate_ChecksumMismatch(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	modA, newA := []byte("mod a 1.0"), []byte("mod a 1.1")
+	srv := newModrinthStub(t, modA, newA, []byte("c"), hex.EncodeToString(make([]byte, 64)))
+	ex.deps.Modrinth.APIBaseURL = srv.URL
+	instDir := setupModsInstance(t, serversRoot, map[string][]byte{"a.jar": modA})
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1", "apply": true}})
+	if !res.OK {
+		t.Fatalf("mc_mods_update failed: %s", res.Error)
+	}
+	files := res.Output["files"].([]*modUpdateInfo)
+	if res.Output["applied"] != 0 || files[0].Error == "" || files[0].Applied {
+		t.Fatalf("expected the update to fail verification: %#v", files[0])
+	}
+	if b, err := os.ReadFile(filepath.Join(instDir, "mods", "a.jar")); err != nil || string(b) != string(modA) {
+		t.Fatalf("old jar should be untouched: %q %v", b, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(instDir, "mods"))
+	if len(entries) != 1 {
+		t.Fatalf("expected no leftover files, got %d entries", len(entries))
+	}
+}
+
+func TestExecutor_MCModsUpdate_VersionType(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	modA, newA := []byte("mod a 1.0"), []byte("mod a 1.1")
+	srv := newModrinthStub(t, modA, newA, []byte("c"), "")
+	ex.deps.Modrinth.APIBaseURL = srv.URL
+	setupModsInstance(t, serversRoot, map[string][]byte{"a.jar": modA})
+
+	// A release jar only moves to newer releases by default.
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1"}})
+	if !res.OK {
+		t.Fatalf("check failed: %s", res.Error)
+	}
+	files := res.Output["files"].([]*modUpdateInfo)
+	if files[0].VersionType != "release" || files[0].Latest == nil || files[0].Latest.VersionNumber != "1.1.0" {
+		t.Fatalf("default channel: %#v %#v", files[0], files[0].Latest)
+	}
+
+	res = ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1", "version_type": "beta"}})
+	if !res.OK {
+		t.Fatalf("check failed: %s", res.Error)
+	}
+	if latest := res.Output["files"].([]*modUpdateInfo)[0].Latest; latest == nil || latest.VersionType != "beta" || latest.VersionNumber != "1.2.0-beta.1" {
+		t.Fatalf("beta channel: %#v", latest)
+	}
+
+	if res := ex.Execute(context.Background(), protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1", "version_type": "snapshot"}}); res.OK {
+		t.Fatalf("expected an invalid version_type to be rejected")
+	}
+}
+
+func TestModrinthChannels(t *testing.T) {
+	for channel, want := range map[string][]string{
+		"release": {"release"},
+		"":        {"release"},
+		"beta":    {"release", "beta"},
+		"alpha":   {"release", "beta", "alpha"},
+	} {
+		if got := modrinthChannels(channel); !reflect.DeepEqual(got, want) {
+			t.Errorf("modrinthChannels(%q) = %v, want %v", channel, got, want)
+		}
+	}
+}
+
+func TestExecutor_MCModsUpdate_RefusesApplyWhileRunning(t *testing.T) {
+	ex, _, serversRoot := newTestExecutor(t)
+	instDir := setupModsInstance(t, serversRoot, map[string][]byte{"a.jar": []byte("mod a 1.0")})
+	if err := os.WriteFile(filepath.Join(instDir, "server.jar"), []byte("jar"), 0o644); err != nil {
+		t.Fatalf("write jar: %v", err)
+	}
+	java := filepath.Join(t.TempDir(), "java")
+	if err := os.WriteFile(java, []byte("#!/bin/sh\nwhile read -r line; do [ \"$line\" = stop ] && exit 0; done\n"), 0o755); err != nil {
+		t.Fatalf("write fake java: %v", err)
+	}
+	ctx := context.Background()
+	if err := ex.deps.MC.Start(ctx, mc.StartOptions{InstanceID: "s1", JarPath: "server.jar", JavaPath: java}, nil); err != nil {
+		t.Skipf("cannot start fake server: %v", err)
+	}
+	t.Cleanup(func() { _ = ex.deps.MC.Stop(ctx, "s1") })
+
+	res := ex.Execute(ctx, protocol.Command{Name: "mc_mods_update", Args: map[string]any{"instance_id": "s1", "apply": true}})
+	if res.OK || !strings.Contains(res.Error, "running") {
+		t.Fatalf("expected apply to be refused while running, got ok=%v err=%s", res.OK, res.Error)
+	}
+}
@@ -0,0 +1,344 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const (
+	defaultUpgradeReadyTimeout = 5 * time.Minute
+	defaultUpgradeGrace        = 60 * time.Second
+)
+
+// upgradeServers is the part of *mc.Manager that mc_upgrade drives; tests replace it
+// through Executor.upgradeMC.
+type upgradeServers interface {
+	List() map[string]mc.Status
+	Start(ctx context.Context, opt mc.StartOptions, logSink func(instanceID, stream, line string)) error
+	Stop(ctx context.Context, instanceID string) error
+	SetRestartPolicy(instanceID string, override *mc.RestartPolicy) error
+	JavaForMajor(ctx context.Context, major int, vendor string) (string, int, error)
+}
+
+func (e *Executor) upgradeServers() upgradeServers {
+	if e.upgradeMC != nil {
+		return e.upgradeMC
+	}
+	return e.deps.MC
+}
+
+// mcUpgrade moves an instance to another server version: it takes a backup, installs
+// the new version, checks the Java requirement, starts the server and waits until it is
+// ready and stays up for a grace period. Any failure restores the backup.
+func (e *Executor) mcUpgrade(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	instanceID, _ := asString(cmd.Args["instance_id"])
+	software, _ := asString(cmd.Args["software"])
+	version, _ := asString(cmd.Args["version"])
+
+	if strings.TrimSpace(instanceID) == "" {
+		return fail("instance_id is required")
+	}
+	if err := validateInstanceID(instanceID); err != nil {
+		return fail(err.Error())
+	}
+	if e.deps.FS == nil {
+		return fail("servers filesystem not configured")
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fail("version is required")
+	}
+	readyTimeout, err := upgradeDuration(cmd.Args["ready_timeout_sec"], defaultUpgradeReadyTimeout, 30*time.Minute)
+	if err != nil {
+		return fail("ready_timeout_sec: " + err.Error())
+	}
+	if readyTimeout <= 0 {
+		readyTimeout = defaultUpgradeReadyTimeout
+	}
+	grace, err := upgradeDuration(cmd.Args["grace_sec"], defaultUpgradeGrace, 10*time.Minute)
+	if err != nil {
+		return fail("grace_sec: " + err.Error())
+	}
+
+	cfg, err := e.readInstanceConfig(instanceID)
+	if err != nil {
+		return fail(err.Error())
+	}
+	fromSoftware, _ := asString(cfg["server_kind"])
+	fromVersion, _ := asString(cfg["server_version"])
+	software = strings.ToLower(strings.TrimSpace(software))
+	if software == "" {
+		software = strings.ToLower(strings.TrimSpace(fromSoftware))
+	}
+	if software == "" {
+		return fail("software is required (instance has no server_kind)")
+	}
+	oldStart := instanceStartOptions(instanceID, cfg)
+	servers := e.upgradeServers()
+	status := servers.List()[instanceID]
+	wasRunning := status.Running
+	if wasRunning && status.JarRel != "" {
+		oldStart.JarPath = status.JarRel
+	}
+
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade: %s %s -> %s %s", orUnknown(fromSoftware), orUnknown(fromVersion), software, version))
+
+	// 1) Backup (stops the server).
+	bk := e.mcBackup(ctx, protocol.Command{Name: "mc_backup", Args: map[string]any{
+		"instance_id": instanceID,
+		"format":      "tar.gz",
+		"comment":     fmt.Sprintf("before upgrade to %s %s", software, version),
+		"stop":        true,
+	}})
+	if !bk.OK {
+		e.emitInstall(instanceID, "upgrade aborted: backup failed: "+bk.Error)
+		if wasRunning {
+			e.restartAfterUpgrade(ctx, instanceID, oldStart)
+		}
+		return fail("pre-upgrade backup failed: " + bk.Error)
+	}
+	backupRel, _ := asString(bk.Output["path"])
+
+	rollback := func(reason string) protocol.CommandResult {
+		// Roll back even if the request was cancelled.
+		rctx := context.WithoutCancel(ctx)
+		e.emitInstall(instanceID, "upgrade failed: "+reason)
+		_ = servers.Stop(rctx, instanceID)
+		res := e.mcRestore(rctx, protocol.Command{Name: "mc_restore", Args: map[string]any{
+			"instance_id": instanceID,
+			"zip_path":    backupRel,
+		}})
+		if !res.OK {
+			e.emitInstall(instanceID, "rollback failed: "+res.Error)
+			return fail(fmt.Sprintf("upgrade failed: %s; rollback failed: %s (backup: %s)", reason, res.Error, backupRel))
+		}
+		e.emitInstall(instanceID, "rolled back from "+backupRel)
+		if wasRunning {
+			e.restartAfterUpgrade(rctx, instanceID, oldStart)
+		}
+		return fail(fmt.Sprintf("upgrade failed: %s; rolled back from %s", reason, backupRel))
+	}
+
+	// 2) Install.
+	installArgs := map[string]any{
+		"instance_id": instanceID,
+		"software":    software,
+		"version":     version,
+	}
+	for _, k := range []string{"build", "loader_version", "installer_version", "accept_eula"} {
+		if v, ok := cmd.Args[k]; ok {
+			installArgs[k] = v
+		}
+	}
+	// Keep a custom jar name when the software does not change.
+	if strings.EqualFold(software, fromSoftware) || (fromSoftware == "" && software == "vanilla") {
+		if validateJarName(oldStart.JarPath) == nil && !mc.IsLaunchDescriptorPath(oldStart.JarPath) {
+			installArgs["jar_name"] = oldStart.JarPath
+		}
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade: install %s %s", software, version))
+	inst := e.mcInstall(ctx, protocol.Command{Name: "mc_install", Args: installArgs})
+	if !inst.OK {
+		return rollback("install: " + inst.Error)
+	}
+	jarPath, _ := asString(inst.Output["jar_path"])
+	if software == "vanilla" {
+		if err := e.updateInstanceConfig(instanceID, map[string]any{
+			"jar_path":       jarPath,
+			"server_kind":    "vanilla",
+			"server_version": version,
+		}); err != nil {
+			return rollback(err.Error())
+		}
+	}
+
+	// 3) Java compatibility.
+	instAbs, err := e.deps.FS.Resolve(instanceID)
+	if err != nil {
+		return rollback(err.Error())
+	}
+	required, err := mc.RequiredJavaMajorForLaunch(instAbs, jarPath)
+	if err != nil {
+		sw, found := mcinstall.LookupSoftware(software)
+		if required = mc.RequiredJavaMajorForGameVersion(version); required == 0 || (found && sw.Proxy) {
+			return rollback("detect required java: " + err.Error())
+		}
+		e.emitInstall(instanceID, fmt.Sprintf("upgrade: cannot inspect %s (%v); using java %d for minecraft %s", jarPath, err, required, version))
+	}
+	javaPath, javaMajor := oldStart.JavaPath, 0
+	if javaPath != "" {
+		if javaMajor, err = mc.ProbeJavaMajor(ctx, javaPath); err != nil {
+			return rollback(fmt.Sprintf("probe java_path %s: %v", javaPath, err))
+		}
+		if javaMajor < required {
+			return rollback(fmt.Sprintf("java_path %s is Java %d but %s %s requires Java %d", javaPath, javaMajor, software, version, required))
+		}
+	} else if javaPath, javaMajor, err = servers.JavaForMajor(ctx, required, oldStart.JavaVendor); err != nil {
+		return rollback(fmt.Sprintf("no Java %d runtime: %v", required, err))
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade: java ok: %s (major %d, required %d)", javaPath, javaMajor, required))
+
+	// 4) Start without auto-restart, so a crash is not hidden. The configured policy is
+	// re-armed once the server has passed the grace window.
+	start := oldStart
+	start.JarPath = jarPath
+	start.JavaPath = javaPath
+	start.Restart = &mc.RestartPolicy{Mode: mc.RestartNever}
+	e.emitInstall(instanceID, "upgrade: starting server")
+	startedAt := time.Now()
+	if err := servers.Start(ctx, start, e.mcLogSink); err != nil {
+		return rollback("start: " + err.Error())
+	}
+
+	// 5) Wait for the ready signal (Server List Ping answer or the "Done" console line).
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade: waiting for server to become ready (timeout %s)", readyTimeout))
+	readyAt, reason := e.waitUpgradeReady(ctx, instanceID, startedAt, readyTimeout)
+	if reason != "" {
+		return rollback(reason)
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade: server ready after %s", readyAt.Sub(startedAt).Round(time.Second)))
+
+	// 6) Grace window: the server must keep running.
+	if grace > 0 {
+		e.emitInstall(instanceID, fmt.Sprintf("upgrade: watching for crashes (%s)", grace))
+		if reason := e.watchUpgradeGrace(ctx, instanceID, grace); reason != "" {
+			return rollback(reason)
+		}
+	}
+	if err := servers.SetRestartPolicy(instanceID, oldStart.Restart); err != nil {
+		e.emitInstall(instanceID, "upgrade: restart policy not restored: "+err.Error())
+	}
+	e.emitInstall(instanceID, fmt.Sprintf("upgrade done: %s %s (backup: %s)", software, version, backupRel))
+
+	return ok(map[string]any{
+		"instance_id":         instanceID,
+		"from":                map[string]any{"software": fromSoftware, "version": fromVersion},
+		"to":                  map[string]any{"software": software, "version": version},
+		"jar_path":            jarPath,
+		"backup_path":         backupRel,
+		"java":                javaPath,
+		"java_major":          javaMajor,
+		"required_java_major": required,
+		"ready_unix":          readyAt.Unix(),
+	})
+}
+
+// waitUpgradeReady polls until the instance is ready. It returns a failure reason if the
+// server exits or the timeout passes first.
+func (e *Executor) waitUpgradeReady(ctx context.Context, instanceID string, startedAt time.Time, timeout time.Duration) (time.Time, string) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		st := e.upgradeServers().List()[instanceID]
+		if !st.Running {
+			return time.Time{}, fmt.Sprintf("server exited during startup (%s)", describeExit(st))
+		}
+		if st.Ready {
+			return time.Unix(st.ReadyUnix, 0), ""
+		}
+		if done := e.serverDoneSince(instanceID, startedAt); !done.IsZero() {
+			return done, ""
+		}
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err().Error()
+		case <-deadline.C:
+			return time.Time{}, fmt.Sprintf("server not ready after %s", timeout)
+		case <-tick.C:
+		}
+	}
+}
+
+func (e *Executor) watchUpgradeGrace(ctx context.Context, instanceID string, grace time.Duration) string {
+	end := time.NewTimer(grace)
+	defer end.Stop()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err().Error()
+		case <-end.C:
+			if st := e.upgradeServers().List()[instanceID]; !st.Running {
+				return fmt.Sprintf("server stopped within %s after startup (%s)", grace, describeExit(st))
+			}
+			return ""
+		case <-tick.C:
+			if st := e.upgradeServers().List()[instanceID]; !st.Running {
+				return fmt.Sprintf("server stopped within %s after startup (%s)", grace, describeExit(st))
+			}
+		}
+	}
+}
+
+// restartAfterUpgrade starts the previous version again after an aborted upgrade
+// (best-effort, the failure is only logged).
+func (e *Executor) restartAfterUpgrade(ctx context.Context, instanceID string, opt mc.StartOptions) {
+	if opt.JarPath == "" {
+		e.emitInstall(instanceID, "previous server not restarted: unknown jar_path")
+		return
+	}
+	if err := e.upgradeServers().Start(ctx, opt, e.mcLogSink); err != nil {
+		e.emitInstall(instanceID, "previous server restart failed: "+err.Error())
+		return
+	}
+	e.emitInstall(instanceID, "previous server restarted")
+}
+
+// instanceStartOptions builds start options from the panel's instance config.
+func instanceStartOptions(instanceID string, cfg map[string]any) mc.StartOptions {
+	opt := mc.StartOptions{InstanceID: instanceID}
+	opt.JarPath, _ = asString(cfg["jar_path"])
+	opt.JavaPath, _ = asString(cfg["java_path"])
+	opt.Xms, _ = asString(cfg["xms"])
+	opt.Xmx, _ = asString(cfg["xmx"])
+	opt.JvmArgs, _ = asStringSlice(cfg["jvm_args"])
+	opt.JavaVendor, _ = asString(cfg["java_vendor"])
+	opt.JvmProfile, _ = asString(cfg["jvm_profile"])
+	opt.JarPath = strings.TrimSpace(opt.JarPath)
+	opt.JavaPath = strings.TrimSpace(opt.JavaPath)
+	return opt
+}
+
+func upgradeDuration(v any, def, max time.Duration) (time.Duration, error) {
+	if v == nil {
+		return def, nil
+	}
+	n, err := asInt(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must be >= 0")
+	}
+	d := time.Duration(n) * time.Second
+	if d > max {
+		d = max
+	}
+	return d, nil
+}
+
+func describeExit(st mc.Status) string {
+	if st.LastExitSignal != "" {
+		return "signal " + st.LastExitSignal
+	}
+	if st.LastExitCode != nil {
+		return fmt.Sprintf("exit code %d", *st.LastExitCode)
+	}
+	return "no exit status"
+}
+
+func orUnknown(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "(unknown)"
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/protocol"
+)
+
+// fakeUpgradeServers stands in for *mc.Manager: started servers are "running" and ready
+// until stopped.
+type fakeUpgradeServers struct {
+	mu       sync.Mutex
+	status   map[string]mc.Status
+	starts   []mc.StartOptions
+	stops    int
+	policies []*mc.RestartPolicy
+	javaErr  error
+}
+
+func newFakeUpgradeServers(running map[string]string) *fakeUpgradeServers {
+	f := &fakeUpgradeServers{status: map[string]mc.Status{}}
+	for id, jar := range running {
+		f.status[id] = mc.Status{Running: true, JarRel: jar}
+	}
+	return f
+}
+
+func (f *fakeUpgradeServers) List() map[string]mc.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]mc.Status, len(f.status))
+	for k, v := range f.status {
+		out[k] = v
+	}
+	return out
+}
+
+func (f *fakeUpgradeServers) Start(ctx context.Context, opt mc.StartOptions, logSink func(instanceID, stream, line string)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.starts = append(f.starts, opt)
+	f.status[opt.InstanceID] = mc.Status{Running: true, Ready: true, ReadyUnix: time.Now().Unix(), JarRel: opt.JarPath}
+	return nil
+}
+
+func (f *fakeUpgradeServers) Stop(ctx context.Context, instanceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stops++
+	st := f.status[instanceID]
+	st.Running = false
+	f.status[instanceID] = st
+	return nil
+}
+
+func (f *fakeUpgradeServers) SetRestartPolicy(instanceID string, override *mc.RestartPolicy) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.policies = append(f.policies, override)
+	return nil
+}
+
+func (f *fakeUpgradeServers) JavaForMajor(ctx context.Context, major int, vendor string) (string, int, error) {
+	if f.javaErr != nil {
+		return "", 0, f.javaErr
+	}
+	return "/opt/java/bin/java", major, nil
+}
+
+// newUpgradeFixture creates a running vanilla 1.20.1 instance "srv1" and a Mojang stub
+// that serves 1.20.4 (newJar) and nothing else.
+func newUpgradeFixture(t *testing.T, newJar []byte) (*Executor, *fakeUpgradeServers, string) {
+	t.Helper()
+	ex, _, serversRoot := newTestExecutor(t)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/mc/game/version_manifest_v2.json":
+			_, _ = w.Write([]byte(`{"versions":[{"id":"1.20.4","url":"` + srv.URL + `/v/1.20.4.json"}]}`))
+		case "/v/1.20.4.json":
+			_, _ = w.Write([]byte(`{"downloads":{"server":{"url":"` + srv.URL + `/1.20.4/server.jar","sha1":"` + sha1Hex(newJar) + `"}}}`))
+		case "/1.20.4/server.jar":
+			_, _ = w.Write(newJar)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ex.deps.Mojang.MetaBaseURL = srv.URL
+	ex.deps.Mojang.DataBaseURL = srv.URL
+
+	instDir := filepath.Join(serversRoot, "srv1")
+	for name, content := range map[string]string{
+		"server.jar":        "old jar",
+		"world/level.dat":   "level",
+		".elegantmc.json":   `{"jar_path":"server.jar","server_kind":"vanilla","server_version":"1.20.1"}`,
+		"server.properties": "motd=old\n",
+	} {
+		p := filepath.Join(instDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	fake := newFakeUpgradeServers(map[string]string{"srv1": "server.jar"})
+	ex.upgradeMC = fake
+	return ex, fake, instDir
+}
+
+func TestExecutor_MCUpgrade_Success(t *testing.T) {
+	newJar := []byte("new jar")
+	ex, fake, instDir := newUpgradeFixture(t, newJar)
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_upgrade", Args: map[string]any{
+		"instance_id": "srv1",
+		"version":     "1.20.4",
+		"grace_sec":   0,
+	}})
+	if !res.OK {
+		t.Fatalf("mc_upgrade failed: %s", res.Error)
+	}
+	if b, _ := os.ReadFile(filepath.Join(instDir, "server.jar")); string(b) != string(newJar) {
+		t.Fatalf("server.jar not upgraded: %q", b)
+	}
+	if len(fake.starts) != 1 {
+		t.Fatalf("expected one start, got %d", len(fake.starts))
+	}
+	if p := fake.starts[0].Restart; p == nil || p.Mode != mc.RestartNever {
+		t.Fatalf("upgrade start should disable auto-restart, got %#v", p)
+	}
+	// After the grace window the configured policy (nil override) must be re-armed.
+	if len(fake.policies) != 1 || fake.policies[0] != nil {
+		t.Fatalf("expected restart policy to be restored from config, got %#v", fake.policies)
+	}
+}
+
+func TestExecutor_MCUpgrade_InstallFailsRollsBack(t *testing.T) {
+	ex, fake, instDir := newUpgradeFixture(t, []byte("new jar"))
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_upgrade", Args: map[string]any{
+		"instance_id": "srv1",
+		"version":     "1.99.9", // not in the manifest
+	}})
+	if res.OK {
+		t.Fatalf("expected upgrade to fail")
+	}
+	if !strings.Contains(res.Error, "rolled back from") {
+		t.Fatalf("expected rollback, got: %s", res.Error)
+	}
+	if b, _ := os.ReadFile(filepath.Join(instDir, "world", "level.dat")); string(b) != "level" {
+		t.Fatalf("world not restored: %q", b)
+	}
+	if fake.stops != 1 || len(fake.starts) != 1 {
+		t.Fatalf("expected one stop and one restart of the old server, got stops=%d starts=%d", fake.stops, len(fake.starts))
+	}
+	old := fake.starts[0]
+	if old.JarPath != "server.jar" || old.Restart != nil {
+		t.Fatalf("old server restarted with %#v", old)
+	}
+	if len(fake.policies) != 0 {
+		t.Fatalf("restart policy should not be touched on rollback")
+	}
+}
+
+func TestExecutor_MCUpgrade_JavaMissingRestoresFiles(t *testing.T) {
+	ex, fake, instDir := newUpgradeFixture(t, []byte("new jar"))
+	fake.javaErr = errors.New("no runtime")
+
+	res := ex.Execute(context.Background(), protocol.Command{Name: "mc_upgrade", Args: map[string]any{
+		"instance_id": "srv1",
+		"version":     "1.20.4",
+	}})
+	if res.OK || !strings.Contains(res.Error, "rolled back from") {
+		t.Fatalf("expected rollback, got ok=%v err=%s", res.OK, res.Error)
+	}
+	// The install had already replaced the jar; the backup must bring the old one back.
+	if b, _ := os.ReadFile(filepath.Join(instDir, "server.jar")); string(b) != "old jar" {
+		t.Fatalf("server.jar not restored: %q", b)
+	}
+	cfg := readInstanceConfigFile(t, instDir)
+	if cfg["server_version"] != "1.20.1" {
+		t.Fatalf("instance config not restored: %#v", cfg)
+	}
+	if len(fake.starts) != 1 || fake.starts[0].JarPath != "server.jar" || fake.starts[0].Restart != nil {
+		t.Fatalf("expected the old server to be restarted with its configured policy, got %#v", fake.starts)
+	}
+}
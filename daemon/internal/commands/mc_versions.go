@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"elegantmc/daemon/internal/mc"
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const defaultVersionCacheTTL = 6 * time.Hour
+
+var (
+	versionSoftwarePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	versionIDPattern       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+ -]{0,63}$`)
+)
+
+type versionCacheEntry struct {
+	FetchedAtUnix int64             `json:"fetched_at_unix"`
+	Catalog       mcinstall.Catalog `json:"catalog"`
+}
+
+// mcVersions lists installable versions (or the builds of one version) of a software.
+// Results are cached on disk: fresh entries are served without a request, and stale ones
+// are served when the upstream (or mirror) cannot be reached.
+func (e *Executor) mcVersions(ctx context.Context, cmd protocol.Command) protocol.CommandResult {
+	software, _ := asString(cmd.Args["software"])
+	version, _ := asString(cmd.Args["version"])
+	refresh, _ := asBool(cmd.Args["refresh"])
+	offline, _ := asBool(cmd.Args["offline"])
+
+	software = strings.ToLower(strings.TrimSpace(software))
+	version = strings.TrimSpace(version)
+	if software == "" {
+		return fail("software is required")
+	}
+	if !versionSoftwarePattern.MatchString(software) {
+		return fail("invalid software")
+	}
+	key := software
+	if version != "" {
+		if !versionIDPattern.MatchString(version) {
+			return fail("invalid version")
+		}
+		key += "@" + version
+	}
+
+	ttl := e.deps.VersionCache.TTL
+	if ttl <= 0 {
+		ttl = defaultVersionCacheTTL
+	}
+	cached, haveCache := e.readVersionCache(key)
+	age := time.Duration(0)
+	if haveCache {
+		age = time.Since(time.Unix(cached.FetchedAtUnix, 0))
+	}
+
+	if haveCache && (offline || (!refresh && age < ttl)) {
+		return e.versionsResult(cached, true, age >= ttl, "")
+	}
+	if offline {
+		return fail("no cached versions for " + key)
+	}
+
+	cat, err := mcinstall.FetchCatalog(ctx, mcinstall.CatalogSources{
+		MojangMetaBaseURL:    e.deps.Mojang.MetaBaseURL,
+		PaperAPIBaseURL:      e.deps.Paper.APIBaseURL,
+		PurpurAPIBaseURL:     e.deps.Purpur.APIBaseURL,
+		FabricMetaBaseURL:    e.deps.Fabric.MetaBaseURL,
+		QuiltMetaBaseURL:     e.deps.Quilt.MetaBaseURL,
+		ForgeMavenBaseURL:    e.deps.Forge.MavenBaseURL,
+		NeoForgeMavenBaseURL: e.deps.NeoForge.MavenBaseURL,
+	}, software, version)
+	if err != nil {
+		if haveCache {
+			return e.versionsResult(cached, true, true, err.Error())
+		}
+		return fail(err.Error())
+	}
+
+	// Proxy versions are not Minecraft versions.
+	if sw, found := mcinstall.LookupSoftware(software); !found || !sw.Proxy {
+		for i := range cat.Versions {
+			cat.Versions[i].JavaMajor = mc.RequiredJavaMajorForGameVersion(cat.Versions[i].ID)
+		}
+	}
+	entry := versionCacheEntry{FetchedAtUnix: time.Now().Unix(), Catalog: cat}
+	if err := e.writeVersionCache(key, entry); err != nil && e.deps.Log != nil {
+		e.deps.Log.Printf("versions cache write failed (%s): %v", key, err)
+	}
+	return e.versionsResult(entry, false, false, "")
+}
+
+func (e *Executor) versionsResult(entry versionCacheEntry, cached, stale bool, fetchErr string) protocol.CommandResult {
+	out := map[string]any{
+		"software":        entry.Catalog.Software,
+		"versions":        entry.Catalog.Versions,
+		"builds":          entry.Catalog.Builds,
+		"cached":          cached,
+		"stale":           stale,
+		"fetched_at_unix": entry.FetchedAtUnix,
+	}
+	if entry.Catalog.Version != "" {
+		out["version"] = entry.Catalog.Version
+	}
+	if len(entry.Catalog.Versions) == 0 {
+		out["versions"] = []mcinstall.CatalogVersion{}
+	}
+	if len(entry.Catalog.Builds) == 0 {
+		out["builds"] = []mcinstall.CatalogBuild{}
+	}
+	if fetchErr != "" {
+		out["error"] = fetchErr
+	}
+	return ok(out)
+}
+
+func (e *Executor) versionCachePath(key string) string {
+	dir := strings.TrimSpace(e.deps.VersionCache.Dir)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, key+".json")
+}
+
+func (e *Executor) readVersionCache(key string) (versionCacheEntry, bool) {
+	p := e.versionCachePath(key)
+	if p == "" {
+		return versionCacheEntry{}, false
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return versionCacheEntry{}, false
+	}
+	var entry versionCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.FetchedAtUnix <= 0 {
+		return versionCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (e *Executor) writeVersionCache(key string, entry versionCacheEntry) error {
+	p := e.versionCachePath(key)
+	if p == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("mkdir versions cache: %w", err)
+	}
+	return writeJSONAtomic(p, entry)
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elegantmc/daemon/internal/mcinstall"
+	"elegantmc/daemon/internal/protocol"
+)
+
+func TestExecutor_MCVersions_Cache(t *testing.T) {
+	ex, _, _ := newTestExecutor(t)
+	var hits atomic.Int32
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if down.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v2/projects/paper" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"versions":["1.20.4","1.21.1"]}`))
+	}))
+	t.Cleanup(srv.Close)
+	ex.deps.Paper.APIBaseURL = srv.URL
+	ex.deps.VersionCache = VersionCacheConfig{Dir: filepath.Join(t.TempDir(), "versions"), TTL: time.Hour}
+
+	run := func(args map[string]any) protocol.CommandResult {
+		t.Helper()
+		args["software"] = "paper"
+		return ex.Execute(context.Background(), protocol.Command{Name: "mc_versions", Args: args})
+	}
+
+	// Offline without a cache has nothing to serve.
+	if res := run(map[string]any{"offline": true}); res.OK {
+		t.Fatalf("expected offline without cache to fail")
+	}
+
+	res := run(map[string]any{})
+	if !res.OK || res.Output["cached"] != false {
+		t.Fatalf("first fetch: ok=%v err=%s out=%#v", res.OK, res.Error, res.Output)
+	}
+	versions := res.Output["versions"].([]mcinstall.CatalogVersion)
+	if len(versions) != 2 || versions[0].ID != "1.21.1" || versions[0].JavaMajor != 21 {
+		t.Fatalf("unexpected versions: %#v", versions)
+	}
+
+	// Fresh: served from the cache without a request.
+	res = run(map[string]any{})
+	if !res.OK || res.Output["cached"] != true || res.Output["stale"] != false || hits.Load() != 1 {
+		t.Fatalf("fresh cache: out=%#v hits=%d", res.Output, hits.Load())
+	}
+
+	// Stale and the upstream is down: the old result comes back with the error.
+	ex.deps.VersionCache.TTL = time.Nanosecond
+	down.Store(true)
+	res = run(map[string]any{})
+	if !res.OK || res.Output["cached"] != true || res.Output["stale"] != true || res.Output["error"] == nil {
+		t.Fatalf("stale cache with fetch error: ok=%v out=%#v", res.OK, res.Output)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("stale cache should try the upstream once, hits=%d", hits.Load())
+	}
+
+	// Offline: the stale cache is served without a request.
+	res = run(map[string]any{"offline": true})
+	if !res.OK || res.Output["cached"] != true || res.Output["stale"] != true || hits.Load() != 2 {
+		t.Fatalf("offline: out=%#v hits=%d", res.Output, hits.Load())
+	}
+
+	// Refresh bypasses a fresh cache.
+	ex.deps.VersionCache.TTL = time.Hour
+	down.Store(false)
+	res = run(map[string]any{"refresh": true})
+	if !res.OK || res.Output["cached"] != false || hits.Load() != 3 {
+		t.Fatalf("refresh: out=%#v hits=%d", res.Output, hits.Load())
+	}
+
+	// Without any cache a fetch error is an error.
+	if res := ex.Execute(context.Background(), protocol.Command{Name: "mc_versions", Args: map[string]any{"software": "paper", "version": "9.9"}}); res.OK {
+		t.Fatalf("expected an uncached fetch error to fail")
+	}
+}
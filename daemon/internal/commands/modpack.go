@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"elegantmc/daemon/internal/download"
+	"elegantmc/daemon/internal/protocol"
+)
+
+const (
+	modpackDownloadWorkers = 4
+	modpackDownloadRetries = 3
+)
+
+// modpackFile is one file of a modpack to download into the instance.
+type modpackFile struct {
+	Rel  string   // relative to the servers root
+	URLs []string // mirrors, tried in order
+	Want download.Expected
+}
+
+// modpackTargetRel validates a pack-relative file path and returns it relative to the
+// servers root (inside the instance).
+func modpackTargetRel(instanceID, p string) (string, error) {
+	name := strings.ReplaceAll(strings.TrimSpace(p), "\\", "/")
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, ":") {
+		return "", fmt.Errorf("invalid modpack path: %q", p)
+	}
+	return filepath.Join(instanceID, filepath.FromSlash(clean)), nil
+}
+
+// extractModpackDir copies every file under prefix (e.g. "overrides/") of a modpack
+// archive into the instance, overwriting existing files.
+func (e *Executor) extractModpackDir(ctx context.Context, zr *zip.Reader, prefix, instanceID string) (int, error) {
+	files := 0
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		if !strings.HasPrefix(name, prefix) || f.FileInfo().IsDir() || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if f.FileInfo().Mode()&os.ModeSymlink != 0 {
+			return files, errors.New("modpack contains symlink (refuse)")
+		}
+		rel, err := modpackTargetRel(instanceID, strings.TrimPrefix(name, prefix))
+		if err != nil {
+			return files, err
+		}
+		outAbs, err := e.deps.FS.Resolve(rel)
+		if err != nil {
+			return files, err
+		}
+		if err := os.MkdirAll(filepath.Dir(outAbs), 0o755); err != nil {
+			return files, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return files, err
+		}
+		dst, err := os.OpenFile(outAbs, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			rc.Close()
+			return files, err
+		}
+		_, copyErr := io.Copy(dst, rc)
+		closeErr := dst.Close()
+		_ = rc.Close()
+		if copyErr != nil {
+			return files, copyErr
+		}
+		if closeErr != nil {
+			return files, closeErr
+		}
+		files++
+	}
+	return files, nil
+}
+
+// installModpackLoader runs the matching server installer for a modpack. loader is one
+// of vanilla/fabric/quilt/forge/neoforge; the caller's java_path/jar_name/accept_eula
+// args are passed through. A jar_name of server.jar (the panel's default) is dropped for
+// Fabric/Quilt packs, where that name holds the vanilla jar behind the launcher.
+func (e *Executor) installModpackLoader(ctx context.Context, cmd protocol.Command, instanceID, loader, gameVersion, loaderVersion string) protocol.CommandResult {
+	args := map[string]any{
+		"instance_id":    instanceID,
+		"version":        gameVersion,
+		"loader_version": loaderVersion,
+	}
+	for _, k := range []string{"java_path", "jar_name", "accept_eula"} {
+		if v, ok := cmd.Args[k]; ok {
+			args[k] = v
+		}
+	}
+	if loader == "fabric" || loader == "quilt" {
+		if jarName, _ := asString(args["jar_name"]); strings.EqualFold(strings.TrimSpace(jarName), vanillaJarName) {
+			e.emitInstall(instanceID, fmt.Sprintf("ignore jar_name %s for %s (kept for the vanilla jar)", vanillaJarName, loader))
+			delete(args, "jar_name")
+		}
+	}
+	sub := protocol.Command{Name: "mc_install_" + loader, Args: args}
+	e.emitInstall(instanceID, fmt.Sprintf("install loader %s %s (minecraft %s)", loader, loaderVersion, gameVersion))
+	switch loader {
+	case "vanilla":
+		return e.mcInstallVanilla(ctx, sub)
+	case "fabric", "quilt":
+		return e.mcInstallLoader(ctx, sub, loader)
+	case "forge", "neoforge":
+		return e.mcInstallForge(ctx, sub, loader)
+	default:
+		return fail("unsupported modpack loader: " + loader)
+	}
+}
+
+// downloadModpackFiles downloads files with a small worker pool. Each file is attempted
+// modpackDownloadRetries times (every mirror per attempt, with backoff); the first
+// failure cancels the remaining downloads.
+func (e *Executor) downloadModpackFiles(ctx context.Context, instanceID string, files []modpackFile) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		done     int
+	)
+	workers := modpackDownloadWorkers
+	if workers > len(files) {
+		workers = len(files)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := e.downloadModpackFile(ctx, instanceID, files[i])
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				if err == nil {
+					done++
+					e.emitInstall(instanceID, fmt.Sprintf("[%d/%d] %s", done, len(files), filepath.ToSlash(files[i].Rel)))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (e *Executor) downloadModpackFile(ctx context.Context, instanceID string, f modpackFile) error {
+	abs, err := e.deps.FS.Resolve(f.Rel)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for attempt := 1; attempt <= modpackDownloadRetries; attempt++ {
+		for _, u := range f.URLs {
+			if _, lastErr = download.DownloadFileVerified(ctx, u, abs, f.Want, nil); lastErr == nil {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.emitInstall(instanceID, fmt.Sprintf("download failed (attempt %d/%d) %s: %v", attempt, modpackDownloadRetries, u, lastErr))
+		}
+		if attempt < modpackDownloadRetries {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no download urls")
+	}
+	return fmt.Errorf("download %s: %w", filepath.ToSlash(f.Rel), lastErr)
+}
@@ -95,6 +95,25 @@ func (e *Executor) scheduleSet(cmd protocol.Command) protocol.CommandResult {
 		if t.KeepLast > 1000 {
 			return fail(fmt.Sprintf("task[%d].keep_last too large (max 1000)", i))
 		}
+		t.Format = strings.ToLower(strings.TrimSpace(t.Format))
+		if t.Format != "" && t.Format != "zip" && t.Format != "repo" {
+			return fail(fmt.Sprintf("task[%d].format must be zip or repo", i))
+		}
+
+		if t.WarnSec < 0 || t.WarnSec > 3600 {
+			return fail(fmt.Sprintf("task[%d].warn_sec must be 0-3600", i))
+		}
+		for _, n := range t.WarnIntervals {
+			if n <= 0 {
+				return fail(fmt.Sprintf("task[%d].warn_intervals must be positive seconds", i))
+			}
+		}
+		if t.StopTimeoutSec < 0 || t.StopTimeoutSec > 3600 {
+			return fail(fmt.Sprintf("task[%d].stop_timeout_sec must be 0-3600", i))
+		}
+		if t.TermTimeoutSec < 0 || t.TermTimeoutSec > 600 {
+			return fail(fmt.Sprintf("task[%d].term_timeout_sec must be 0-600", i))
+		}
 
 		if tt == "announce" {
 			t.Message = strings.TrimSpace(t.Message)
@@ -161,6 +180,7 @@ func (e *Executor) scheduleRunTask(ctx context.Context, cmd protocol.Command) pr
 		ServersFS: e.deps.FS,
 		MC:        e.deps.MC,
 		Log:       e.deps.Log,
+		Progress:  e.emitInstall,
 	})
 
 	err = m.RunTaskNow(ctx, s.Tasks[idx])
@@ -21,11 +21,28 @@ type Config struct {
 	FRPWorkDir string
 
 	JavaCandidates []string
+	JavaDiscover bool
 	JavaAutoDownload bool
 	JavaCacheDir string
 	JavaAdoptiumAPIBaseURL string
+	JavaZuluAPIBaseURL string
+	JavaManifestURL string
+	JavaVendor string
 	PreferredConnectAddrs []string
 
+	RuntimeDir string
+	MCDetach   bool
+
+	Cgroups      bool
+	CgroupParent string
+
+	MCIsolation     bool
+	MCUID           int
+	MCGID           int
+	MCIsolationHide []string
+	// MCIsolationEnv names daemon environment variables passed to isolated servers.
+	MCIsolationEnv []string
+
 	BindPanel        bool
 	PanelBindingPath string
 
@@ -36,6 +53,18 @@ type Config struct {
 	MojangMetaBaseURL string
 	MojangDataBaseURL string
 	PaperAPIBaseURL   string
+	PurpurAPIBaseURL  string
+	FabricMetaBaseURL string
+	QuiltMetaBaseURL  string
+	ForgeMavenBaseURL    string
+	NeoForgeMavenBaseURL string
+	CurseForgeAPIBaseURL string
+	ModrinthAPIBaseURL   string
+
+	VersionsCacheDir    string
+	VersionsCacheTTLSec int
+
+	ClientModDenylistFile string
 }
 
 func LoadFromEnv() (Config, error) {
@@ -86,8 +115,21 @@ func LoadFromEnv() (Config, error) {
 	if len(cfg.JavaCandidates) == 0 {
 		cfg.JavaCandidates = []string{"java"}
 	}
+	// Scan JAVA_HOME, /usr/lib/jvm, SDKMAN, asdf, /opt and the java cache for runtimes.
+	// Set ELEGANTMC_JAVA_DISCOVER=0 to only use ELEGANTMC_JAVA_CANDIDATES.
+	cfg.JavaDiscover = true
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_JAVA_DISCOVER")); v != "" {
+		switch v {
+		case "1", "true", "TRUE", "yes", "YES", "on", "ON":
+			cfg.JavaDiscover = true
+		case "0", "false", "FALSE", "no", "NO", "off", "OFF":
+			cfg.JavaDiscover = false
+		default:
+			return Config{}, errors.New("ELEGANTMC_JAVA_DISCOVER must be 0/1")
+		}
+	}
 
-	// Java runtime auto-download (Temurin / Adoptium).
+	// Java runtime auto-download (Temurin by default, see ELEGANTMC_JAVA_VENDOR).
 	// Set ELEGANTMC_JAVA_AUTO_DOWNLOAD=0 to disable.
 	cfg.JavaAutoDownload = true
 	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_JAVA_AUTO_DOWNLOAD")); v != "" {
@@ -108,9 +150,85 @@ func LoadFromEnv() (Config, error) {
 	if cfg.JavaAdoptiumAPIBaseURL == "" {
 		cfg.JavaAdoptiumAPIBaseURL = "https://api.adoptium.net"
 	}
+	cfg.JavaZuluAPIBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_JAVA_ZULU_API_BASE_URL"))
+	if cfg.JavaZuluAPIBaseURL == "" {
+		cfg.JavaZuluAPIBaseURL = "https://api.azul.com"
+	}
+	cfg.JavaManifestURL = strings.TrimSpace(os.Getenv("ELEGANTMC_JAVA_MANIFEST_URL"))
+	cfg.JavaVendor = strings.ToLower(strings.TrimSpace(os.Getenv("ELEGANTMC_JAVA_VENDOR")))
+	if cfg.JavaVendor == "" {
+		cfg.JavaVendor = "temurin"
+	}
+	if strings.ContainsAny(cfg.JavaVendor, `/\ `) || strings.HasPrefix(cfg.JavaVendor, ".") {
+		return Config{}, errors.New("invalid ELEGANTMC_JAVA_VENDOR")
+	}
 
 	cfg.PreferredConnectAddrs = splitListEnv(os.Getenv("ELEGANTMC_PREFERRED_CONNECT_ADDRS"))
 
+	// Runtime state of running servers (pid/args/console FIFO), used to re-adopt them after a daemon restart.
+	cfg.RuntimeDir = strings.TrimSpace(os.Getenv("ELEGANTMC_RUNTIME_DIR"))
+	if cfg.RuntimeDir == "" {
+		cfg.RuntimeDir = filepath.Join(cfg.BaseDir, "run")
+	}
+	// Keep servers running when the daemon exits (they are re-adopted on next start).
+	// Set ELEGANTMC_MC_DETACH=1 to enable.
+	cfg.MCDetach = false
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_MC_DETACH")); v != "" {
+		switch v {
+		case "1", "true", "TRUE", "yes", "YES", "on", "ON":
+			cfg.MCDetach = true
+		case "0", "false", "FALSE", "no", "NO", "off", "OFF":
+			cfg.MCDetach = false
+		default:
+			return Config{}, errors.New("ELEGANTMC_MC_DETACH must be 0/1")
+		}
+	}
+
+	// Per-instance cgroup v2 groups (Linux): resource_limits from .elegantmc.json and
+	// per-instance accounting. Requires a delegated cgroup. Set ELEGANTMC_CGROUPS=1 to enable.
+	cfg.Cgroups = false
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_CGROUPS")); v != "" {
+		switch v {
+		case "1", "true", "TRUE", "yes", "YES", "on", "ON":
+			cfg.Cgroups = true
+		case "0", "false", "FALSE", "no", "NO", "off", "OFF":
+			cfg.Cgroups = false
+		default:
+			return Config{}, errors.New("ELEGANTMC_CGROUPS must be 0/1")
+		}
+	}
+	cfg.CgroupParent = strings.TrimSpace(os.Getenv("ELEGANTMC_CGROUP_PARENT"))
+
+	// Sandbox server processes (Linux): private mount namespace with a read-only host,
+	// hidden daemon directories and no capabilities. Set ELEGANTMC_MC_ISOLATION=1 to enable.
+	cfg.MCIsolation = false
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_MC_ISOLATION")); v != "" {
+		switch v {
+		case "1", "true", "TRUE", "yes", "YES", "on", "ON":
+			cfg.MCIsolation = true
+		case "0", "false", "FALSE", "no", "NO", "off", "OFF":
+			cfg.MCIsolation = false
+		default:
+			return Config{}, errors.New("ELEGANTMC_MC_ISOLATION must be 0/1")
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_MC_UID")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, errors.New("ELEGANTMC_MC_UID must be a uid number")
+		}
+		cfg.MCUID = n
+	}
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_MC_GID")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, errors.New("ELEGANTMC_MC_GID must be a gid number")
+		}
+		cfg.MCGID = n
+	}
+	cfg.MCIsolationHide = splitListEnv(os.Getenv("ELEGANTMC_MC_ISOLATION_HIDE"))
+	cfg.MCIsolationEnv = splitListEnv(os.Getenv("ELEGANTMC_MC_ISOLATION_ENV"))
+
 	// Security: bind this daemon to the first panel it connects to (by panel_id).
 	// Set ELEGANTMC_BIND_PANEL=0 to disable.
 	cfg.BindPanel = true
@@ -163,6 +281,50 @@ func LoadFromEnv() (Config, error) {
 	if cfg.PaperAPIBaseURL == "" {
 		cfg.PaperAPIBaseURL = "https://api.papermc.io"
 	}
+	cfg.PurpurAPIBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_PURPUR_API_BASE_URL"))
+	if cfg.PurpurAPIBaseURL == "" {
+		cfg.PurpurAPIBaseURL = "https://api.purpurmc.org"
+	}
+	cfg.FabricMetaBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_FABRIC_META_BASE_URL"))
+	if cfg.FabricMetaBaseURL == "" {
+		cfg.FabricMetaBaseURL = "https://meta.fabricmc.net"
+	}
+	cfg.QuiltMetaBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_QUILT_META_BASE_URL"))
+	if cfg.QuiltMetaBaseURL == "" {
+		cfg.QuiltMetaBaseURL = "https://meta.quiltmc.org"
+	}
+	cfg.ForgeMavenBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_FORGE_MAVEN_BASE_URL"))
+	if cfg.ForgeMavenBaseURL == "" {
+		cfg.ForgeMavenBaseURL = "https://maven.minecraftforge.net"
+	}
+	cfg.NeoForgeMavenBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_NEOFORGE_MAVEN_BASE_URL"))
+	if cfg.NeoForgeMavenBaseURL == "" {
+		cfg.NeoForgeMavenBaseURL = "https://maven.neoforged.net/releases"
+	}
+	cfg.CurseForgeAPIBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_CURSEFORGE_API_BASE_URL"))
+	if cfg.CurseForgeAPIBaseURL == "" {
+		cfg.CurseForgeAPIBaseURL = "https://api.curseforge.com"
+	}
+	cfg.ModrinthAPIBaseURL = strings.TrimSpace(os.Getenv("ELEGANTMC_MODRINTH_API_BASE_URL"))
+	if cfg.ModrinthAPIBaseURL == "" {
+		cfg.ModrinthAPIBaseURL = "https://api.modrinth.com"
+	}
+	cfg.ClientModDenylistFile = strings.TrimSpace(os.Getenv("ELEGANTMC_CLIENT_MOD_DENYLIST"))
+	if cfg.ClientModDenylistFile == "" {
+		cfg.ClientModDenylistFile = filepath.Join(cfg.BaseDir, "client-mod-denylist.txt")
+	}
+	cfg.VersionsCacheDir = strings.TrimSpace(os.Getenv("ELEGANTMC_VERSIONS_CACHE_DIR"))
+	if cfg.VersionsCacheDir == "" {
+		cfg.VersionsCacheDir = filepath.Join(cfg.BaseDir, "cache", "versions")
+	}
+	cfg.VersionsCacheTTLSec = 6 * 60 * 60
+	if v := strings.TrimSpace(os.Getenv("ELEGANTMC_VERSIONS_CACHE_TTL_SEC")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Config{}, errors.New("ELEGANTMC_VERSIONS_CACHE_TTL_SEC must be a positive integer")
+		}
+		cfg.VersionsCacheTTLSec = n
+	}
 
 	if cfg.PanelWSURL == "" {
 		return Config{}, errors.New("ELEGANTMC_PANEL_WS_URL is required")
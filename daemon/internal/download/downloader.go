@@ -2,11 +2,14 @@ package download
 
 import (
 	"context"
+	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
@@ -19,6 +22,17 @@ type Result struct {
 	Bytes  int64
 	SHA256 string
 	SHA1   string
+	// SHA512 and MD5 are only computed when the matching Expected field is set.
+	SHA512 string
+	MD5    string
+}
+
+// Expected holds the checksums a download must match; empty fields are not checked.
+type Expected struct {
+	SHA256 string
+	SHA1   string
+	SHA512 string
+	MD5    string
 }
 
 type Progress struct {
@@ -37,6 +51,10 @@ func DownloadFileWithChecksums(ctx context.Context, url string, destPath string,
 }
 
 func DownloadFileWithChecksumsProgress(ctx context.Context, url string, destPath string, expectedSHA256 string, expectedSHA1 string, onProgress ProgressFunc) (Result, error) {
+	return DownloadFileVerified(ctx, url, destPath, Expected{SHA256: expectedSHA256, SHA1: expectedSHA1}, onProgress)
+}
+
+func DownloadFileVerified(ctx context.Context, url string, destPath string, want Expected, onProgress ProgressFunc) (Result, error) {
 	url = strings.TrimSpace(url)
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		return Result{}, errors.New("only http/https URLs are supported")
@@ -82,7 +100,17 @@ func DownloadFileWithChecksumsProgress(ctx context.Context, url string, destPath
 
 	hasher := sha256.New()
 	hasher1 := sha1.New()
-	w := io.MultiWriter(f, hasher, hasher1)
+	writers := []io.Writer{f, hasher, hasher1}
+	var hasher512, hasherMD5 hash.Hash
+	if want.SHA512 != "" {
+		hasher512 = sha512.New()
+		writers = append(writers, hasher512)
+	}
+	if want.MD5 != "" {
+		hasherMD5 = md5.New()
+		writers = append(writers, hasherMD5)
+	}
+	w := io.MultiWriter(writers...)
 	buf := make([]byte, 32*1024)
 	var n int64
 	lastEmit := time.Now()
@@ -120,12 +148,25 @@ func DownloadFileWithChecksumsProgress(ctx context.Context, url string, destPath
 
 	sum256 := hex.EncodeToString(hasher.Sum(nil))
 	sum1 := hex.EncodeToString(hasher1.Sum(nil))
-	if expectedSHA256 != "" && !strings.EqualFold(sum256, strings.TrimSpace(expectedSHA256)) {
+	var sum512, sumMD5 string
+	if hasher512 != nil {
+		sum512 = hex.EncodeToString(hasher512.Sum(nil))
+	}
+	if hasherMD5 != nil {
+		sumMD5 = hex.EncodeToString(hasherMD5.Sum(nil))
+	}
+	if want.SHA256 != "" && !strings.EqualFold(sum256, strings.TrimSpace(want.SHA256)) {
 		return Result{}, errors.New("sha256 mismatch")
 	}
-	if expectedSHA1 != "" && !strings.EqualFold(sum1, strings.TrimSpace(expectedSHA1)) {
+	if want.SHA1 != "" && !strings.EqualFold(sum1, strings.TrimSpace(want.SHA1)) {
 		return Result{}, errors.New("sha1 mismatch")
 	}
+	if want.SHA512 != "" && !strings.EqualFold(sum512, strings.TrimSpace(want.SHA512)) {
+		return Result{}, errors.New("sha512 mismatch")
+	}
+	if want.MD5 != "" && !strings.EqualFold(sumMD5, strings.TrimSpace(want.MD5)) {
+		return Result{}, errors.New("md5 mismatch")
+	}
 
 	if err := os.Chmod(tmpPath, 0o644); err != nil {
 		return Result{}, err
@@ -134,5 +175,5 @@ func DownloadFileWithChecksumsProgress(ctx context.Context, url string, destPath
 		return Result{}, err
 	}
 
-	return Result{Bytes: n, SHA256: sum256, SHA1: sum1}, nil
+	return Result{Bytes: n, SHA256: sum256, SHA1: sum1, SHA512: sum512, MD5: sumMD5}, nil
 }
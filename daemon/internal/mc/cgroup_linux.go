@@ -0,0 +1,253 @@
+//go:build linux
+
+package mc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const cgroupMount = "/sys/fs/cgroup"
+
+// daemonLeaf is the child group the daemon moves itself into when it uses its own cgroup
+// as the parent of the instance groups.
+const daemonLeaf = "daemon"
+
+// cgroupController places instances into cgroup v2 sub-groups of a delegated parent.
+type cgroupController struct {
+	parent string // configured parent, relative to the cgroup mount ("" = the daemon's own cgroup)
+
+	once sync.Once
+	base string
+	err  error
+}
+
+func newCgroupController(parent string) *cgroupController {
+	return &cgroupController{parent: strings.Trim(strings.TrimSpace(parent), "/")}
+}
+
+// setup resolves the base cgroup and enables the controllers for its children.
+func (c *cgroupController) setup() (string, error) {
+	c.once.Do(func() {
+		c.base, c.err = c.resolveBase()
+	})
+	return c.base, c.err
+}
+
+func (c *cgroupController) resolveBase() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMount, "cgroup.controllers")); err != nil {
+		return "", errors.New("cgroup v2 (unified hierarchy) not mounted at " + cgroupMount)
+	}
+
+	var base string
+	if c.parent != "" {
+		base = filepath.Join(cgroupMount, c.parent)
+		if err := os.MkdirAll(base, 0o755); err != nil {
+			return "", err
+		}
+	} else {
+		self, err := ownCgroup()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(cgroupMount, self)
+		// After a restart the daemon already sits in the leaf it created last time: use the
+		// parent again instead of nesting one level deeper.
+		if filepath.Base(base) == daemonLeaf {
+			if parent := filepath.Dir(base); parent != cgroupMount && cgroupIsInner(parent) {
+				base = parent
+			}
+		}
+		// cgroup v2 forbids processes in inner nodes: move our own processes into a leaf first.
+		if err := moveProcsToLeaf(base, daemonLeaf); err != nil {
+			return "", fmt.Errorf("cgroup %s is not delegated: %w", base, err)
+		}
+	}
+
+	available, err := os.ReadFile(filepath.Join(base, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	enabled := 0
+	for _, ctrl := range strings.Fields(string(available)) {
+		switch ctrl {
+		case "memory", "cpu", "pids", "io":
+			if err := os.WriteFile(filepath.Join(base, "cgroup.subtree_control"), []byte("+"+ctrl), 0o644); err == nil {
+				enabled++
+			}
+		}
+	}
+	if enabled == 0 {
+		return "", fmt.Errorf("cgroup %s: unable to enable memory/cpu/pids/io controllers", base)
+	}
+	return base, nil
+}
+
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rest, ok := strings.CutPrefix(sc.Text(), "0::"); ok {
+			return strings.Trim(rest, "/"), nil
+		}
+	}
+	return "", errors.New("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// cgroupIsInner reports whether dir has no processes of its own and delegates
+// controllers to its children (the shape resolveBase leaves the daemon's cgroup in).
+func cgroupIsInner(dir string) bool {
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil || strings.TrimSpace(string(procs)) != "" {
+		return false
+	}
+	subtree, err := os.ReadFile(filepath.Join(dir, "cgroup.subtree_control"))
+	return err == nil && strings.TrimSpace(string(subtree)) != ""
+}
+
+func moveProcsToLeaf(base string, leaf string) error {
+	b, err := os.ReadFile(filepath.Join(base, "cgroup.procs"))
+	if err != nil {
+		return err
+	}
+	pids := strings.Fields(string(b))
+	if len(pids) == 0 {
+		return nil
+	}
+	leafDir := filepath.Join(base, leaf)
+	if err := os.MkdirAll(leafDir, 0o755); err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if err := os.WriteFile(filepath.Join(leafDir, "cgroup.procs"), []byte(pid), 0o644); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// create makes (or reuses) the instance cgroup and applies limits. Limits that cannot be
+// written are reported in warnings; the cgroup is still usable for accounting.
+func (c *cgroupController) create(instanceID string, limits ResourceLimits) (string, []string, error) {
+	base, err := c.setup()
+	if err != nil {
+		return "", nil, err
+	}
+	dir := filepath.Join(base, "elegantmc-"+instanceID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", nil, err
+	}
+
+	var warnings []string
+	write := func(file string, value string) {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s=%s: %v", file, value, err))
+		}
+	}
+
+	mem, _ := limits.memoryMaxBytes()
+	if mem > 0 {
+		write("memory.max", strconv.FormatUint(mem, 10))
+	} else {
+		write("memory.max", "max")
+	}
+	if limits.CPUs > 0 {
+		const period = 100000
+		quota := int64(limits.CPUs * period)
+		if quota < 1000 {
+			quota = 1000
+		}
+		write("cpu.max", fmt.Sprintf("%d %d", quota, period))
+	} else {
+		write("cpu.max", "max")
+	}
+	if limits.PidsMax > 0 {
+		write("pids.max", strconv.Itoa(limits.PidsMax))
+	} else {
+		write("pids.max", "max")
+	}
+	if limits.IOWeight > 0 {
+		if _, err := os.Stat(filepath.Join(dir, "io.weight")); err == nil {
+			write("io.weight", fmt.Sprintf("default %d", limits.IOWeight))
+		} else {
+			warnings = append(warnings, "io.weight: io controller not available")
+		}
+	}
+	return dir, warnings, nil
+}
+
+// startInCgroup makes cmd start directly inside dir (clone3 CLONE_INTO_CGROUP, Linux
+// 5.7+), so the server never runs outside its limits. The returned directory handle must
+// be closed after cmd.Start. It returns nil on older kernels, where callers fall back to
+// attachCgroup after the start.
+func startInCgroup(cmd *exec.Cmd, dir string) (*os.File, error) {
+	if !kernelAtLeast(5, 7) {
+		return nil, nil
+	}
+	f, err := os.OpenFile(dir, os.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return f, nil
+}
+
+func kernelAtLeast(major, minor int) bool {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return false
+	}
+	var b strings.Builder
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		b.WriteByte(byte(c))
+	}
+	parts := strings.SplitN(b.String(), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	maj, err1 := strconv.Atoi(parts[0])
+	min, err2 := strconv.Atoi(strings.TrimFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return maj > major || (maj == major && min >= minor)
+}
+
+func attachCgroup(dir string, pid int) error {
+	return os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0o644)
+}
+
+// removeCgroup deletes an instance cgroup once its processes are gone. It polls for up to
+// a second, so callers must not hold inst.mu.
+func removeCgroup(dir string) {
+	if dir == "" {
+		return
+	}
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(dir); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		// Exiting processes can linger in cgroup.procs for a moment.
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package mc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+type cgroupController struct{}
+
+func newCgroupController(parent string) *cgroupController {
+	return &cgroupController{}
+}
+
+func (c *cgroupController) create(instanceID string, limits ResourceLimits) (string, []string, error) {
+	return "", nil, errors.New("cgroups are only supported on linux")
+}
+
+func startInCgroup(cmd *exec.Cmd, dir string) (*os.File, error) {
+	return nil, nil
+}
+
+func attachCgroup(dir string, pid int) error {
+	return errors.New("cgroups are only supported on linux")
+}
+
+func removeCgroup(dir string) {}
@@ -0,0 +1,199 @@
+package mc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Console event kinds.
+const (
+	EventServerDone  = "server_done"
+	EventPlayerJoin  = "player_join"
+	EventPlayerLeave = "player_leave"
+	EventChat        = "chat"
+	EventDeath       = "death"
+	EventAdvancement = "advancement"
+	EventLag         = "lag"
+	EventException   = "exception"
+)
+
+const maxStackTraceLines = 100
+
+// ConsoleEvent is a typed occurrence recognized in server console output.
+type ConsoleEvent struct {
+	Kind   string
+	Stream string
+	Line   string // raw console line that produced the event (header line for exceptions)
+
+	Player  string
+	UUID    string
+	Message string // chat text, death message, advancement title, exception message
+
+	StartupMs    int64 // server_done
+	BehindMs     int64 // lag
+	SkippedTicks int   // lag
+
+	Exception  string   // exception class
+	StackTrace []string // "at ..." / "Caused by: ..." lines
+}
+
+var (
+	reANSI       = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	reLogPrefix  = regexp.MustCompile(`^(?:\[[^\]]*\]\s*)+:\s?`)
+	reDone       = regexp.MustCompile(`^Done \((\d+(?:[.,]\d+)?)s\)!`)
+	reUUID       = regexp.MustCompile(`^UUID of player (\w{1,16}) is ([0-9a-fA-F-]{32,36})$`)
+	reJoin       = regexp.MustCompile(`^(\w{1,16}) joined the game$`)
+	reLoggedIn   = regexp.MustCompile(`^(\w{1,16})\[[^\]]*\] logged in with entity id `)
+	reOnlineList = regexp.MustCompile(`^There are \d+ ?(?:of a max of|/) ?\d+ players online:\s*(.*)$`)
+	reLeave      = regexp.MustCompile(`^(\w{1,16}) left the game$`)
+	reChat       = regexp.MustCompile(`^(?:\[Not Secure\] )?<(\w{1,16})> (.*)$`)
+	reAdvance    = regexp.MustCompile(`^(\w{1,16}) has (?:made the advancement|completed the challenge|reached the goal) \[(.+)\]$`)
+	reLag        = regexp.MustCompile(`^Can't keep up! .*?Running (\d+)ms or (\d+) ticks behind`)
+	reTraceLine  = regexp.MustCompile(`^(?:\s+at |\s+\.\.\. \d+ (?:more|common frames omitted)|\s*Caused by: |\s+Suppressed: )`)
+	reException  = regexp.MustCompile(`([A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)+(?:Exception|Error|Throwable))(?::\s*(.*))?$`)
+	reDeathStart = regexp.MustCompile(`^(\w{1,16}) (.+)$`)
+)
+
+// Leading words of vanilla death messages (see the death.* language keys).
+var deathPhrases = []string{
+	"was slain by", "was shot by", "was pummeled by", "was fireballed by", "was killed",
+	"was blown up by", "blew up", "was struck by lightning", "was squashed", "was squished",
+	"was pricked to death", "was poked to death", "was stung to death", "was impaled",
+	"was skewered", "was obliterated", "was roasted", "was frozen", "was doomed to fall",
+	"was burned to a crisp", "was burnt to a crisp", "was impaled on a stalagmite",
+	"drowned", "died", "starved to death", "suffocated in a wall", "fell from", "fell off",
+	"fell while", "fell too far", "fell out of the world", "hit the ground too hard",
+	"burned to death", "went up in flames", "walked into", "tried to swim in lava",
+	"discovered the floor was lava", "experienced kinetic energy", "froze to death",
+	"withered away", "left the confines of this world", "didn't want to live",
+	"went off with a bang", "was shot by a skull", "was speared by",
+}
+
+// consoleParser turns console lines of one output stream into events. Stack traces are
+// buffered until the first line that does not continue them (or Flush).
+type consoleParser struct {
+	stream string
+	emit   func(ConsoleEvent)
+
+	uuids  map[string]string
+	online map[string]bool
+
+	prev    string // previous raw line (candidate exception header)
+	prevMsg string
+	trace   *ConsoleEvent
+}
+
+func newConsoleParser(stream string, emit func(ConsoleEvent)) *consoleParser {
+	return &consoleParser{
+		stream: stream,
+		emit:   emit,
+		uuids:  make(map[string]string),
+		online: make(map[string]bool),
+	}
+}
+
+func (p *consoleParser) Feed(raw string) {
+	if p == nil || p.emit == nil {
+		return
+	}
+	line := reANSI.ReplaceAllString(strings.TrimRight(raw, "\r"), "")
+	if strings.HasPrefix(line, "[elegantmc]") {
+		return
+	}
+
+	if reTraceLine.MatchString(line) {
+		if p.trace == nil {
+			ev := ConsoleEvent{Kind: EventException, Stream: p.stream, Line: p.prev}
+			if m := reException.FindStringSubmatch(p.prevMsg); m != nil {
+				ev.Exception = m[1]
+				ev.Message = strings.TrimSpace(m[2])
+			} else {
+				ev.Message = p.prevMsg
+			}
+			p.trace = &ev
+		}
+		if len(p.trace.StackTrace) < maxStackTraceLines {
+			p.trace.StackTrace = append(p.trace.StackTrace, strings.TrimSpace(line))
+		}
+		return
+	}
+	p.Flush()
+
+	msg := reLogPrefix.ReplaceAllString(line, "")
+	p.prev = line
+	p.prevMsg = msg
+	if ev, ok := p.match(msg); ok {
+		ev.Stream = p.stream
+		ev.Line = line
+		p.emit(ev)
+	}
+}
+
+// Flush emits a pending stack trace.
+func (p *consoleParser) Flush() {
+	if p == nil || p.trace == nil {
+		return
+	}
+	ev := *p.trace
+	p.trace = nil
+	p.emit(ev)
+}
+
+func (p *consoleParser) match(msg string) (ConsoleEvent, bool) {
+	if m := reDone.FindStringSubmatch(msg); m != nil {
+		sec, _ := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+		return ConsoleEvent{Kind: EventServerDone, StartupMs: int64(sec * 1000)}, true
+	}
+	if m := reUUID.FindStringSubmatch(msg); m != nil {
+		p.uuids[m[1]] = m[2]
+		return ConsoleEvent{}, false
+	}
+	if m := reChat.FindStringSubmatch(msg); m != nil {
+		return ConsoleEvent{Kind: EventChat, Player: m[1], UUID: p.uuids[m[1]], Message: m[2]}, true
+	}
+	if m := reJoin.FindStringSubmatch(msg); m != nil {
+		p.online[m[1]] = true
+		return ConsoleEvent{Kind: EventPlayerJoin, Player: m[1], UUID: p.uuids[m[1]]}, true
+	}
+	if m := reLoggedIn.FindStringSubmatch(msg); m != nil {
+		p.online[m[1]] = true
+		return ConsoleEvent{}, false
+	}
+	if m := reOnlineList.FindStringSubmatch(msg); m != nil {
+		// Output of "list": learn who is online on a server whose joins were missed (adopted).
+		for _, name := range strings.Split(m[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				p.online[name] = true
+			}
+		}
+		return ConsoleEvent{}, false
+	}
+	if m := reLeave.FindStringSubmatch(msg); m != nil {
+		delete(p.online, m[1])
+		uuid := p.uuids[m[1]]
+		delete(p.uuids, m[1])
+		return ConsoleEvent{Kind: EventPlayerLeave, Player: m[1], UUID: uuid}, true
+	}
+	if m := reAdvance.FindStringSubmatch(msg); m != nil {
+		return ConsoleEvent{Kind: EventAdvancement, Player: m[1], UUID: p.uuids[m[1]], Message: m[2]}, true
+	}
+	if m := reLag.FindStringSubmatch(msg); m != nil {
+		ms, _ := strconv.ParseInt(m[1], 10, 64)
+		ticks, _ := strconv.Atoi(m[2])
+		return ConsoleEvent{Kind: EventLag, BehindMs: ms, SkippedTicks: ticks}, true
+	}
+	if m := reDeathStart.FindStringSubmatch(msg); m != nil {
+		// Death messages have no marker of their own: only accept them for players known to
+		// be online, otherwise any "<word> ... died" line from a mod would match.
+		if !p.online[m[1]] {
+			return ConsoleEvent{}, false
+		}
+		for _, phrase := range deathPhrases {
+			if m[2] == phrase || strings.HasPrefix(m[2], phrase+" ") {
+				return ConsoleEvent{Kind: EventDeath, Player: m[1], UUID: p.uuids[m[1]], Message: msg}, true
+			}
+		}
+	}
+	return ConsoleEvent{}, false
+}
@@ -0,0 +1,167 @@
+package mc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseConsole(lines []string) []ConsoleEvent {
+	var events []ConsoleEvent
+	p := newConsoleParser("stdout", func(ev ConsoleEvent) { events = append(events, ev) })
+	for _, line := range lines {
+		p.Feed(line)
+	}
+	p.Flush()
+	return events
+}
+
+// eventSummary drops the raw line and stream so cases only spell out the parsed fields.
+type eventSummary struct {
+	Kind, Player, UUID, Message string
+	StartupMs                   int64
+}
+
+func summarize(events []ConsoleEvent) []eventSummary {
+	out := make([]eventSummary, 0, len(events))
+	for _, ev := range events {
+		out = append(out, eventSummary{ev.Kind, ev.Player, ev.UUID, ev.Message, ev.StartupMs})
+	}
+	return out
+}
+
+const steveUUID = "8667ba71-b85a-4004-af54-457a9734eed7"
+
+func TestConsoleParser_ServerLogs(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		want  []eventSummary
+	}{
+		{
+			name: "vanilla",
+			lines: []string{
+				`[12:00:00] [Server thread/INFO]: Done (3.456s)! For help, type "help"`,
+				`[12:00:01] [User Authenticator #1/INFO]: UUID of player Steve is ` + steveUUID,
+				`[12:00:01] [Server thread/INFO]: Steve[/127.0.0.1:52134] logged in with entity id 123 at (0.5, 64.0, 0.5)`,
+				`[12:00:01] [Server thread/INFO]: Steve joined the game`,
+				`[12:00:05] [Server thread/INFO]: <Steve> hello there`,
+				`[12:00:06] [Server thread/INFO]: [Not Secure] <Steve> unsigned`,
+				`[12:00:07] [Server thread/INFO]: Steve was slain by Zombie`,
+				`[12:00:08] [Server thread/INFO]: Steve has made the advancement [Stone Age]`,
+				`[12:00:09] [Server thread/INFO]: Steve lost connection: Disconnected`,
+				`[12:00:09] [Server thread/INFO]: Steve left the game`,
+			},
+			want: []eventSummary{
+				{Kind: EventServerDone, StartupMs: 3456},
+				{Kind: EventPlayerJoin, Player: "Steve", UUID: steveUUID},
+				{Kind: EventChat, Player: "Steve", UUID: steveUUID, Message: "hello there"},
+				{Kind: EventChat, Player: "Steve", UUID: steveUUID, Message: "unsigned"},
+				{Kind: EventDeath, Player: "Steve", UUID: steveUUID, Message: "Steve was slain by Zombie"},
+				{Kind: EventAdvancement, Player: "Steve", UUID: steveUUID, Message: "Stone Age"},
+				{Kind: EventPlayerLeave, Player: "Steve", UUID: steveUUID},
+			},
+		},
+		{
+			name: "paper",
+			lines: []string{
+				`[12:00:00 INFO]: Done (12,345s)! For help, type "help"`,
+				`[12:00:01 INFO]: UUID of player Alex is ` + steveUUID,
+				`[12:00:01 INFO]: Alex joined the game`,
+				`[12:00:02 INFO]: <Alex> hi`,
+				`[12:00:03 INFO]: Alex fell from a high place`,
+				`[12:00:04 INFO]: Alex has completed the challenge [Monster Hunter]`,
+				`[12:00:05 INFO]: Alex left the game`,
+			},
+			want: []eventSummary{
+				{Kind: EventServerDone, StartupMs: 12345},
+				{Kind: EventPlayerJoin, Player: "Alex", UUID: steveUUID},
+				{Kind: EventChat, Player: "Alex", UUID: steveUUID, Message: "hi"},
+				{Kind: EventDeath, Player: "Alex", UUID: steveUUID, Message: "Alex fell from a high place"},
+				{Kind: EventAdvancement, Player: "Alex", UUID: steveUUID, Message: "Monster Hunter"},
+				{Kind: EventPlayerLeave, Player: "Alex", UUID: steveUUID},
+			},
+		},
+		{
+			name: "forge",
+			lines: []string{
+				`[12:00:00] [Server thread/INFO] [minecraft/DedicatedServer]: Done (20.1s)! For help, type "help"`,
+				`[12:00:01] [Server thread/INFO] [minecraft/MinecraftServer]: Herobrine joined the game`,
+				`[12:00:02] [Server thread/INFO] [minecraft/MinecraftServer]: <Herobrine> test`,
+				`[12:00:03] [Server thread/INFO] [minecraft/MinecraftServer]: Herobrine drowned`,
+				`[12:00:04] [Server thread/INFO] [minecraft/PlayerAdvancements]: Herobrine has reached the goal [Sky's the Limit]`,
+				`[12:00:05] [Server thread/INFO] [minecraft/MinecraftServer]: Herobrine left the game`,
+			},
+			want: []eventSummary{
+				{Kind: EventServerDone, StartupMs: 20100},
+				{Kind: EventPlayerJoin, Player: "Herobrine"},
+				{Kind: EventChat, Player: "Herobrine", Message: "test"},
+				{Kind: EventDeath, Player: "Herobrine", Message: "Herobrine drowned"},
+				{Kind: EventAdvancement, Player: "Herobrine", Message: "Sky's the Limit"},
+				{Kind: EventPlayerLeave, Player: "Herobrine"},
+			},
+		},
+		{
+			name: "death-like lines of unknown names are ignored",
+			lines: []string{
+				`[12:00:00] [Server thread/INFO] [somemod/]: Worker died`,
+				`[12:00:01] [Server thread/WARN]: Scheduler fell too far behind`,
+				`[12:00:02] [Server thread/INFO]: Steve joined the game`,
+				`[12:00:03] [Server thread/INFO]: Alex was slain by Zombie`,
+			},
+			want: []eventSummary{
+				{Kind: EventPlayerJoin, Player: "Steve"},
+			},
+		},
+		{
+			name: "no deaths after the player left",
+			lines: []string{
+				`[12:00:00] [Server thread/INFO]: Steve joined the game`,
+				`[12:00:01] [Server thread/INFO]: Steve left the game`,
+				`[12:00:02] [Server thread/INFO]: Steve drowned`,
+			},
+			want: []eventSummary{
+				{Kind: EventPlayerJoin, Player: "Steve"},
+				{Kind: EventPlayerLeave, Player: "Steve"},
+			},
+		},
+		{
+			name: "adopted server learns players from list output",
+			lines: []string{
+				`[12:00:00] [Server thread/INFO]: Steve drowned`,
+				`[12:00:01] [Server thread/INFO]: There are 2 of a max of 20 players online: Steve, Alex`,
+				`[12:00:02] [Server thread/INFO]: Alex starved to death`,
+			},
+			want: []eventSummary{
+				{Kind: EventDeath, Player: "Alex", Message: "Alex starved to death"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := summarize(parseConsole(tc.lines))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("events:\n got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConsoleParser_Exception(t *testing.T) {
+	events := parseConsole([]string{
+		`[12:00:00] [Server thread/ERROR]: Encountered an unexpected exception`,
+		`java.lang.IllegalStateException: boom`,
+		`	at net.minecraft.server.MinecraftServer.tick(MinecraftServer.java:100)`,
+		`	at java.base/java.lang.Thread.run(Thread.java:833)`,
+		`[12:00:01] [Server thread/INFO]: Steve joined the game`,
+	})
+	if len(events) != 2 {
+		t.Fatalf("got %+v", events)
+	}
+	ev := events[0]
+	if ev.Kind != EventException || ev.Exception != "java.lang.IllegalStateException" || ev.Message != "boom" || len(ev.StackTrace) != 2 {
+		t.Fatalf("unexpected exception event: %+v", ev)
+	}
+	if events[1].Kind != EventPlayerJoin {
+		t.Fatalf("expected join after the trace, got %+v", events[1])
+	}
+}
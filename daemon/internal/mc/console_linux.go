@@ -0,0 +1,51 @@
+//go:build linux
+
+package mc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const consoleFIFOSupported = true
+
+// openConsoleFIFO creates (if needed) and opens a named FIFO for the server's stdin.
+// It is opened O_RDWR so neither side blocks on open and the reader never sees EOF
+// while the daemon is away.
+func openConsoleFIFO(path string) (*os.File, error) {
+	st, err := os.Lstat(path)
+	if err == nil && st.Mode()&os.ModeNamedPipe == 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+		err = os.ErrNotExist
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+func detachedSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// reapProcess collects the exit status of pid if it is (or was re-parented as) our child.
+// ok is false when the process is still running or is not our child.
+func reapProcess(pid int) (exitCode *int, exitSignal string, ok bool) {
+	var ws syscall.WaitStatus
+	wpid, err := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+	if err != nil || wpid != pid {
+		return nil, "", false
+	}
+	if ws.Signaled() {
+		return nil, ws.Signal().String(), true
+	}
+	code := ws.ExitStatus()
+	return &code, "", true
+}
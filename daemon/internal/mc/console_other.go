@@ -0,0 +1,19 @@
+//go:build !linux
+
+package mc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const consoleFIFOSupported = false
+
+func openConsoleFIFO(path string) (*os.File, error) {
+	return nil, errors.New("console fifo is not supported on this platform")
+}
+
+func detachedSysProcAttr() *syscall.SysProcAttr { return nil }
+
+func reapProcess(pid int) (exitCode *int, exitSignal string, ok bool) { return nil, "", false }
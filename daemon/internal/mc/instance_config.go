@@ -0,0 +1,40 @@
+package mc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const instanceConfigName = ".elegantmc.json"
+
+// instanceConfigFile is the subset of servers/<instance>/.elegantmc.json the manager reads itself.
+// The panel owns the file; unknown fields are ignored.
+type instanceConfigFile struct {
+	RestartPolicy  *RestartPolicy  `json:"restart_policy,omitempty"`
+	ResourceLimits *ResourceLimits `json:"resource_limits,omitempty"`
+	// JavaVendor picks the auto-download vendor (temurin, zulu, or one from the java manifest).
+	JavaVendor string `json:"java_vendor,omitempty"`
+	// JvmProfile names a flag preset the daemon expands at start (aikar, zgc, shenandoah, lowmem).
+	JvmProfile string `json:"jvm_profile,omitempty"`
+}
+
+func readInstanceConfigFile(instanceDir string) (instanceConfigFile, error) {
+	f, err := os.Open(filepath.Join(instanceDir, instanceConfigName))
+	if err != nil {
+		return instanceConfigFile{}, err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, 1024*1024))
+	if err != nil {
+		return instanceConfigFile{}, err
+	}
+	var cfg instanceConfigFile
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return instanceConfigFile{}, errors.New("invalid instance config (.elegantmc.json)")
+	}
+	return cfg, nil
+}
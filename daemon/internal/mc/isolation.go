@@ -0,0 +1,101 @@
+package mc
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IsolationHelperArg is argv[1] of the daemon binary when it is re-executed as the
+// isolation helper; main must call RunIsolationHelper before anything else in that case.
+const IsolationHelperArg = "__elegantmc_isolate"
+
+// IsolationConfig enables sandboxing of server processes (Linux only).
+//
+// The server runs in a private mount namespace where the whole filesystem is read-only,
+// HidePaths are covered by empty tmpfs mounts, /tmp is a private tmpfs and only the
+// instance directory is writable. Capabilities are dropped and no_new_privs is set.
+// A root daemon switches to UID/GID; a non-root daemon uses a user namespace.
+// The server gets a minimal environment (see isolationEnv), not the daemon's.
+type IsolationConfig struct {
+	Enabled   bool
+	UID       int
+	GID       int
+	HidePaths []string
+	// EnvAllow names extra daemon environment variables passed to the server.
+	EnvAllow []string
+}
+
+// isolationSpec is passed to the helper process as JSON (argv[2]).
+type isolationSpec struct {
+	InstanceDir string   `json:"instance_dir"`
+	Hide        []string `json:"hide,omitempty"`
+	ExposeRO    []string `json:"expose_ro,omitempty"`
+	Env         []string `json:"env"`
+	UID         int      `json:"uid"`
+	GID         int      `json:"gid"`
+	SetID       bool     `json:"set_id"`
+}
+
+// isolationPassEnv is passed through to isolated servers when set.
+var isolationPassEnv = []string{"LANG", "LC_ALL", "LC_CTYPE", "TZ", "TERM"}
+
+const isolationDefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// isolationEnv builds the environment of an isolated server from scratch: PATH, HOME,
+// locale/timezone and the operator's EnvAllow names. Nothing else of the daemon's
+// environment (ELEGANTMC_TOKEN and other secrets) reaches the server.
+func isolationEnv(getenv func(string) string, home string, allow []string) []string {
+	path := getenv("PATH")
+	if path == "" {
+		path = isolationDefaultPath
+	}
+	env := []string{"PATH=" + path, "HOME=" + home}
+	seen := map[string]bool{"PATH": true, "HOME": true}
+	for _, name := range append(append([]string(nil), isolationPassEnv...), allow...) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] || strings.ContainsAny(name, "=\x00") {
+			continue
+		}
+		seen[name] = true
+		if v := getenv(name); v != "" {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// normalizeHidePaths cleans, de-duplicates and drops paths nested in another hidden path.
+func normalizeHidePaths(paths []string) []string {
+	var cleaned []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		p = abs
+		if p == "/" {
+			continue
+		}
+		cleaned = append(cleaned, p)
+	}
+	sort.Slice(cleaned, func(i, j int) bool { return len(cleaned[i]) < len(cleaned[j]) })
+	var out []string
+	for _, p := range cleaned {
+		nested := false
+		for _, q := range out {
+			if p == q || isWithinDir(q, p) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,367 @@
+//go:build linux
+
+package mc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	prCapBSetDrop         = 24
+	prSetNoNewPrivs       = 38
+	prCapAmbient          = 47
+	prCapAmbientClearAll  = 4
+	linuxCapabilityV3     = 0x20080522
+	isolationHelperFailed = 126
+)
+
+// isolateCommand rewrites cmd so that it runs through the isolation helper (the daemon
+// binary re-executed with IsolationHelperArg), which sets up the sandbox and execs Java.
+func (m *Manager) isolateCommand(cmd *exec.Cmd, instanceDir string) error {
+	cfg := m.cfg.Isolation
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("isolation: locate daemon binary: %w", err)
+	}
+
+	spec := isolationSpec{
+		InstanceDir: instanceDir,
+		Hide:        normalizeHidePaths(cfg.HidePaths),
+	}
+	// Keep the Java runtime visible (read-only) when it lives in a hidden directory.
+	if javaReal, err := filepath.EvalSymlinks(cmd.Path); err == nil {
+		javaHome := filepath.Dir(filepath.Dir(javaReal))
+		for _, h := range spec.Hide {
+			if isWithinDir(h, javaHome) {
+				spec.ExposeRO = append(spec.ExposeRO, javaHome)
+				break
+			}
+		}
+	}
+
+	attr := cmd.SysProcAttr
+	if attr == nil {
+		attr = &syscall.SysProcAttr{}
+	}
+	// A PID namespace with its own /proc (mounted by the helper) keeps the daemon's
+	// /proc/<pid>/root and environ out of reach, which would bypass the hidden paths.
+	attr.Cloneflags |= syscall.CLONE_NEWNS | syscall.CLONE_NEWPID
+	if os.Geteuid() == 0 {
+		if cfg.UID <= 0 || cfg.GID <= 0 {
+			return errors.New("isolation: ELEGANTMC_MC_UID / ELEGANTMC_MC_GID (non-zero) are required when the daemon runs as root")
+		}
+		spec.UID, spec.GID, spec.SetID = cfg.UID, cfg.GID, true
+		if err := chownTreeIfNeeded(instanceDir, cfg.UID, cfg.GID); err != nil {
+			return fmt.Errorf("isolation: chown instance dir: %w", err)
+		}
+	} else {
+		// Rootless: a user namespace maps the daemon user to root inside the sandbox.
+		if b, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil && strings.TrimSpace(string(b)) == "0" {
+			return errors.New("isolation: user namespaces are disabled (user.max_user_namespaces=0)")
+		}
+		if b, err := os.ReadFile("/proc/sys/kernel/unprivileged_userns_clone"); err == nil && strings.TrimSpace(string(b)) == "0" {
+			return errors.New("isolation: unprivileged user namespaces are disabled (kernel.unprivileged_userns_clone=0)")
+		}
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+	}
+	cmd.SysProcAttr = attr
+
+	home := instanceDir
+	if u, err := user.LookupId(strconv.Itoa(targetUID(spec))); err == nil && u.HomeDir != "" {
+		home = u.HomeDir
+	}
+	spec.Env = isolationEnv(os.Getenv, home, cfg.EnvAllow)
+	// The helper itself gets the same minimal environment as the server.
+	cmd.Env = spec.Env
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	args := append([]string{self, IsolationHelperArg, string(specJSON), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	cmd.Args = args
+	return nil
+}
+
+// targetUID is the host uid the server runs as.
+func targetUID(spec isolationSpec) int {
+	if spec.SetID {
+		return spec.UID
+	}
+	return os.Getuid()
+}
+
+// chownTreeIfNeeded hands the instance directory to the sandbox user. Every entry is
+// checked (files added by the daemon, uploads or restores keep their owner otherwise);
+// only entries with a different owner are changed.
+func chownTreeIfNeeded(dir string, uid int, gid int) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) == uid && int(st.Gid) == gid {
+			return nil
+		}
+		return os.Lchown(p, uid, gid)
+	})
+}
+
+// RunIsolationHelper runs inside the new mount and PID (and user) namespaces: it builds the
+// sandboxed view of the filesystem, drops privileges and execs the server command.
+// args are os.Args[2:]: spec JSON, executable, arguments. It never returns.
+func RunIsolationHelper(args []string) {
+	// prctl and capset act on the calling thread, which must also be the one calling execve.
+	runtime.LockOSThread()
+	if err := runIsolationHelper(args); err != nil {
+		fmt.Fprintf(os.Stderr, "[elegantmc] isolation failed: %v\n", err)
+		os.Exit(isolationHelperFailed)
+	}
+}
+
+func runIsolationHelper(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: " + IsolationHelperArg + " <spec> <executable> [args...]")
+	}
+	var spec isolationSpec
+	if err := json.Unmarshal([]byte(args[0]), &spec); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	if !filepath.IsAbs(spec.InstanceDir) {
+		return errors.New("instance_dir must be absolute")
+	}
+	exe := args[1]
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make / private: %w", err)
+	}
+
+	// Keep handles to the directories that stay visible before hiding their parents.
+	type exposed struct {
+		path string
+		f    *os.File
+	}
+	open := func(p string) (exposed, error) {
+		f, err := os.OpenFile(p, os.O_RDONLY|syscall.O_DIRECTORY, 0)
+		return exposed{path: p, f: f}, err
+	}
+	inst, err := open(spec.InstanceDir)
+	if err != nil {
+		return err
+	}
+	var ro []exposed
+	for _, p := range spec.ExposeRO {
+		if e, err := open(p); err == nil {
+			ro = append(ro, e)
+		}
+	}
+
+	// The inherited /proc still shows every host process; replace it with one for the new
+	// PID namespace, where the server is PID 1 and sees only its own children.
+	if err := syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", "/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	for _, p := range spec.Hide {
+		if fi, err := os.Stat(p); err != nil || !fi.IsDir() || isWithinDir("/tmp", p) {
+			continue
+		}
+		if err := syscall.Mount("tmpfs", p, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755,size=64k"); err != nil {
+			return fmt.Errorf("hide %s: %w", p, err)
+		}
+	}
+
+	bind := func(e exposed) error {
+		defer e.f.Close()
+		if err := os.MkdirAll(e.path, 0o755); err != nil {
+			return err
+		}
+		src := "/proc/self/fd/" + strconv.Itoa(int(e.f.Fd()))
+		return syscall.Mount(src, e.path, "", syscall.MS_BIND|syscall.MS_REC, "")
+	}
+	for _, e := range ro {
+		if err := bind(e); err != nil {
+			return fmt.Errorf("expose %s: %w", e.path, err)
+		}
+	}
+	if err := bind(inst); err != nil {
+		return fmt.Errorf("bind instance dir: %w", err)
+	}
+
+	if err := remountReadOnly(spec.InstanceDir); err != nil {
+		return err
+	}
+	if err := os.Chdir(spec.InstanceDir); err != nil {
+		return err
+	}
+
+	if err := dropBoundingSet(); err != nil {
+		return err
+	}
+	if spec.SetID {
+		if err := syscall.Setgroups([]int{spec.GID}); err != nil {
+			return fmt.Errorf("setgroups: %w", err)
+		}
+		if err := syscall.Setgid(spec.GID); err != nil {
+			return fmt.Errorf("setgid: %w", err)
+		}
+		// Switching every uid away from 0 clears the permitted/effective capability sets.
+		if err := syscall.Setuid(spec.UID); err != nil {
+			return fmt.Errorf("setuid: %w", err)
+		}
+	}
+	if err := clearCapabilities(); err != nil {
+		return err
+	}
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("no_new_privs: %w", errno)
+	}
+	return syscall.Exec(exe, args[1:], spec.Env)
+}
+
+// remountReadOnly makes every mount read-only except the writable instance dir, /tmp and
+// the kernel filesystems under /proc, /sys and /dev.
+func remountReadOnly(writable string) error {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var points []struct {
+		path  string
+		flags uintptr
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		p := unescapeMountPath(fields[4])
+		skip := false
+		for _, keep := range []string{writable, "/tmp", "/proc", "/sys", "/dev"} {
+			if isWithinDir(keep, p) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			points = append(points, struct {
+				path  string
+				flags uintptr
+			}{p, mountFlags(fields[5])})
+		}
+	}
+	for _, mp := range points {
+		flags := mp.flags | syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY
+		if err := syscall.Mount("", mp.path, "", flags, ""); err != nil {
+			if mp.path == "/" {
+				return fmt.Errorf("remount / read-only: %w", err)
+			}
+			if !errors.Is(err, syscall.ENOENT) && !errors.Is(err, syscall.EACCES) {
+				return fmt.Errorf("remount %s read-only: %w", mp.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func mountFlags(opts string) uintptr {
+	var flags uintptr
+	for _, o := range strings.Split(opts, ",") {
+		switch o {
+		case "nosuid":
+			flags |= syscall.MS_NOSUID
+		case "nodev":
+			flags |= syscall.MS_NODEV
+		case "noexec":
+			flags |= syscall.MS_NOEXEC
+		case "noatime":
+			flags |= syscall.MS_NOATIME
+		case "nodiratime":
+			flags |= syscall.MS_NODIRATIME
+		case "relatime":
+			flags |= syscall.MS_RELATIME
+		case "strictatime":
+			flags |= syscall.MS_STRICTATIME
+		}
+	}
+	return flags
+}
+
+// unescapeMountPath decodes the octal escapes (\040 etc.) used in mountinfo.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func dropBoundingSet() error {
+	last := 40
+	if b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+			last = n
+		}
+	}
+	for c := 0; c <= last; c++ {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prCapBSetDrop, uintptr(c), 0, 0, 0, 0); errno != 0 && errno != syscall.EINVAL {
+			return fmt.Errorf("drop capability bounding set: %w", errno)
+		}
+	}
+	return nil
+}
+
+func clearCapabilities() error {
+	_, _, _ = syscall.RawSyscall6(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0, 0, 0, 0)
+	hdr := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityV3}
+	var data [2]struct {
+		effective   uint32
+		permitted   uint32
+		inheritable uint32
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("capset: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+package mc
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// The isolation helper re-executes the current binary; in tests that is the test binary.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == IsolationHelperArg {
+		RunIsolationHelper(os.Args[2:])
+	}
+	os.Exit(m.Run())
+}
+
+func TestIsolateCommand_DoesNotLeakDaemonEnv(t *testing.T) {
+	t.Setenv("ELEGANTMC_TOKEN", "panel-secret")
+	t.Setenv("LANG", "C.UTF-8")
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	cfg := IsolationConfig{Enabled: true}
+	if os.Geteuid() == 0 {
+		cfg.UID, cfg.GID = 65534, 65534
+	}
+	m := &Manager{cfg: ManagerConfig{Isolation: cfg}}
+
+	cmd := exec.Command("/usr/bin/env")
+	cmd.Dir = dir
+	if err := m.isolateCommand(cmd, dir); err != nil {
+		t.Skipf("isolation unavailable: %v", err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == isolationHelperFailed {
+			t.Skipf("isolation unavailable: %s", strings.TrimSpace(string(out)))
+		}
+		t.Fatalf("run: %v\n%s", err, out)
+	}
+	env := string(out)
+	if strings.Contains(env, "ELEGANTMC_TOKEN") || strings.Contains(env, "panel-secret") {
+		t.Fatalf("daemon secret reached the server:\n%s", env)
+	}
+	if !strings.Contains(env, "LANG=C.UTF-8") || !strings.Contains(env, "PATH=") {
+		t.Fatalf("expected minimal env, got:\n%s", env)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package mc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func (m *Manager) isolateCommand(cmd *exec.Cmd, instanceDir string) error {
+	return errors.New("isolation is only supported on linux")
+}
+
+func RunIsolationHelper(args []string) {
+	fmt.Fprintln(os.Stderr, "[elegantmc] isolation is only supported on linux")
+	os.Exit(126)
+}
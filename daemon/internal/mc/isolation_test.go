@@ -0,0 +1,31 @@
+package mc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsolationEnv(t *testing.T) {
+	daemonEnv := map[string]string{
+		"PATH":                  "/usr/bin:/bin",
+		"HOME":                  "/root",
+		"LANG":                  "C.UTF-8",
+		"ELEGANTMC_TOKEN":       "secret",
+		"ELEGANTMC_PANEL_WS":    "wss://panel.example",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"JAVA_TOOL_OPTIONS":     "-Dfile.encoding=UTF-8",
+	}
+	getenv := func(k string) string { return daemonEnv[k] }
+
+	got := isolationEnv(getenv, "/home/mc", []string{"JAVA_TOOL_OPTIONS", " ", "LANG", "BAD=NAME"})
+	want := []string{"PATH=/usr/bin:/bin", "HOME=/home/mc", "LANG=C.UTF-8", "JAVA_TOOL_OPTIONS=-Dfile.encoding=UTF-8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("isolationEnv() = %q, want %q", got, want)
+	}
+
+	got = isolationEnv(func(string) string { return "" }, "/srv/mc", nil)
+	want = []string{"PATH=" + isolationDefaultPath, "HOME=/srv/mc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("isolationEnv() with empty env = %q, want %q", got, want)
+	}
+}
@@ -11,118 +11,105 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
+// javaSelector picks a runtime for a required major version from the java inventory.
 type javaSelector struct {
-	candidates []string
-
-	mu     sync.Mutex
-	cached map[string]javaProbeResult // javaPath -> probe result
+	inventory *javaInventory
 }
 
-type javaProbeResult struct {
-	major int
-	err   string
+func newJavaSelector(inventory *javaInventory) *javaSelector {
+	return &javaSelector{inventory: inventory}
 }
 
-func newJavaSelector(candidates []string) *javaSelector {
-	var cleaned []string
-	for _, c := range candidates {
-		c = strings.TrimSpace(c)
-		if c == "" {
-			continue
-		}
-		cleaned = append(cleaned, c)
-	}
-	if len(cleaned) == 0 {
-		cleaned = []string{"java"}
-	}
-	return &javaSelector{
-		candidates: cleaned,
-		cached:     make(map[string]javaProbeResult, len(cleaned)),
-	}
+func (s *javaSelector) Select(ctx context.Context, requiredMajor int) (string, int, error) {
+	return s.SelectRange(ctx, requiredMajor, 0)
 }
 
-func (s *javaSelector) Select(ctx context.Context, requiredMajor int) (string, int, error) {
+// SelectRange is Select with an upper bound (0 = none), for tools that break on newer
+// runtimes (legacy Forge installers only run on Java 8).
+func (s *javaSelector) SelectRange(ctx context.Context, requiredMajor, maxMajor int) (string, int, error) {
 	if requiredMajor <= 0 {
 		requiredMajor = 8
 	}
 
-	type cand struct {
-		path  string
-		major int
-		err   error
+	list, _ := s.inventory.List(ctx, false)
+	path, major, ok := pickJava(list, requiredMajor, maxMajor)
+	if !ok {
+		// A runtime may have been installed since the last scan.
+		list, _ = s.inventory.List(ctx, true)
+		path, major, ok = pickJava(list, requiredMajor, maxMajor)
 	}
-
-	var probed []cand
-	for _, c := range s.candidates {
-		maj, err := s.probe(ctx, c)
-		probed = append(probed, cand{path: c, major: maj, err: err})
+	if ok {
+		return path, major, nil
 	}
 
-	// Choose the smallest major version that satisfies the requirement (more compatible than picking the newest).
-	bestPath := ""
-	bestMajor := 0
-	for _, p := range probed {
-		if p.err != nil || p.major <= 0 {
+	_, hostArch, _ := adoptiumOSArch()
+	var msg strings.Builder
+	if maxMajor > 0 {
+		msg.WriteString(fmt.Sprintf("no java runtime %d..%d found. candidates:", requiredMajor, maxMajor))
+	} else {
+		msg.WriteString(fmt.Sprintf("no java runtime >= %d found. candidates:", requiredMajor))
+	}
+	for _, j := range list {
+		if j.Error != "" {
+			msg.WriteString(fmt.Sprintf(" %s(err=%s);", j.Path, j.Error))
 			continue
 		}
-		if p.major < requiredMajor {
+		if j.Major > 0 && j.Arch != "" && j.Arch != hostArch {
+			msg.WriteString(fmt.Sprintf(" %s(major=%d arch=%s);", j.Path, j.Major, j.Arch))
 			continue
 		}
-		if bestPath == "" || p.major < bestMajor {
-			bestPath = p.path
-			bestMajor = p.major
-		}
-	}
-	if bestPath != "" {
-		return bestPath, bestMajor, nil
-	}
-
-	var msg strings.Builder
-	msg.WriteString(fmt.Sprintf("no java runtime >= %d found. candidates:", requiredMajor))
-	for _, p := range probed {
-		if p.err != nil {
-			msg.WriteString(fmt.Sprintf(" %s(err=%s);", p.path, p.err.Error()))
+		if j.Major > 0 {
+			msg.WriteString(fmt.Sprintf(" %s(major=%d);", j.Path, j.Major))
 			continue
 		}
-		if p.major > 0 {
-			msg.WriteString(fmt.Sprintf(" %s(major=%d);", p.path, p.major))
-			continue
-		}
-		msg.WriteString(fmt.Sprintf(" %s(unknown);", p.path))
+		msg.WriteString(fmt.Sprintf(" %s(unknown);", j.Path))
 	}
 	msg.WriteString(" set ELEGANTMC_JAVA_CANDIDATES or pass java_path to mc_start")
 	return "", 0, errors.New(msg.String())
 }
 
-func (s *javaSelector) probe(ctx context.Context, javaPath string) (int, error) {
-	s.mu.Lock()
-	res, ok := s.cached[javaPath]
-	s.mu.Unlock()
-	if ok {
-		if res.err != "" {
-			return 0, errors.New(res.err)
+// pickJava chooses the smallest major version that satisfies the requirement (more
+// compatible than picking the newest). A satisfying configured candidate always wins
+// over discovered runtimes, which are only a fallback; on a tie the earlier entry wins.
+// Runtimes newer than maxMajor (when > 0) or built for another architecture are skipped.
+func pickJava(list []JavaInstallation, requiredMajor, maxMajor int) (string, int, bool) {
+	_, hostArch, _ := adoptiumOSArch()
+	bestPath := ""
+	bestMajor := 0
+	bestCandidate := false
+	for _, j := range list {
+		if j.Error != "" || j.Major < requiredMajor || (maxMajor > 0 && j.Major > maxMajor) {
+			continue
+		}
+		if j.Arch != "" && hostArch != "" && j.Arch != hostArch {
+			continue
+		}
+		candidate := j.Source == "candidate"
+		if bestPath == "" || (candidate && !bestCandidate) || (candidate == bestCandidate && j.Major < bestMajor) {
+			bestPath = j.Path
+			bestMajor = j.Major
+			bestCandidate = candidate
 		}
-		return res.major, nil
-	}
-
-	maj, err := probeJavaMajor(ctx, javaPath)
-	s.mu.Lock()
-	if err != nil {
-		s.cached[javaPath] = javaProbeResult{major: 0, err: err.Error()}
-	} else {
-		s.cached[javaPath] = javaProbeResult{major: maj}
 	}
-	s.mu.Unlock()
-	return maj, err
+	return bestPath, bestMajor, bestPath != ""
 }
 
-var reJavaVersion = regexp.MustCompile(`(?m)version "([^"]+)"`)
+var (
+	reJavaVersion = regexp.MustCompile(`(?m)version "([^"]+)"`)
+	reJavaBuild   = regexp.MustCompile(`(?m)Runtime Environment.*\(build ([^)]+)\)`)
+)
 
 func probeJavaMajor(ctx context.Context, javaPath string) (int, error) {
+	major, _, err := probeJavaVersion(ctx, javaPath)
+	return major, err
+}
+
+// probeJavaVersion runs "java -version" and returns the major version and the full
+// runtime version ("21.0.2+13-LTS"; the quoted version when there is no build line).
+func probeJavaVersion(ctx context.Context, javaPath string) (int, string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -134,14 +121,18 @@ func probeJavaMajor(ctx context.Context, javaPath string) (int, error) {
 	text := string(out)
 	m := reJavaVersion.FindStringSubmatch(text)
 	if len(m) < 2 {
-		return 0, fmt.Errorf("cannot parse java -version output: %s", strings.TrimSpace(firstLine(text)))
+		return 0, "", fmt.Errorf("cannot parse java -version output: %s", strings.TrimSpace(firstLine(text)))
 	}
 	ver := strings.TrimSpace(m[1])
 	major, ok := parseJavaMajor(ver)
 	if !ok || major <= 0 {
-		return 0, fmt.Errorf("unsupported java version string: %q", ver)
+		return 0, "", fmt.Errorf("unsupported java version string: %q", ver)
+	}
+	full := ver
+	if b := reJavaBuild.FindStringSubmatch(text); len(b) == 2 && strings.TrimSpace(b[1]) != "" {
+		full = strings.TrimSpace(b[1])
 	}
-	return major, nil
+	return major, full, nil
 }
 
 func parseJavaMajor(ver string) (int, bool) {
@@ -261,6 +252,11 @@ func RequiredJavaMajorFromJar(jarPath string) (int, error) {
 	return requiredJavaMajorFromJar(jarPath)
 }
 
+// ProbeJavaMajor runs "<javaPath> -version" and returns the runtime's major version.
+func ProbeJavaMajor(ctx context.Context, javaPath string) (int, error) {
+	return probeJavaMajor(ctx, javaPath)
+}
+
 // parseJarManifestMainClass reads a minimal subset of MANIFEST.MF.
 // It also supports continuation lines starting with one space.
 func parseJarManifestMainClass(manifest string) string {
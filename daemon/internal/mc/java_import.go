@@ -0,0 +1,138 @@
+package mc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JavaImportOptions describes a runtime archive that is already on disk.
+type JavaImportOptions struct {
+	// Vendor overrides the vendor read from the runtime's release file.
+	Vendor string
+	// SHA256 is checked against the archive when set.
+	SHA256 string
+	// Source is recorded as the sidecar's source_url (e.g. the sandbox path).
+	Source string
+	// Replace overwrites a cached runtime with the same key.
+	Replace bool
+}
+
+// ImportArchive installs a JDK/JRE archive (.tar.gz, .tgz or .zip) into the cache with
+// the same layout and sidecar as downloaded runtimes, so EnsureJRE and the Java
+// inventory pick it up without network access.
+func (m *JavaRuntimeManager) ImportArchive(ctx context.Context, archivePath string, opt JavaImportOptions) (JavaCacheEntry, error) {
+	if strings.TrimSpace(m.cfg.CacheDir) == "" {
+		return JavaCacheEntry{}, errors.New("java cache dir not configured")
+	}
+	lower := strings.ToLower(archivePath)
+	var archive string
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		archive = "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		archive = "tar.gz"
+	default:
+		return JavaCacheEntry{}, errors.New("java archive must be .tar.gz, .tgz or .zip")
+	}
+	vendor := strings.ToLower(strings.TrimSpace(opt.Vendor))
+	if vendor != "" && !reJavaVendor.MatchString(vendor) {
+		return JavaCacheEntry{}, fmt.Errorf("invalid java vendor: %q", vendor)
+	}
+	osID, archID, err := adoptiumOSArch()
+	if err != nil {
+		return JavaCacheEntry{}, err
+	}
+
+	sum, err := fileSHA256(archivePath)
+	if err != nil {
+		return JavaCacheEntry{}, err
+	}
+	if want := strings.ToLower(strings.TrimSpace(opt.SHA256)); want != "" && want != sum {
+		return JavaCacheEntry{}, fmt.Errorf("sha256 mismatch: want=%s got=%s", want, sum)
+	}
+
+	if err := os.MkdirAll(m.cfg.CacheDir, 0o755); err != nil {
+		return JavaCacheEntry{}, err
+	}
+	tmpDir, err := os.MkdirTemp(m.cfg.CacheDir, ".import-")
+	if err != nil {
+		return JavaCacheEntry{}, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	unpackDir := filepath.Join(tmpDir, "runtime")
+	info, err := unpackJavaRuntime(ctx, archivePath, archive, unpackDir)
+	if err != nil {
+		return JavaCacheEntry{}, err
+	}
+	home := filepath.Dir(filepath.Dir(filepath.Join(unpackDir, filepath.FromSlash(info.JavaRel))))
+	release := readJavaRelease(home)
+	if arch := normalizeJavaArch(release["OS_ARCH"]); arch != "" && arch != archID {
+		return JavaCacheEntry{}, fmt.Errorf("runtime is built for %s, this host is %s", arch, archID)
+	}
+	if vendor == "" {
+		vendor = javaVendorFromRelease(release)
+	}
+	if vendor == "" {
+		vendor = "openjdk"
+	}
+	info.Vendor = vendor
+	info.ImageType = javaImageType(home, release)
+	if info.ImageType != "jdk" {
+		info.ImageType = "jre"
+	}
+	info.Provider = "import"
+	info.SourceURL = opt.Source
+	info.SHA256 = sum
+	if err := writeJSONFileAtomic(filepath.Join(unpackDir, javaCacheInfoName), info); err != nil {
+		return JavaCacheEntry{}, err
+	}
+
+	dir := m.runtimeDir(vendor, info.ImageType, info.Major, osID, archID)
+	if _, err := os.Stat(dir); err == nil {
+		if !opt.Replace {
+			return JavaCacheEntry{}, fmt.Errorf("java runtime already cached: %s (set replace to overwrite)", filepath.Base(dir))
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return JavaCacheEntry{}, err
+		}
+	}
+	if err := os.Rename(unpackDir, dir); err != nil {
+		return JavaCacheEntry{}, err
+	}
+	if m.cfg.Log != nil {
+		m.cfg.Log.Printf("java: imported %s %s %d (%s) as %s", vendor, info.ImageType, info.Major, info.FullVersion, filepath.Base(dir))
+	}
+
+	return JavaCacheEntry{
+		Key:             filepath.Base(dir),
+		Vendor:          vendor,
+		Major:           info.Major,
+		FullVersion:     info.FullVersion,
+		ImageType:       info.ImageType,
+		Provider:        info.Provider,
+		JavaPath:        filepath.Join(dir, filepath.FromSlash(info.JavaRel)),
+		SHA256:          info.SHA256,
+		InstalledAtUnix: info.InstalledAtUnix,
+	}, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
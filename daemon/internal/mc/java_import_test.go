@@ -0,0 +1,127 @@
+package mc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// writeFakeJavaArchive writes a .tar.gz runtime whose bin/java is a shell script that
+// prints a Java 21 version banner.
+func writeFakeJavaArchive(t *testing.T, path, implementor string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	files := []struct {
+		name string
+		mode int64
+		body string
+	}{
+		{"jdk-21.0.2+13-jre/release", 0o644, "IMPLEMENTOR=\"" + implementor + "\"\nIMAGE_TYPE=\"JRE\"\n"},
+		{"jdk-21.0.2+13-jre/bin/java", 0o755, "#!/bin/sh\n" +
+			"echo 'openjdk version \"21.0.2\" 2024-01-16 LTS' >&2\n" +
+			"echo 'OpenJDK Runtime Environment (build 21.0.2+13-LTS)' >&2\n"},
+	}
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: file.mode, Size: int64(len(file.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(file.body)); err != nil {
+			t.Fatalf("tar write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+}
+
+func TestJavaRuntimeManager_ImportedRuntimeIsResolved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake java is a shell script")
+	}
+	var apiHits int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&apiHits, 1)
+		http.Error(w, "offline", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(api.Close)
+	rt := NewJavaRuntimeManager(JavaRuntimeManagerConfig{
+		CacheDir:           t.TempDir(),
+		AdoptiumAPIBaseURL: api.URL,
+		ZuluAPIBaseURL:     api.URL,
+	})
+	ctx := context.Background()
+	osID, archID, err := adoptiumOSArch()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "zulu21.tar.gz")
+	writeFakeJavaArchive(t, archive, "Azul Systems, Inc.")
+	if _, err := rt.ImportArchive(ctx, archive, JavaImportOptions{SHA256: strings.Repeat("0", 64)}); err == nil {
+		t.Fatalf("expected sha256 mismatch")
+	}
+	entry, err := rt.ImportArchive(ctx, archive, JavaImportOptions{Source: "uploads/zulu21.tar.gz"})
+	if err != nil {
+		t.Fatalf("ImportArchive(): %v", err)
+	}
+	if entry.Vendor != "zulu" || entry.Major != 21 || entry.ImageType != "jre" || entry.Provider != "import" || entry.Key != "zulu-jre-21-"+osID+"-"+archID {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if _, err := rt.ImportArchive(ctx, archive, JavaImportOptions{}); err == nil {
+		t.Fatalf("expected an error when the key is already cached")
+	}
+
+	// No vendor requested: the imported zulu runtime satisfies the default (temurin) lookup.
+	javaPath, major, err := rt.EnsureJRE(ctx, "", 21)
+	if err != nil {
+		t.Fatalf("EnsureJRE(\"\", 21): %v", err)
+	}
+	if javaPath != entry.JavaPath || major != 21 {
+		t.Fatalf("EnsureJRE() = %s, %d; want %s", javaPath, major, entry.JavaPath)
+	}
+	if n := atomic.LoadInt32(&apiHits); n != 0 {
+		t.Fatalf("cached runtime should be used without network, got %d api calls", n)
+	}
+
+	// An explicit vendor is not substituted, and other majors are not matched.
+	if _, _, err := rt.EnsureJRE(ctx, "temurin", 21); err == nil {
+		t.Fatalf("expected explicit temurin request to miss the zulu runtime")
+	}
+	if _, _, err := rt.EnsureJRE(ctx, "", 17); err == nil {
+		t.Fatalf("expected java 17 to miss the cache")
+	}
+
+	// A runtime of the default vendor wins over other vendors.
+	temurin, err := rt.ImportArchive(ctx, archive, JavaImportOptions{Vendor: "temurin"})
+	if err != nil {
+		t.Fatalf("ImportArchive(temurin): %v", err)
+	}
+	if javaPath, _, err := rt.EnsureJRE(ctx, "", 21); err != nil || javaPath != temurin.JavaPath {
+		t.Fatalf("EnsureJRE() = %s, %v; want %s", javaPath, err, temurin.JavaPath)
+	}
+
+	cached, err := rt.ListCached()
+	if err != nil {
+		t.Fatalf("ListCached(): %v", err)
+	}
+	if len(cached) != 2 || cached[0].Key != temurin.Key || cached[1].Key != entry.Key {
+		t.Fatalf("unexpected cache listing: %+v", cached)
+	}
+}
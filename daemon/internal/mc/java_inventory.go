@@ -0,0 +1,365 @@
+package mc
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const javaInventoryTTL = 10 * time.Minute
+
+// JavaInstallation is one Java runtime found on the host.
+type JavaInstallation struct {
+	// Path is the java binary as it is passed to exec (candidates keep their original
+	// spelling, e.g. "java").
+	Path        string `json:"path"`
+	Home        string `json:"home,omitempty"`
+	Source      string `json:"source"` // candidate, java_home, jvm, sdkman, asdf, opt, cache
+	Vendor      string `json:"vendor,omitempty"`
+	Major       int    `json:"major,omitempty"`
+	FullVersion string `json:"full_version,omitempty"`
+	Arch        string `json:"arch,omitempty"`       // x64 / aarch64 / x86 / ...
+	ImageType   string `json:"image_type,omitempty"` // jdk / jre
+	Error       string `json:"error,omitempty"`
+}
+
+// javaInventory lists the configured candidates plus, with discovery enabled, runtimes
+// in well-known install locations. Results are cached for javaInventoryTTL; probes are
+// reused while a binary's size and mtime are unchanged.
+type javaInventory struct {
+	candidates []string
+	discover   bool
+	cacheDir   string
+
+	// scanMu serializes scans; mu only guards the fields below and is never held while
+	// a java binary is executed, so callers served from the cache never wait on a scan.
+	scanMu      sync.Mutex
+	mu          sync.Mutex
+	list        []JavaInstallation
+	scannedAt   time.Time
+	invalidated time.Time                 // scans started earlier are stale
+	probes      map[string]javaProbeEntry // resolved binary -> last probe
+}
+
+type javaProbeEntry struct {
+	size    int64
+	modTime time.Time
+	inst    JavaInstallation
+}
+
+func newJavaInventory(candidates []string, discover bool, cacheDir string) *javaInventory {
+	var cleaned []string
+	for _, c := range candidates {
+		if c = strings.TrimSpace(c); c != "" {
+			cleaned = append(cleaned, c)
+		}
+	}
+	if len(cleaned) == 0 {
+		cleaned = []string{"java"}
+	}
+	return &javaInventory{
+		candidates: cleaned,
+		discover:   discover,
+		cacheDir:   strings.TrimSpace(cacheDir),
+		probes:     make(map[string]javaProbeEntry),
+	}
+}
+
+// List returns the inventory, rescanning when it is older than javaInventoryTTL or
+// when refresh is set.
+func (v *javaInventory) List(ctx context.Context, refresh bool) ([]JavaInstallation, time.Time) {
+	requested := time.Now()
+	if !refresh {
+		if list, at, ok := v.cached(time.Time{}); ok {
+			return list, at
+		}
+	}
+
+	v.scanMu.Lock()
+	defer v.scanMu.Unlock()
+	// A scan that started after this call is as good as our own.
+	minScan := requested
+	if !refresh {
+		minScan = time.Time{}
+	}
+	if list, at, ok := v.cached(minScan); ok {
+		return list, at
+	}
+
+	started := time.Now()
+	list := v.scan(ctx)
+	if ctx.Err() != nil {
+		// Partial scan: do not keep it.
+		return list, time.Now()
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.list = list
+	v.scannedAt = started
+	return append([]JavaInstallation(nil), v.list...), v.scannedAt
+}
+
+// cached returns a copy of the cached list when it is within javaInventoryTTL and was
+// scanned at or after minScan.
+func (v *javaInventory) cached(minScan time.Time) ([]JavaInstallation, time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.list == nil || time.Since(v.scannedAt) > javaInventoryTTL || v.scannedAt.Before(minScan) || v.scannedAt.Before(v.invalidated) {
+		return nil, time.Time{}, false
+	}
+	return append([]JavaInstallation(nil), v.list...), v.scannedAt, true
+}
+
+// invalidate makes the next List rescan (probes stay cached).
+func (v *javaInventory) invalidate() {
+	v.mu.Lock()
+	v.list = nil
+	v.invalidated = time.Now()
+	v.mu.Unlock()
+}
+
+type javaLocation struct {
+	path   string
+	source string
+}
+
+func (v *javaInventory) scan(ctx context.Context) []JavaInstallation {
+	var locs []javaLocation
+	for _, c := range v.candidates {
+		locs = append(locs, javaLocation{c, "candidate"})
+	}
+	if v.discover {
+		locs = append(locs, discoverJavaLocations(v.cacheDir)...)
+	}
+
+	var out []JavaInstallation
+	seen := make(map[string]bool)
+	for _, loc := range locs {
+		if ctx.Err() != nil {
+			break
+		}
+		resolved := loc.path
+		if !strings.ContainsAny(resolved, `/\`) {
+			p, err := exec.LookPath(resolved)
+			if err != nil {
+				if loc.source == "candidate" {
+					out = append(out, JavaInstallation{Path: loc.path, Source: loc.source, Error: err.Error()})
+				}
+				continue
+			}
+			resolved = p
+		}
+		if real, err := filepath.EvalSymlinks(resolved); err == nil {
+			resolved = real
+		}
+		if seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		inst := v.probe(ctx, resolved)
+		inst.Path, inst.Source = loc.path, loc.source
+		out = append(out, inst)
+	}
+	return out
+}
+
+func (v *javaInventory) probe(ctx context.Context, resolved string) JavaInstallation {
+	st, err := os.Stat(resolved)
+	if err != nil {
+		return JavaInstallation{Error: err.Error()}
+	}
+	v.mu.Lock()
+	e, ok := v.probes[resolved]
+	v.mu.Unlock()
+	if ok && e.size == st.Size() && e.modTime.Equal(st.ModTime()) {
+		return e.inst
+	}
+
+	home := filepath.Dir(filepath.Dir(resolved))
+	inst := JavaInstallation{Home: home}
+	release := readJavaRelease(home)
+	major, full, err := probeJavaVersion(ctx, resolved)
+	if err != nil {
+		inst.Error = err.Error()
+	} else {
+		inst.Major, inst.FullVersion = major, full
+	}
+	if rv := release["JAVA_RUNTIME_VERSION"]; rv != "" && inst.FullVersion == "" {
+		inst.FullVersion = rv
+	}
+	inst.Vendor = javaVendorFromRelease(release)
+	if info, ok := javaCacheInfoFor(home); ok && info.Vendor != "" {
+		inst.Vendor = info.Vendor
+	}
+	inst.Arch = normalizeJavaArch(release["OS_ARCH"])
+	inst.ImageType = javaImageType(home, release)
+	v.mu.Lock()
+	v.probes[resolved] = javaProbeEntry{size: st.Size(), modTime: st.ModTime(), inst: inst}
+	v.mu.Unlock()
+	return inst
+}
+
+// discoverJavaLocations lists java binaries in the usual install locations.
+func discoverJavaLocations(cacheDir string) []javaLocation {
+	var locs []javaLocation
+	add := func(source string, homes ...string) {
+		for _, home := range homes {
+			for _, rel := range []string{"bin/java", "jre/bin/java", "Contents/Home/bin/java"} {
+				p := filepath.Join(home, filepath.FromSlash(rel))
+				if runtime.GOOS == "windows" {
+					p += ".exe"
+				}
+				if st, err := os.Stat(p); err == nil && !st.IsDir() {
+					locs = append(locs, javaLocation{p, source})
+					break
+				}
+			}
+		}
+	}
+	glob := func(pattern string) []string {
+		m, _ := filepath.Glob(pattern)
+		sort.Strings(m)
+		return m
+	}
+
+	if home := strings.TrimSpace(os.Getenv("JAVA_HOME")); home != "" {
+		add("java_home", home)
+	}
+	add("jvm", glob("/usr/lib/jvm/*")...)
+	add("jvm", glob("/usr/java/*")...)
+	add("jvm", glob("/Library/Java/JavaVirtualMachines/*")...)
+
+	userHome, _ := os.UserHomeDir()
+	sdkman := strings.TrimSpace(os.Getenv("SDKMAN_DIR"))
+	if sdkman == "" && userHome != "" {
+		sdkman = filepath.Join(userHome, ".sdkman")
+	}
+	if sdkman != "" {
+		add("sdkman", glob(filepath.Join(sdkman, "candidates", "java", "*"))...)
+	}
+	asdf := strings.TrimSpace(os.Getenv("ASDF_DATA_DIR"))
+	if asdf == "" && userHome != "" {
+		asdf = filepath.Join(userHome, ".asdf")
+	}
+	if asdf != "" {
+		add("asdf", glob(filepath.Join(asdf, "installs", "java", "*"))...)
+	}
+
+	add("opt", glob("/opt/*jdk*")...)
+	add("opt", glob("/opt/*jre*")...)
+	add("opt", glob("/opt/java/*")...)
+
+	if cacheDir != "" {
+		for _, dir := range glob(filepath.Join(cacheDir, "*")) {
+			if javaAbs, _, ok := loadJavaCacheInfo(dir); ok {
+				locs = append(locs, javaLocation{javaAbs, "cache"})
+			}
+		}
+	}
+	return locs
+}
+
+// readJavaRelease parses the "release" file of a Java home (KEY="value" lines). Java 8
+// JRE binaries live in <jdk>/jre/bin, so the parent directory is tried as well.
+func readJavaRelease(home string) map[string]string {
+	out := make(map[string]string)
+	for _, dir := range []string{home, filepath.Dir(home)} {
+		f, err := os.Open(filepath.Join(dir, "release"))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			k, val, ok := strings.Cut(sc.Text(), "=")
+			if !ok {
+				continue
+			}
+			out[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(val), `"`)
+		}
+		f.Close()
+		break
+	}
+	return out
+}
+
+// javaImageType returns "jdk" or "jre" from the release file, falling back to looking
+// for javac next to java.
+func javaImageType(home string, release map[string]string) string {
+	if t := strings.ToLower(release["IMAGE_TYPE"]); t != "" {
+		return t
+	}
+	for _, dir := range []string{home, filepath.Dir(home)} {
+		if _, err := os.Stat(filepath.Join(dir, "bin", "javac")); err == nil {
+			return "jdk"
+		}
+	}
+	return "jre"
+}
+
+// javaCacheInfoFor returns the cache sidecar when the binary belongs to a runtime in
+// the java cache dir (<key>/elegantmc-java.json next to the extracted directory).
+func javaCacheInfoFor(home string) (javaCacheInfo, bool) {
+	dir := home
+	for i := 0; i < 3; i++ {
+		dir = filepath.Dir(dir)
+		if _, info, ok := loadJavaCacheInfo(dir); ok {
+			return info, true
+		}
+	}
+	return javaCacheInfo{}, false
+}
+
+// javaVendorFromRelease maps the IMPLEMENTOR of a release file to a short vendor name.
+func javaVendorFromRelease(release map[string]string) string {
+	impl := strings.ToLower(release["IMPLEMENTOR"] + " " + release["IMPLEMENTOR_VERSION"])
+	switch {
+	case strings.Contains(impl, "adoptium") || strings.Contains(impl, "temurin"):
+		return "temurin"
+	case strings.Contains(impl, "adoptopenjdk"):
+		return "adoptopenjdk"
+	case strings.Contains(impl, "azul") || strings.Contains(impl, "zulu"):
+		return "zulu"
+	case strings.Contains(impl, "graalvm"):
+		return "graalvm"
+	case strings.Contains(impl, "microsoft"):
+		return "microsoft"
+	case strings.Contains(impl, "amazon") || strings.Contains(impl, "corretto"):
+		return "corretto"
+	case strings.Contains(impl, "bellsoft") || strings.Contains(impl, "liberica"):
+		return "liberica"
+	case strings.Contains(impl, "sap"):
+		return "sapmachine"
+	case strings.Contains(impl, "red hat"):
+		return "redhat"
+	case strings.Contains(impl, "jetbrains"):
+		return "jetbrains"
+	case strings.Contains(impl, "oracle"):
+		return "oracle"
+	case strings.TrimSpace(impl) != "":
+		return "openjdk"
+	}
+	return ""
+}
+
+func normalizeJavaArch(arch string) string {
+	switch strings.ToLower(strings.TrimSpace(arch)) {
+	case "":
+		return ""
+	case "x86_64", "amd64", "x64":
+		return "x64"
+	case "aarch64", "arm64":
+		return "aarch64"
+	case "i386", "i486", "i586", "i686", "x86":
+		return "x86"
+	default:
+		return strings.ToLower(strings.TrimSpace(arch))
+	}
+}
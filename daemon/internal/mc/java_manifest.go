@@ -0,0 +1,115 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// manifestProvider serves runtimes listed in a JSON manifest (URL + sha256 per archive),
+// so a mirror can offer any vendor:
+//
+//	{"runtimes": [{"vendor": "graalvm", "major": 21, "version": "21.0.2", "image_type": "jdk",
+//	  "os": "linux", "arch": "x64", "url": "graalvm-21-linux-x64.tar.gz", "sha256": "..."}]}
+//
+// Relative URLs are resolved against the manifest URL.
+type manifestProvider struct {
+	url string
+}
+
+type javaManifest struct {
+	Runtimes []javaManifestEntry `json:"runtimes"`
+}
+
+type javaManifestEntry struct {
+	Vendor    string `json:"vendor"`
+	Major     int    `json:"major"`
+	Version   string `json:"version"`
+	ImageType string `json:"image_type"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Archive   string `json:"archive"`
+}
+
+func (p *manifestProvider) Name() string { return "manifest" }
+
+func (p *manifestProvider) Resolve(ctx context.Context, req JavaRequest) (JavaRelease, error) {
+	m, err := p.load(ctx)
+	if err != nil {
+		return JavaRelease{}, err
+	}
+	// First matching entry wins; a JRE is preferred over a JDK.
+	var found *javaManifestEntry
+	for i := range m.Runtimes {
+		r := &m.Runtimes[i]
+		if !strings.EqualFold(strings.TrimSpace(r.Vendor), req.Vendor) || r.Major != req.Major ||
+			!strings.EqualFold(r.OS, req.OS) || !strings.EqualFold(r.Arch, req.Arch) {
+			continue
+		}
+		if found == nil || (!strings.EqualFold(found.ImageType, "jre") && strings.EqualFold(r.ImageType, "jre")) {
+			found = r
+		}
+	}
+	if found == nil {
+		return JavaRelease{}, ErrJavaVendorUnsupported
+	}
+	link, err := p.resolveURL(found.URL)
+	if err != nil {
+		return JavaRelease{}, err
+	}
+	return JavaRelease{
+		Version:   strings.TrimSpace(found.Version),
+		ImageType: strings.ToLower(strings.TrimSpace(found.ImageType)),
+		URL:       link,
+		SHA256:    strings.ToLower(strings.TrimSpace(found.SHA256)),
+		Archive:   strings.ToLower(strings.TrimSpace(found.Archive)),
+	}, nil
+}
+
+func (p *manifestProvider) load(ctx context.Context) (javaManifest, error) {
+	var m javaManifest
+	if isHTTPURL(p.url) {
+		if err := getJavaJSON(ctx, p.url, &m); err != nil {
+			return javaManifest{}, err
+		}
+		return m, nil
+	}
+	f, err := os.Open(p.url)
+	if err != nil {
+		return javaManifest{}, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(io.LimitReader(f, 16*1024*1024)).Decode(&m); err != nil {
+		return javaManifest{}, fmt.Errorf("invalid java manifest %s: %w", p.url, err)
+	}
+	return m, nil
+}
+
+func (p *manifestProvider) resolveURL(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return "", fmt.Errorf("java manifest entry has no url")
+	}
+	if isHTTPURL(link) || !isHTTPURL(p.url) {
+		return link, nil
+	}
+	base, err := url.Parse(p.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
@@ -0,0 +1,84 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testJavaManifest = map[string]any{"runtimes": []map[string]any{
+	{"vendor": "graalvm", "major": 21, "version": "21.0.2", "image_type": "jdk", "os": "linux", "arch": "x64", "url": "graalvm-21-jdk.tar.gz", "sha256": "AA"},
+	{"vendor": "GraalVM", "major": 21, "version": "21.0.2", "image_type": "jre", "os": "linux", "arch": "x64", "url": "../files/graalvm-21-jre.tar.gz", "sha256": "BB"},
+	{"vendor": "graalvm", "major": 21, "image_type": "jre", "os": "linux", "arch": "aarch64", "url": "https://cdn.example/graalvm-21-aarch64.tar.gz", "sha256": "CC"},
+	{"vendor": "graalvm", "major": 17, "image_type": "jdk", "os": "linux", "arch": "x64", "url": "graalvm-17-jdk.zip", "sha256": "DD", "archive": "zip"},
+}}
+
+func TestManifestProvider_Resolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mirror/java/manifest.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(testJavaManifest)
+	}))
+	t.Cleanup(srv.Close)
+	p := &manifestProvider{url: srv.URL + "/mirror/java/manifest.json"}
+	ctx := context.Background()
+
+	cases := []struct {
+		req  JavaRequest
+		want JavaRelease
+	}{
+		// The JRE wins over the JDK listed first; relative URLs resolve against the manifest.
+		{JavaRequest{Vendor: "graalvm", Major: 21, OS: "linux", Arch: "x64"},
+			JavaRelease{Version: "21.0.2", ImageType: "jre", URL: srv.URL + "/mirror/files/graalvm-21-jre.tar.gz", SHA256: "bb"}},
+		{JavaRequest{Vendor: "graalvm", Major: 21, OS: "linux", Arch: "aarch64"},
+			JavaRelease{ImageType: "jre", URL: "https://cdn.example/graalvm-21-aarch64.tar.gz", SHA256: "cc"}},
+		// Without a JRE the JDK is used.
+		{JavaRequest{Vendor: "graalvm", Major: 17, OS: "linux", Arch: "x64"},
+			JavaRelease{ImageType: "jdk", URL: srv.URL + "/mirror/java/graalvm-17-jdk.zip", SHA256: "dd", Archive: "zip"}},
+	}
+	for _, tc := range cases {
+		got, err := p.Resolve(ctx, tc.req)
+		if err != nil || got != tc.want {
+			t.Errorf("Resolve(%+v) = %+v, %v; want %+v", tc.req, got, err, tc.want)
+		}
+	}
+
+	for _, req := range []JavaRequest{
+		{Vendor: "temurin", Major: 21, OS: "linux", Arch: "x64"},
+		{Vendor: "graalvm", Major: 21, OS: "windows", Arch: "x64"},
+	} {
+		if _, err := p.Resolve(ctx, req); !errors.Is(err, ErrJavaVendorUnsupported) {
+			t.Errorf("Resolve(%+v): expected ErrJavaVendorUnsupported, got %v", req, err)
+		}
+	}
+}
+
+func TestManifestProvider_LocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	b, _ := json.Marshal(testJavaManifest)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	got, err := (&manifestProvider{url: path}).Resolve(context.Background(), JavaRequest{Vendor: "graalvm", Major: 21, OS: "linux", Arch: "x64"})
+	if err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	// A local manifest has no base URL; its links are used as written.
+	if got.URL != "../files/graalvm-21-jre.tar.gz" {
+		t.Fatalf("URL = %q", got.URL)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if _, err := (&manifestProvider{url: path}).Resolve(context.Background(), JavaRequest{Vendor: "graalvm", Major: 21}); err == nil {
+		t.Fatalf("expected an invalid manifest to fail")
+	}
+}
@@ -0,0 +1,64 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrJavaVendorUnsupported is returned by a JavaProvider that does not carry the
+// requested vendor; the runtime manager then tries the next provider.
+var ErrJavaVendorUnsupported = errors.New("java vendor not supported by provider")
+
+// JavaRequest asks a provider for the latest GA runtime of a vendor and major version.
+// OS and Arch use Adoptium's naming (linux/windows/mac, x64/aarch64/x86).
+type JavaRequest struct {
+	Vendor string
+	Major  int
+	OS     string
+	Arch   string
+}
+
+// JavaRelease is a downloadable runtime archive.
+type JavaRelease struct {
+	Version   string // vendor version, used when the runtime does not report one
+	ImageType string // "jre" or "jdk"
+	URL       string
+	SHA256    string
+	Archive   string // "tar.gz" or "zip"; guessed from URL when empty
+}
+
+// JavaProvider resolves runtimes from one distribution source. The runtime manager
+// downloads, verifies, unpacks and caches what it returns.
+type JavaProvider interface {
+	Name() string
+	Resolve(ctx context.Context, req JavaRequest) (JavaRelease, error)
+}
+
+// getJavaJSON fetches and decodes a JSON document from a provider API.
+func getJavaJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ElegantMC-Daemon/0.1.0")
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status=%d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16*1024*1024)).Decode(out); err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	return nil
+}
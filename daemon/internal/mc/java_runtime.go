@@ -0,0 +1,700 @@
+package mc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"elegantmc/daemon/internal/download"
+)
+
+const (
+	// DefaultJavaVendor is used when neither the daemon config nor the instance picks a vendor.
+	DefaultJavaVendor = "temurin"
+
+	javaCacheInfoName = "elegantmc-java.json"
+)
+
+type JavaRuntimeManagerConfig struct {
+	CacheDir           string
+	AdoptiumAPIBaseURL string
+	ZuluAPIBaseURL     string
+	// ManifestURL points to a JSON list of runtimes (URL + sha256), e.g. on a mirror.
+	// It may also be a local file path. Empty disables the manifest provider.
+	ManifestURL string
+	// DefaultVendor is used when an instance does not set java_vendor.
+	DefaultVendor string
+	Log           *log.Logger
+}
+
+type JavaRuntimeManager struct {
+	cfg       JavaRuntimeManagerConfig
+	providers []JavaProvider
+
+	mu       sync.Mutex
+	inflight map[string]*javaEnsureState
+}
+
+type javaEnsureState struct {
+	done      chan struct{}
+	javaPath  string
+	javaMajor int
+	err       error
+}
+
+type javaCacheInfo struct {
+	JavaRel         string `json:"java_rel"`
+	Major           int    `json:"major"`
+	Vendor          string `json:"vendor,omitempty"`
+	FullVersion     string `json:"full_version,omitempty"`
+	ImageType       string `json:"image_type,omitempty"`
+	Provider        string `json:"provider,omitempty"`
+	SourceURL       string `json:"source_url,omitempty"`
+	SHA256          string `json:"sha256"`
+	InstalledAtUnix int64  `json:"installed_at_unix"`
+}
+
+type JavaCacheEntry struct {
+	Key             string `json:"key"`
+	Vendor          string `json:"vendor"`
+	Major           int    `json:"major"`
+	FullVersion     string `json:"full_version,omitempty"`
+	ImageType       string `json:"image_type,omitempty"`
+	Provider        string `json:"provider,omitempty"`
+	JavaPath        string `json:"java_path"`
+	SHA256          string `json:"sha256"`
+	InstalledAtUnix int64  `json:"installed_at_unix"`
+}
+
+var reJavaVendor = regexp.MustCompile(`^[a-z0-9][a-z0-9._]*$`)
+
+func NewJavaRuntimeManager(cfg JavaRuntimeManagerConfig) *JavaRuntimeManager {
+	if strings.TrimSpace(cfg.AdoptiumAPIBaseURL) == "" {
+		cfg.AdoptiumAPIBaseURL = "https://api.adoptium.net"
+	}
+	if strings.TrimSpace(cfg.ZuluAPIBaseURL) == "" {
+		cfg.ZuluAPIBaseURL = "https://api.azul.com"
+	}
+	cfg.DefaultVendor = strings.ToLower(strings.TrimSpace(cfg.DefaultVendor))
+	if cfg.DefaultVendor == "" {
+		cfg.DefaultVendor = DefaultJavaVendor
+	}
+	// The manifest comes first so a mirror can also serve the built-in vendors.
+	var providers []JavaProvider
+	if strings.TrimSpace(cfg.ManifestURL) != "" {
+		providers = append(providers, &manifestProvider{url: strings.TrimSpace(cfg.ManifestURL)})
+	}
+	providers = append(providers,
+		&temurinProvider{apiBaseURL: cfg.AdoptiumAPIBaseURL},
+		&zuluProvider{apiBaseURL: cfg.ZuluAPIBaseURL},
+	)
+	return &JavaRuntimeManager{
+		cfg:       cfg,
+		providers: providers,
+		inflight:  make(map[string]*javaEnsureState),
+	}
+}
+
+func (m *JavaRuntimeManager) CacheDir() string { return m.cfg.CacheDir }
+
+// DefaultVendor returns the vendor used when an instance does not pick one.
+func (m *JavaRuntimeManager) DefaultVendor() string { return m.cfg.DefaultVendor }
+
+// Providers returns the names of the configured providers, in lookup order.
+func (m *JavaRuntimeManager) Providers() []string {
+	out := make([]string, 0, len(m.providers))
+	for _, p := range m.providers {
+		out = append(out, p.Name())
+	}
+	return out
+}
+
+func (m *JavaRuntimeManager) vendor(vendor string) (string, error) {
+	vendor = strings.ToLower(strings.TrimSpace(vendor))
+	if vendor == "" {
+		vendor = m.cfg.DefaultVendor
+	}
+	if !reJavaVendor.MatchString(vendor) {
+		return "", fmt.Errorf("invalid java vendor: %q", vendor)
+	}
+	return vendor, nil
+}
+
+func (m *JavaRuntimeManager) ListCached() ([]JavaCacheEntry, error) {
+	if m == nil {
+		return nil, errors.New("java runtime manager is nil")
+	}
+	root := strings.TrimSpace(m.cfg.CacheDir)
+	if root == "" {
+		return nil, errors.New("java cache dir not configured")
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []JavaCacheEntry{}, nil
+		}
+		return nil, err
+	}
+
+	var out []JavaCacheEntry
+	for _, ent := range entries {
+		if ent == nil || !ent.IsDir() || strings.HasPrefix(ent.Name(), ".") {
+			continue
+		}
+		key := ent.Name()
+		javaAbs, info, ok := loadJavaCacheInfo(filepath.Join(root, key))
+		if !ok {
+			continue
+		}
+		vendor := info.Vendor
+		if vendor == "" {
+			// Written before vendors existed: the key starts with the vendor.
+			vendor, _, _ = strings.Cut(key, "-")
+		}
+		out = append(out, JavaCacheEntry{
+			Key:             key,
+			Vendor:          vendor,
+			Major:           info.Major,
+			FullVersion:     info.FullVersion,
+			ImageType:       info.ImageType,
+			Provider:        info.Provider,
+			JavaPath:        javaAbs,
+			SHA256:          info.SHA256,
+			InstalledAtUnix: info.InstalledAtUnix,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Major != out[j].Major {
+			return out[i].Major < out[j].Major
+		}
+		return out[i].Key < out[j].Key
+	})
+
+	return out, nil
+}
+
+// EnsureJRE returns a cached runtime of the vendor ("" for the default vendor) and
+// major version, downloading it through the first provider that carries the vendor.
+// Without a vendor, a cached runtime of any vendor for that major is used before
+// downloading.
+func (m *JavaRuntimeManager) EnsureJRE(ctx context.Context, vendor string, major int) (string, int, error) {
+	if major <= 0 {
+		return "", 0, errors.New("invalid java major")
+	}
+	if strings.TrimSpace(m.cfg.CacheDir) == "" {
+		return "", 0, errors.New("java cache dir not configured")
+	}
+	anyVendor := strings.TrimSpace(vendor) == ""
+	vendor, err := m.vendor(vendor)
+	if err != nil {
+		return "", 0, err
+	}
+	osID, archID, err := adoptiumOSArch()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if javaPath, javaMajor, ok := m.tryLoadCached(vendor, major, osID, archID, anyVendor); ok {
+		return javaPath, javaMajor, nil
+	}
+
+	key := fmt.Sprintf("%s-%d-%s-%s", vendor, major, osID, archID)
+	m.mu.Lock()
+	if st, ok := m.inflight[key]; ok {
+		done := st.done
+		m.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return "", 0, ctx.Err()
+		case <-done:
+			return st.javaPath, st.javaMajor, st.err
+		}
+	}
+	st := &javaEnsureState{done: make(chan struct{})}
+	m.inflight[key] = st
+	m.mu.Unlock()
+
+	javaPath, javaMajor, err := m.installJRE(ctx, vendor, major, osID, archID)
+
+	m.mu.Lock()
+	st.javaPath = javaPath
+	st.javaMajor = javaMajor
+	st.err = err
+	close(st.done)
+	delete(m.inflight, key)
+	m.mu.Unlock()
+
+	return javaPath, javaMajor, err
+}
+
+func (m *JavaRuntimeManager) runtimeDir(vendor, imageType string, major int, osID, archID string) string {
+	return filepath.Join(m.cfg.CacheDir, fmt.Sprintf("%s-%s-%d-%s-%s", vendor, imageType, major, osID, archID))
+}
+
+// tryLoadCached prefers a JRE over a JDK of the same vendor and major. With anyVendor
+// (no vendor was requested) it falls back to a cached runtime of any other vendor for
+// that major, e.g. one added through java_import on a node without network access.
+func (m *JavaRuntimeManager) tryLoadCached(vendor string, major int, osID, archID string, anyVendor bool) (string, int, bool) {
+	for _, imageType := range []string{"jre", "jdk"} {
+		javaAbs, info, ok := loadJavaCacheInfo(m.runtimeDir(vendor, imageType, major, osID, archID))
+		if ok {
+			return javaAbs, info.Major, true
+		}
+	}
+	if !anyVendor {
+		return "", 0, false
+	}
+	entries, err := os.ReadDir(m.cfg.CacheDir)
+	if err != nil {
+		return "", 0, false
+	}
+	for _, imageType := range []string{"jre", "jdk"} {
+		suffix := fmt.Sprintf("-%s-%d-%s-%s", imageType, major, osID, archID)
+		for _, ent := range entries {
+			name := ent.Name()
+			if !ent.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			if javaAbs, info, ok := loadJavaCacheInfo(filepath.Join(m.cfg.CacheDir, name)); ok && info.Major == major {
+				return javaAbs, info.Major, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// loadJavaCacheInfo reads the sidecar of a cached runtime and checks that its java
+// binary exists.
+func loadJavaCacheInfo(dir string) (string, javaCacheInfo, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, javaCacheInfoName))
+	if err != nil {
+		return "", javaCacheInfo{}, false
+	}
+	var info javaCacheInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return "", javaCacheInfo{}, false
+	}
+	if info.Major <= 0 || strings.TrimSpace(info.JavaRel) == "" {
+		return "", javaCacheInfo{}, false
+	}
+	javaAbs := filepath.Join(dir, filepath.FromSlash(info.JavaRel))
+	st, err := os.Stat(javaAbs)
+	if err != nil || st.IsDir() {
+		return "", javaCacheInfo{}, false
+	}
+	return javaAbs, info, true
+}
+
+func (m *JavaRuntimeManager) resolve(ctx context.Context, req JavaRequest) (JavaProvider, JavaRelease, error) {
+	var errs []string
+	for _, p := range m.providers {
+		rel, err := p.Resolve(ctx, req)
+		if errors.Is(err, ErrJavaVendorUnsupported) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		return p, rel, nil
+	}
+	if len(errs) == 0 {
+		return nil, JavaRelease{}, fmt.Errorf("no java provider for vendor %q (providers: %s)", req.Vendor, strings.Join(m.Providers(), ", "))
+	}
+	return nil, JavaRelease{}, fmt.Errorf("resolve %s java %d: %s", req.Vendor, req.Major, strings.Join(errs, "; "))
+}
+
+func (m *JavaRuntimeManager) installJRE(ctx context.Context, vendor string, major int, osID, archID string) (string, int, error) {
+	if err := os.MkdirAll(m.cfg.CacheDir, 0o755); err != nil {
+		return "", 0, err
+	}
+	if javaPath, javaMajor, ok := m.tryLoadCached(vendor, major, osID, archID, false); ok {
+		return javaPath, javaMajor, nil
+	}
+
+	provider, release, err := m.resolve(ctx, JavaRequest{Vendor: vendor, Major: major, OS: osID, Arch: archID})
+	if err != nil {
+		return "", 0, err
+	}
+	if release.SHA256 == "" {
+		return "", 0, fmt.Errorf("%s: no sha256 for %s", provider.Name(), release.URL)
+	}
+	imageType := release.ImageType
+	if imageType != "jdk" {
+		imageType = "jre"
+	}
+	archive := release.Archive
+	if archive == "" {
+		archive = archiveTypeFromName(release.URL)
+	}
+
+	tmpDir, err := os.MkdirTemp(m.cfg.CacheDir, fmt.Sprintf(".%s-%s-%d-", vendor, imageType, major))
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	archivePath := filepath.Join(tmpDir, vendor+"."+archive)
+	if m.cfg.Log != nil {
+		m.cfg.Log.Printf("java: downloading %s %s %d (%s/%s) via %s", vendor, imageType, major, osID, archID, provider.Name())
+	}
+	if _, err := download.DownloadFileVerified(ctx, release.URL, archivePath, download.Expected{SHA256: release.SHA256}, nil); err != nil {
+		return "", 0, err
+	}
+
+	unpackDir := filepath.Join(tmpDir, "runtime")
+	info, err := unpackJavaRuntime(ctx, archivePath, archive, unpackDir)
+	if err != nil {
+		return "", 0, err
+	}
+	if info.Major != major {
+		return "", 0, fmt.Errorf("downloaded java major mismatch: want=%d got=%d", major, info.Major)
+	}
+	if info.FullVersion == "" {
+		info.FullVersion = release.Version
+	}
+	info.Vendor = vendor
+	info.ImageType = imageType
+	info.Provider = provider.Name()
+	info.SourceURL = release.URL
+	info.SHA256 = release.SHA256
+	if err := writeJSONFileAtomic(filepath.Join(unpackDir, javaCacheInfoName), info); err != nil {
+		return "", 0, err
+	}
+
+	// Replace existing install (if any) after we have a valid runtime.
+	dir := m.runtimeDir(vendor, imageType, major, osID, archID)
+	_ = os.RemoveAll(dir)
+	if err := os.Rename(unpackDir, dir); err != nil {
+		return "", 0, err
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(info.JavaRel)), info.Major, nil
+}
+
+// unpackJavaRuntime extracts a runtime archive ("tar.gz" or "zip") into dir and probes
+// its java binary. The returned info has JavaRel, Major, FullVersion and InstalledAtUnix set.
+func unpackJavaRuntime(ctx context.Context, archivePath, archive, dir string) (javaCacheInfo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return javaCacheInfo{}, err
+	}
+	var topDir string
+	var err error
+	switch archive {
+	case "zip":
+		topDir, err = extractZip(archivePath, dir)
+	case "tar.gz":
+		topDir, err = extractTarGz(archivePath, dir)
+	default:
+		return javaCacheInfo{}, fmt.Errorf("unsupported java archive type: %q", archive)
+	}
+	if err != nil {
+		return javaCacheInfo{}, err
+	}
+
+	javaRel, err := discoverJavaRel(dir, topDir)
+	if err != nil {
+		return javaCacheInfo{}, err
+	}
+	major, full, err := probeJavaVersion(ctx, filepath.Join(dir, filepath.FromSlash(javaRel)))
+	if err != nil {
+		return javaCacheInfo{}, err
+	}
+	return javaCacheInfo{
+		JavaRel:         filepath.ToSlash(javaRel),
+		Major:           major,
+		FullVersion:     full,
+		InstalledAtUnix: time.Now().Unix(),
+	}, nil
+}
+
+// archiveTypeFromName guesses "zip" or "tar.gz" from a file name or URL.
+func archiveTypeFromName(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	if strings.HasSuffix(name, ".zip") {
+		return "zip"
+	}
+	if strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") {
+		return "tar.gz"
+	}
+	if runtime.GOOS == "windows" {
+		return "zip"
+	}
+	return "tar.gz"
+}
+
+func writeJSONFileAtomic(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// adoptiumOSArch returns the platform in Adoptium's naming (linux/windows/mac,
+// x64/aarch64/x86), which is also used for cache keys and java manifests.
+func adoptiumOSArch() (string, string, error) {
+	var osID string
+	switch runtime.GOOS {
+	case "linux":
+		osID = "linux"
+	case "windows":
+		osID = "windows"
+	case "darwin":
+		osID = "mac"
+	default:
+		return "", "", fmt.Errorf("unsupported os: %s", runtime.GOOS)
+	}
+
+	var archID string
+	switch runtime.GOARCH {
+	case "amd64":
+		archID = "x64"
+	case "arm64":
+		archID = "aarch64"
+	case "386":
+		archID = "x86"
+	default:
+		return "", "", fmt.Errorf("unsupported arch: %s", runtime.GOARCH)
+	}
+	return osID, archID, nil
+}
+
+func discoverJavaRel(rootDir string, topDir string) (string, error) {
+	topDir = strings.TrimSpace(topDir)
+	if topDir == "" {
+		return "", errors.New("cannot determine java root dir")
+	}
+	candidates := []string{
+		path.Join(topDir, "bin", "java"),
+		path.Join(topDir, "Contents", "Home", "bin", "java"),
+		path.Join(topDir, "bin", "java.exe"),
+		path.Join(topDir, "Contents", "Home", "bin", "java.exe"),
+	}
+	for _, rel := range candidates {
+		abs := filepath.Join(rootDir, filepath.FromSlash(rel))
+		st, err := os.Stat(abs)
+		if err == nil && !st.IsDir() {
+			return rel, nil
+		}
+	}
+	return "", errors.New("java binary not found in extracted runtime")
+}
+
+func extractTarGz(archivePath string, destDir string) (string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	destAbs, err := filepath.Abs(destDir)
+	if err != nil {
+		return "", err
+	}
+	topDir := ""
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		name = strings.TrimPrefix(name, "/")
+		if name == "" {
+			continue
+		}
+		clean := path.Clean(name)
+		if clean == "." || clean == "/" {
+			continue
+		}
+		if strings.HasPrefix(clean, "../") || clean == ".." || strings.HasPrefix(clean, "/") {
+			return "", errors.New("tar entry escapes destination")
+		}
+		if topDir == "" {
+			topDir = strings.SplitN(clean, "/", 2)[0]
+		}
+
+		outAbs := filepath.Join(destAbs, filepath.FromSlash(clean))
+		if !isWithinDir(destAbs, outAbs) {
+			return "", errors.New("tar entry escapes destination")
+		}
+
+		mode := os.FileMode(hdr.Mode) & 0o777
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(outAbs, mode|0o700); err != nil {
+				return "", err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(outAbs), 0o755); err != nil {
+				return "", err
+			}
+			dst, err := os.OpenFile(outAbs, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return "", err
+			}
+			if _, err := io.Copy(dst, tr); err != nil {
+				_ = dst.Close()
+				return "", err
+			}
+			if err := dst.Close(); err != nil {
+				return "", err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(outAbs), 0o755); err != nil {
+				return "", err
+			}
+			link := hdr.Linkname
+			if strings.HasPrefix(link, "/") {
+				return "", errors.New("tar symlink is absolute")
+			}
+			linkClean := filepath.Clean(filepath.FromSlash(link))
+			targetAbs := filepath.Clean(filepath.Join(filepath.Dir(outAbs), linkClean))
+			if !isWithinDir(destAbs, targetAbs) {
+				return "", errors.New("tar symlink escapes destination")
+			}
+			_ = os.RemoveAll(outAbs)
+			if err := os.Symlink(link, outAbs); err != nil {
+				return "", err
+			}
+		default:
+			// ignore other entry types
+		}
+	}
+
+	if topDir == "" {
+		return "", errors.New("empty archive")
+	}
+	return topDir, nil
+}
+
+func extractZip(archivePath string, destDir string) (string, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	destAbs, err := filepath.Abs(destDir)
+	if err != nil {
+		return "", err
+	}
+	topDir := ""
+
+	for _, f := range zr.File {
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		name = strings.TrimPrefix(name, "/")
+		if name == "" {
+			continue
+		}
+		clean := path.Clean(name)
+		if clean == "." || clean == "/" {
+			continue
+		}
+		if strings.HasPrefix(clean, "../") || clean == ".." || strings.HasPrefix(clean, "/") {
+			return "", errors.New("zip entry escapes destination")
+		}
+		if topDir == "" {
+			topDir = strings.SplitN(clean, "/", 2)[0]
+		}
+
+		outAbs := filepath.Join(destAbs, filepath.FromSlash(clean))
+		if !isWithinDir(destAbs, outAbs) {
+			return "", errors.New("zip entry escapes destination")
+		}
+
+		if f.FileInfo().IsDir() || strings.HasSuffix(clean, "/") {
+			if err := os.MkdirAll(outAbs, 0o755); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(outAbs), 0o755); err != nil {
+			return "", err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		dst, err := os.OpenFile(outAbs, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			_ = rc.Close()
+			return "", err
+		}
+		_, copyErr := io.Copy(dst, rc)
+		_ = dst.Close()
+		_ = rc.Close()
+		if copyErr != nil {
+			return "", copyErr
+		}
+	}
+
+	if topDir == "" {
+		return "", errors.New("empty archive")
+	}
+	return topDir, nil
+}
+
+func isWithinDir(rootAbs string, childAbs string) bool {
+	rootAbs = filepath.Clean(rootAbs)
+	childAbs = filepath.Clean(childAbs)
+	if rootAbs == childAbs {
+		return true
+	}
+	if !strings.HasSuffix(rootAbs, string(os.PathSeparator)) {
+		rootAbs += string(os.PathSeparator)
+	}
+	return strings.HasPrefix(childAbs, rootAbs)
+}
@@ -0,0 +1,63 @@
+package mc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// Runtimes downloaded before vendors existed live in "temurin-jre-<major>-<os>-<arch>"
+// with a sidecar that has no vendor; they must keep being used without a download.
+func TestJavaRuntimeManager_LegacyTemurinCache(t *testing.T) {
+	osID, archID, err := adoptiumOSArch()
+	if err != nil {
+		t.Skip(err)
+	}
+	var apiHits atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiHits.Add(1)
+		http.Error(w, "offline", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(api.Close)
+
+	cacheDir := t.TempDir()
+	key := "temurin-jre-17-" + osID + "-" + archID
+	java := filepath.Join(cacheDir, key, "jdk-17.0.10+7-jre", "bin", "java")
+	if err := os.MkdirAll(filepath.Dir(java), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(java, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write java: %v", err)
+	}
+	sidecar := `{"java_rel":"jdk-17.0.10+7-jre/bin/java","major":17,"sha256":"abc","installed_at_unix":1700000000}`
+	if err := os.WriteFile(filepath.Join(cacheDir, key, javaCacheInfoName), []byte(sidecar), 0o644); err != nil {
+		t.Fatalf("write sidecar: %v", err)
+	}
+
+	rt := NewJavaRuntimeManager(JavaRuntimeManagerConfig{CacheDir: cacheDir, AdoptiumAPIBaseURL: api.URL, ZuluAPIBaseURL: api.URL})
+	for _, vendor := range []string{"", "temurin"} {
+		got, major, err := rt.EnsureJRE(context.Background(), vendor, 17)
+		if err != nil || got != java || major != 17 {
+			t.Fatalf("EnsureJRE(%q) = %s, %d, %v; want the legacy cache entry", vendor, got, major, err)
+		}
+	}
+	if n := apiHits.Load(); n != 0 {
+		t.Fatalf("legacy cache entry should not trigger a download, api hits=%d", n)
+	}
+	// Another vendor is not satisfied by the temurin runtime.
+	if _, _, err := rt.EnsureJRE(context.Background(), "zulu", 17); err == nil {
+		t.Fatalf("expected EnsureJRE(zulu) to need a download")
+	}
+
+	entries, err := rt.ListCached()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListCached() = %+v, %v", entries, err)
+	}
+	if e := entries[0]; e.Key != key || e.Vendor != "temurin" || e.Major != 17 || e.JavaPath != java {
+		t.Fatalf("unexpected cache entry: %+v", e)
+	}
+}
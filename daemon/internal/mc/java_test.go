@@ -0,0 +1,154 @@
+package mc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPickJava(t *testing.T) {
+	_, hostArch, err := adoptiumOSArch()
+	if err != nil {
+		t.Skipf("unsupported host: %v", err)
+	}
+	otherArch := "x64"
+	if hostArch == "x64" {
+		otherArch = "aarch64"
+	}
+
+	cases := []struct {
+		name          string
+		list          []JavaInstallation
+		required, max int
+		want          string
+	}{
+		{
+			name: "configured candidate beats a smaller discovered runtime",
+			list: []JavaInstallation{
+				{Path: "/opt/java21/bin/java", Source: "candidate", Major: 21},
+				{Path: "/usr/lib/jvm/java-17/bin/java", Source: "jvm", Major: 17},
+			},
+			required: 17,
+			want:     "/opt/java21/bin/java",
+		},
+		{
+			name: "smallest satisfying candidate",
+			list: []JavaInstallation{
+				{Path: "/opt/java21/bin/java", Source: "candidate", Major: 21},
+				{Path: "/opt/java17/bin/java", Source: "candidate", Major: 17},
+				{Path: "/opt/java8/bin/java", Source: "candidate", Major: 8},
+			},
+			required: 11,
+			want:     "/opt/java17/bin/java",
+		},
+		{
+			name: "discovered runtimes are the fallback",
+			list: []JavaInstallation{
+				{Path: "java", Source: "candidate", Major: 8},
+				{Path: "/usr/lib/jvm/java-21/bin/java", Source: "jvm", Major: 21},
+				{Path: "/usr/lib/jvm/java-17/bin/java", Source: "jvm", Major: 17},
+			},
+			required: 17,
+			want:     "/usr/lib/jvm/java-17/bin/java",
+		},
+		{
+			name: "tie keeps the earlier entry",
+			list: []JavaInstallation{
+				{Path: "/usr/lib/jvm/a/bin/java", Source: "jvm", Major: 17},
+				{Path: "/usr/lib/jvm/b/bin/java", Source: "jvm", Major: 17},
+			},
+			required: 17,
+			want:     "/usr/lib/jvm/a/bin/java",
+		},
+		{
+			name: "foreign architecture and broken entries are skipped",
+			list: []JavaInstallation{
+				{Path: "/opt/foreign/bin/java", Source: "candidate", Major: 17, Arch: otherArch},
+				{Path: "/opt/broken/bin/java", Source: "candidate", Major: 17, Error: "exec format error"},
+				{Path: "/opt/native/bin/java", Source: "opt", Major: 21, Arch: hostArch},
+			},
+			required: 17,
+			want:     "/opt/native/bin/java",
+		},
+		{
+			name: "max major excludes newer candidates",
+			list: []JavaInstallation{
+				{Path: "/opt/java17/bin/java", Source: "candidate", Major: 17},
+				{Path: "/usr/lib/jvm/java-8/bin/java", Source: "jvm", Major: 8},
+			},
+			required: 8,
+			max:      8,
+			want:     "/usr/lib/jvm/java-8/bin/java",
+		},
+		{
+			name: "nothing fits",
+			list: []JavaInstallation{
+				{Path: "/opt/java17/bin/java", Source: "candidate", Major: 17},
+				{Path: "/opt/foreign/bin/java", Source: "opt", Major: 21, Arch: otherArch},
+			},
+			required: 21,
+		},
+	}
+	for _, tc := range cases {
+		path, major, ok := pickJava(tc.list, tc.required, tc.max)
+		if path != tc.want || ok != (tc.want != "") {
+			t.Errorf("%s: pickJava() = %q (major %d, ok=%v), want %q", tc.name, path, major, ok, tc.want)
+		}
+	}
+}
+
+// writeFakeJava writes a java whose "-version" reports version after sleepSec seconds.
+func writeFakeJava(t *testing.T, path, version string, sleepSec int) {
+	t.Helper()
+	script := fmt.Sprintf("#!/bin/sh\nsleep %d\necho 'openjdk version \"%s\"' >&2\n", sleepSec, version)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake java: %v", err)
+	}
+}
+
+func TestJavaInventory_CachedListDoesNotWaitForScan(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake java is a shell script")
+	}
+	java := filepath.Join(t.TempDir(), "java")
+	writeFakeJava(t, java, "17.0.9", 0)
+	inv := newJavaInventory([]string{java}, false, "")
+	ctx := context.Background()
+
+	list, _ := inv.List(ctx, false)
+	if len(list) != 1 || list[0].Major != 17 {
+		t.Fatalf("initial scan: %+v", list)
+	}
+
+	// The binary changed, so a refresh re-probes it (slowly).
+	writeFakeJava(t, java, "21.0.2", 2)
+	refreshed := make(chan []JavaInstallation)
+	go func() {
+		list, _ := inv.List(ctx, true)
+		refreshed <- list
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	list, _ = inv.List(ctx, false)
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("cached List waited %s for the running scan", waited)
+	}
+	if len(list) != 1 || list[0].Major != 17 {
+		t.Fatalf("cached list: %+v", list)
+	}
+	if list := <-refreshed; len(list) != 1 || list[0].Major != 21 {
+		t.Fatalf("refreshed list: %+v", list)
+	}
+
+	// After invalidate the next List rescans even though the cache is recent.
+	inv.invalidate()
+	writeFakeJava(t, java, "22.0.1", 0)
+	if list, _ := inv.List(ctx, false); len(list) != 1 || list[0].Major != 22 {
+		t.Fatalf("list after invalidate: %+v", list)
+	}
+}
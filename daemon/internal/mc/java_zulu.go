@@ -0,0 +1,104 @@
+package mc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// zuluProvider fetches Azul Zulu JREs through the Azul metadata API.
+type zuluProvider struct {
+	apiBaseURL string
+}
+
+type zuluPackage struct {
+	PackageUUID   string `json:"package_uuid"`
+	Name          string `json:"name"`
+	DownloadURL   string `json:"download_url"`
+	JavaVersion   []int  `json:"java_version"`
+	DistroVersion []int  `json:"distro_version"`
+	SHA256Hash    string `json:"sha256_hash"`
+}
+
+func (p *zuluProvider) Name() string { return "zulu" }
+
+func (p *zuluProvider) Resolve(ctx context.Context, req JavaRequest) (JavaRelease, error) {
+	if req.Vendor != "zulu" {
+		return JavaRelease{}, ErrJavaVendorUnsupported
+	}
+	osID := req.OS
+	if osID == "mac" {
+		osID = "macos"
+	}
+	arch := req.Arch
+	if arch == "x86" {
+		arch = "i686"
+	}
+	archive := "tar.gz"
+	if runtime.GOOS == "windows" {
+		archive = "zip"
+	}
+	q := url.Values{}
+	q.Set("java_version", strconv.Itoa(req.Major))
+	q.Set("os", osID)
+	q.Set("arch", arch)
+	q.Set("archive_type", archive)
+	q.Set("java_package_type", "jre")
+	q.Set("javafx_bundled", "false")
+	q.Set("crac_supported", "false")
+	q.Set("release_status", "ga")
+	q.Set("availability_types", "CA")
+	q.Set("latest", "true")
+	q.Set("page_size", "20")
+	base := strings.TrimRight(p.apiBaseURL, "/") + "/metadata/v1/zulu/packages/"
+
+	var pkgs []zuluPackage
+	if err := getJavaJSON(ctx, base+"?"+q.Encode(), &pkgs); err != nil {
+		return JavaRelease{}, err
+	}
+	var pkg *zuluPackage
+	for i := range pkgs {
+		// "linux" also matches musl builds, which do not run on glibc hosts.
+		if strings.Contains(pkgs[i].Name, "musl") || pkgs[i].DownloadURL == "" {
+			continue
+		}
+		if len(pkgs[i].JavaVersion) > 0 && pkgs[i].JavaVersion[0] != req.Major {
+			continue
+		}
+		pkg = &pkgs[i]
+		break
+	}
+	if pkg == nil {
+		return JavaRelease{}, fmt.Errorf("no zulu jre %d for %s/%s", req.Major, osID, arch)
+	}
+	if pkg.SHA256Hash == "" {
+		// The list endpoint omits checksums; the package details have them.
+		if pkg.PackageUUID == "" {
+			return JavaRelease{}, errors.New("zulu package has no uuid")
+		}
+		var detail zuluPackage
+		if err := getJavaJSON(ctx, base+url.PathEscape(pkg.PackageUUID), &detail); err != nil {
+			return JavaRelease{}, err
+		}
+		pkg.SHA256Hash = detail.SHA256Hash
+	}
+	return JavaRelease{
+		Version:   joinVersion(pkg.JavaVersion),
+		ImageType: "jre",
+		URL:       pkg.DownloadURL,
+		SHA256:    strings.ToLower(strings.TrimSpace(pkg.SHA256Hash)),
+		Archive:   archive,
+	}, nil
+}
+
+func joinVersion(parts []int) string {
+	s := make([]string, len(parts))
+	for i, n := range parts {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ".")
+}
@@ -0,0 +1,100 @@
+package mc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// newZuluStub serves the Azul metadata API. The package list carries no checksums, like
+// the real list endpoint; the package details do.
+func newZuluStub(t *testing.T, archive []byte) *httptest.Server {
+	t.Helper()
+	sum := sha256.Sum256(archive)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/v1/zulu/packages/":
+			q := r.URL.Query()
+			if q.Get("java_version") != "21" || q.Get("java_package_type") != "jre" || q.Get("os") != "linux" || q.Get("release_status") != "ga" {
+				http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"package_uuid": "musl", "name": "zulu21-ca-jre21.0.2-linux_musl_x64.tar.gz", "download_url": srv.URL + "/musl.tar.gz", "java_version": []int{21, 0, 2}},
+				{"package_uuid": "old", "name": "zulu17-ca-jre17-linux_x64.tar.gz", "download_url": srv.URL + "/old.tar.gz", "java_version": []int{17, 0, 10}},
+				{"package_uuid": "good", "name": "zulu21-ca-jre21.0.2-linux_x64.tar.gz", "download_url": srv.URL + "/zulu21.tar.gz", "java_version": []int{21, 0, 2}},
+			})
+		case "/metadata/v1/zulu/packages/good":
+			_ = json.NewEncoder(w).Encode(map[string]any{"package_uuid": "good", "sha256_hash": hex.EncodeToString(sum[:])})
+		case "/zulu21.tar.gz":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestZuluProvider_Resolve(t *testing.T) {
+	archive := []byte("zulu archive")
+	srv := newZuluStub(t, archive)
+	p := &zuluProvider{apiBaseURL: srv.URL}
+	ctx := context.Background()
+
+	rel, err := p.Resolve(ctx, JavaRequest{Vendor: "zulu", Major: 21, OS: "linux", Arch: "x64"})
+	if err != nil {
+		t.Fatalf("Resolve(): %v", err)
+	}
+	sum := sha256.Sum256(archive)
+	if rel.URL != srv.URL+"/zulu21.tar.gz" || rel.SHA256 != hex.EncodeToString(sum[:]) || rel.Version != "21.0.2" || rel.ImageType != "jre" {
+		t.Fatalf("unexpected release: %+v", rel)
+	}
+
+	if _, err := p.Resolve(ctx, JavaRequest{Vendor: "temurin", Major: 21, OS: "linux", Arch: "x64"}); !errors.Is(err, ErrJavaVendorUnsupported) {
+		t.Fatalf("expected ErrJavaVendorUnsupported for temurin, got %v", err)
+	}
+	if _, err := p.Resolve(ctx, JavaRequest{Vendor: "zulu", Major: 17, OS: "linux", Arch: "x64"}); err == nil {
+		t.Fatalf("expected an error for an unexpected query")
+	}
+}
+
+func TestJavaRuntimeManager_EnsureZulu(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fake runtime is a linux tar.gz with a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "zulu21.tar.gz")
+	writeFakeJavaArchive(t, path, "Azul Systems, Inc.")
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	srv := newZuluStub(t, archive)
+	cacheDir := t.TempDir()
+	rt := NewJavaRuntimeManager(JavaRuntimeManagerConfig{CacheDir: cacheDir, ZuluAPIBaseURL: srv.URL, AdoptiumAPIBaseURL: srv.URL})
+	_, archID, err := adoptiumOSArch()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	java, major, err := rt.EnsureJRE(context.Background(), "zulu", 21)
+	if err != nil {
+		t.Fatalf("EnsureJRE(zulu): %v", err)
+	}
+	if major != 21 || filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(java)))) != "zulu-jre-21-linux-"+archID {
+		t.Fatalf("EnsureJRE(zulu) = %s, %d", java, major)
+	}
+	entries, err := rt.ListCached()
+	if err != nil || len(entries) != 1 || entries[0].Vendor != "zulu" || entries[0].Provider != "zulu" || entries[0].SHA256 == "" {
+		t.Fatalf("ListCached() = %+v, %v", entries, err)
+	}
+}
@@ -0,0 +1,195 @@
+package mc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"elegantmc/daemon/internal/sysinfo"
+)
+
+const (
+	autoHeapMin   = 512 << 20
+	autoHeapMax   = 16 << 30
+	autoHeapAlign = 256 << 20
+)
+
+// reserveHeap records the max heap of a running server for later xmx=auto starts.
+func (m *Manager) reserveHeap(instanceID string, bytes uint64) {
+	m.heapResMu.Lock()
+	defer m.heapResMu.Unlock()
+	m.heapRes[instanceID] = bytes
+}
+
+func (m *Manager) releaseHeap(instanceID string) {
+	m.heapResMu.Lock()
+	defer m.heapResMu.Unlock()
+	delete(m.heapRes, instanceID)
+}
+
+// otherHeapReservationsLocked sums what the other running servers may use: their max
+// heap plus the JVM's off-heap share (see jvmFootprint). m.heapResMu must be held.
+func (m *Manager) otherHeapReservationsLocked(instanceID string) (uint64, int) {
+	var total uint64
+	n := 0
+	for id, b := range m.heapRes {
+		if id == instanceID {
+			continue
+		}
+		total += jvmFootprint(b)
+		n++
+	}
+	return total, n
+}
+
+// jvmFootprint estimates the resident size of a JVM with the given max heap
+// (metaspace, code cache, threads and GC structures add roughly a quarter).
+func jvmFootprint(heap uint64) uint64 {
+	return heap + heap/4
+}
+
+// heapFromArgs returns the last -Xmx of a command line, or the JVM default (a quarter of
+// the host memory) when there is none.
+func heapFromArgs(args []string, hostTotal uint64) uint64 {
+	var heap uint64
+	for _, a := range args {
+		if strings.HasPrefix(a, "-Xmx") {
+			if n, err := parseByteSize(strings.TrimPrefix(a, "-Xmx")); err == nil {
+				heap = n
+			}
+		}
+	}
+	if heap == 0 {
+		heap = hostTotal / 4
+	}
+	return heap
+}
+
+// autoHeapSize picks -Xmx for xmx=auto: host memory minus headroom for the OS and the
+// daemon, minus the other servers' reservations, leaving room for this JVM's off-heap
+// memory. memoryMax (resource_limits, 0: none) caps it the same way.
+func autoHeapSize(hostTotal, reservedOthers, memoryMax uint64) (uint64, error) {
+	headroom := hostTotal / 8
+	if headroom < 1<<30 {
+		headroom = 1 << 30
+	}
+	var avail uint64
+	if hostTotal > headroom+reservedOthers {
+		avail = hostTotal - headroom - reservedOthers
+	}
+	heap := avail * 4 / 5
+	if memoryMax > 0 && heap > memoryMax*4/5 {
+		heap = memoryMax * 4 / 5
+	}
+	if heap > autoHeapMax {
+		heap = autoHeapMax
+	}
+	heap -= heap % autoHeapAlign
+	if heap < autoHeapMin {
+		return 0, fmt.Errorf("xmx=auto: not enough memory (host %s, %s reserved by other instances, need at least %s heap)",
+			formatHeapSize(hostTotal), formatHeapSize(reservedOthers), formatHeapSize(autoHeapMin))
+	}
+	return heap, nil
+}
+
+// formatHeapSize renders bytes in the -Xmx style (whole G when possible, else M).
+func formatHeapSize(b uint64) string {
+	if b >= 1<<30 && b%(1<<30) == 0 {
+		return fmt.Sprintf("%dG", b>>30)
+	}
+	return fmt.Sprintf("%dM", b>>20)
+}
+
+func isAutoMemory(v string) bool {
+	return strings.EqualFold(strings.TrimSpace(v), "auto")
+}
+
+// resolveMemory expands xms/xmx "auto". Xmx comes from autoHeapSize; Xms equals the heap
+// for profiles that pre-touch it and is a quarter of it otherwise. The note describes
+// the computation for the start log ("" when nothing was automatic).
+func (m *Manager) resolveMemory(instanceID, xms, xmx, profile string, limits ResourceLimits) (string, string, string, error) {
+	if !isAutoMemory(xmx) {
+		return m.resolveMemoryFor(0, instanceID, xms, xmx, profile, limits)
+	}
+	mem, err := sysinfo.ReadMemStats()
+	if err != nil {
+		return "", "", "", fmt.Errorf("xmx=auto: %w", err)
+	}
+	if mem.TotalBytes == 0 {
+		return "", "", "", errors.New("xmx=auto: host memory is unknown on this platform")
+	}
+	return m.resolveMemoryFor(mem.TotalBytes, instanceID, xms, xmx, profile, limits)
+}
+
+// resolveMemoryFor is resolveMemory for a host with hostTotal bytes of memory. An
+// automatic heap is reserved for the instance before heapResMu is released, so
+// concurrent xmx=auto starts do not both get the full budget; Start drops the
+// reservation if the server does not come up.
+func (m *Manager) resolveMemoryFor(hostTotal uint64, instanceID, xms, xmx, profile string, limits ResourceLimits) (string, string, string, error) {
+	xms, xmx = strings.TrimSpace(xms), strings.TrimSpace(xmx)
+	if !isAutoMemory(xms) && !isAutoMemory(xmx) {
+		return xms, xmx, "", nil
+	}
+
+	var heap uint64
+	note := ""
+	if isAutoMemory(xmx) {
+		memoryMax, err := limits.memoryMaxBytes()
+		if err != nil {
+			return "", "", "", err
+		}
+		m.heapResMu.Lock()
+		reserved, others := m.otherHeapReservationsLocked(instanceID)
+		heap, err = autoHeapSize(hostTotal, reserved, memoryMax)
+		if err == nil {
+			m.heapRes[instanceID] = heap
+		}
+		m.heapResMu.Unlock()
+		if err != nil {
+			return "", "", "", err
+		}
+		xmx = formatHeapSize(heap)
+		note = fmt.Sprintf("host %s, %d other instance(s) reserve %s", formatHeapSize(hostTotal), others, formatHeapSize(reserved))
+		if memoryMax > 0 {
+			note += ", memory_max " + formatHeapSize(memoryMax)
+		}
+	} else if xmx != "" {
+		n, err := parseByteSize(xmx)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid xmx: %q", xmx)
+		}
+		heap = n
+	}
+
+	switch {
+	case isAutoMemory(xms) && heap == 0:
+		// No heap size to derive from: let the JVM pick its initial heap.
+		xms = ""
+	case isAutoMemory(xms) && jvmProfiles[profile].fullXms:
+		xms = formatHeapSize(heap)
+	case isAutoMemory(xms):
+		initial := heap / 4
+		initial -= initial % autoHeapAlign
+		if initial < autoHeapAlign {
+			initial = autoHeapAlign
+		}
+		if initial > heap {
+			initial = heap
+		}
+		xms = formatHeapSize(initial)
+	case xms != "":
+		if n, err := parseByteSize(xms); err == nil && n > heap {
+			xms = xmx
+		}
+	}
+	if note == "" {
+		note = "xms derived from xmx"
+	}
+	return xms, xmx, note, nil
+}
+
+// hostMemTotal is the physical memory of the host (0 when unknown).
+func hostMemTotal() uint64 {
+	mem, _ := sysinfo.ReadMemStats()
+	return mem.TotalBytes
+}
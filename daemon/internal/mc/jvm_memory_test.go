@@ -0,0 +1,136 @@
+package mc
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testMiB = uint64(1) << 20
+	testGiB = uint64(1) << 30
+)
+
+func TestAutoHeapSize(t *testing.T) {
+	cases := []struct {
+		name      string
+		host      uint64
+		others    uint64
+		memoryMax uint64
+		want      uint64 // 0: error
+	}{
+		// 1G headroom, 4/5 of the rest, rounded down to 256M.
+		{"small host keeps 1G headroom", 8 * testGiB, 0, 0, 5632 * testMiB},
+		{"headroom is an eighth above 8G", 32 * testGiB, 0, 0, 16 * testGiB},
+		{"other instances are subtracted", 16 * testGiB, 4 * testGiB, 0, 8 * testGiB},
+		{"memory_max caps the heap", 16 * testGiB, 0, 4 * testGiB, 3 * testGiB},
+		{"memory_max above the budget is no cap", 16 * testGiB, 0, 64 * testGiB, 11 * testGiB},
+		{"heap is capped at 16G", 128 * testGiB, 0, 0, 16 * testGiB},
+		{"others leave less than the floor", 4 * testGiB, 2560 * testMiB, 0, 0},
+		{"2G host", 2 * testGiB, 0, 0, 768 * testMiB},
+		{"below the 512M floor", 1536 * testMiB, 0, 0, 0},
+		{"others use everything", 8 * testGiB, 8 * testGiB, 0, 0},
+		{"memory_max below the floor", 16 * testGiB, 0, 512 * testMiB, 0},
+	}
+	for _, tc := range cases {
+		got, err := autoHeapSize(tc.host, tc.others, tc.memoryMax)
+		if tc.want == 0 {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tc.name, formatHeapSize(got))
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: autoHeapSize() = %s, %v; want %s", tc.name, formatHeapSize(got), err, formatHeapSize(tc.want))
+		}
+	}
+}
+
+func TestResolveMemory(t *testing.T) {
+	cases := []struct {
+		name              string
+		xms, xmx, profile string
+		limits            ResourceLimits
+		wantXms, wantXmx  string
+		wantNote          bool
+	}{
+		{"fixed sizes untouched", "1G", "4G", "", ResourceLimits{}, "1G", "4G", false},
+		{"auto xmx", "", "auto", "", ResourceLimits{}, "", "16G", true},
+		{"auto xms is a quarter", "auto", "auto", "", ResourceLimits{}, "4G", "16G", true},
+		{"auto xms for a pre-touch profile", "AUTO", "auto", "aikar", ResourceLimits{}, "16G", "16G", true},
+		{"auto xms from a fixed xmx", "auto", "2G", "", ResourceLimits{}, "512M", "2G", true},
+		{"auto xms floor", "auto", "512M", "", ResourceLimits{}, "256M", "512M", true},
+		{"auto xms without xmx", "auto", "", "", ResourceLimits{}, "", "", true},
+		{"xms above the auto heap", "32G", "auto", "", ResourceLimits{}, "16G", "16G", true},
+		{"memory_max", "auto", "auto", "zgc", ResourceLimits{MemoryMax: "6G"}, "4864M", "4864M", true},
+	}
+	for _, tc := range cases {
+		m := NewManager(ManagerConfig{})
+		xms, xmx, note, err := m.resolveMemoryFor(32*testGiB, "srv1", tc.xms, tc.xmx, tc.profile, tc.limits)
+		if err != nil || xms != tc.wantXms || xmx != tc.wantXmx || (note != "") != tc.wantNote {
+			t.Errorf("%s: got xms=%q xmx=%q note=%q err=%v; want xms=%q xmx=%q", tc.name, xms, xmx, note, err, tc.wantXms, tc.wantXmx)
+		}
+	}
+
+	m := NewManager(ManagerConfig{})
+	if _, _, _, err := m.resolveMemoryFor(32*testGiB, "srv1", "", "auto", "", ResourceLimits{MemoryMax: "1k"}); err == nil {
+		t.Fatalf("expected invalid memory_max to be rejected")
+	}
+	if _, _, _, err := m.resolveMemoryFor(32*testGiB, "srv1", "", "lots", "", ResourceLimits{}); err != nil {
+		t.Fatalf("a non-auto xmx is passed through unparsed: %v", err)
+	}
+	if _, _, _, err := m.resolveMemoryFor(32*testGiB, "srv1", "auto", "lots", "", ResourceLimits{}); err == nil {
+		t.Fatalf("expected invalid xmx to be rejected when deriving xms")
+	}
+}
+
+func TestResolveMemory_ReservesAutoHeap(t *testing.T) {
+	m := NewManager(ManagerConfig{})
+	host := 32 * testGiB
+
+	// The first auto start reserves its heap before Start returns, so a concurrent
+	// second start only gets what is left.
+	_, xmxA, _, err := m.resolveMemoryFor(host, "a", "", "auto", "", ResourceLimits{})
+	if err != nil || xmxA != "16G" {
+		t.Fatalf("first start: xmx=%s err=%v", xmxA, err)
+	}
+	_, xmxB, note, err := m.resolveMemoryFor(host, "b", "", "auto", "", ResourceLimits{})
+	if err != nil || xmxB != "6400M" {
+		t.Fatalf("second start: xmx=%s err=%v", xmxB, err)
+	}
+	if !strings.Contains(note, "1 other instance(s) reserve 20G") {
+		t.Fatalf("unexpected note: %q", note)
+	}
+	// A third start finds no room left.
+	if _, _, _, err := m.resolveMemoryFor(host, "c", "", "auto", "", ResourceLimits{}); err == nil {
+		t.Fatalf("expected third start to fail")
+	}
+
+	// Re-resolving an instance does not count its own reservation.
+	if _, xmx, _, err := m.resolveMemoryFor(host, "a", "", "auto", "", ResourceLimits{}); err != nil || xmx != "16G" {
+		t.Fatalf("re-resolve: xmx=%s err=%v", xmx, err)
+	}
+
+	// A failed start releases its reservation.
+	m.releaseHeap("a")
+	if _, xmx, _, err := m.resolveMemoryFor(host, "b", "", "auto", "", ResourceLimits{}); err != nil || xmx != "16G" {
+		t.Fatalf("after release: xmx=%s err=%v", xmx, err)
+	}
+
+	// Fixed sizes are recorded at start (reserveHeap), not while resolving.
+	m.releaseHeap("b")
+	if _, _, _, err := m.resolveMemoryFor(host, "d", "auto", "4G", "", ResourceLimits{}); err != nil {
+		t.Fatalf("fixed xmx: %v", err)
+	}
+	if len(m.heapRes) != 0 {
+		t.Fatalf("fixed xmx should not reserve while resolving: %v", m.heapRes)
+	}
+}
+
+func TestHeapFromArgs(t *testing.T) {
+	if got := heapFromArgs([]string{"-Xmx2G", "-Xms1G", "-Xmx3G", "-jar", "server.jar"}, 16*testGiB); got != 3*testGiB {
+		t.Fatalf("last -Xmx should win, got %s", formatHeapSize(got))
+	}
+	if got := heapFromArgs([]string{"-jar", "server.jar"}, 16*testGiB); got != 4*testGiB {
+		t.Fatalf("JVM default is a quarter of the host, got %s", formatHeapSize(got))
+	}
+}
@@ -0,0 +1,256 @@
+package mc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jvmFlag is one flag of a JVM profile, limited to the Java majors that understand it.
+type jvmFlag struct {
+	arg      string
+	minMajor int // 0: any
+	maxMajor int // 0: no upper bound
+}
+
+// jvmProfile is a named flag set the daemon expands at start ("jvm_profile").
+type jvmProfile struct {
+	// fullXms makes xms=auto equal to the heap (profiles that pre-touch the heap).
+	fullXms bool
+	flags   func(heapBytes uint64) []jvmFlag
+}
+
+var jvmProfiles = map[string]jvmProfile{
+	// Aikar's G1 flags (https://mcflags.emc.gs), with the large-heap variant above 12G.
+	"aikar": {fullXms: true, flags: func(heap uint64) []jvmFlag {
+		newSize, maxNewSize, region, reserve, ihop := "30", "40", "8M", "20", "15"
+		if heap >= 12<<30 {
+			newSize, maxNewSize, region, reserve, ihop = "40", "50", "16M", "15", "20"
+		}
+		return []jvmFlag{
+			{arg: "-XX:+UseG1GC"},
+			{arg: "-XX:+ParallelRefProcEnabled"},
+			{arg: "-XX:MaxGCPauseMillis=200"},
+			{arg: "-XX:+UnlockExperimentalVMOptions"},
+			{arg: "-XX:+DisableExplicitGC"},
+			{arg: "-XX:+AlwaysPreTouch"},
+			{arg: "-XX:G1NewSizePercent=" + newSize},
+			{arg: "-XX:G1MaxNewSizePercent=" + maxNewSize},
+			{arg: "-XX:G1HeapRegionSize=" + region},
+			{arg: "-XX:G1ReservePercent=" + reserve},
+			{arg: "-XX:G1HeapWastePercent=5"},
+			{arg: "-XX:G1MixedGCCountTarget=4"},
+			{arg: "-XX:InitiatingHeapOccupancyPercent=" + ihop},
+			{arg: "-XX:G1MixedGCLiveThresholdPercent=90"},
+			{arg: "-XX:G1RSetUpdatingPauseTimePercent=5"},
+			{arg: "-XX:SurvivorRatio=32"},
+			{arg: "-XX:+PerfDisableSharedMem"},
+			{arg: "-XX:MaxTenuringThreshold=1"},
+			{arg: "-Dusing.aikars.flags=https://mcflags.emc.gs"},
+			{arg: "-Daikars.new.flags=true"},
+		}
+	}},
+	// ZGC; generational mode exists from 21 and is the only mode from 24 (flag obsolete).
+	"zgc": {fullXms: true, flags: func(uint64) []jvmFlag {
+		return []jvmFlag{
+			{arg: "-XX:+UnlockExperimentalVMOptions", minMajor: 11, maxMajor: 14},
+			{arg: "-XX:+UseZGC", minMajor: 11},
+			{arg: "-XX:+ZGenerational", minMajor: 21, maxMajor: 22},
+			{arg: "-XX:+AlwaysPreTouch"},
+			{arg: "-XX:+DisableExplicitGC"},
+			{arg: "-XX:+PerfDisableSharedMem"},
+		}
+	}},
+	// Shenandoah is not in every build (e.g. Oracle JDK); the flag probe drops it there.
+	"shenandoah": {fullXms: true, flags: func(uint64) []jvmFlag {
+		return []jvmFlag{
+			{arg: "-XX:+UseShenandoahGC"},
+			{arg: "-XX:+AlwaysPreTouch"},
+			{arg: "-XX:+DisableExplicitGC"},
+			{arg: "-XX:+PerfDisableSharedMem"},
+		}
+	}},
+	// Small servers and proxies: serial GC that returns unused heap to the OS.
+	"lowmem": {flags: func(uint64) []jvmFlag {
+		return []jvmFlag{
+			{arg: "-XX:+UseSerialGC"},
+			{arg: "-XX:MinHeapFreeRatio=10"},
+			{arg: "-XX:MaxHeapFreeRatio=20"},
+			{arg: "-XX:+UseStringDeduplication", minMajor: 18},
+			{arg: "-XX:+PerfDisableSharedMem"},
+		}
+	}},
+}
+
+// JvmProfileNames lists the profiles accepted as jvm_profile.
+func JvmProfileNames() []string {
+	out := make([]string, 0, len(jvmProfiles))
+	for name := range jvmProfiles {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// normalizeJvmProfile returns the canonical profile name ("" for none).
+func normalizeJvmProfile(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "", "none", "default":
+		return "", nil
+	case "low-memory", "low_memory":
+		name = "lowmem"
+	case "g1":
+		name = "aikar"
+	}
+	if _, ok := jvmProfiles[name]; !ok {
+		return "", fmt.Errorf("unknown jvm_profile %q (supported: %s)", name, strings.Join(JvmProfileNames(), ", "))
+	}
+	return name, nil
+}
+
+// expandJvmProfile returns the profile's flags for a Java major (0: unknown, no filtering)
+// plus one note per flag skipped because the JVM is too old for it.
+func expandJvmProfile(name string, major int, heapBytes uint64) ([]string, []string) {
+	p, ok := jvmProfiles[name]
+	if !ok {
+		return nil, nil
+	}
+	var args, skipped []string
+	for _, f := range p.flags(heapBytes) {
+		if major > 0 && f.minMajor > 0 && major < f.minMajor {
+			skipped = append(skipped, fmt.Sprintf("%s (requires Java %d+)", f.arg, f.minMajor))
+			continue
+		}
+		if major > 0 && f.maxMajor > 0 && major > f.maxMajor {
+			// Default or obsolete on newer JVMs: dropped silently.
+			continue
+		}
+		args = append(args, f.arg)
+	}
+	return args, skipped
+}
+
+// jvmFlagChecker asks a JVM which -XX flags it accepts ("java <flags> -version"), so a
+// profile never keeps a server from starting. Results are cached per binary and flag.
+type jvmFlagChecker struct {
+	mu      sync.Mutex
+	results map[string]string // java + "\x00" + flag -> "" (accepted) or the JVM's complaint
+}
+
+func newJvmFlagChecker() *jvmFlagChecker {
+	return &jvmFlagChecker{results: make(map[string]string)}
+}
+
+func isJvmUnlockFlag(arg string) bool {
+	return strings.HasPrefix(arg, "-XX:+Unlock")
+}
+
+// filter returns the flags java accepts and one note per rejected flag. When the JVM
+// cannot be run at all the flags are returned unchanged. The probes run without c.mu
+// held so a slow JVM does not block other starts.
+func (c *jvmFlagChecker) filter(ctx context.Context, java string, flags []string) ([]string, []string) {
+	var unlock, unknown []string
+	c.mu.Lock()
+	for _, f := range flags {
+		switch {
+		case isJvmUnlockFlag(f):
+			unlock = append(unlock, f)
+		case strings.HasPrefix(f, "-XX:"):
+			if _, ok := c.results[java+"\x00"+f]; !ok {
+				unknown = append(unknown, f)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if len(unknown) > 0 {
+		probed := make(map[string]string, len(unknown))
+		reason, err := probeJvmFlags(ctx, java, append(append([]string(nil), unlock...), unknown...))
+		if err != nil {
+			return flags, nil
+		}
+		if reason == "" {
+			for _, f := range unknown {
+				probed[f] = ""
+			}
+		} else {
+			// Some flag is rejected: find out which one(s).
+			for _, f := range unknown {
+				r, err := probeJvmFlags(ctx, java, append(append([]string(nil), unlock...), f))
+				if err != nil {
+					return flags, nil
+				}
+				probed[f] = r
+			}
+		}
+		c.mu.Lock()
+		for f, r := range probed {
+			c.results[java+"\x00"+f] = r
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var kept, rejected []string
+	for _, f := range flags {
+		if r := c.results[java+"\x00"+f]; r != "" && !isJvmUnlockFlag(f) {
+			rejected = append(rejected, fmt.Sprintf("%s (%s)", f, r))
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, rejected
+}
+
+// probeJvmFlags runs "java <flags> -version" and returns why the JVM rejected or
+// ignored them ("" if accepted). err is set when java could not be run.
+func probeJvmFlags(ctx context.Context, java string, flags []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, java, append(flags, "-version")...).CombinedOutput()
+	text := string(out)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "Unrecognized") || strings.HasPrefix(line, "Error:") {
+				return line, nil
+			}
+		}
+		if line := strings.TrimSpace(firstLine(text)); line != "" {
+			return line, nil
+		}
+		return "rejected", nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, "Ignoring option") {
+			return strings.TrimSpace(line), nil
+		}
+	}
+	return "", nil
+}
+
+// formatCommandLine renders java + args as a shell-like line for logs and status.
+func formatCommandLine(java string, args []string) string {
+	if java == "" {
+		return ""
+	}
+	parts := make([]string, 0, len(args)+1)
+	for _, a := range append([]string{java}, args...) {
+		if a == "" || strings.ContainsAny(a, " \t\"'\\$") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
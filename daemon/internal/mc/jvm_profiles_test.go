@@ -0,0 +1,126 @@
+package mc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandJvmProfile(t *testing.T) {
+	cases := []struct {
+		name        string
+		profile     string
+		major       int
+		heap        uint64
+		has, hasNot []string
+		skipped     []string
+	}{
+		{"zgc on 11 unlocks experimental", "zgc", 11, 0,
+			[]string{"-XX:+UnlockExperimentalVMOptions", "-XX:+UseZGC"}, []string{"-XX:+ZGenerational"}, []string{"-XX:+ZGenerational (requires Java 21+)"}},
+		{"zgc on 14 still unlocks", "zgc", 14, 0,
+			[]string{"-XX:+UnlockExperimentalVMOptions", "-XX:+UseZGC"}, nil, []string{"-XX:+ZGenerational (requires Java 21+)"}},
+		{"zgc on 15 is production", "zgc", 15, 0,
+			[]string{"-XX:+UseZGC"}, []string{"-XX:+UnlockExperimentalVMOptions", "-XX:+ZGenerational"}, []string{"-XX:+ZGenerational (requires Java 21+)"}},
+		{"zgc on 17 has no generational mode", "zgc", 17, 0,
+			[]string{"-XX:+UseZGC", "-XX:+AlwaysPreTouch"}, []string{"-XX:+ZGenerational"}, []string{"-XX:+ZGenerational (requires Java 21+)"}},
+		{"zgc on 21 is generational", "zgc", 21, 0,
+			[]string{"-XX:+UseZGC", "-XX:+ZGenerational"}, []string{"-XX:+UnlockExperimentalVMOptions"}, nil},
+		{"zgc on 22 is generational", "zgc", 22, 0,
+			[]string{"-XX:+UseZGC", "-XX:+ZGenerational"}, nil, nil},
+		{"zgc on 23 drops the flag silently", "zgc", 23, 0,
+			[]string{"-XX:+UseZGC"}, []string{"-XX:+ZGenerational"}, nil},
+		{"zgc on 8 is not available", "zgc", 8, 0,
+			[]string{"-XX:+AlwaysPreTouch"}, []string{"-XX:+UseZGC", "-XX:+UnlockExperimentalVMOptions"},
+			[]string{"-XX:+UnlockExperimentalVMOptions (requires Java 11+)", "-XX:+UseZGC (requires Java 11+)", "-XX:+ZGenerational (requires Java 21+)"}},
+		{"unknown major keeps everything", "zgc", 0, 0,
+			[]string{"-XX:+UnlockExperimentalVMOptions", "-XX:+UseZGC", "-XX:+ZGenerational"}, nil, nil},
+		{"aikar below 12G", "aikar", 17, 8 << 30,
+			[]string{"-XX:+UseG1GC", "-XX:G1HeapRegionSize=8M", "-XX:G1NewSizePercent=30"}, []string{"-XX:G1HeapRegionSize=16M"}, nil},
+		{"aikar from 12G", "aikar", 17, 12 << 30,
+			[]string{"-XX:G1HeapRegionSize=16M", "-XX:G1NewSizePercent=40", "-XX:InitiatingHeapOccupancyPercent=20"}, []string{"-XX:G1HeapRegionSize=8M"}, nil},
+		{"lowmem on 17", "lowmem", 17, 0,
+			[]string{"-XX:+UseSerialGC"}, []string{"-XX:+UseStringDeduplication"}, []string{"-XX:+UseStringDeduplication (requires Java 18+)"}},
+		{"lowmem on 21", "lowmem", 21, 0,
+			[]string{"-XX:+UseSerialGC", "-XX:+UseStringDeduplication"}, nil, nil},
+	}
+	for _, tc := range cases {
+		args, skipped := expandJvmProfile(tc.profile, tc.major, tc.heap)
+		for _, a := range tc.has {
+			if !slices.Contains(args, a) {
+				t.Errorf("%s: missing %s in %v", tc.name, a, args)
+			}
+		}
+		for _, a := range tc.hasNot {
+			if slices.Contains(args, a) {
+				t.Errorf("%s: unexpected %s in %v", tc.name, a, args)
+			}
+		}
+		if !slices.Equal(skipped, tc.skipped) {
+			t.Errorf("%s: skipped = %q, want %q", tc.name, skipped, tc.skipped)
+		}
+	}
+
+	if args, skipped := expandJvmProfile("nope", 21, 0); args != nil || skipped != nil {
+		t.Fatalf("unknown profile should expand to nothing, got %v %v", args, skipped)
+	}
+}
+
+func TestNormalizeJvmProfile(t *testing.T) {
+	for in, want := range map[string]string{
+		"": "", "none": "", "Default": "", " AIKAR ": "aikar", "g1": "aikar",
+		"low-memory": "lowmem", "low_memory": "lowmem", "zgc": "zgc", "shenandoah": "shenandoah",
+	} {
+		if got, err := normalizeJvmProfile(in); err != nil || got != want {
+			t.Errorf("normalizeJvmProfile(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeJvmProfile("cms"); err == nil {
+		t.Fatalf("expected unknown profile to be rejected")
+	}
+}
+
+func TestJvmFlagChecker_DropsRejectedFlags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake java is a shell script")
+	}
+	dir := t.TempDir()
+	java := filepath.Join(dir, "java")
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\n" +
+		"echo \"$*\" >> " + calls + "\n" +
+		"for a in \"$@\"; do\n" +
+		"  if [ \"$a\" = \"-XX:+UseShenandoahGC\" ]; then echo \"Unrecognized VM option 'UseShenandoahGC'\" >&2; echo 'Error: Could not create the Java Virtual Machine.' >&2; exit 1; fi\n" +
+		"done\n" +
+		"echo 'openjdk version \"17.0.9\"' >&2\n"
+	if err := os.WriteFile(java, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake java: %v", err)
+	}
+
+	c := newJvmFlagChecker()
+	flags := []string{"-XX:+UnlockExperimentalVMOptions", "-XX:+UseShenandoahGC", "-XX:+AlwaysPreTouch", "-Dfoo=bar"}
+	kept, rejected := c.filter(context.Background(), java, flags)
+	if want := []string{"-XX:+UnlockExperimentalVMOptions", "-XX:+AlwaysPreTouch", "-Dfoo=bar"}; !slices.Equal(kept, want) {
+		t.Fatalf("kept = %q, want %q", kept, want)
+	}
+	if len(rejected) != 1 || !strings.HasPrefix(rejected[0], "-XX:+UseShenandoahGC (Unrecognized VM option") {
+		t.Fatalf("rejected = %q", rejected)
+	}
+
+	// Results are cached per binary and flag.
+	before, _ := os.ReadFile(calls)
+	if kept2, _ := c.filter(context.Background(), java, flags); !slices.Equal(kept2, kept) {
+		t.Fatalf("cached filter = %q", kept2)
+	}
+	if after, _ := os.ReadFile(calls); string(after) != string(before) {
+		t.Fatalf("expected no new probes, got %q", strings.TrimPrefix(string(after), string(before)))
+	}
+
+	// A JVM that cannot be run leaves the flags alone.
+	if kept, rejected := newJvmFlagChecker().filter(context.Background(), filepath.Join(dir, "missing"), flags); !slices.Equal(kept, flags) || rejected != nil {
+		t.Fatalf("missing java: kept=%q rejected=%q", kept, rejected)
+	}
+}
@@ -0,0 +1,188 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LaunchDescriptorName is the launch descriptor written by loader installers whose
+// output is not a single runnable jar (Forge/NeoForge 1.17+). Passing it as jar_path
+// makes the manager launch from the descriptor instead of "-jar <jar> nogui".
+const LaunchDescriptorName = ".elegantmc-launch.json"
+
+// LaunchDescriptor describes how to launch a server. Paths are relative to the instance dir.
+type LaunchDescriptor struct {
+	Kind          string `json:"kind"` // "forge" | "neoforge"
+	GameVersion   string `json:"game_version,omitempty"`
+	LoaderVersion string `json:"loader_version,omitempty"`
+	// JavaMajor is the minimum Java version (used when there is no jar to inspect).
+	JavaMajor int `json:"java_major,omitempty"`
+
+	// Either Jar (legacy layout: java -jar <jar>) or ArgFiles (java @file...) is set.
+	Jar      string   `json:"jar,omitempty"`
+	ArgFiles []string `json:"arg_files,omitempty"`
+	// Args are program arguments; nil means ["nogui"].
+	Args []string `json:"args,omitempty"`
+}
+
+// IsLaunchDescriptorPath reports whether jar_path refers to a launch descriptor.
+func IsLaunchDescriptorPath(p string) bool {
+	return filepath.Base(filepath.Clean(strings.TrimSpace(p))) == LaunchDescriptorName
+}
+
+func ReadLaunchDescriptor(path string) (LaunchDescriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return LaunchDescriptor{}, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, 256*1024))
+	if err != nil {
+		return LaunchDescriptor{}, err
+	}
+	var d LaunchDescriptor
+	if err := json.Unmarshal(b, &d); err != nil {
+		return LaunchDescriptor{}, fmt.Errorf("invalid launch descriptor (%s): %w", filepath.Base(path), err)
+	}
+	if strings.TrimSpace(d.Jar) == "" && len(d.ArgFiles) == 0 {
+		return LaunchDescriptor{}, fmt.Errorf("invalid launch descriptor (%s): jar or arg_files is required", filepath.Base(path))
+	}
+	return d, nil
+}
+
+func WriteLaunchDescriptor(path string, d LaunchDescriptor) error {
+	return writeJSONFileAtomic(path, d)
+}
+
+// resolveInstanceFile returns the absolute path of rel, which must exist inside instanceDir.
+func resolveInstanceFile(instanceDir string, rel string) (string, error) {
+	rel = strings.TrimSpace(rel)
+	if rel == "" || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("invalid path in launch descriptor: %q", rel)
+	}
+	abs := filepath.Join(instanceDir, filepath.FromSlash(rel))
+	if !isWithinDir(instanceDir, abs) {
+		return "", fmt.Errorf("path escapes instance dir: %q", rel)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("launch file not found: %w", err)
+	}
+	return abs, nil
+}
+
+// launchArgs returns the java arguments following the JVM options.
+func (d LaunchDescriptor) launchArgs(instanceDir string) ([]string, error) {
+	var args []string
+	if jar := strings.TrimSpace(d.Jar); jar != "" {
+		abs, err := resolveInstanceFile(instanceDir, jar)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-jar", abs)
+	} else {
+		for _, f := range d.ArgFiles {
+			abs, err := resolveInstanceFile(instanceDir, f)
+			if err != nil {
+				// user_jvm_args.txt is optional (it only holds user settings).
+				if filepath.Base(f) == "user_jvm_args.txt" && errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			args = append(args, "@"+abs)
+		}
+	}
+	if d.Args == nil {
+		return append(args, "nogui"), nil
+	}
+	return append(args, d.Args...), nil
+}
+
+// RequiredJavaMajorForLaunch returns the minimum Java major version needed to launch
+// jarRel (a server jar or a launch descriptor) from instanceDir.
+func RequiredJavaMajorForLaunch(instanceDir, jarRel string) (int, error) {
+	abs, err := resolveInstanceFile(instanceDir, jarRel)
+	if err != nil {
+		return 0, err
+	}
+	if !IsLaunchDescriptorPath(jarRel) {
+		return requiredJavaMajorFromJar(abs)
+	}
+	d, err := ReadLaunchDescriptor(abs)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(d.Jar) != "" {
+		jar, err := resolveInstanceFile(instanceDir, d.Jar)
+		if err != nil {
+			return 0, err
+		}
+		return requiredJavaMajorFromJar(jar)
+	}
+	if d.JavaMajor <= 0 {
+		return 0, errors.New("launch descriptor has no java_major")
+	}
+	return d.JavaMajor, nil
+}
+
+// RequiredJavaMajorForGameVersion returns the minimum Java major version for a
+// Minecraft release ("1.20.1" -> 17). Unknown formats (snapshots) return 0.
+func RequiredJavaMajorForGameVersion(version string) int {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	patch := 0
+	if len(parts) > 2 {
+		if n, err := strconv.Atoi(parts[2]); err == nil {
+			patch = n
+		}
+	}
+	switch {
+	case minor > 20 || (minor == 20 && patch >= 5):
+		return 21
+	case minor >= 18:
+		return 17
+	case minor == 17:
+		return 16
+	default:
+		return 8
+	}
+}
+
+// JavaForMajor returns a Java runtime with at least the given major version. With a
+// vendor it downloads that vendor's runtime; otherwise it prefers the configured
+// candidates and downloads the default vendor when auto-download is enabled.
+func (m *Manager) JavaForMajor(ctx context.Context, major int, vendor string) (string, int, error) {
+	return m.JavaForMajorRange(ctx, major, 0, vendor)
+}
+
+// JavaForMajorRange is JavaForMajor with an upper bound (0 = none). Downloads always
+// fetch the minimum major, which satisfies any bound.
+func (m *Manager) JavaForMajorRange(ctx context.Context, major, maxMajor int, vendor string) (string, int, error) {
+	if strings.TrimSpace(vendor) != "" {
+		if m.javaRuntime == nil {
+			return "", 0, fmt.Errorf("java_vendor %q requires java auto-download (ELEGANTMC_JAVA_AUTO_DOWNLOAD)", vendor)
+		}
+		return m.javaRuntime.EnsureJRE(ctx, vendor, major)
+	}
+	java, got, err := m.java.SelectRange(ctx, major, maxMajor)
+	if err == nil {
+		return java, got, nil
+	}
+	if m.javaRuntime == nil {
+		return "", 0, err
+	}
+	return m.javaRuntime.EnsureJRE(ctx, "", major)
+}
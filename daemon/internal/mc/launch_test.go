@@ -0,0 +1,172 @@
+package mc
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeClassJar writes a jar whose Main-Class is compiled for the given class file major.
+func writeClassJar(t *testing.T, path string, classMajor byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create jar: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, body := range map[string][]byte{
+		"META-INF/MANIFEST.MF":                  []byte("Manifest-Version: 1.0\r\nMain-Class: net.minecraftforge.Main\r\n"),
+		"net/minecraftforge/Main.class":         {0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, classMajor},
+		"net/minecraftforge/Other.class":        {0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, 65},
+		"META-INF/versions/9/module-info.class": {0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, 53},
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		_, _ = w.Write(body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+}
+
+func TestIsLaunchDescriptorPath(t *testing.T) {
+	for p, want := range map[string]bool{
+		LaunchDescriptorName:             true,
+		" " + LaunchDescriptorName + " ": true,
+		"sub/" + LaunchDescriptorName:    true,
+		"server.jar":                     false,
+		"config.json":                    false,
+		"server.JSON":                    false,
+		".elegantmc.json":                false,
+	} {
+		if got := IsLaunchDescriptorPath(p); got != want {
+			t.Errorf("IsLaunchDescriptorPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestReadLaunchDescriptor(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		p := filepath.Join(dir, LaunchDescriptorName)
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatalf("write descriptor: %v", err)
+		}
+		return p
+	}
+
+	d, err := ReadLaunchDescriptor(write(`{"kind":"forge","game_version":"1.20.1","java_major":17,"arg_files":["user_jvm_args.txt","libraries/unix_args.txt"]}`))
+	if err != nil {
+		t.Fatalf("ReadLaunchDescriptor(): %v", err)
+	}
+	if d.Kind != "forge" || d.JavaMajor != 17 || len(d.ArgFiles) != 2 || d.Args != nil {
+		t.Fatalf("unexpected descriptor: %+v", d)
+	}
+
+	for _, body := range []string{`{"kind":"forge"}`, `{"kind":`, `{"jar":"  "}`} {
+		if _, err := ReadLaunchDescriptor(write(body)); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestLaunchDescriptor_LaunchArgs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "libraries"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "libraries", "unix_args.txt"), []byte("-cp x"), 0o644); err != nil {
+		t.Fatalf("write args: %v", err)
+	}
+
+	// user_jvm_args.txt is optional; the loader's argument file is not.
+	d := LaunchDescriptor{ArgFiles: []string{"user_jvm_args.txt", "libraries/unix_args.txt"}}
+	got, err := d.launchArgs(dir)
+	if err != nil {
+		t.Fatalf("launchArgs(): %v", err)
+	}
+	if want := []string{"@" + filepath.Join(dir, "libraries", "unix_args.txt"), "nogui"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("launchArgs() = %q, want %q", got, want)
+	}
+
+	d = LaunchDescriptor{ArgFiles: []string{"libraries/missing.txt"}}
+	if _, err := d.launchArgs(dir); err == nil {
+		t.Fatalf("expected a missing argument file to fail")
+	}
+	for _, jar := range []string{"../outside.jar", "/abs/server.jar"} {
+		if _, err := (LaunchDescriptor{Jar: jar}).launchArgs(dir); err == nil {
+			t.Errorf("expected jar %q to be rejected", jar)
+		}
+	}
+
+	writeClassJar(t, filepath.Join(dir, "forge-1.12.2.jar"), 52)
+	got, err = LaunchDescriptor{Jar: "forge-1.12.2.jar", Args: []string{}}.launchArgs(dir)
+	if err != nil {
+		t.Fatalf("launchArgs(jar): %v", err)
+	}
+	if want := []string{"-jar", filepath.Join(dir, "forge-1.12.2.jar")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("launchArgs(jar) = %q, want %q", got, want)
+	}
+}
+
+func TestRequiredJavaMajorForLaunch(t *testing.T) {
+	dir := t.TempDir()
+	writeClassJar(t, filepath.Join(dir, "forge-1.12.2.jar"), 52)
+	writeClassJar(t, filepath.Join(dir, "server.jar"), 61)
+	for name, d := range map[string]LaunchDescriptor{
+		"legacy": {Kind: "forge", JavaMajor: 17, Jar: "forge-1.12.2.jar"},
+		"modern": {Kind: "neoforge", JavaMajor: 21, ArgFiles: []string{"unix_args.txt"}},
+		"nojava": {Kind: "forge", ArgFiles: []string{"unix_args.txt"}},
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := WriteLaunchDescriptor(filepath.Join(dir, name, LaunchDescriptorName), d); err != nil {
+			t.Fatalf("write descriptor: %v", err)
+		}
+	}
+
+	cases := []struct {
+		jar  string
+		want int
+		ok   bool
+	}{
+		{"server.jar", 17, true},
+		// A legacy descriptor is probed through its jar, not java_major.
+		{"legacy/" + LaunchDescriptorName, 8, true},
+		{"modern/" + LaunchDescriptorName, 21, true},
+		{"nojava/" + LaunchDescriptorName, 0, false},
+		{"missing.jar", 0, false},
+	}
+	for _, tc := range cases {
+		got, err := RequiredJavaMajorForLaunch(dir, tc.jar)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("RequiredJavaMajorForLaunch(%q) = %d, %v; want %d ok=%v", tc.jar, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestRequiredJavaMajorForGameVersion(t *testing.T) {
+	for version, want := range map[string]int{
+		"1.7.10": 8,
+		"1.12.2": 8,
+		"1.16.5": 8,
+		"1.17.1": 16,
+		"1.18":   17,
+		"1.20.4": 17,
+		"1.20.5": 21,
+		"1.21.1": 21,
+		"24w14a": 0,
+		"":       0,
+		"2.0":    0,
+	} {
+		if got := RequiredJavaMajorForGameVersion(version); got != want {
+			t.Errorf("RequiredJavaMajorForGameVersion(%q) = %d, want %d", version, got, want)
+		}
+	}
+}
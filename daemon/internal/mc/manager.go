@@ -24,6 +24,31 @@ type ManagerConfig struct {
 	JavaAutoDownload       bool
 	JavaCacheDir           string
 	JavaAdoptiumAPIBaseURL string
+	JavaZuluAPIBaseURL     string
+	// JavaManifestURL lists extra runtimes (URL + sha256), see JavaRuntimeManagerConfig.
+	JavaManifestURL string
+	// JavaVendor is the auto-download vendor for instances without java_vendor.
+	JavaVendor string
+	// JavaDiscover adds runtimes from well-known install locations (JAVA_HOME,
+	// /usr/lib/jvm, SDKMAN, asdf, /opt, the java cache) to the candidates.
+	JavaDiscover bool
+
+	// RuntimeDir holds per-instance runtime state (pid, args, console FIFO) so that
+	// running servers can be re-adopted after a daemon restart. Empty disables it.
+	RuntimeDir string
+	// Detach starts servers outside the daemon's lifetime (own process group, not
+	// killed when the daemon exits) so they keep running across daemon restarts.
+	Detach bool
+
+	// Cgroups puts each instance into its own cgroup v2 group (Linux) so that
+	// resource_limits from .elegantmc.json apply and usage is accounted per instance.
+	Cgroups bool
+	// CgroupParent is the delegated parent group relative to /sys/fs/cgroup
+	// (empty: the daemon's own cgroup).
+	CgroupParent string
+
+	// Isolation sandboxes server processes (Linux, opt-in).
+	Isolation IsolationConfig
 }
 
 type Manager struct {
@@ -34,13 +59,21 @@ type Manager struct {
 
 	java        *javaSelector
 	javaRuntime *JavaRuntimeManager
+	cgroups     *cgroupController
+	jvmFlags    *jvmFlagChecker
+
+	heapResMu sync.Mutex
+	heapRes   map[string]uint64 // instance_id -> max heap of a running (or starting) server
+
+	eventSink func(instanceID string, ev ConsoleEvent)
 }
 
 type Instance struct {
 	ID string
 
 	mu                sync.Mutex
-	cmd               *exec.Cmd
+	proc              *os.Process
+	adopted           bool
 	stdin             io.WriteCloser
 	done              chan error
 	portKey           string
@@ -48,11 +81,21 @@ type Instance struct {
 	java              string
 	javaMajor         int
 	requiredJavaMajor int
+	jvmProfile        string
 	args              []string
+	cgroupPath        string
 	startedAt         time.Time
 	lastExitUnix      int64
 	lastExitCode      *int
 	lastExitSignal    string
+
+	probeQuit chan struct{}
+	ping      *PingStatus
+	readyUnix int64
+
+	stopRequested bool
+	restart       restartState
+	onExit        func(inst *Instance, exitCode *int, exitSignal string, requested bool)
 }
 
 type StartOptions struct {
@@ -63,6 +106,15 @@ type StartOptions struct {
 	Xmx        string
 	JvmArgs    []string
 	ExtraArgs  []string
+
+	// JavaVendor overrides java_vendor from .elegantmc.json when set.
+	JavaVendor string
+	// JvmProfile overrides jvm_profile from .elegantmc.json when set (see JvmProfileNames).
+	// Xms/Xmx may be "auto" to size the heap from host memory.
+	JvmProfile string
+
+	// Restart overrides restart_policy from .elegantmc.json when set.
+	Restart *RestartPolicy
 }
 
 type Status struct {
@@ -75,6 +127,20 @@ type Status struct {
 	LastExitUnix      int64
 	LastExitCode      *int
 	LastExitSignal    string
+	RestartPolicy     string
+	RestartCount      int
+	NextRestartUnix   int64
+	RestartGaveUp     bool
+	Adopted           bool
+	CgroupPath        string
+	JvmProfile        string
+	// CommandLine is the resolved java command line of the current/last start.
+	CommandLine string
+
+	// Ready is set once the server answered a Server List Ping since it started.
+	Ready     bool
+	ReadyUnix int64
+	Ping      *PingStatus
 }
 
 func NewManager(cfg ManagerConfig) *Manager {
@@ -83,21 +149,71 @@ func NewManager(cfg ManagerConfig) *Manager {
 		rt = NewJavaRuntimeManager(JavaRuntimeManagerConfig{
 			CacheDir:           cfg.JavaCacheDir,
 			AdoptiumAPIBaseURL: cfg.JavaAdoptiumAPIBaseURL,
+			ZuluAPIBaseURL:     cfg.JavaZuluAPIBaseURL,
+			ManifestURL:        cfg.JavaManifestURL,
+			DefaultVendor:      cfg.JavaVendor,
 			Log:                cfg.Log,
 		})
 	}
+	var cg *cgroupController
+	if cfg.Cgroups {
+		cg = newCgroupController(cfg.CgroupParent)
+	}
 	return &Manager{
 		cfg:         cfg,
 		instances:   make(map[string]*Instance),
-		java:        newJavaSelector(cfg.JavaCandidates),
+		java:        newJavaSelector(newJavaInventory(cfg.JavaCandidates, cfg.JavaDiscover, cfg.JavaCacheDir)),
 		javaRuntime: rt,
+		cgroups:     cg,
+		jvmFlags:    newJvmFlagChecker(),
+		heapRes:     make(map[string]uint64),
+	}
+}
+
+// SetEventSink registers the receiver of typed events parsed from console output.
+func (m *Manager) SetEventSink(sink func(instanceID string, ev ConsoleEvent)) {
+	m.mu.Lock()
+	m.eventSink = sink
+	m.mu.Unlock()
+}
+
+// consoleParser returns a parser for one output stream of an instance, or nil without a sink.
+func (m *Manager) consoleParser(instanceID string, stream string) *consoleParser {
+	m.mu.Lock()
+	sink := m.eventSink
+	m.mu.Unlock()
+	if sink == nil {
+		return nil
 	}
+	return newConsoleParser(stream, func(ev ConsoleEvent) { sink(instanceID, ev) })
 }
 
 func (m *Manager) JavaRuntimeManager() *JavaRuntimeManager {
 	return m.javaRuntime
 }
 
+// JavaInventory lists the Java runtimes the selector chooses from. The scan is cached;
+// refresh forces a rescan.
+func (m *Manager) JavaInventory(ctx context.Context, refresh bool) ([]JavaInstallation, time.Time) {
+	return m.java.inventory.List(ctx, refresh)
+}
+
+// ImportJava installs a runtime archive into the java cache (see
+// JavaRuntimeManager.ImportArchive). Auto-download without a java_vendor uses it for
+// its major before going to the network; with auto-download disabled the runtime is
+// only found through the Java inventory.
+func (m *Manager) ImportJava(ctx context.Context, archivePath string, opt JavaImportOptions) (JavaCacheEntry, error) {
+	rt := m.javaRuntime
+	if rt == nil {
+		rt = NewJavaRuntimeManager(JavaRuntimeManagerConfig{CacheDir: m.cfg.JavaCacheDir, Log: m.cfg.Log})
+	}
+	entry, err := rt.ImportArchive(ctx, archivePath, opt)
+	if err == nil {
+		m.java.inventory.invalidate()
+	}
+	return entry, err
+}
+
 func (m *Manager) List() map[string]Status {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -117,25 +233,25 @@ func (m *Manager) Start(ctx context.Context, opt StartOptions, logSink func(inst
 		return errors.New("jar_path is required")
 	}
 
+	policy, err := m.restartPolicyFor(opt.InstanceID, opt.Restart)
+	if err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	inst := m.instances[opt.InstanceID]
 	if inst == nil {
-		inst = &Instance{ID: opt.InstanceID}
+		inst = &Instance{ID: opt.InstanceID, onExit: m.handleInstanceExit}
 		m.instances[opt.InstanceID] = inst
 	}
 	m.mu.Unlock()
 
-	return inst.start(ctx, m.cfg.ServersFS, opt, logSink, m.cfg.Log, m.java, m.javaRuntime)
+	inst.armRestart(ctx, policy, opt, logSink)
+	return inst.start(ctx, m, opt, logSink)
 }
 
 func (m *Manager) Stop(ctx context.Context, instanceID string) error {
-	m.mu.Lock()
-	inst := m.instances[instanceID]
-	m.mu.Unlock()
-	if inst == nil {
-		return nil
-	}
-	return inst.stop(ctx, m.cfg.Log)
+	return m.StopWithOptions(ctx, instanceID, DefaultStopOptions())
 }
 
 func (m *Manager) Delete(ctx context.Context, instanceID string) error {
@@ -176,21 +292,7 @@ func (inst *Instance) Status() Status {
 	inst.mu.Lock()
 	defer inst.mu.Unlock()
 
-	if inst.cmd == nil || inst.cmd.Process == nil {
-		return Status{
-			Running:           false,
-			JarRel:            inst.jarRel,
-			Java:              inst.java,
-			JavaMajor:         inst.javaMajor,
-			RequiredJavaMajor: inst.requiredJavaMajor,
-			LastExitUnix:      inst.lastExitUnix,
-			LastExitCode:      inst.lastExitCode,
-			LastExitSignal:    inst.lastExitSignal,
-		}
-	}
-	return Status{
-		Running:           true,
-		PID:               inst.cmd.Process.Pid,
+	st := Status{
 		JarRel:            inst.jarRel,
 		Java:              inst.java,
 		JavaMajor:         inst.javaMajor,
@@ -198,17 +300,44 @@ func (inst *Instance) Status() Status {
 		LastExitUnix:      inst.lastExitUnix,
 		LastExitCode:      inst.lastExitCode,
 		LastExitSignal:    inst.lastExitSignal,
-	}
+		RestartPolicy:     inst.restart.policy.Mode,
+		RestartCount:      inst.restart.count,
+		NextRestartUnix:   inst.restart.nextUnix,
+		RestartGaveUp:     inst.restart.gaveUp,
+		Adopted:           inst.adopted,
+		CgroupPath:        inst.cgroupPath,
+		JvmProfile:        inst.jvmProfile,
+		CommandLine:       formatCommandLine(inst.java, inst.args),
+		Ready:             inst.readyUnix > 0,
+		ReadyUnix:         inst.readyUnix,
+	}
+	if inst.ping != nil {
+		p := *inst.ping
+		st.Ping = &p
+	}
+	if inst.proc != nil {
+		st.Running = true
+		st.PID = inst.proc.Pid
+	}
+	return st
 }
 
-func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOptions, logSink func(instanceID, stream, line string), logger *log.Logger, javaSel *javaSelector, javaRuntime *JavaRuntimeManager) error {
+func (inst *Instance) start(ctx context.Context, m *Manager, opt StartOptions, logSink func(instanceID, stream, line string)) error {
 	inst.mu.Lock()
 	defer inst.mu.Unlock()
 
-	if inst.cmd != nil && inst.cmd.Process != nil {
+	if inst.proc != nil {
 		return errors.New("instance already running")
 	}
 
+	fs := m.cfg.ServersFS
+	logger := m.cfg.Log
+	javaSel := m.java
+	javaRuntime := m.javaRuntime
+	if fs == nil {
+		return errors.New("servers filesystem not configured")
+	}
+
 	instanceDir, err := fs.Resolve(filepath.Join(opt.InstanceID))
 	if err != nil {
 		return err
@@ -224,6 +353,43 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 	if _, err := os.Stat(jarAbs); err != nil {
 		return fmt.Errorf("jar not found: %w", err)
 	}
+	// jar_path may name a launch descriptor (Forge/NeoForge argument-file layout).
+	var launch *LaunchDescriptor
+	javaProbeJar := jarAbs
+	if IsLaunchDescriptorPath(opt.JarPath) {
+		d, err := ReadLaunchDescriptor(jarAbs)
+		if err != nil {
+			return err
+		}
+		launch = &d
+		javaProbeJar = ""
+		if strings.TrimSpace(d.Jar) != "" {
+			if javaProbeJar, err = resolveInstanceFile(instanceDir, d.Jar); err != nil {
+				return err
+			}
+		}
+	}
+
+	var limits ResourceLimits
+	javaVendor := strings.TrimSpace(opt.JavaVendor)
+	jvmProfile := strings.TrimSpace(opt.JvmProfile)
+	if cfg, err := readInstanceConfigFile(instanceDir); err == nil {
+		if cfg.ResourceLimits != nil {
+			limits = *cfg.ResourceLimits
+			if err := limits.Validate(); err != nil {
+				return err
+			}
+		}
+		if javaVendor == "" {
+			javaVendor = strings.TrimSpace(cfg.JavaVendor)
+		}
+		if jvmProfile == "" {
+			jvmProfile = cfg.JvmProfile
+		}
+	}
+	if jvmProfile, err = normalizeJvmProfile(jvmProfile); err != nil {
+		return err
+	}
 
 	startedOk := false
 	defer func() {
@@ -234,6 +400,7 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 			releasePort(inst.ID, inst.portKey)
 			inst.portKey = ""
 		}
+		m.releaseHeap(inst.ID)
 	}()
 
 	if host, port, ok := detectServerListenAddr(instanceDir); ok {
@@ -249,7 +416,12 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 
 	java := opt.JavaPath
 	javaSource := "explicit"
-	requiredMajor, err := requiredJavaMajorFromJar(jarAbs)
+	var requiredMajor int
+	if javaProbeJar != "" {
+		requiredMajor, err = requiredJavaMajorFromJar(javaProbeJar)
+	} else if requiredMajor = launch.JavaMajor; requiredMajor <= 0 {
+		err = errors.New("launch descriptor has no java_major")
+	}
 	detectedMajor := err == nil
 	if err != nil {
 		requiredMajor = 8
@@ -262,7 +434,20 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 	}
 
 	var selectedMajor int
-	if java == "" {
+	if java == "" && javaVendor != "" {
+		// An instance that picks a vendor always runs that vendor's runtime.
+		if javaRuntime == nil {
+			return fmt.Errorf("java_vendor %q requires java auto-download (ELEGANTMC_JAVA_AUTO_DOWNLOAD)", javaVendor)
+		}
+		if logSink != nil {
+			logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] ensuring %s JRE %d (java_vendor)", javaVendor, requiredMajor))
+		}
+		java, selectedMajor, err = javaRuntime.EnsureJRE(ctx, javaVendor, requiredMajor)
+		if err != nil {
+			return fmt.Errorf("java_vendor %s: %w", javaVendor, err)
+		}
+		javaSource = strings.ToLower(javaVendor)
+	} else if java == "" {
 		if javaSel == nil {
 			java = "java"
 			javaSource = "default"
@@ -273,12 +458,12 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 			if selErr != nil {
 				if detectedMajor && javaRuntime != nil {
 					if logSink != nil {
-						logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] ensuring Temurin JRE %d (auto)", requiredMajor))
+						logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] ensuring %s JRE %d (auto)", javaRuntime.DefaultVendor(), requiredMajor))
 					}
-					if ensuredJava, ensuredMajor, err := javaRuntime.EnsureTemurinJRE(ctx, requiredMajor); err == nil {
+					if ensuredJava, ensuredMajor, err := javaRuntime.EnsureJRE(ctx, "", requiredMajor); err == nil {
 						java = ensuredJava
 						selectedMajor = ensuredMajor
-						javaSource = "temurin-auto"
+						javaSource = javaRuntime.DefaultVendor() + "-auto"
 						selErr = nil
 					} else {
 						if logger != nil {
@@ -295,12 +480,12 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 			}
 			if detectedMajor && javaRuntime != nil && selectedMajor > 0 && selectedMajor < requiredMajor {
 				if logSink != nil {
-					logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] ensuring Temurin JRE %d (auto)", requiredMajor))
+					logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] ensuring %s JRE %d (auto)", javaRuntime.DefaultVendor(), requiredMajor))
 				}
-				if ensuredJava, ensuredMajor, err := javaRuntime.EnsureTemurinJRE(ctx, requiredMajor); err == nil {
+				if ensuredJava, ensuredMajor, err := javaRuntime.EnsureJRE(ctx, "", requiredMajor); err == nil {
 					java = ensuredJava
 					selectedMajor = ensuredMajor
-					javaSource = "temurin-auto"
+					javaSource = javaRuntime.DefaultVendor() + "-auto"
 				} else {
 					if logger != nil {
 						logger.Printf("mc: java auto-download failed (major=%d): %v", requiredMajor, err)
@@ -334,7 +519,30 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 		logSink(inst.ID, "stdout", msg)
 	}
 
+	xms, xmx, memNote, err := m.resolveMemory(inst.ID, opt.Xms, opt.Xmx, jvmProfile, limits)
+	if err != nil {
+		return err
+	}
+	if memNote != "" && logSink != nil {
+		logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] memory auto: xms=%s xmx=%s (%s)", xms, xmx, memNote))
+	}
+
+	// Profile flags come first so that explicit jvm_args override them.
 	var args []string
+	if jvmProfile != "" {
+		var heap uint64
+		if xmx != "" {
+			heap, _ = parseByteSize(xmx)
+		}
+		profileArgs, skipped := expandJvmProfile(jvmProfile, selectedMajor, heap)
+		profileArgs, rejected := m.jvmFlags.filter(ctx, java, profileArgs)
+		if logSink != nil {
+			for _, s := range append(skipped, rejected...) {
+				logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] jvm profile %s: skipped %s", jvmProfile, s))
+			}
+		}
+		args = append(args, profileArgs...)
+	}
 	for _, a := range opt.JvmArgs {
 		a = strings.TrimSpace(a)
 		if a == "" {
@@ -342,59 +550,188 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 		}
 		args = append(args, a)
 	}
-	if opt.Xms != "" {
-		args = append(args, "-Xms"+opt.Xms)
+	if xms != "" {
+		args = append(args, "-Xms"+xms)
 	}
-	if opt.Xmx != "" {
-		args = append(args, "-Xmx"+opt.Xmx)
+	if xmx != "" {
+		args = append(args, "-Xmx"+xmx)
+	}
+	if launch != nil {
+		launchArgs, err := launch.launchArgs(instanceDir)
+		if err != nil {
+			return err
+		}
+		args = append(args, launchArgs...)
+	} else {
+		args = append(args, "-jar", jarAbs, "nogui")
 	}
-	args = append(args, "-jar", jarAbs, "nogui")
 	args = append(args, opt.ExtraArgs...)
+	inst.jvmProfile = jvmProfile
+	if logSink != nil {
+		logSink(inst.ID, "stdout", "[elegantmc] command: "+formatCommandLine(java, args))
+	}
 
-	cmd := exec.CommandContext(ctx, java, args...)
+	var cmd *exec.Cmd
+	if m.cfg.Detach {
+		cmd = exec.Command(java, args...)
+		cmd.SysProcAttr = detachedSysProcAttr()
+	} else {
+		cmd = exec.CommandContext(ctx, java, args...)
+	}
 	cmd.Dir = instanceDir
+	if m.cfg.Isolation.Enabled {
+		if err := m.isolateCommand(cmd, instanceDir); err != nil {
+			return err
+		}
+		if logSink != nil {
+			logSink(inst.ID, "stdout", "[elegantmc] isolation enabled (private mount namespace, read-only host, no capabilities)")
+		}
+	}
+
+	var stdout, stderr io.ReadCloser
+	consoleLogs, logErr := m.openConsoleLogs(inst.ID)
+	if logErr != nil {
+		m.logf("mc: console log files unavailable (instance=%s): %v", inst.ID, logErr)
+	}
+	if consoleLogs != nil {
+		cmd.Stdout = consoleLogs[0]
+		cmd.Stderr = consoleLogs[1]
+	} else {
+		stdout, _ = cmd.StdoutPipe()
+		stderr, _ = cmd.StderrPipe()
+	}
+
+	// Prefer a named FIFO for stdin so the console survives a daemon restart.
+	var stdin io.WriteCloser
+	fifoPath := m.consoleFIFOPath(inst.ID)
+	if fifoPath != "" {
+		if f, err := openConsoleFIFO(fifoPath); err == nil {
+			cmd.Stdin = f
+			stdin = f
+		} else {
+			fifoPath = ""
+			if logger != nil {
+				logger.Printf("mc: console fifo unavailable (instance=%s): %v", inst.ID, err)
+			}
+		}
+	}
+	if stdin == nil {
+		stdin, _ = cmd.StdinPipe()
+	}
 
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-	stdin, _ := cmd.StdinPipe()
+	cgroupPath := ""
+	if m.cgroups != nil {
+		path, warnings, err := m.cgroups.create(inst.ID, limits)
+		if err != nil {
+			m.logf("mc: cgroups unavailable (instance=%s): %v", inst.ID, err)
+			if logSink != nil && !limits.IsZero() {
+				logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] resource limits not applied: %v", err))
+			}
+		} else {
+			cgroupPath = path
+			for _, w := range warnings {
+				if logSink != nil {
+					logSink(inst.ID, "stdout", "[elegantmc] cgroup limit not applied: "+w)
+				}
+			}
+		}
+	} else if !limits.IsZero() && logSink != nil {
+		logSink(inst.ID, "stdout", "[elegantmc] resource_limits ignored (cgroups disabled, set ELEGANTMC_CGROUPS=1)")
+	}
+
+	var cgroupFD *os.File
+	if cgroupPath != "" {
+		f, err := startInCgroup(cmd, cgroupPath)
+		if err != nil {
+			m.logf("mc: open cgroup failed (instance=%s): %v", inst.ID, err)
+			go removeCgroup(cgroupPath)
+			cgroupPath = ""
+		}
+		cgroupFD = f
+	}
 
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	if cgroupFD != nil {
+		_ = cgroupFD.Close()
+	}
+	for _, f := range consoleLogs {
+		_ = f.Close()
+	}
+	if err != nil {
+		if fifoPath != "" {
+			_ = stdin.Close()
+		}
+		// removeCgroup may poll; don't hold inst.mu while it does.
+		go removeCgroup(cgroupPath)
 		return err
 	}
+	if cgroupPath != "" && cgroupFD == nil {
+		// Kernel without CLONE_INTO_CGROUP: move the process in right after the start.
+		if err := attachCgroup(cgroupPath, cmd.Process.Pid); err != nil {
+			m.logf("mc: attach cgroup failed (instance=%s): %v", inst.ID, err)
+			go removeCgroup(cgroupPath)
+			cgroupPath = ""
+		}
+	}
 
 	done := make(chan error, 1)
 
-	inst.cmd = cmd
+	inst.proc = cmd.Process
+	inst.adopted = false
 	inst.stdin = stdin
 	inst.done = done
 	inst.jarRel = opt.JarPath
 	inst.java = java
 	inst.args = args
+	inst.cgroupPath = cgroupPath
 	inst.startedAt = time.Now()
+	m.reserveHeap(inst.ID, heapFromArgs(args, hostMemTotal()))
+
+	m.saveRuntimeState(inst, opt, instanceDir, fifoPath, consoleLogs != nil)
+	inst.startProber(m, instanceDir, logSink)
 
 	if logger != nil {
 		logger.Printf("mc started: instance=%s pid=%d", inst.ID, cmd.Process.Pid)
 	}
 
 	if stdout != nil {
-		go scanLines(stdout, func(line string) {
-			if logSink != nil {
-				logSink(inst.ID, "stdout", line)
-			}
-		})
+		parser := m.consoleParser(inst.ID, "stdout")
+		go func() {
+			scanLines(stdout, func(line string) {
+				if logSink != nil {
+					logSink(inst.ID, "stdout", line)
+				}
+				parser.Feed(line)
+			})
+			parser.Flush()
+		}()
 	}
 	if stderr != nil {
-		go scanLines(stderr, func(line string) {
-			if logSink != nil {
-				logSink(inst.ID, "stderr", line)
-			}
-		})
+		parser := m.consoleParser(inst.ID, "stderr")
+		go func() {
+			scanLines(stderr, func(line string) {
+				if logSink != nil {
+					logSink(inst.ID, "stderr", line)
+				}
+				parser.Feed(line)
+			})
+			parser.Flush()
+		}()
+	}
+
+	tailQuit := make(chan struct{})
+	var tailDone <-chan struct{}
+	if consoleLogs != nil {
+		tailDone = m.followConsoleLogs(inst.ID, true, tailQuit, logSink)
 	}
 
 	go func() {
 		err := cmd.Wait()
+		close(tailQuit)
+		if tailDone != nil {
+			<-tailDone
+		}
 
-		exitUnix := time.Now().Unix()
 		var exitCode *int
 		if cmd.ProcessState != nil {
 			code := cmd.ProcessState.ExitCode()
@@ -403,66 +740,50 @@ func (inst *Instance) start(ctx context.Context, fs *sandbox.FS, opt StartOption
 			}
 		}
 		exitSignal := exitSignalFromProcessState(cmd.ProcessState)
-
-		var portKey string
-		inst.mu.Lock()
-		inst.lastExitUnix = exitUnix
-		inst.lastExitCode = exitCode
-		inst.lastExitSignal = exitSignal
-		inst.cmd = nil
-		inst.stdin = nil
-		inst.done = nil
-		portKey = inst.portKey
-		inst.portKey = ""
-		inst.mu.Unlock()
-		if portKey != "" {
-			releasePort(inst.ID, portKey)
-		}
-		done <- err
-		close(done)
-		if err != nil && logger != nil {
-			logger.Printf("mc exited: instance=%s err=%v", inst.ID, err)
-		}
+		inst.finishExit(m, done, err, exitCode, exitSignal, ctx.Err() != nil && !m.cfg.Detach)
 	}()
 
 	startedOk = true
 	return nil
 }
 
-func (inst *Instance) stop(ctx context.Context, logger *log.Logger) error {
+// finishExit records the exit of the current process and notifies waiters.
+// It is shared by child processes (cmd.Wait) and adopted processes (liveness polling).
+func (inst *Instance) finishExit(m *Manager, done chan error, err error, exitCode *int, exitSignal string, ctxCanceled bool) {
+	var portKey string
 	inst.mu.Lock()
-	cmd := inst.cmd
-	stdin := inst.stdin
-	done := inst.done
+	inst.lastExitUnix = time.Now().Unix()
+	inst.lastExitCode = exitCode
+	inst.lastExitSignal = exitSignal
+	requested := inst.stopRequested || ctxCanceled
+	inst.stopRequested = false
+	onExit := inst.onExit
+	inst.stopProberLocked()
+	if inst.stdin != nil {
+		_ = inst.stdin.Close()
+	}
+	inst.proc = nil
+	inst.adopted = false
+	inst.stdin = nil
+	inst.done = nil
+	portKey = inst.portKey
+	inst.portKey = ""
+	cgroupPath := inst.cgroupPath
+	inst.cgroupPath = ""
 	inst.mu.Unlock()
-
-	if cmd == nil || cmd.Process == nil {
-		return nil
-	}
-	if done == nil {
-		_ = cmd.Process.Kill()
-		return nil
-	}
-
-	if stdin != nil {
-		_, _ = io.WriteString(stdin, "stop\n")
+	if portKey != "" {
+		releasePort(inst.ID, portKey)
+	}
+	removeCgroup(cgroupPath)
+	m.removeRuntimeState(inst.ID)
+	m.releaseHeap(inst.ID)
+	done <- err
+	close(done)
+	if err != nil {
+		m.logf("mc exited: instance=%s err=%v", inst.ID, err)
 	}
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-done:
-		if logger != nil {
-			logger.Printf("mc stopped: instance=%s err=%v", inst.ID, err)
-		}
-		return nil
-	case <-time.After(20 * time.Second):
-		_ = cmd.Process.Kill()
-		<-done
-		if logger != nil {
-			logger.Printf("mc killed: instance=%s", inst.ID)
-		}
-		return nil
+	if onExit != nil {
+		onExit(inst, exitCode, exitSignal, requested)
 	}
 }
 
@@ -0,0 +1,95 @@
+package mc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"elegantmc/daemon/internal/slp"
+)
+
+const (
+	pingIntervalStarting = 2 * time.Second
+	pingIntervalReady    = 15 * time.Second
+	pingTimeout          = 3 * time.Second
+)
+
+// PingStatus is the latest Server List Ping answer of a running instance.
+type PingStatus struct {
+	slp.Status
+	CheckedUnix int64
+}
+
+// startProber pings the server's listen address until quit is closed. The first
+// successful ping marks the instance ready. Caller holds inst.mu.
+func (inst *Instance) startProber(m *Manager, instanceDir string, logSink func(instanceID, stream, line string)) {
+	quit := make(chan struct{})
+	inst.probeQuit = quit
+	inst.ping = nil
+	inst.readyUnix = 0
+
+	go func() {
+		interval := pingIntervalStarting
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-timer.C:
+			}
+
+			host, port, ok := detectServerListenAddr(instanceDir)
+			if !ok {
+				// server.properties is written on first boot; default port until then.
+				host, port = "", 25565
+			}
+			if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+				host = "127.0.0.1"
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+			st, err := slp.Ping(ctx, host, port)
+			cancel()
+
+			inst.mu.Lock()
+			if inst.probeQuit != quit {
+				inst.mu.Unlock()
+				return
+			}
+			becameReady := false
+			if err == nil {
+				inst.ping = &PingStatus{Status: st, CheckedUnix: time.Now().Unix()}
+				if inst.readyUnix == 0 {
+					inst.readyUnix = time.Now().Unix()
+					becameReady = true
+				}
+				interval = pingIntervalReady
+			} else {
+				inst.ping = nil
+			}
+			startedAt := inst.startedAt
+			inst.mu.Unlock()
+
+			if becameReady {
+				m.logf("mc ready: instance=%s after=%s", inst.ID, time.Since(startedAt).Round(time.Second))
+				if logSink != nil {
+					logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] server ready (%s:%d, %s, %d/%d players, ping %dms)",
+						host, port, st.VersionName, st.Online, st.Max, st.Latency.Milliseconds()))
+				}
+			}
+			timer.Reset(interval)
+		}
+	}()
+}
+
+// stopProberLocked stops the prober and clears ping state. Caller holds inst.mu.
+func (inst *Instance) stopProberLocked() {
+	if inst.probeQuit != nil {
+		close(inst.probeQuit)
+		inst.probeQuit = nil
+	}
+	inst.ping = nil
+	inst.readyUnix = 0
+}
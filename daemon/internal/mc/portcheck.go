@@ -13,18 +13,10 @@ import (
 )
 
 func detectServerListenAddr(instanceDir string) (string, int, bool) {
-	propsPath := filepath.Join(instanceDir, "server.properties")
-	f, err := os.Open(propsPath)
-	if err != nil {
-		return "", 0, false
-	}
-	defer f.Close()
-
-	b, err := io.ReadAll(io.LimitReader(f, 256*1024))
-	if err != nil {
+	text, ok := readServerProperties(instanceDir)
+	if !ok {
 		return "", 0, false
 	}
-	text := string(b)
 
 	portStr := strings.TrimSpace(getPropValue(text, "server-port"))
 	if portStr == "" {
@@ -45,6 +37,21 @@ func detectServerListenAddr(instanceDir string) (string, int, bool) {
 	return host, port, true
 }
 
+func readServerProperties(instanceDir string) (string, bool) {
+	propsPath := filepath.Join(instanceDir, "server.properties")
+	f, err := os.Open(propsPath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(io.LimitReader(f, 256*1024))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
 func getPropValue(text string, key string) string {
 	k := key + "="
 	for _, raw := range strings.Split(text, "\n") {
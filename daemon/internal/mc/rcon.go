@@ -0,0 +1,81 @@
+package mc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"elegantmc/daemon/internal/rcon"
+)
+
+type RCONConfig struct {
+	Enabled  bool
+	Host     string
+	Port     int
+	Password string
+}
+
+// DetectRCONConfig reads enable-rcon / rcon.port / rcon.password from server.properties.
+func DetectRCONConfig(instanceDir string) (RCONConfig, error) {
+	text, ok := readServerProperties(instanceDir)
+	if !ok {
+		return RCONConfig{}, errors.New("server.properties not found")
+	}
+	cfg := RCONConfig{
+		Enabled:  strings.EqualFold(strings.TrimSpace(getPropValue(text, "enable-rcon")), "true"),
+		Port:     25575,
+		Password: getPropValue(text, "rcon.password"),
+	}
+	if portStr := strings.TrimSpace(getPropValue(text, "rcon.port")); portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 1 || port > 65535 {
+			return RCONConfig{}, errors.New("invalid rcon.port in server.properties")
+		}
+		cfg.Port = port
+	}
+	// RCON binds to server-ip when set; otherwise loopback reaches the wildcard listener.
+	cfg.Host = "127.0.0.1"
+	if host := strings.TrimSpace(getPropValue(text, "server-ip")); host != "" {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+			cfg.Host = host
+		}
+	}
+	return cfg, nil
+}
+
+// RCONCommand runs a console command over RCON and returns the server's response.
+// Unlike SendConsole it works for adopted or externally started servers and ties output to the command.
+func (m *Manager) RCONCommand(ctx context.Context, instanceID string, command string) (string, error) {
+	if m.cfg.ServersFS == nil {
+		return "", errors.New("servers filesystem not configured")
+	}
+	dir, err := m.cfg.ServersFS.Resolve(instanceID)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := DetectRCONConfig(dir)
+	if err != nil {
+		return "", err
+	}
+	if !cfg.Enabled {
+		return "", errors.New("rcon is disabled (set enable-rcon=true in server.properties)")
+	}
+	if cfg.Password == "" {
+		return "", errors.New("rcon.password is empty in server.properties")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+	}
+	c, err := rcon.Dial(ctx, net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), cfg.Password)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	return c.Command(ctx, command)
+}
@@ -0,0 +1,76 @@
+package mc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResourceLimits are per-instance cgroup v2 limits, stored as "resource_limits" in
+// servers/<instance>/.elegantmc.json. Zero values mean "no limit".
+type ResourceLimits struct {
+	MemoryMax string  `json:"memory_max,omitempty"` // e.g. "6G", "4096M" or bytes
+	CPUs      float64 `json:"cpus,omitempty"`       // CPU cores, e.g. 2 or 1.5 (cpu.max)
+	PidsMax   int     `json:"pids_max,omitempty"`
+	IOWeight  int     `json:"io_weight,omitempty"` // 1-10000 (io.weight, default 100)
+}
+
+func (l ResourceLimits) IsZero() bool {
+	return strings.TrimSpace(l.MemoryMax) == "" && l.CPUs == 0 && l.PidsMax == 0 && l.IOWeight == 0
+}
+
+func (l ResourceLimits) Validate() error {
+	if _, err := l.memoryMaxBytes(); err != nil {
+		return err
+	}
+	if l.CPUs < 0 || l.CPUs > 1024 {
+		return errors.New("resource_limits.cpus must be 0-1024")
+	}
+	if l.PidsMax < 0 {
+		return errors.New("resource_limits.pids_max must be >= 0")
+	}
+	if l.IOWeight != 0 && (l.IOWeight < 1 || l.IOWeight > 10000) {
+		return errors.New("resource_limits.io_weight must be 1-10000")
+	}
+	return nil
+}
+
+func (l ResourceLimits) memoryMaxBytes() (uint64, error) {
+	s := strings.TrimSpace(l.MemoryMax)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := parseByteSize(s)
+	if err != nil || n < 64*1024*1024 {
+		return 0, fmt.Errorf("invalid resource_limits.memory_max: %q (min 64M)", l.MemoryMax)
+	}
+	return n, nil
+}
+
+// parseByteSize parses sizes in the -Xmx style: 512M, 4G, 1T, 2GiB or plain bytes.
+func parseByteSize(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+	mult := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid size")
+	}
+	return uint64(n * float64(mult)), nil
+}
@@ -0,0 +1,252 @@
+package mc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// RestartPolicy controls whether the manager brings an instance back after the
+// Java process exits without a stop being requested.
+// It is stored as "restart_policy" in servers/<instance>/.elegantmc.json.
+type RestartPolicy struct {
+	Mode           string `json:"mode"`                       // "never" | "on-failure" | "always"
+	MaxRetries     int    `json:"max_retries,omitempty"`      // restarts allowed inside WindowSec (default 5)
+	WindowSec      int    `json:"window_sec,omitempty"`       // sliding window for MaxRetries (default 600)
+	BackoffBaseSec int    `json:"backoff_base_sec,omitempty"` // first delay, doubled per retry (default 5)
+	BackoffMaxSec  int    `json:"backoff_max_sec,omitempty"`  // delay cap (default 300)
+}
+
+// Normalize fills defaults and validates the policy.
+func (p RestartPolicy) Normalize() (RestartPolicy, error) {
+	p.Mode = strings.ToLower(strings.TrimSpace(p.Mode))
+	switch p.Mode {
+	case "", RestartNever:
+		p.Mode = RestartNever
+	case "on_failure", "onfailure":
+		p.Mode = RestartOnFailure
+	case RestartOnFailure, RestartAlways:
+	default:
+		return RestartPolicy{}, fmt.Errorf("invalid restart mode: %s (allowed: never/on-failure/always)", p.Mode)
+	}
+	if p.MaxRetries < 0 || p.WindowSec < 0 || p.BackoffBaseSec < 0 || p.BackoffMaxSec < 0 {
+		return RestartPolicy{}, errors.New("restart policy values must be >= 0")
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 5
+	}
+	if p.MaxRetries > 100 {
+		p.MaxRetries = 100
+	}
+	if p.WindowSec == 0 {
+		p.WindowSec = 600
+	}
+	if p.BackoffBaseSec == 0 {
+		p.BackoffBaseSec = 5
+	}
+	if p.BackoffMaxSec == 0 {
+		p.BackoffMaxSec = 300
+	}
+	if p.BackoffMaxSec < p.BackoffBaseSec {
+		p.BackoffMaxSec = p.BackoffBaseSec
+	}
+	return p, nil
+}
+
+func (p RestartPolicy) shouldRestart(crashed bool) bool {
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return crashed
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before restart attempt n (0-based).
+func (p RestartPolicy) backoff(n int) time.Duration {
+	d := time.Duration(p.BackoffBaseSec) * time.Second
+	max := time.Duration(p.BackoffMaxSec) * time.Second
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// restartPolicyFor returns override, or the instance's configured restart_policy when it is nil.
+func (m *Manager) restartPolicyFor(instanceID string, override *RestartPolicy) (RestartPolicy, error) {
+	policy := RestartPolicy{Mode: RestartNever}
+	if override != nil {
+		policy = *override
+	} else if m.cfg.ServersFS != nil {
+		if dir, err := m.cfg.ServersFS.Resolve(instanceID); err == nil {
+			if cfg, err := readInstanceConfigFile(dir); err == nil && cfg.RestartPolicy != nil {
+				policy = *cfg.RestartPolicy
+			}
+		}
+	}
+	return policy.Normalize()
+}
+
+// SetRestartPolicy replaces the restart policy of a running instance without restarting it
+// (nil re-reads restart_policy from the instance config, as Start does).
+func (m *Manager) SetRestartPolicy(instanceID string, override *RestartPolicy) error {
+	policy, err := m.restartPolicyFor(instanceID, override)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	inst := m.instances[instanceID]
+	m.mu.Unlock()
+	if inst == nil {
+		return errors.New("instance not running")
+	}
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.proc == nil {
+		return errors.New("instance not running")
+	}
+	inst.restart.policy = policy
+	inst.restart.opt.Restart = override
+	return nil
+}
+
+type restartState struct {
+	policy  RestartPolicy
+	ctx     context.Context
+	opt     StartOptions
+	logSink func(instanceID, stream, line string)
+
+	history    []time.Time // auto-restarts inside the current window
+	count      int         // auto-restarts since the last manual start
+	nextUnix   int64
+	gaveUp     bool
+	timer      *time.Timer
+	generation int
+}
+
+// armRestart records the policy and start parameters used for automatic restarts.
+// Counters are reset since this is a manual (panel/scheduler) start.
+func (inst *Instance) armRestart(ctx context.Context, policy RestartPolicy, opt StartOptions, logSink func(instanceID, stream, line string)) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if inst.proc != nil {
+		return
+	}
+	inst.cancelRestartLocked()
+	inst.restart = restartState{
+		policy:     policy,
+		ctx:        ctx,
+		opt:        opt,
+		logSink:    logSink,
+		generation: inst.restart.generation,
+	}
+}
+
+// cancelRestartLocked drops a pending restart. Caller must hold inst.mu.
+func (inst *Instance) cancelRestartLocked() {
+	if inst.restart.timer != nil {
+		inst.restart.timer.Stop()
+		inst.restart.timer = nil
+	}
+	inst.restart.nextUnix = 0
+	inst.restart.generation++
+}
+
+func (m *Manager) handleInstanceExit(inst *Instance, exitCode *int, exitSignal string, requested bool) {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	rs := &inst.restart
+	if requested || rs.ctx == nil || rs.ctx.Err() != nil {
+		return
+	}
+	crashed := exitSignal != "" || exitCode == nil || *exitCode != 0
+	if !rs.policy.shouldRestart(crashed) {
+		return
+	}
+
+	now := time.Now()
+	window := time.Duration(rs.policy.WindowSec) * time.Second
+	kept := rs.history[:0]
+	for _, t := range rs.history {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	rs.history = kept
+
+	reason := "exited"
+	if exitSignal != "" {
+		reason = fmt.Sprintf("exited (signal=%s)", exitSignal)
+	} else if exitCode != nil {
+		reason = fmt.Sprintf("exited (code=%d)", *exitCode)
+	}
+
+	if len(rs.history) >= rs.policy.MaxRetries {
+		rs.gaveUp = true
+		rs.nextUnix = 0
+		m.logf("mc restart gave up: instance=%s retries=%d window=%ds", inst.ID, len(rs.history), rs.policy.WindowSec)
+		if rs.logSink != nil {
+			rs.logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] server %s; giving up after %d restarts in %ds", reason, len(rs.history), rs.policy.WindowSec))
+		}
+		return
+	}
+
+	delay := rs.policy.backoff(len(rs.history))
+	rs.history = append(rs.history, now)
+	rs.count++
+	rs.nextUnix = now.Add(delay).Unix()
+	rs.generation++
+	gen := rs.generation
+	rs.timer = time.AfterFunc(delay, func() { m.runScheduledRestart(inst, gen) })
+
+	m.logf("mc restart scheduled: instance=%s in=%s attempt=%d/%d", inst.ID, delay, len(rs.history), rs.policy.MaxRetries)
+	if rs.logSink != nil {
+		rs.logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] server %s; restarting in %s (attempt %d/%d)", reason, delay, len(rs.history), rs.policy.MaxRetries))
+	}
+}
+
+func (m *Manager) runScheduledRestart(inst *Instance, gen int) {
+	inst.mu.Lock()
+	if inst.restart.generation != gen {
+		inst.mu.Unlock()
+		return
+	}
+	inst.restart.timer = nil
+	inst.restart.nextUnix = 0
+	ctx := inst.restart.ctx
+	opt := inst.restart.opt
+	logSink := inst.restart.logSink
+	inst.mu.Unlock()
+
+	if ctx == nil || ctx.Err() != nil {
+		return
+	}
+	if err := inst.start(ctx, m, opt, logSink); err != nil {
+		m.logf("mc restart failed: instance=%s err=%v", inst.ID, err)
+		if logSink != nil {
+			logSink(inst.ID, "stdout", fmt.Sprintf("[elegantmc] restart failed: %v", err))
+		}
+		// Count a failed launch as another crash so backoff and retry limits apply.
+		m.handleInstanceExit(inst, nil, "", false)
+	}
+}
+
+func (m *Manager) logf(format string, args ...any) {
+	if m.cfg.Log != nil {
+		m.cfg.Log.Printf(format, args...)
+	}
+}
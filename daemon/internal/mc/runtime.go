@@ -0,0 +1,479 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"elegantmc/daemon/internal/sysinfo"
+)
+
+// runtimeState is persisted to <RuntimeDir>/<instance>.json while a server runs.
+type runtimeState struct {
+	InstanceID        string   `json:"instance_id"`
+	PID               int      `json:"pid"`
+	StartTicks        uint64   `json:"start_ticks,omitempty"`
+	StartedAtUnix     int64    `json:"started_at_unix"`
+	InstanceDir       string   `json:"instance_dir"`
+	JarRel            string   `json:"jar_rel"`
+	Java              string   `json:"java"`
+	JavaMajor         int      `json:"java_major,omitempty"`
+	RequiredJavaMajor int      `json:"required_java_major,omitempty"`
+	Args              []string `json:"args"`
+	ConsoleFIFO       string   `json:"console_fifo,omitempty"`
+	ConsoleLogs       bool     `json:"console_logs,omitempty"` // output goes to <RuntimeDir>/<instance>.<stream>.log
+	ListenHost        string   `json:"listen_host,omitempty"`
+	ListenPort        int      `json:"listen_port,omitempty"`
+	Cgroup            string   `json:"cgroup,omitempty"`
+
+	// Original start options, used when the restart policy relaunches an adopted server.
+	JavaPath  string   `json:"java_path,omitempty"`
+	Xms       string   `json:"xms,omitempty"`
+	Xmx       string   `json:"xmx,omitempty"`
+	JvmArgs   []string `json:"jvm_args,omitempty"`
+	ExtraArgs []string `json:"extra_args,omitempty"`
+	// JvmProfile is the expanded profile; Xms/Xmx keep "auto" so a restart re-sizes.
+	JvmProfile string `json:"jvm_profile,omitempty"`
+}
+
+func (m *Manager) runtimeStatePath(instanceID string) string {
+	dir := strings.TrimSpace(m.cfg.RuntimeDir)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, instanceID+".json")
+}
+
+func (m *Manager) consoleFIFOPath(instanceID string) string {
+	dir := strings.TrimSpace(m.cfg.RuntimeDir)
+	if dir == "" || !consoleFIFOSupported {
+		return ""
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ""
+	}
+	return filepath.Join(dir, instanceID+".stdin")
+}
+
+// consoleLogStreams are the output streams of a detached server, in the order
+// openConsoleLogs returns their files.
+var consoleLogStreams = [...]string{"stdout", "stderr"}
+
+// consoleLogTruncateAt is the size at which a fully read console log is truncated. The
+// server's fd is O_APPEND, so it keeps writing at the new end; output written between the
+// last read and the truncate is lost.
+const consoleLogTruncateAt = 8 << 20
+
+func (m *Manager) consoleLogPath(instanceID, stream string) string {
+	dir := strings.TrimSpace(m.cfg.RuntimeDir)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, instanceID+"."+stream+".log")
+}
+
+// openConsoleLogs creates the stdout/stderr files of a detached server. A detached server
+// must not write to pipes: they break (SIGPIPE) when the daemon exits, and files can be
+// tailed again after adoption. It returns nil when servers are not detached.
+func (m *Manager) openConsoleLogs(instanceID string) ([]*os.File, error) {
+	if !m.cfg.Detach || strings.TrimSpace(m.cfg.RuntimeDir) == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(m.cfg.RuntimeDir, 0o700); err != nil {
+		return nil, err
+	}
+	files := make([]*os.File, 0, len(consoleLogStreams))
+	for _, stream := range consoleLogStreams {
+		f, err := os.OpenFile(m.consoleLogPath(instanceID, stream), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o600)
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// followConsoleLogs forwards the console log files of a detached server until quit is
+// closed. The returned channel is closed once everything written before that was delivered.
+func (m *Manager) followConsoleLogs(instanceID string, fromStart bool, quit <-chan struct{}, logSink func(instanceID, stream, line string)) <-chan struct{} {
+	var wg sync.WaitGroup
+	for _, stream := range consoleLogStreams {
+		stream := stream
+		parser := m.consoleParser(instanceID, stream)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tailFile(m.consoleLogPath(instanceID, stream), fromStart, consoleLogTruncateAt, quit, func(line string) {
+				if logSink != nil {
+					logSink(instanceID, stream, line)
+				}
+				parser.Feed(line)
+			})
+			parser.Flush()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// saveRuntimeState writes the state file for a freshly started process. Caller holds inst.mu.
+func (m *Manager) saveRuntimeState(inst *Instance, opt StartOptions, instanceDir string, fifoPath string, consoleLogs bool) {
+	p := m.runtimeStatePath(inst.ID)
+	if p == "" || inst.proc == nil {
+		return
+	}
+	st := runtimeState{
+		InstanceID:        inst.ID,
+		PID:               inst.proc.Pid,
+		StartedAtUnix:     inst.startedAt.Unix(),
+		InstanceDir:       instanceDir,
+		JarRel:            inst.jarRel,
+		Java:              inst.java,
+		JavaMajor:         inst.javaMajor,
+		RequiredJavaMajor: inst.requiredJavaMajor,
+		Args:              inst.args,
+		ConsoleFIFO:       fifoPath,
+		ConsoleLogs:       consoleLogs,
+		Cgroup:            inst.cgroupPath,
+		JavaPath:          opt.JavaPath,
+		Xms:               opt.Xms,
+		Xmx:               opt.Xmx,
+		JvmArgs:           opt.JvmArgs,
+		ExtraArgs:         opt.ExtraArgs,
+		JvmProfile:        inst.jvmProfile,
+	}
+	if ticks, err := sysinfo.ReadProcStartTicks(st.PID); err == nil {
+		st.StartTicks = ticks
+	}
+	if host, port, ok := detectServerListenAddr(instanceDir); ok {
+		st.ListenHost = host
+		st.ListenPort = port
+	}
+	if err := writeJSONFileAtomic(p, st); err != nil {
+		m.logf("mc: write runtime state failed (instance=%s): %v", inst.ID, err)
+	}
+}
+
+func (m *Manager) removeRuntimeState(instanceID string) {
+	if p := m.runtimeStatePath(instanceID); p != "" {
+		_ = os.Remove(p)
+	}
+	if p := m.consoleFIFOPath(instanceID); p != "" {
+		_ = os.Remove(p)
+	}
+	for _, stream := range consoleLogStreams {
+		if p := m.consoleLogPath(instanceID, stream); p != "" {
+			_ = os.Remove(p)
+		}
+	}
+}
+
+// processMatches reports whether pid is still the process recorded in the state file.
+func processMatches(st runtimeState) bool {
+	if st.PID <= 0 {
+		return false
+	}
+	ticks, err := sysinfo.ReadProcStartTicks(st.PID)
+	if err != nil {
+		return false
+	}
+	if st.StartTicks != 0 && ticks != st.StartTicks {
+		return false
+	}
+	return true
+}
+
+// Adopt re-attaches servers left running by a previous daemon process, using the
+// runtime state files. Stale state files are removed. It returns the adopted instance ids.
+// Only Linux can verify a pid's start time, so elsewhere nothing is adopted.
+func (m *Manager) Adopt(ctx context.Context, logSink func(instanceID, stream, line string)) []string {
+	dir := strings.TrimSpace(m.cfg.RuntimeDir)
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var adopted []string
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var st runtimeState
+		if err := json.Unmarshal(b, &st); err != nil || st.InstanceID == "" || st.InstanceID+".json" != name {
+			continue
+		}
+		if !processMatches(st) {
+			if runtime.GOOS != "linux" {
+				// No process start times to verify the pid against: never adopt blindly.
+				m.logf("mc adopt: not supported on %s, dropping state of instance=%s pid=%d", runtime.GOOS, st.InstanceID, st.PID)
+			}
+			m.removeRuntimeState(st.InstanceID)
+			continue
+		}
+		if err := m.adoptOne(ctx, st, logSink); err != nil {
+			m.logf("mc adopt failed: instance=%s pid=%d err=%v", st.InstanceID, st.PID, err)
+			continue
+		}
+		adopted = append(adopted, st.InstanceID)
+	}
+	return adopted
+}
+
+func (m *Manager) adoptOne(ctx context.Context, st runtimeState, logSink func(instanceID, stream, line string)) error {
+	// Paths in the state file are not trusted: derive them from the instance id.
+	if m.cfg.ServersFS == nil {
+		return errors.New("servers filesystem not configured")
+	}
+	instanceDir, err := m.cfg.ServersFS.Resolve(st.InstanceID)
+	if err != nil {
+		return err
+	}
+	st.InstanceDir = instanceDir
+	if st.ConsoleFIFO != m.consoleFIFOPath(st.InstanceID) {
+		st.ConsoleFIFO = ""
+	}
+	if st.Cgroup != "" && filepath.Base(st.Cgroup) != "elegantmc-"+st.InstanceID {
+		st.Cgroup = ""
+	}
+
+	proc, err := os.FindProcess(st.PID)
+	if err != nil {
+		return err
+	}
+
+	var stdin io.WriteCloser
+	if st.ConsoleFIFO != "" {
+		if fi, err := os.Stat(st.ConsoleFIFO); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+			if f, err := openConsoleFIFO(st.ConsoleFIFO); err == nil {
+				stdin = f
+			}
+		}
+	}
+
+	m.mu.Lock()
+	inst := m.instances[st.InstanceID]
+	if inst == nil {
+		inst = &Instance{ID: st.InstanceID, onExit: m.handleInstanceExit}
+		m.instances[st.InstanceID] = inst
+	}
+	m.mu.Unlock()
+
+	opt := StartOptions{
+		InstanceID: st.InstanceID,
+		JarPath:    st.JarRel,
+		JavaPath:   st.JavaPath,
+		Xms:        st.Xms,
+		Xmx:        st.Xmx,
+		JvmArgs:    st.JvmArgs,
+		ExtraArgs:  st.ExtraArgs,
+		JvmProfile: st.JvmProfile,
+	}
+	policy := RestartPolicy{Mode: RestartNever}
+	if cfg, err := readInstanceConfigFile(st.InstanceDir); err == nil && cfg.RestartPolicy != nil {
+		policy = *cfg.RestartPolicy
+	}
+	if p, err := policy.Normalize(); err == nil {
+		inst.armRestart(ctx, p, opt, logSink)
+	}
+
+	done := make(chan error, 1)
+	quit := make(chan struct{})
+
+	inst.mu.Lock()
+	if inst.proc != nil {
+		inst.mu.Unlock()
+		if stdin != nil {
+			_ = stdin.Close()
+		}
+		return fmt.Errorf("instance already running")
+	}
+	inst.proc = proc
+	inst.adopted = true
+	inst.stdin = stdin
+	inst.done = done
+	inst.jarRel = st.JarRel
+	inst.java = st.Java
+	inst.javaMajor = st.JavaMajor
+	inst.requiredJavaMajor = st.RequiredJavaMajor
+	inst.jvmProfile = st.JvmProfile
+	inst.args = st.Args
+	inst.startedAt = time.Unix(st.StartedAtUnix, 0)
+	m.reserveHeap(inst.ID, heapFromArgs(st.Args, hostMemTotal()))
+	if st.Cgroup != "" {
+		if _, err := os.Stat(st.Cgroup); err == nil {
+			inst.cgroupPath = st.Cgroup
+		}
+	}
+	if st.ListenPort > 0 {
+		if key, err := reservePort(inst.ID, st.ListenHost, st.ListenPort); err == nil {
+			inst.portKey = key
+		}
+	}
+	inst.startProber(m, st.InstanceDir, logSink)
+	inst.mu.Unlock()
+
+	m.logf("mc adopted: instance=%s pid=%d console=%t", st.InstanceID, st.PID, stdin != nil)
+	if logSink != nil {
+		msg := fmt.Sprintf("[elegantmc] re-attached to running server (pid=%d)", st.PID)
+		if stdin == nil {
+			msg += "; console input unavailable"
+		}
+		logSink(st.InstanceID, "stdout", msg)
+	}
+	var tailDone <-chan struct{}
+	if st.ConsoleLogs {
+		tailDone = m.followConsoleLogs(st.InstanceID, false, quit, logSink)
+	} else {
+		// Started with pipes by an older daemon: the console output is gone, follow the
+		// server's own log instead.
+		parser := m.consoleParser(st.InstanceID, "stdout")
+		ch := make(chan struct{})
+		tailDone = ch
+		go func() {
+			defer close(ch)
+			tailFile(filepath.Join(st.InstanceDir, "logs", "latest.log"), false, 0, quit, func(line string) {
+				if logSink != nil {
+					logSink(st.InstanceID, "stdout", line)
+				}
+				parser.Feed(line)
+			})
+			parser.Flush()
+		}()
+	}
+
+	// The joins happened before the adoption: ask for the player list so console events
+	// (deaths) can be attributed again.
+	inst.mu.Lock()
+	if stdin != nil && inst.stdin == stdin {
+		_, _ = io.WriteString(stdin, "list\n")
+	}
+	inst.mu.Unlock()
+
+	go func() {
+		stopTail := func() {
+			close(quit)
+			<-tailDone
+		}
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				close(quit)
+				return
+			case <-ticker.C:
+			}
+			if code, sig, ok := reapProcess(st.PID); ok {
+				stopTail()
+				inst.finishExit(m, done, nil, code, sig, false)
+				return
+			}
+			if !processMatches(st) {
+				stopTail()
+				inst.finishExit(m, done, nil, nil, "", false)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// tailFile follows a growing text file until quit is closed, starting at its current end
+// (or at the beginning with fromStart), and delivers what was written before quit.
+// Truncation (log rotation) restarts reading from the beginning. With truncateAt > 0 the
+// file is truncated once it has been read up to that size.
+func tailFile(path string, fromStart bool, truncateAt int64, quit <-chan struct{}, onLine func(string)) {
+	var f *os.File
+	var offset int64
+	var partial string
+	defer func() {
+		if f != nil {
+			_ = f.Close()
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	drain := func() {
+		for {
+			n, err := f.ReadAt(buf, offset)
+			if n > 0 {
+				offset += int64(n)
+				text := partial + string(buf[:n])
+				lines := strings.Split(text, "\n")
+				partial = lines[len(lines)-1]
+				for _, line := range lines[:len(lines)-1] {
+					onLine(strings.TrimRight(line, "\r"))
+				}
+			}
+			if err != nil || n == 0 {
+				return
+			}
+		}
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	first := true
+	for {
+		if f == nil {
+			if nf, err := os.Open(path); err == nil {
+				f = nf
+				offset = 0
+				if first && !fromStart {
+					if end, err := f.Seek(0, io.SeekEnd); err == nil {
+						offset = end
+					}
+				}
+			}
+			first = false
+		}
+		if f != nil {
+			if st, err := os.Stat(path); err != nil || st.Size() < offset {
+				_ = f.Close()
+				f = nil
+				partial = ""
+				continue
+			}
+			drain()
+			if truncateAt > 0 && offset >= truncateAt {
+				if err := os.Truncate(path, 0); err == nil {
+					offset = 0
+				}
+			}
+		}
+		select {
+		case <-quit:
+			if f != nil {
+				drain()
+				if partial != "" {
+					onLine(strings.TrimRight(partial, "\r"))
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"elegantmc/daemon/internal/sandbox"
+	"elegantmc/daemon/internal/sysinfo"
+)
+
+func newRuntimeTestManager(t *testing.T) (*Manager, string, string) {
+	t.Helper()
+	base := t.TempDir()
+	serversRoot := filepath.Join(base, "servers")
+	runtimeDir := filepath.Join(base, "run")
+	for _, dir := range []string{serversRoot, runtimeDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	fs, err := sandbox.NewFS(serversRoot)
+	if err != nil {
+		t.Fatalf("sandbox.NewFS: %v", err)
+	}
+	m := NewManager(ManagerConfig{ServersFS: fs, RuntimeDir: runtimeDir, Detach: true})
+	return m, serversRoot, runtimeDir
+}
+
+func writeRuntimeState(t *testing.T, runtimeDir, name string, st runtimeState) {
+	t.Helper()
+	b, err := json.Marshal(st)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runtimeDir, name), b, 0o600); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+}
+
+// deadPID returns the pid of a process that has already exited and been reaped.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run true: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestProcessMatches(t *testing.T) {
+	self := os.Getpid()
+	ticks, err := sysinfo.ReadProcStartTicks(self)
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+	cases := []struct {
+		name string
+		st   runtimeState
+		want bool
+	}{
+		{"same process", runtimeState{PID: self, StartTicks: ticks}, true},
+		{"legacy state without start time", runtimeState{PID: self}, true},
+		{"pid reused by another process", runtimeState{PID: self, StartTicks: ticks + 1}, false},
+		{"no pid", runtimeState{PID: 0}, false},
+		{"exited process", runtimeState{PID: deadPID(t), StartTicks: ticks}, false},
+	}
+	for _, tc := range cases {
+		if got := processMatches(tc.st); got != tc.want {
+			t.Errorf("%s: processMatches() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAdopt_RemovesStaleState(t *testing.T) {
+	m, _, runtimeDir := newRuntimeTestManager(t)
+	writeRuntimeState(t, runtimeDir, "srv1.json", runtimeState{InstanceID: "srv1", PID: deadPID(t), StartTicks: 1})
+	for _, name := range []string{"srv1.stdin", "srv1.stdout.log", "srv1.stderr.log"} {
+		if err := os.WriteFile(filepath.Join(runtimeDir, name), nil, 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	// A state file whose name does not match its instance id is ignored, not acted upon.
+	writeRuntimeState(t, runtimeDir, "other.json", runtimeState{InstanceID: "srv2", PID: deadPID(t)})
+
+	if got := m.Adopt(context.Background(), nil); len(got) != 0 {
+		t.Fatalf("Adopt() = %v, want none", got)
+	}
+	for _, name := range []string{"srv1.json", "srv1.stdin", "srv1.stdout.log", "srv1.stderr.log"} {
+		if _, err := os.Stat(filepath.Join(runtimeDir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed, stat err=%v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(runtimeDir, "other.json")); err != nil {
+		t.Fatalf("mismatched state file should be left alone: %v", err)
+	}
+}
+
+func TestAdopt_FollowsConsoleLogsAndResolvesInstanceDir(t *testing.T) {
+	m, serversRoot, runtimeDir := newRuntimeTestManager(t)
+
+	// The restart policy is read from the resolved instance dir, not from the state file.
+	instDir := filepath.Join(serversRoot, "srv1")
+	if err := os.MkdirAll(instDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(instDir, ".elegantmc.json"), []byte(`{"restart_policy":{"mode":"always"}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	elsewhere := t.TempDir()
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+	ticks, err := sysinfo.ReadProcStartTicks(cmd.Process.Pid)
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+	stdoutLog := filepath.Join(runtimeDir, "srv1.stdout.log")
+	appendFile(t, stdoutLog, "written while the daemon was away\n")
+	writeRuntimeState(t, runtimeDir, "srv1.json", runtimeState{
+		InstanceID:  "srv1",
+		PID:         cmd.Process.Pid,
+		StartTicks:  ticks,
+		InstanceDir: elsewhere,
+		ConsoleLogs: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var c lineCollector
+	sink := func(instanceID, stream, line string) {
+		if stream == "stdout" && line != "" && line[0] != '[' {
+			c.add(line)
+		}
+	}
+	if got := m.Adopt(ctx, sink); len(got) != 1 || got[0] != "srv1" {
+		t.Fatalf("Adopt() = %v", got)
+	}
+	st := m.List()["srv1"]
+	if !st.Running || st.RestartPolicy != RestartAlways {
+		t.Fatalf("unexpected status after adopt: running=%v policy=%q", st.Running, st.RestartPolicy)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	appendFile(t, stdoutLog, "after adopt\n")
+	if got := c.waitLines(t, 1); got[0] != "after adopt" {
+		t.Fatalf("got %q", got)
+	}
+
+	// Stop the restart policy from relaunching "sleep" once it is killed.
+	if err := m.SetRestartPolicy("srv1", &RestartPolicy{Mode: RestartNever}); err != nil {
+		t.Fatalf("SetRestartPolicy(): %v", err)
+	}
+	_ = cmd.Process.Kill()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.List()["srv1"].Running {
+		if time.Now().After(deadline) {
+			t.Fatalf("adopted process exit not noticed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := os.Stat(stdoutLog); !os.IsNotExist(err) {
+		t.Fatalf("console log should be removed after exit, stat err=%v", err)
+	}
+}
+
+func TestAdopt_ReusedPIDAndMissingFIFO(t *testing.T) {
+	m, _, runtimeDir := newRuntimeTestManager(t)
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+	ticks, err := sysinfo.ReadProcStartTicks(cmd.Process.Pid)
+	if err != nil {
+		t.Skipf("no /proc: %v", err)
+	}
+
+	// srv1's pid now belongs to a process that started later: not ours.
+	writeRuntimeState(t, runtimeDir, "srv1.json", runtimeState{InstanceID: "srv1", PID: cmd.Process.Pid, StartTicks: ticks - 1})
+	// srv2 is ours, but its console FIFO is gone.
+	fifo := m.consoleFIFOPath("srv2")
+	writeRuntimeState(t, runtimeDir, "srv2.json", runtimeState{InstanceID: "srv2", PID: cmd.Process.Pid, StartTicks: ticks, ConsoleFIFO: fifo})
+
+	got := m.Adopt(context.Background(), nil)
+	if len(got) != 1 || got[0] != "srv2" {
+		t.Fatalf("Adopt() = %v, want [srv2]", got)
+	}
+	if _, err := os.Stat(filepath.Join(runtimeDir, "srv1.json")); !os.IsNotExist(err) {
+		t.Fatalf("state of the reused pid should be removed, stat err=%v", err)
+	}
+	if st := m.List()["srv1"]; st.Running {
+		t.Fatalf("reused pid must not be adopted")
+	}
+	if st := m.List()["srv2"]; !st.Running || !st.Adopted {
+		t.Fatalf("srv2 should be adopted: %+v", st)
+	}
+	if err := m.SendConsole(context.Background(), "srv2", "list"); err == nil {
+		t.Fatalf("console should be unavailable without the FIFO")
+	}
+}
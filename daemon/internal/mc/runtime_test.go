@@ -0,0 +1,125 @@
+package mc
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lineCollector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *lineCollector) add(line string) {
+	c.mu.Lock()
+	c.lines = append(c.lines, line)
+	c.mu.Unlock()
+}
+
+func (c *lineCollector) snapshot() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+// waitLines waits until the collector holds n lines.
+func (c *lineCollector) waitLines(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := c.snapshot(); len(got) >= n {
+			return got
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d lines, got %q", n, c.snapshot())
+	return nil
+}
+
+func appendFile(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestTailFile_FollowsAndHandlesRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latest.log")
+	appendFile(t, path, "before start\n")
+
+	var c lineCollector
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tailFile(path, false, 0, quit, c.add)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	appendFile(t, path, "one\ntw")
+	appendFile(t, path, "o\n")
+	if got := c.waitLines(t, 2); got[0] != "one" || got[1] != "two" {
+		t.Fatalf("got %q", got)
+	}
+
+	// Rotation: the file is replaced by a shorter one and read from the beginning.
+	if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if got := c.waitLines(t, 3); got[2] != "new" {
+		t.Fatalf("after rotation got %q", got)
+	}
+
+	// Output written right before quit (including an unterminated line) is delivered.
+	appendFile(t, path, "last\npartial")
+	close(quit)
+	<-done
+	got := c.snapshot()
+	if len(got) != 5 || got[3] != "last" || got[4] != "partial" {
+		t.Fatalf("after quit got %q", got)
+	}
+}
+
+func TestTailFile_TruncatesConsumedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "srv.stdout.log")
+	appendFile(t, path, "first line\n")
+
+	var c lineCollector
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tailFile(path, true, 8, quit, c.add)
+	}()
+	defer func() {
+		close(quit)
+		<-done
+	}()
+
+	if got := c.waitLines(t, 1); got[0] != "first line" {
+		t.Fatalf("got %q", got)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := os.Stat(path)
+		if err == nil && st.Size() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("file was not truncated after being read")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	appendFile(t, path, "after truncate\n")
+	if got := c.waitLines(t, 2); got[1] != "after truncate" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package mc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var defaultWarnIntervals = []int{600, 300, 120, 60, 30, 10, 5, 4, 3, 2, 1}
+
+// StopOptions controls how a running server is shut down.
+type StopOptions struct {
+	// WarnSec broadcasts a countdown to players for this many seconds before stopping (0 = none).
+	WarnSec int
+	// WarnIntervals are the remaining-seconds marks at which a warning is sent
+	// (default 600/300/120/60/30/10/5..1, limited to WarnSec).
+	WarnIntervals []int
+	// WarnMessage is the broadcast text; "{seconds}" is replaced with the remaining time.
+	WarnMessage string
+	// Tellraw broadcasts with tellraw (yellow text) instead of say.
+	Tellraw bool
+	// SaveAll sends "save-all flush" before "stop".
+	SaveAll bool
+	// TimeoutSec is how long to wait for the server to exit after "stop" before SIGTERM.
+	TimeoutSec int
+	// TermTimeoutSec is how long to wait after SIGTERM before SIGKILL.
+	TermTimeoutSec int
+	// Progress receives human readable progress lines (optional).
+	Progress func(line string)
+}
+
+// DefaultStopOptions keeps the historical stop budget: "stop", then at most 20s in total
+// before the process is killed. Longer waits, save-all and countdowns are opt-in.
+func DefaultStopOptions() StopOptions {
+	return StopOptions{TimeoutSec: defaultStopTimeoutSec, TermTimeoutSec: defaultTermTimeoutSec}
+}
+
+const (
+	defaultStopTimeoutSec = 15
+	defaultTermTimeoutSec = 5
+)
+
+func (o StopOptions) normalize() StopOptions {
+	if o.WarnSec < 0 {
+		o.WarnSec = 0
+	}
+	if o.WarnSec > 3600 {
+		o.WarnSec = 3600
+	}
+	if o.TimeoutSec <= 0 {
+		o.TimeoutSec = defaultStopTimeoutSec
+	}
+	if o.TimeoutSec > 3600 {
+		o.TimeoutSec = 3600
+	}
+	if o.TermTimeoutSec <= 0 {
+		o.TermTimeoutSec = defaultTermTimeoutSec
+	}
+	if o.TermTimeoutSec > 600 {
+		o.TermTimeoutSec = 600
+	}
+	if strings.TrimSpace(o.WarnMessage) == "" {
+		o.WarnMessage = "Server will stop in {seconds}s"
+	}
+	o.WarnMessage = strings.NewReplacer("\r", " ", "\n", " ").Replace(o.WarnMessage)
+
+	intervals := o.WarnIntervals
+	if len(intervals) == 0 {
+		intervals = defaultWarnIntervals
+	}
+	seen := make(map[int]bool)
+	var marks []int
+	for _, v := range intervals {
+		if v <= 0 || v > o.WarnSec || seen[v] {
+			continue
+		}
+		seen[v] = true
+		marks = append(marks, v)
+	}
+	if o.WarnSec > 0 && !seen[o.WarnSec] {
+		marks = append(marks, o.WarnSec)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(marks)))
+	o.WarnIntervals = marks
+	return o
+}
+
+// StopWithOptions stops an instance gracefully: optional countdown, save-all, "stop",
+// then SIGTERM and finally SIGKILL if the server does not exit in time.
+func (m *Manager) StopWithOptions(ctx context.Context, instanceID string, opt StopOptions) error {
+	m.mu.Lock()
+	inst := m.instances[instanceID]
+	m.mu.Unlock()
+	if inst == nil {
+		return nil
+	}
+	if opt.Progress == nil {
+		opt.Progress = func(line string) { m.logf("mc stop: instance=%s %s", instanceID, line) }
+	}
+	return inst.stop(ctx, m, opt.normalize())
+}
+
+func (inst *Instance) stop(ctx context.Context, m *Manager, opt StopOptions) error {
+	inst.mu.Lock()
+	inst.cancelRestartLocked()
+	proc := inst.proc
+	stdin := inst.stdin
+	done := inst.done
+	if proc != nil {
+		inst.stopRequested = true
+	}
+	inst.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+	if done == nil {
+		_ = proc.Kill()
+		return nil
+	}
+
+	// Warning countdown.
+	if stdin != nil && opt.WarnSec > 0 {
+		opt.Progress(fmt.Sprintf("warning players, stopping in %ds", opt.WarnSec))
+		deadline := time.Now().Add(time.Duration(opt.WarnSec) * time.Second)
+		for _, remaining := range opt.WarnIntervals {
+			wait := time.Until(deadline.Add(-time.Duration(remaining) * time.Second))
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					inst.mu.Lock()
+					inst.stopRequested = false
+					inst.mu.Unlock()
+					return ctx.Err()
+				case err := <-done:
+					opt.Progress(fmt.Sprintf("server exited during countdown (err=%v)", err))
+					return nil
+				case <-time.After(wait):
+				}
+			}
+			_, _ = io.WriteString(stdin, broadcastCommand(opt, remaining))
+		}
+		select {
+		case <-ctx.Done():
+			inst.mu.Lock()
+			inst.stopRequested = false
+			inst.mu.Unlock()
+			return ctx.Err()
+		case err := <-done:
+			opt.Progress(fmt.Sprintf("server exited during countdown (err=%v)", err))
+			return nil
+		case <-time.After(time.Until(deadline)):
+		}
+	}
+
+	if stdin != nil {
+		if opt.SaveAll {
+			opt.Progress("save-all flush")
+			_, _ = io.WriteString(stdin, "save-all flush\n")
+		}
+		opt.Progress(fmt.Sprintf("sending stop (timeout %ds)", opt.TimeoutSec))
+		_, _ = io.WriteString(stdin, "stop\n")
+	} else {
+		// No console (e.g. adopted without FIFO): go straight to SIGTERM.
+		opt.TimeoutSec = 0
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		opt.Progress("stopped")
+		m.logf("mc stopped: instance=%s err=%v", inst.ID, err)
+		return nil
+	case <-time.After(time.Duration(opt.TimeoutSec) * time.Second):
+	}
+
+	opt.Progress(fmt.Sprintf("sending SIGTERM (timeout %ds)", opt.TermTimeoutSec))
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		_ = proc.Kill()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		opt.Progress("stopped after SIGTERM")
+		m.logf("mc terminated: instance=%s", inst.ID)
+		return nil
+	case <-time.After(time.Duration(opt.TermTimeoutSec) * time.Second):
+	}
+
+	opt.Progress("sending SIGKILL")
+	_ = proc.Kill()
+	<-done
+	opt.Progress("killed")
+	m.logf("mc killed: instance=%s", inst.ID)
+	return nil
+}
+
+func broadcastCommand(opt StopOptions, remaining int) string {
+	msg := strings.ReplaceAll(opt.WarnMessage, "{seconds}", strconv.Itoa(remaining))
+	if opt.Tellraw {
+		b, _ := json.Marshal(map[string]any{"text": msg, "color": "yellow"})
+		return "tellraw @a " + string(b) + "\n"
+	}
+	return "say " + msg + "\n"
+}
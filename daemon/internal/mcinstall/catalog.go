@@ -0,0 +1,262 @@
+package mcinstall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CatalogSources are the API base URLs used to list installable versions (empty = default).
+type CatalogSources struct {
+	MojangMetaBaseURL    string
+	PaperAPIBaseURL      string
+	PurpurAPIBaseURL     string
+	FabricMetaBaseURL    string
+	QuiltMetaBaseURL     string
+	ForgeMavenBaseURL    string
+	NeoForgeMavenBaseURL string
+}
+
+// CatalogVersion is an installable game (or proxy) version.
+type CatalogVersion struct {
+	ID          string `json:"id"`
+	Type        string `json:"type,omitempty"` // vanilla: release | snapshot | old_beta | old_alpha
+	Stable      bool   `json:"stable"`
+	ReleaseTime string `json:"release_time,omitempty"`
+	JavaMajor   int    `json:"java_major,omitempty"`
+}
+
+// CatalogBuild is a build (Paper, Purpur) or loader version (Fabric, Quilt, Forge,
+// NeoForge) of one game version.
+type CatalogBuild struct {
+	ID     string `json:"id"`
+	Stable bool   `json:"stable"`
+}
+
+// Catalog lists versions of a software, or the builds of one version when Version is set.
+// Both lists are newest first.
+type Catalog struct {
+	Software string           `json:"software"`
+	Version  string           `json:"version,omitempty"`
+	Versions []CatalogVersion `json:"versions,omitempty"`
+	Builds   []CatalogBuild   `json:"builds,omitempty"`
+}
+
+// FetchCatalog lists the versions of software, or the builds of version if it is set.
+func FetchCatalog(ctx context.Context, src CatalogSources, software, version string) (Catalog, error) {
+	software = strings.ToLower(strings.TrimSpace(software))
+	version = strings.TrimSpace(version)
+	cat := Catalog{Software: software, Version: version}
+	var err error
+	switch software {
+	case "vanilla":
+		if version != "" {
+			return Catalog{}, errors.New("vanilla has no builds")
+		}
+		cat.Versions, err = vanillaCatalog(ctx, src.MojangMetaBaseURL)
+	case "purpur":
+		if version == "" {
+			cat.Versions, err = purpurVersions(ctx, src.PurpurAPIBaseURL)
+		} else {
+			cat.Builds, err = purpurBuilds(ctx, src.PurpurAPIBaseURL, version)
+		}
+	case "fabric":
+		cat.Versions, cat.Builds, err = loaderCatalog(ctx, orDefault(src.FabricMetaBaseURL, "https://meta.fabricmc.net")+"/v2", version)
+	case "quilt":
+		cat.Versions, cat.Builds, err = loaderCatalog(ctx, orDefault(src.QuiltMetaBaseURL, "https://meta.quiltmc.org")+"/v3", version)
+	case "forge":
+		cat.Versions, cat.Builds, err = forgeCatalog(ctx, orDefault(src.ForgeMavenBaseURL, "https://maven.minecraftforge.net"), version)
+	case "neoforge":
+		cat.Versions, cat.Builds, err = neoForgeCatalog(ctx, orDefault(src.NeoForgeMavenBaseURL, "https://maven.neoforged.net/releases"), version)
+	default:
+		sw, ok := LookupSoftware(software)
+		if !ok || sw.Provider != ProviderPaperMC {
+			return Catalog{}, fmt.Errorf("unsupported software: %s", software)
+		}
+		if version == "" {
+			cat.Versions, err = paperMCVersions(ctx, src.PaperAPIBaseURL, sw.ID)
+		} else {
+			cat.Builds, err = paperMCBuilds(ctx, src.PaperAPIBaseURL, sw.ID, version)
+		}
+	}
+	if err != nil {
+		return Catalog{}, err
+	}
+	return cat, nil
+}
+
+func orDefault(base, def string) string {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	if base == "" {
+		return def
+	}
+	return base
+}
+
+func vanillaCatalog(ctx context.Context, metaBaseURL string) ([]CatalogVersion, error) {
+	var manifest vanillaManifest
+	if err := fetchJSON(ctx, orDefault(metaBaseURL, "https://piston-meta.mojang.com")+"/mc/game/version_manifest_v2.json", &manifest); err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	out := make([]CatalogVersion, 0, len(manifest.Versions))
+	for _, v := range manifest.Versions {
+		out = append(out, CatalogVersion{ID: v.ID, Type: v.Type, Stable: v.Type == "release", ReleaseTime: v.ReleaseTime})
+	}
+	return out, nil
+}
+
+func paperMCVersions(ctx context.Context, apiBaseURL, project string) ([]CatalogVersion, error) {
+	var resp struct {
+		Versions []string `json:"versions"`
+	}
+	if err := fetchJSONLenient(ctx, orDefault(apiBaseURL, "https://api.papermc.io")+"/v2/projects/"+project, &resp); err != nil {
+		return nil, fmt.Errorf("fetch %s versions: %w", project, err)
+	}
+	out := make([]CatalogVersion, 0, len(resp.Versions))
+	for i := len(resp.Versions) - 1; i >= 0; i-- {
+		v := resp.Versions[i]
+		out = append(out, CatalogVersion{ID: v, Stable: !isPreReleaseVersion(v)})
+	}
+	return out, nil
+}
+
+func paperMCBuilds(ctx context.Context, apiBaseURL, project, version string) ([]CatalogBuild, error) {
+	var resp paperVersionResp
+	if err := fetchJSONLenient(ctx, orDefault(apiBaseURL, "https://api.papermc.io")+"/v2/projects/"+project+"/versions/"+url.PathEscape(version), &resp); err != nil {
+		return nil, fmt.Errorf("fetch %s builds: %w", project, err)
+	}
+	out := make([]CatalogBuild, 0, len(resp.Builds))
+	for i := len(resp.Builds) - 1; i >= 0; i-- {
+		out = append(out, CatalogBuild{ID: strconv.Itoa(resp.Builds[i]), Stable: true})
+	}
+	return out, nil
+}
+
+func purpurVersions(ctx context.Context, apiBaseURL string) ([]CatalogVersion, error) {
+	var resp struct {
+		Versions []string `json:"versions"`
+	}
+	if err := fetchJSONLenient(ctx, orDefault(apiBaseURL, "https://api.purpurmc.org")+"/v2/purpur", &resp); err != nil {
+		return nil, fmt.Errorf("fetch purpur versions: %w", err)
+	}
+	out := make([]CatalogVersion, 0, len(resp.Versions))
+	for i := len(resp.Versions) - 1; i >= 0; i-- {
+		out = append(out, CatalogVersion{ID: resp.Versions[i], Stable: true})
+	}
+	return out, nil
+}
+
+func purpurBuilds(ctx context.Context, apiBaseURL, version string) ([]CatalogBuild, error) {
+	var resp purpurVersionResp
+	if err := fetchJSONLenient(ctx, orDefault(apiBaseURL, "https://api.purpurmc.org")+"/v2/purpur/"+url.PathEscape(version), &resp); err != nil {
+		return nil, fmt.Errorf("fetch purpur builds: %w", err)
+	}
+	out := make([]CatalogBuild, 0, len(resp.Builds.All))
+	for i := len(resp.Builds.All) - 1; i >= 0; i-- {
+		out = append(out, CatalogBuild{ID: resp.Builds.All[i], Stable: true})
+	}
+	return out, nil
+}
+
+// loaderCatalog lists Fabric/Quilt game versions, or the loader versions for one.
+func loaderCatalog(ctx context.Context, apiBase, version string) ([]CatalogVersion, []CatalogBuild, error) {
+	if version == "" {
+		var games []struct {
+			Version string `json:"version"`
+			Stable  *bool  `json:"stable"`
+		}
+		if err := fetchJSONLenient(ctx, apiBase+"/versions/game", &games); err != nil {
+			return nil, nil, fmt.Errorf("fetch game versions: %w", err)
+		}
+		out := make([]CatalogVersion, 0, len(games))
+		for _, g := range games {
+			out = append(out, CatalogVersion{ID: g.Version, Stable: isStableLoaderVersion(g.Version, g.Stable)})
+		}
+		return out, nil, nil
+	}
+	var loaders []loaderMetaEntry
+	if err := fetchJSONLenient(ctx, apiBase+"/versions/loader/"+url.PathEscape(version), &loaders); err != nil {
+		return nil, nil, fmt.Errorf("fetch loader versions: %w", err)
+	}
+	out := make([]CatalogBuild, 0, len(loaders))
+	for _, l := range loaders {
+		out = append(out, CatalogBuild{ID: l.Loader.Version, Stable: isStableLoaderVersion(l.Loader.Version, l.Loader.Stable)})
+	}
+	return nil, out, nil
+}
+
+// forgeCatalog groups the Forge maven versions ("<mc>-<forge>") by Minecraft version.
+func forgeCatalog(ctx context.Context, mavenBase, version string) ([]CatalogVersion, []CatalogBuild, error) {
+	versions, err := fetchMavenVersions(ctx, mavenBase+"/net/minecraftforge/forge/maven-metadata.xml")
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch forge versions: %w", err)
+	}
+	games := map[string][]string{}
+	for _, v := range versions {
+		i := strings.IndexByte(v, '-')
+		if i <= 0 {
+			continue
+		}
+		games[v[:i]] = append(games[v[:i]], v[i+1:])
+	}
+	if version == "" {
+		return catalogGameVersions(games), nil, nil
+	}
+	builds := games[version]
+	sort.Slice(builds, func(i, j int) bool { return CompareVersions(builds[i], builds[j]) > 0 })
+	out := make([]CatalogBuild, 0, len(builds))
+	for _, b := range builds {
+		out = append(out, CatalogBuild{ID: b, Stable: true})
+	}
+	return nil, out, nil
+}
+
+// neoForgeCatalog maps NeoForge versions ("21.1.77") to Minecraft versions ("1.21.1").
+func neoForgeCatalog(ctx context.Context, mavenBase, version string) ([]CatalogVersion, []CatalogBuild, error) {
+	versions, err := fetchMavenVersions(ctx, mavenBase+"/net/neoforged/neoforge/maven-metadata.xml")
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch neoforge versions: %w", err)
+	}
+	games := map[string][]string{}
+	for _, v := range versions {
+		parts := strings.SplitN(v, ".", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		game := "1." + parts[0]
+		if parts[1] != "0" {
+			game += "." + parts[1]
+		}
+		games[game] = append(games[game], v)
+	}
+	if version == "" {
+		return catalogGameVersions(games), nil, nil
+	}
+	builds := games[version]
+	sort.Slice(builds, func(i, j int) bool { return CompareVersions(builds[i], builds[j]) > 0 })
+	out := make([]CatalogBuild, 0, len(builds))
+	for _, b := range builds {
+		out = append(out, CatalogBuild{ID: b, Stable: !strings.Contains(b, "-")})
+	}
+	return nil, out, nil
+}
+
+func catalogGameVersions(games map[string][]string) []CatalogVersion {
+	out := make([]CatalogVersion, 0, len(games))
+	for g := range games {
+		out = append(out, CatalogVersion{ID: g, Stable: !isPreReleaseVersion(g)})
+	}
+	sort.Slice(out, func(i, j int) bool { return CompareVersions(out[i].ID, out[j].ID) > 0 })
+	return out
+}
+
+// isPreReleaseVersion reports whether a version id looks like a pre-release
+// ("1.20.5-pre1", "1.21-rc1", "3.3.0-SNAPSHOT", "1.7.10_pre4").
+func isPreReleaseVersion(v string) bool {
+	v = strings.ToLower(v)
+	return strings.Contains(v, "-") || strings.Contains(v, "_pre") || strings.Contains(v, "snapshot")
+}
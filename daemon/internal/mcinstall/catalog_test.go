@@ -0,0 +1,145 @@
+package mcinstall
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newCatalogStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/net/minecraftforge/forge/maven-metadata.xml":
+			_, _ = w.Write([]byte(`<metadata><versioning><versions>
+				<version>1.7.10_pre4-10.12.2.1149-prerelease</version>
+				<version>1.12.2-14.23.5.2859</version>
+				<version>1.20.1-47.2.0</version>
+				<version>1.20.1-47.10.1</version>
+				<version>1.20.1-47.9.0</version>
+				<version>broken</version>
+			</versions></versioning></metadata>`))
+		case "/releases/net/neoforged/neoforge/maven-metadata.xml":
+			_, _ = w.Write([]byte(`<metadata><versioning><versions>
+				<version>20.2.86</version>
+				<version>21.0.1-beta</version>
+				<version>21.1.77</version>
+				<version>21.1.80-beta</version>
+				<version>21.1.9</version>
+				<version>21</version>
+			</versions></versioning></metadata>`))
+		case "/v2/projects/velocity":
+			_, _ = w.Write([]byte(`{"versions":["3.2.0-SNAPSHOT","3.3.0"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func versionIDs(versions []CatalogVersion) ([]string, []bool) {
+	var ids []string
+	var stable []bool
+	for _, v := range versions {
+		ids, stable = append(ids, v.ID), append(stable, v.Stable)
+	}
+	return ids, stable
+}
+
+func buildIDs(builds []CatalogBuild) ([]string, []bool) {
+	var ids []string
+	var stable []bool
+	for _, b := range builds {
+		ids, stable = append(ids, b.ID), append(stable, b.Stable)
+	}
+	return ids, stable
+}
+
+func TestFetchCatalog_Forge(t *testing.T) {
+	srv := newCatalogStub(t)
+	src := CatalogSources{ForgeMavenBaseURL: srv.URL}
+	ctx := context.Background()
+
+	cat, err := FetchCatalog(ctx, src, "forge", "")
+	if err != nil {
+		t.Fatalf("FetchCatalog(forge): %v", err)
+	}
+	ids, stable := versionIDs(cat.Versions)
+	if want := []string{"1.20.1", "1.12.2", "1.7.10_pre4"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("forge versions = %q, want %q", ids, want)
+	}
+	if want := []bool{true, true, false}; !reflect.DeepEqual(stable, want) {
+		t.Fatalf("forge stable = %v, want %v", stable, want)
+	}
+
+	cat, err = FetchCatalog(ctx, src, "forge", "1.20.1")
+	if err != nil {
+		t.Fatalf("FetchCatalog(forge 1.20.1): %v", err)
+	}
+	if ids, _ := buildIDs(cat.Builds); !reflect.DeepEqual(ids, []string{"47.10.1", "47.9.0", "47.2.0"}) {
+		t.Fatalf("forge builds = %q", ids)
+	}
+}
+
+func TestFetchCatalog_NeoForge(t *testing.T) {
+	srv := newCatalogStub(t)
+	src := CatalogSources{NeoForgeMavenBaseURL: srv.URL + "/releases"}
+	ctx := context.Background()
+
+	cat, err := FetchCatalog(ctx, src, "neoforge", "")
+	if err != nil {
+		t.Fatalf("FetchCatalog(neoforge): %v", err)
+	}
+	// 21.0.x is Minecraft 1.21 (no ".0" patch); "21" has no build and is skipped.
+	if ids, _ := versionIDs(cat.Versions); !reflect.DeepEqual(ids, []string{"1.21.1", "1.21", "1.20.2"}) {
+		t.Fatalf("neoforge versions = %q", ids)
+	}
+
+	cat, err = FetchCatalog(ctx, src, "neoforge", "1.21.1")
+	if err != nil {
+		t.Fatalf("FetchCatalog(neoforge 1.21.1): %v", err)
+	}
+	ids, stable := buildIDs(cat.Builds)
+	if want := []string{"21.1.80-beta", "21.1.77", "21.1.9"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("neoforge builds = %q, want %q", ids, want)
+	}
+	if want := []bool{false, true, true}; !reflect.DeepEqual(stable, want) {
+		t.Fatalf("neoforge stable = %v, want %v", stable, want)
+	}
+}
+
+func TestFetchCatalog_PaperMCNewestFirst(t *testing.T) {
+	srv := newCatalogStub(t)
+	cat, err := FetchCatalog(context.Background(), CatalogSources{PaperAPIBaseURL: srv.URL}, "velocity", "")
+	if err != nil {
+		t.Fatalf("FetchCatalog(velocity): %v", err)
+	}
+	ids, stable := versionIDs(cat.Versions)
+	if !reflect.DeepEqual(ids, []string{"3.3.0", "3.2.0-SNAPSHOT"}) || !reflect.DeepEqual(stable, []bool{true, false}) {
+		t.Fatalf("velocity versions = %q %v", ids, stable)
+	}
+
+	for _, software := range []string{"bukkit", "purpur-ish"} {
+		if _, err := FetchCatalog(context.Background(), CatalogSources{}, software, ""); err == nil {
+			t.Errorf("expected %q to be unsupported", software)
+		}
+	}
+}
+
+func TestIsPreReleaseVersion(t *testing.T) {
+	for v, want := range map[string]bool{
+		"1.20.4":         false,
+		"1.20.5-pre1":    true,
+		"1.21-rc1":       true,
+		"3.3.0-SNAPSHOT": true,
+		"1.7.10_pre4":    true,
+		"23w45a":         false,
+	} {
+		if got := isPreReleaseVersion(v); got != want {
+			t.Errorf("isPreReleaseVersion(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package mcinstall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CurseForgeFile is a mod file resolved through the CurseForge (or compatible) API.
+type CurseForgeFile struct {
+	ID          int
+	ModID       int
+	FileName    string
+	DownloadURL string
+	SHA1        string
+	MD5         string
+	Length      int64
+}
+
+type curseForgeFileResp struct {
+	ID          int    `json:"id"`
+	ModID       int    `json:"modId"`
+	FileName    string `json:"fileName"`
+	DownloadURL string `json:"downloadUrl"`
+	FileLength  int64  `json:"fileLength"`
+	Hashes      []struct {
+		Value string `json:"value"`
+		Algo  int    `json:"algo"` // 1 = sha1, 2 = md5
+	} `json:"hashes"`
+}
+
+// curseForgeBatchSize keeps POST /v1/mods/files requests small.
+const curseForgeBatchSize = 100
+
+// ResolveCurseForgeFiles resolves file ids to download URLs and hashes in batches.
+// Files whose author disabled third-party downloads come back without a downloadUrl;
+// their DownloadURL is empty and they have to be downloaded by hand.
+func ResolveCurseForgeFiles(ctx context.Context, apiBaseURL, apiKey string, fileIDs []int) (map[int]CurseForgeFile, error) {
+	apiBase := strings.TrimRight(strings.TrimSpace(apiBaseURL), "/")
+	if apiBase == "" {
+		apiBase = "https://api.curseforge.com"
+	}
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, errors.New("curseforge api key is required")
+	}
+
+	out := make(map[int]CurseForgeFile, len(fileIDs))
+	for start := 0; start < len(fileIDs); start += curseForgeBatchSize {
+		end := start + curseForgeBatchSize
+		if end > len(fileIDs) {
+			end = len(fileIDs)
+		}
+		var resp struct {
+			Data []curseForgeFileResp `json:"data"`
+		}
+		body := map[string]any{"fileIds": fileIDs[start:end]}
+		if err := postJSON(ctx, apiBase+"/v1/mods/files", map[string]string{"x-api-key": apiKey}, body, &resp); err != nil {
+			return nil, fmt.Errorf("resolve curseforge files: %w", err)
+		}
+		for _, f := range resp.Data {
+			file := CurseForgeFile{
+				ID:          f.ID,
+				ModID:       f.ModID,
+				FileName:    strings.TrimSpace(f.FileName),
+				DownloadURL: strings.TrimSpace(f.DownloadURL),
+				Length:      f.FileLength,
+			}
+			for _, h := range f.Hashes {
+				switch h.Algo {
+				case 1:
+					file.SHA1 = strings.ToLower(strings.TrimSpace(h.Value))
+				case 2:
+					file.MD5 = strings.ToLower(strings.TrimSpace(h.Value))
+				}
+			}
+			out[f.ID] = file
+		}
+	}
+	for _, id := range fileIDs {
+		if _, ok := out[id]; !ok {
+			return nil, fmt.Errorf("curseforge file %d not found", id)
+		}
+	}
+	return out, nil
+}
+
+// ParseCurseForgeModLoader splits a manifest modLoaders id ("forge-47.2.0",
+// "neoforge-20.4.80", "fabric-0.15.0", "quilt-0.20.0") into installer loader and version.
+func ParseCurseForgeModLoader(id string) (string, string, error) {
+	id = strings.TrimSpace(id)
+	i := strings.IndexByte(id, '-')
+	if i <= 0 || i == len(id)-1 {
+		return "", "", fmt.Errorf("invalid mod loader id: %q", id)
+	}
+	loader, version := strings.ToLower(id[:i]), id[i+1:]
+	switch loader {
+	case "forge", "neoforge", "fabric", "quilt":
+		return loader, version, nil
+	default:
+		return "", "", fmt.Errorf("unsupported mod loader: %s", loader)
+	}
+}
+
+// postJSON posts in as JSON with the extra headers and decodes the response into out.
+func postJSON(ctx context.Context, urlStr string, header map[string]string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ElegantMC-Daemon/0.1.0")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
+		return fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
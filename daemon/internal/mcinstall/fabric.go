@@ -0,0 +1,256 @@
+package mcinstall
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// LoaderServerJar is a Fabric/Quilt server launcher jar. The launcher loads the vanilla
+// server jar (server.jar in the working directory by default) at startup.
+//
+// Fabric meta generates the launcher (URL). Quilt meta has no such endpoint: ProfileURL
+// points at the server launch profile, and the launcher is assembled from its libraries
+// the way quilt-installer does it (see BuildQuiltServerLaunchJar).
+type LoaderServerJar struct {
+	Loader           string // "fabric" | "quilt"
+	GameVersion      string
+	LoaderVersion    string
+	InstallerVersion string
+	URL              string
+	ProfileURL       string
+}
+
+// LoaderServerProfile is the server launch profile served by the loader meta API
+// (/versions/loader/{game}/{loader}/server/json).
+type LoaderServerProfile struct {
+	MainClass string          `json:"mainClass"`
+	Libraries []LoaderLibrary `json:"libraries"`
+}
+
+// LoaderLibrary is a maven artifact from a launch profile.
+type LoaderLibrary struct {
+	Name string `json:"name"` // group:artifact:version[:classifier]
+	URL  string `json:"url"`  // maven repository base
+	SHA1 string `json:"sha1,omitempty"`
+}
+
+// QuiltServerLauncherMainClass is the Main-Class of quilt-installer's server launch jar.
+// It reads launch.mainClass from quilt-server-launch.properties inside the jar and puts
+// the vanilla server jar on the class path.
+const QuiltServerLauncherMainClass = "org.quiltmc.loader.impl.launch.server.QuiltServerLauncher"
+
+// Path returns the library's path inside a maven repository (and under libraries/).
+func (l LoaderLibrary) Path() (string, error) {
+	parts := strings.Split(strings.TrimSpace(l.Name), ":")
+	if len(parts) < 3 || len(parts) > 4 {
+		return "", fmt.Errorf("invalid library name: %q", l.Name)
+	}
+	for _, p := range parts {
+		if p == "" || p == "." || p == ".." || strings.ContainsAny(p, "/\\") {
+			return "", fmt.Errorf("invalid library name: %q", l.Name)
+		}
+	}
+	group, artifact, version := parts[0], parts[1], parts[2]
+	file := artifact + "-" + version
+	if len(parts) == 4 {
+		file += "-" + parts[3]
+	}
+	return strings.ReplaceAll(group, ".", "/") + "/" + artifact + "/" + version + "/" + file + ".jar", nil
+}
+
+// DownloadURL returns where the library is fetched from.
+func (l LoaderLibrary) DownloadURL() (string, error) {
+	p, err := l.Path()
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimRight(strings.TrimSpace(l.URL), "/")
+	if base == "" {
+		return "", fmt.Errorf("library %s has no repository url", l.Name)
+	}
+	return base + "/" + p, nil
+}
+
+// FetchLoaderServerProfile downloads a server launch profile.
+func FetchLoaderServerProfile(ctx context.Context, profileURL string) (LoaderServerProfile, error) {
+	var prof LoaderServerProfile
+	if err := fetchJSONLenient(ctx, profileURL, &prof); err != nil {
+		return LoaderServerProfile{}, fmt.Errorf("fetch server profile: %w", err)
+	}
+	if strings.TrimSpace(prof.MainClass) == "" {
+		return LoaderServerProfile{}, errors.New("server profile has no mainClass")
+	}
+	if len(prof.Libraries) == 0 {
+		return LoaderServerProfile{}, errors.New("server profile has no libraries")
+	}
+	return prof, nil
+}
+
+// BuildQuiltServerLaunchJar writes the launcher jar quilt-installer generates for servers:
+// a manifest whose Class-Path lists the libraries (paths relative to the jar) and a
+// quilt-server-launch.properties naming the profile's main class.
+func BuildQuiltServerLaunchJar(w io.Writer, mainClass string, classPath []string) error {
+	zw := zip.NewWriter(w)
+	mf, err := zw.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	writeManifestAttr(&b, "Manifest-Version", "1.0")
+	writeManifestAttr(&b, "Main-Class", QuiltServerLauncherMainClass)
+	writeManifestAttr(&b, "Class-Path", strings.Join(classPath, " "))
+	b.WriteString("\r\n")
+	if _, err := io.WriteString(mf, b.String()); err != nil {
+		return err
+	}
+	pf, err := zw.Create("quilt-server-launch.properties")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(pf, "launch.mainClass="+mainClass+"\n"); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeManifestAttr writes one manifest attribute, wrapping at 72 bytes per line with
+// single-space continuation lines as the jar spec requires.
+func writeManifestAttr(b *strings.Builder, name, value string) {
+	line := name + ": " + value
+	limit := 72
+	for len(line) > limit {
+		b.WriteString(line[:limit])
+		b.WriteString("\r\n ")
+		line = line[limit:]
+		limit = 71
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+type loaderMetaEntry struct {
+	Loader struct {
+		Version string `json:"version"`
+		Stable  *bool  `json:"stable"`
+	} `json:"loader"`
+}
+
+type installerMetaEntry struct {
+	Version string `json:"version"`
+	Stable  *bool  `json:"stable"`
+}
+
+// ResolveFabricServerJar resolves the server launcher jar from the Fabric meta API.
+// Empty loader/installer versions select the latest stable ones.
+func ResolveFabricServerJar(ctx context.Context, metaBaseURL, gameVersion, loaderVersion, installerVersion string) (LoaderServerJar, error) {
+	metaBase := strings.TrimRight(strings.TrimSpace(metaBaseURL), "/")
+	if metaBase == "" {
+		metaBase = "https://meta.fabricmc.net"
+	}
+	apiBase := metaBase + "/v2"
+	gameVersion = strings.TrimSpace(gameVersion)
+	installerVersion = strings.TrimSpace(installerVersion)
+	loaderVersion, err := resolveLoaderVersion(ctx, "fabric", apiBase, gameVersion, loaderVersion)
+	if err != nil {
+		return LoaderServerJar{}, err
+	}
+
+	if installerVersion == "" {
+		var installers []installerMetaEntry
+		if err := fetchJSONLenient(ctx, apiBase+"/versions/installer", &installers); err != nil {
+			return LoaderServerJar{}, fmt.Errorf("fetch fabric installer versions: %w", err)
+		}
+		for _, in := range installers {
+			if isStableLoaderVersion(in.Version, in.Stable) {
+				installerVersion = in.Version
+				break
+			}
+		}
+		if installerVersion == "" && len(installers) > 0 {
+			installerVersion = installers[0].Version
+		}
+		if installerVersion == "" {
+			return LoaderServerJar{}, errors.New("no fabric installer versions")
+		}
+	}
+
+	jarURL := apiBase + "/versions/loader/" + url.PathEscape(gameVersion) + "/" + url.PathEscape(loaderVersion) + "/" + url.PathEscape(installerVersion) + "/server/jar"
+	return LoaderServerJar{
+		Loader:           "fabric",
+		GameVersion:      gameVersion,
+		LoaderVersion:    loaderVersion,
+		InstallerVersion: installerVersion,
+		URL:              jarURL,
+	}, nil
+}
+
+// ResolveQuiltServerJar is the Quilt equivalent of ResolveFabricServerJar (meta API v3).
+// Quilt meta only serves the launch profile, so the result carries ProfileURL instead of URL;
+// installerVersion is not used.
+func ResolveQuiltServerJar(ctx context.Context, metaBaseURL, gameVersion, loaderVersion, installerVersion string) (LoaderServerJar, error) {
+	metaBase := strings.TrimRight(strings.TrimSpace(metaBaseURL), "/")
+	if metaBase == "" {
+		metaBase = "https://meta.quiltmc.org"
+	}
+	apiBase := metaBase + "/v3"
+	gameVersion = strings.TrimSpace(gameVersion)
+	loaderVersion, err := resolveLoaderVersion(ctx, "quilt", apiBase, gameVersion, loaderVersion)
+	if err != nil {
+		return LoaderServerJar{}, err
+	}
+	profileURL := apiBase + "/versions/loader/" + url.PathEscape(gameVersion) + "/" + url.PathEscape(loaderVersion) + "/server/json"
+	return LoaderServerJar{
+		Loader:        "quilt",
+		GameVersion:   gameVersion,
+		LoaderVersion: loaderVersion,
+		ProfileURL:    profileURL,
+	}, nil
+}
+
+// resolveLoaderVersion picks (or checks) the loader version for a game version.
+func resolveLoaderVersion(ctx context.Context, loader, apiBase, gameVersion, loaderVersion string) (string, error) {
+	loaderVersion = strings.TrimSpace(loaderVersion)
+	if gameVersion == "" {
+		return "", errors.New("version is required")
+	}
+
+	// The loader list for a game version also tells whether the game version is supported.
+	var loaders []loaderMetaEntry
+	if err := fetchJSONLenient(ctx, apiBase+"/versions/loader/"+url.PathEscape(gameVersion), &loaders); err != nil {
+		return "", fmt.Errorf("fetch %s loader versions: %w", loader, err)
+	}
+	if len(loaders) == 0 {
+		return "", fmt.Errorf("%s does not support minecraft %s", loader, gameVersion)
+	}
+	if loaderVersion == "" {
+		for _, l := range loaders {
+			if isStableLoaderVersion(l.Loader.Version, l.Loader.Stable) {
+				return l.Loader.Version, nil
+			}
+		}
+		return loaders[0].Loader.Version, nil
+	}
+	for _, l := range loaders {
+		if l.Loader.Version == loaderVersion {
+			return loaderVersion, nil
+		}
+	}
+	return "", fmt.Errorf("%s loader %s not found for minecraft %s", loader, loaderVersion, gameVersion)
+}
+
+// isStableLoaderVersion uses the meta "stable" flag when present (Fabric) and otherwise
+// treats versions with a pre-release suffix (e.g. "0.20.0-beta.1") as unstable (Quilt).
+func isStableLoaderVersion(version string, stable *bool) bool {
+	if strings.TrimSpace(version) == "" {
+		return false
+	}
+	if stable != nil {
+		return *stable
+	}
+	return !strings.Contains(version, "-")
+}
@@ -0,0 +1,168 @@
+package mcinstall
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newLoaderMetaStub(t *testing.T, installerHits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/versions/loader/1.20.1":
+			_, _ = w.Write([]byte(`[{"loader":{"version":"0.16.0","stable":false}},{"loader":{"version":"0.15.11","stable":true}}]`))
+		case "/v2/versions/installer":
+			atomic.AddInt32(installerHits, 1)
+			_, _ = w.Write([]byte(`[{"version":"1.1.0","stable":false},{"version":"1.0.1","stable":true}]`))
+		case "/v3/versions/loader/1.20.1":
+			_, _ = w.Write([]byte(`[{"loader":{"version":"0.27.0-beta.1"}},{"loader":{"version":"0.26.4"}}]`))
+		case "/v3/versions/installer":
+			atomic.AddInt32(installerHits, 1)
+			_, _ = w.Write([]byte(`[{"version":"0.9.2"}]`))
+		case "/v2/versions/loader/1.0", "/v3/versions/loader/1.0":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveFabricServerJar(t *testing.T) {
+	var hits int32
+	srv := newLoaderMetaStub(t, &hits)
+	ctx := context.Background()
+
+	got, err := ResolveFabricServerJar(ctx, srv.URL, "1.20.1", "", "")
+	if err != nil {
+		t.Fatalf("ResolveFabricServerJar(): %v", err)
+	}
+	if got.LoaderVersion != "0.15.11" || got.InstallerVersion != "1.0.1" {
+		t.Fatalf("expected latest stable versions, got loader=%s installer=%s", got.LoaderVersion, got.InstallerVersion)
+	}
+	if want := srv.URL + "/v2/versions/loader/1.20.1/0.15.11/1.0.1/server/jar"; got.URL != want || got.ProfileURL != "" {
+		t.Fatalf("URL=%q ProfileURL=%q, want URL %q", got.URL, got.ProfileURL, want)
+	}
+
+	got, err = ResolveFabricServerJar(ctx, srv.URL, "1.20.1", "0.16.0", "1.1.0")
+	if err != nil {
+		t.Fatalf("ResolveFabricServerJar(pinned): %v", err)
+	}
+	if got.LoaderVersion != "0.16.0" || got.InstallerVersion != "1.1.0" {
+		t.Fatalf("pinned versions not kept: %+v", got)
+	}
+
+	if _, err := ResolveFabricServerJar(ctx, srv.URL, "1.20.1", "9.9.9", ""); err == nil {
+		t.Fatalf("expected error for unknown loader version")
+	}
+	if _, err := ResolveFabricServerJar(ctx, srv.URL, "1.0", "", ""); err == nil {
+		t.Fatalf("expected error for unsupported game version")
+	}
+}
+
+func TestResolveQuiltServerJar(t *testing.T) {
+	var hits int32
+	srv := newLoaderMetaStub(t, &hits)
+
+	got, err := ResolveQuiltServerJar(context.Background(), srv.URL, "1.20.1", "", "")
+	if err != nil {
+		t.Fatalf("ResolveQuiltServerJar(): %v", err)
+	}
+	if got.LoaderVersion != "0.26.4" {
+		t.Fatalf("expected latest stable loader, got %s", got.LoaderVersion)
+	}
+	if got.URL != "" {
+		t.Fatalf("quilt has no generated launcher, got URL %q", got.URL)
+	}
+	if want := srv.URL + "/v3/versions/loader/1.20.1/0.26.4/server/json"; got.ProfileURL != want {
+		t.Fatalf("ProfileURL=%q, want %q", got.ProfileURL, want)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Fatalf("quilt resolve should not need the installer list")
+	}
+}
+
+func TestLoaderLibrary_Path(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"org.quiltmc:quilt-loader:0.26.4", "org/quiltmc/quilt-loader/0.26.4/quilt-loader-0.26.4.jar", true},
+		{"net.fabricmc:intermediary:1.20.1", "net/fabricmc/intermediary/1.20.1/intermediary-1.20.1.jar", true},
+		{"org.lwjgl:lwjgl:3.3.1:natives-linux", "org/lwjgl/lwjgl/3.3.1/lwjgl-3.3.1-natives-linux.jar", true},
+		{"org.ow2.asm:asm", "", false},
+		{"a:b:c:d:e", "", false},
+		{"a:..:1", "", false},
+		{"a:b/c:1", "", false},
+	}
+	for _, tc := range cases {
+		got, err := LoaderLibrary{Name: tc.name}.Path()
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Fatalf("Path(%q) = %q, %v; want %q ok=%v", tc.name, got, err, tc.want, tc.ok)
+		}
+	}
+
+	u, err := LoaderLibrary{Name: "org.quiltmc:hashed:1.20.1", URL: "https://maven.example/release/"}.DownloadURL()
+	if err != nil || u != "https://maven.example/release/org/quiltmc/hashed/1.20.1/hashed-1.20.1.jar" {
+		t.Fatalf("DownloadURL() = %q, %v", u, err)
+	}
+	if _, err := (LoaderLibrary{Name: "org.quiltmc:hashed:1.20.1"}).DownloadURL(); err == nil {
+		t.Fatalf("expected error without repository url")
+	}
+}
+
+func TestBuildQuiltServerLaunchJar(t *testing.T) {
+	var classPath []string
+	for i := 0; i < 12; i++ {
+		classPath = append(classPath, "libraries/org/example/lib"+strings.Repeat("x", i)+"/1.0/lib-1.0.jar")
+	}
+	var buf bytes.Buffer
+	if err := BuildQuiltServerLaunchJar(&buf, "org.quiltmc.loader.impl.launch.knot.KnotServer", classPath); err != nil {
+		t.Fatalf("BuildQuiltServerLaunchJar(): %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	if got := files["quilt-server-launch.properties"]; got != "launch.mainClass=org.quiltmc.loader.impl.launch.knot.KnotServer\n" {
+		t.Fatalf("properties = %q", got)
+	}
+	mf := files["META-INF/MANIFEST.MF"]
+	for _, line := range strings.Split(mf, "\r\n") {
+		if len(line) > 72 {
+			t.Fatalf("manifest line longer than 72 bytes: %q", line)
+		}
+	}
+	attrs := map[string]string{}
+	for _, line := range strings.Split(strings.ReplaceAll(mf, "\r\n ", ""), "\r\n") {
+		if k, v, ok := strings.Cut(line, ": "); ok {
+			attrs[k] = v
+		}
+	}
+	if attrs["Main-Class"] != QuiltServerLauncherMainClass {
+		t.Fatalf("Main-Class = %q", attrs["Main-Class"])
+	}
+	if attrs["Class-Path"] != strings.Join(classPath, " ") {
+		t.Fatalf("Class-Path = %q", attrs["Class-Path"])
+	}
+}
@@ -0,0 +1,245 @@
+package mcinstall
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ForgeInstaller is a Forge/NeoForge installer jar published on a maven repository.
+type ForgeInstaller struct {
+	Loader        string // "forge" | "neoforge"
+	GameVersion   string
+	LoaderVersion string // e.g. "47.2.0" (forge) / "21.1.77" (neoforge)
+	URL           string
+	SHA1          string
+}
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+var sha1HexPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}`)
+
+// ResolveForgeInstaller resolves the Forge installer for a Minecraft version. An empty
+// forgeVersion selects the newest build for that Minecraft version.
+func ResolveForgeInstaller(ctx context.Context, mavenBaseURL, gameVersion, forgeVersion string) (ForgeInstaller, error) {
+	gameVersion = strings.TrimSpace(gameVersion)
+	forgeVersion = strings.TrimSpace(forgeVersion)
+	if gameVersion == "" {
+		return ForgeInstaller{}, errors.New("version is required")
+	}
+	base := strings.TrimRight(strings.TrimSpace(mavenBaseURL), "/")
+	if base == "" {
+		base = "https://maven.minecraftforge.net"
+	}
+	artifact := base + "/net/minecraftforge/forge/"
+
+	// Maven versions are "<mc>-<forge>" (older ones may carry a "-<mc>" branch suffix).
+	full := ""
+	if forgeVersion != "" {
+		full = gameVersion + "-" + strings.TrimPrefix(forgeVersion, gameVersion+"-")
+	} else {
+		versions, err := fetchMavenVersions(ctx, artifact+"maven-metadata.xml")
+		if err != nil {
+			return ForgeInstaller{}, fmt.Errorf("fetch forge versions: %w", err)
+		}
+		for _, v := range versions {
+			if !strings.HasPrefix(v, gameVersion+"-") {
+				continue
+			}
+			if full == "" || CompareVersions(strings.TrimPrefix(v, gameVersion+"-"), strings.TrimPrefix(full, gameVersion+"-")) > 0 {
+				full = v
+			}
+		}
+		if full == "" {
+			return ForgeInstaller{}, fmt.Errorf("no forge builds for minecraft %s", gameVersion)
+		}
+	}
+
+	url := artifact + full + "/forge-" + full + "-installer.jar"
+	sha1, err := fetchMavenSHA1(ctx, url)
+	if err != nil {
+		return ForgeInstaller{}, fmt.Errorf("fetch forge installer checksum: %w", err)
+	}
+	return ForgeInstaller{
+		Loader:        "forge",
+		GameVersion:   gameVersion,
+		LoaderVersion: strings.TrimPrefix(full, gameVersion+"-"),
+		URL:           url,
+		SHA1:          sha1,
+	}, nil
+}
+
+// ResolveNeoForgeInstaller resolves the NeoForge installer. NeoForge versions follow the
+// Minecraft version (1.21.1 -> 21.1.x); an empty neoforgeVersion selects the newest stable
+// build (or the newest beta when there is none).
+func ResolveNeoForgeInstaller(ctx context.Context, mavenBaseURL, gameVersion, neoforgeVersion string) (ForgeInstaller, error) {
+	gameVersion = strings.TrimSpace(gameVersion)
+	neoforgeVersion = strings.TrimSpace(neoforgeVersion)
+	if gameVersion == "" {
+		return ForgeInstaller{}, errors.New("version is required")
+	}
+	base := strings.TrimRight(strings.TrimSpace(mavenBaseURL), "/")
+	if base == "" {
+		base = "https://maven.neoforged.net/releases"
+	}
+	artifact := base + "/net/neoforged/neoforge/"
+
+	if neoforgeVersion == "" {
+		parts := strings.Split(gameVersion, ".")
+		if len(parts) < 2 || parts[0] != "1" {
+			return ForgeInstaller{}, fmt.Errorf("unsupported minecraft version for neoforge: %s", gameVersion)
+		}
+		minor := "0"
+		if len(parts) > 2 {
+			minor = parts[2]
+		}
+		prefix := parts[1] + "." + minor + "."
+		if CompareVersions(parts[1]+"."+minor, "20.2") < 0 {
+			return ForgeInstaller{}, errors.New("neoforge supports minecraft 1.20.2 and newer")
+		}
+
+		versions, err := fetchMavenVersions(ctx, artifact+"maven-metadata.xml")
+		if err != nil {
+			return ForgeInstaller{}, fmt.Errorf("fetch neoforge versions: %w", err)
+		}
+		stable, latest := "", ""
+		for _, v := range versions {
+			if !strings.HasPrefix(v, prefix) {
+				continue
+			}
+			if latest == "" || CompareVersions(v, latest) > 0 {
+				latest = v
+			}
+			if !strings.Contains(v, "-") && (stable == "" || CompareVersions(v, stable) > 0) {
+				stable = v
+			}
+		}
+		neoforgeVersion = stable
+		if neoforgeVersion == "" {
+			neoforgeVersion = latest
+		}
+		if neoforgeVersion == "" {
+			return ForgeInstaller{}, fmt.Errorf("no neoforge builds for minecraft %s", gameVersion)
+		}
+	}
+
+	url := artifact + neoforgeVersion + "/neoforge-" + neoforgeVersion + "-installer.jar"
+	sha1, err := fetchMavenSHA1(ctx, url)
+	if err != nil {
+		return ForgeInstaller{}, fmt.Errorf("fetch neoforge installer checksum: %w", err)
+	}
+	return ForgeInstaller{
+		Loader:        "neoforge",
+		GameVersion:   gameVersion,
+		LoaderVersion: neoforgeVersion,
+		URL:           url,
+		SHA1:          sha1,
+	}, nil
+}
+
+func fetchMavenVersions(ctx context.Context, metadataURL string) ([]string, error) {
+	body, err := fetchSmall(ctx, metadataURL, 8*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	var md mavenMetadata
+	if err := xml.Unmarshal(body, &md); err != nil {
+		return nil, fmt.Errorf("invalid maven-metadata.xml: %w", err)
+	}
+	return md.Versions, nil
+}
+
+// fetchMavenSHA1 reads the "<artifact>.sha1" file published next to a maven artifact.
+func fetchMavenSHA1(ctx context.Context, artifactURL string) (string, error) {
+	body, err := fetchSmall(ctx, artifactURL+".sha1", 1024)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1HexPattern.FindString(strings.TrimSpace(string(body)))
+	if sum == "" {
+		return "", errors.New("invalid .sha1 file")
+	}
+	return strings.ToLower(sum), nil
+}
+
+func fetchSmall(ctx context.Context, urlStr string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ElegantMC-Daemon/0.1.0")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// CompareVersions compares dotted versions numerically ("47.10.0" > "47.9.1"). A
+// pre-release suffix ("21.0.1-beta") sorts before the release.
+func CompareVersions(a, b string) int {
+	splitPre := func(v string) (string, string) {
+		if i := strings.IndexByte(v, '-'); i >= 0 {
+			return v[:i], v[i+1:]
+		}
+		return v, ""
+	}
+	ac, apre := splitPre(strings.TrimSpace(a))
+	bc, bpre := splitPre(strings.TrimSpace(b))
+	ap := strings.Split(ac, ".")
+	bp := strings.Split(bc, ".")
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		var x, y string
+		if i < len(ap) {
+			x = ap[i]
+		}
+		if i < len(bp) {
+			y = bp[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		if x == "" {
+			xn, xerr = 0, nil
+		}
+		if y == "" {
+			yn, yerr = 0, nil
+		}
+		if xerr == nil && yerr == nil {
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case apre == bpre:
+		return 0
+	case apre == "":
+		return 1
+	case bpre == "":
+		return -1
+	default:
+		return strings.Compare(apre, bpre)
+	}
+}
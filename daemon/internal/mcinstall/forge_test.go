@@ -0,0 +1,117 @@
+package mcinstall
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSHA1 = "0123456789abcdef0123456789abcdef01234567"
+
+func newMavenStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/net/minecraftforge/forge/maven-metadata.xml":
+			_, _ = w.Write([]byte(`<metadata><versioning><versions>
+				<version>1.20.1-47.2.0</version>
+				<version>1.20.1-47.10.1</version>
+				<version>1.20.10-99.0.0</version>
+				<version>1.7.10-10.13.4.1614-1.7.10</version>
+			</versions></versioning></metadata>`))
+		case r.URL.Path == "/net/neoforged/neoforge/maven-metadata.xml":
+			_, _ = w.Write([]byte(`<metadata><versioning><versions>
+				<version>20.4.237</version>
+				<version>21.1.77</version>
+				<version>21.1.80-beta</version>
+				<version>21.3.1-beta</version>
+				<version>21.10.0</version>
+			</versions></versioning></metadata>`))
+		case strings.HasSuffix(r.URL.Path, "-installer.jar.sha1"):
+			// Some mirrors append the file name.
+			_, _ = w.Write([]byte(strings.ToUpper(testSHA1) + "  installer.jar\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveForgeInstaller(t *testing.T) {
+	srv := newMavenStub(t)
+	ctx := context.Background()
+
+	got, err := ResolveForgeInstaller(ctx, srv.URL, "1.20.1", "")
+	if err != nil {
+		t.Fatalf("ResolveForgeInstaller(): %v", err)
+	}
+	if got.LoaderVersion != "47.10.1" || got.GameVersion != "1.20.1" || got.SHA1 != testSHA1 {
+		t.Fatalf("unexpected installer: %+v", got)
+	}
+	if want := srv.URL + "/net/minecraftforge/forge/1.20.1-47.10.1/forge-1.20.1-47.10.1-installer.jar"; got.URL != want {
+		t.Fatalf("URL = %q, want %q", got.URL, want)
+	}
+
+	// Pinned versions may be given with or without the "<mc>-" prefix.
+	for _, pinned := range []string{"47.2.0", "1.20.1-47.2.0"} {
+		got, err := ResolveForgeInstaller(ctx, srv.URL, "1.20.1", pinned)
+		if err != nil || got.LoaderVersion != "47.2.0" {
+			t.Fatalf("ResolveForgeInstaller(%q) = %+v, %v", pinned, got, err)
+		}
+	}
+
+	got, err = ResolveForgeInstaller(ctx, srv.URL, "1.7.10", "")
+	if err != nil || got.LoaderVersion != "10.13.4.1614-1.7.10" {
+		t.Fatalf("legacy branch suffix: %+v, %v", got, err)
+	}
+	if _, err := ResolveForgeInstaller(ctx, srv.URL, "1.19.2", ""); err == nil {
+		t.Fatalf("expected error without builds")
+	}
+}
+
+func TestResolveNeoForgeInstaller(t *testing.T) {
+	srv := newMavenStub(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		game string
+		want string
+	}{
+		{"1.21.1", "21.1.77"},     // newest stable wins over a newer beta
+		{"1.21.3", "21.3.1-beta"}, // only betas
+		{"1.20.4", "20.4.237"},
+		{"1.21.10", "21.10.0"},
+	}
+	for _, tc := range cases {
+		got, err := ResolveNeoForgeInstaller(ctx, srv.URL, tc.game, "")
+		if err != nil || got.LoaderVersion != tc.want {
+			t.Errorf("ResolveNeoForgeInstaller(%s) = %+v, %v; want %s", tc.game, got, err, tc.want)
+		}
+	}
+	for _, game := range []string{"1.20.1", "1.22", "24w14a"} {
+		if _, err := ResolveNeoForgeInstaller(ctx, srv.URL, game, ""); err == nil {
+			t.Errorf("expected %s to be rejected", game)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"47.10.0", "47.9.1", 1},
+		{"47.2", "47.2.0", 0},
+		{"21.0.1-beta", "21.0.1", -1},
+		{"21.0.1-alpha", "21.0.1-beta", -1},
+		{"10.13.4.1614-1.7.10", "10.13.4.1558-1.7.10", 1},
+	}
+	for _, tc := range cases {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package mcinstall
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ModrinthFile is a downloadable file of a Modrinth version.
+type ModrinthFile struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	SHA1     string `json:"sha1"`
+	SHA512   string `json:"sha512"`
+	Size     int64  `json:"size"`
+	Primary  bool   `json:"primary"`
+}
+
+// ModrinthVersion is a version of a Modrinth project.
+type ModrinthVersion struct {
+	ID            string         `json:"id"`
+	ProjectID     string         `json:"project_id"`
+	Name          string         `json:"name"`
+	VersionNumber string         `json:"version_number"`
+	VersionType   string         `json:"version_type"`
+	GameVersions  []string       `json:"game_versions"`
+	Loaders       []string       `json:"loaders"`
+	DatePublished string         `json:"date_published"`
+	Files         []ModrinthFile `json:"files"`
+}
+
+// PrimaryFile returns the primary file of the version (the first file if none is marked).
+func (v ModrinthVersion) PrimaryFile() (ModrinthFile, bool) {
+	for _, f := range v.Files {
+		if f.Primary {
+			return f, true
+		}
+	}
+	if len(v.Files) > 0 {
+		return v.Files[0], true
+	}
+	return ModrinthFile{}, false
+}
+
+// HasFileHash reports whether one of the version's files has the given sha1.
+func (v ModrinthVersion) HasFileHash(sha1 string) bool {
+	for _, f := range v.Files {
+		if strings.EqualFold(f.SHA1, sha1) {
+			return true
+		}
+	}
+	return false
+}
+
+type modrinthVersionResp struct {
+	ModrinthVersion
+	Files []struct {
+		Hashes   map[string]string `json:"hashes"`
+		URL      string            `json:"url"`
+		Filename string            `json:"filename"`
+		Primary  bool              `json:"primary"`
+		Size     int64             `json:"size"`
+	} `json:"files"`
+}
+
+func (r modrinthVersionResp) version() ModrinthVersion {
+	v := r.ModrinthVersion
+	v.Files = nil
+	for _, f := range r.Files {
+		v.Files = append(v.Files, ModrinthFile{
+			Filename: strings.TrimSpace(f.Filename),
+			URL:      strings.TrimSpace(f.URL),
+			SHA1:     strings.ToLower(strings.TrimSpace(f.Hashes["sha1"])),
+			SHA512:   strings.ToLower(strings.TrimSpace(f.Hashes["sha512"])),
+			Size:     f.Size,
+			Primary:  f.Primary,
+		})
+	}
+	return v
+}
+
+// modrinthBatchSize keeps version_files requests small.
+const modrinthBatchSize = 500
+
+// ModrinthVersionsBySHA1 looks up the versions that contain files with the given sha1
+// hashes (POST /v2/version_files). Unknown hashes are missing from the result.
+func ModrinthVersionsBySHA1(ctx context.Context, apiBaseURL string, hashes []string) (map[string]ModrinthVersion, error) {
+	return modrinthVersionFiles(ctx, orDefault(apiBaseURL, "https://api.modrinth.com")+"/v2/version_files", hashes, nil)
+}
+
+// ModrinthLatestVersionsBySHA1 returns, for each known sha1, the newest version of the
+// same project that matches the loaders, game versions and version types ("release",
+// "beta", "alpha"; empty: any) (POST /v2/version_files/update).
+func ModrinthLatestVersionsBySHA1(ctx context.Context, apiBaseURL string, hashes, loaders, gameVersions, versionTypes []string) (map[string]ModrinthVersion, error) {
+	extra := map[string]any{}
+	if len(loaders) > 0 {
+		extra["loaders"] = loaders
+	}
+	if len(gameVersions) > 0 {
+		extra["game_versions"] = gameVersions
+	}
+	if len(versionTypes) > 0 {
+		extra["version_types"] = versionTypes
+	}
+	return modrinthVersionFiles(ctx, orDefault(apiBaseURL, "https://api.modrinth.com")+"/v2/version_files/update", hashes, extra)
+}
+
+func modrinthVersionFiles(ctx context.Context, urlStr string, hashes []string, extra map[string]any) (map[string]ModrinthVersion, error) {
+	out := make(map[string]ModrinthVersion, len(hashes))
+	for start := 0; start < len(hashes); start += modrinthBatchSize {
+		end := start + modrinthBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		body := map[string]any{"hashes": hashes[start:end], "algorithm": "sha1"}
+		for k, v := range extra {
+			body[k] = v
+		}
+		var resp map[string]modrinthVersionResp
+		if err := postJSON(ctx, urlStr, nil, body, &resp); err != nil {
+			return nil, fmt.Errorf("modrinth lookup: %w", err)
+		}
+		for hash, v := range resp {
+			out[strings.ToLower(hash)] = v.version()
+		}
+	}
+	return out, nil
+}
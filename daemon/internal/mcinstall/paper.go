@@ -14,14 +14,6 @@ import (
 	"time"
 )
 
-type PaperJar struct {
-	Version string
-	Build   int
-	Name    string
-	URL     string
-	SHA256  string
-}
-
 type paperVersionResp struct {
 	Builds []int `json:"builds"`
 }
@@ -35,10 +27,20 @@ type paperBuildResp struct {
 	} `json:"downloads"`
 }
 
-func ResolvePaperJar(ctx context.Context, apiBaseURL, version string, build int) (PaperJar, error) {
+func ResolvePaperJar(ctx context.Context, apiBaseURL, version string, build int) (ServerJar, error) {
+	return ResolvePaperMCJar(ctx, apiBaseURL, "paper", version, build)
+}
+
+// ResolvePaperMCJar resolves a jar of any project on the PaperMC downloads API v2
+// (paper, folia, velocity, waterfall). build 0 selects the latest build.
+func ResolvePaperMCJar(ctx context.Context, apiBaseURL, project, version string, build int) (ServerJar, error) {
+	project = strings.TrimSpace(project)
 	version = strings.TrimSpace(version)
+	if !projectPattern.MatchString(project) {
+		return ServerJar{}, fmt.Errorf("invalid project: %q", project)
+	}
 	if version == "" {
-		return PaperJar{}, errors.New("version is required")
+		return ServerJar{}, errors.New("version is required")
 	}
 
 	apiBase := strings.TrimRight(strings.TrimSpace(apiBaseURL), "/")
@@ -46,38 +48,39 @@ func ResolvePaperJar(ctx context.Context, apiBaseURL, version string, build int)
 		apiBase = "https://api.papermc.io"
 	}
 
-	verURL := apiBase + "/v2/projects/paper/versions/" + url.PathEscape(version)
+	projectBase := apiBase + "/v2/projects/" + project + "/versions/" + url.PathEscape(version)
 	var ver paperVersionResp
-	if err := fetchJSONLenient(ctx, verURL, &ver); err != nil {
-		return PaperJar{}, fmt.Errorf("fetch paper versions: %w", err)
+	if err := fetchJSONLenient(ctx, projectBase, &ver); err != nil {
+		return ServerJar{}, fmt.Errorf("fetch %s versions: %w", project, err)
 	}
 
 	if len(ver.Builds) == 0 {
-		return PaperJar{}, fmt.Errorf("no builds for paper %s", version)
+		return ServerJar{}, fmt.Errorf("no builds for %s %s", project, version)
 	}
 
 	if build == 0 {
 		build = ver.Builds[len(ver.Builds)-1]
 	}
 
-	buildURL := apiBase + "/v2/projects/paper/versions/" + url.PathEscape(version) + "/builds/" + strconv.Itoa(build)
+	buildURL := projectBase + "/builds/" + strconv.Itoa(build)
 	var br paperBuildResp
 	if err := fetchJSONLenient(ctx, buildURL, &br); err != nil {
-		return PaperJar{}, fmt.Errorf("fetch paper build: %w", err)
+		return ServerJar{}, fmt.Errorf("fetch %s build: %w", project, err)
 	}
 
 	name := strings.TrimSpace(br.Downloads.Application.Name)
 	if name == "" {
-		return PaperJar{}, errors.New("paper build missing downloads.application.name")
+		return ServerJar{}, fmt.Errorf("%s build missing downloads.application.name", project)
 	}
 
-	downloadURL := apiBase + "/v2/projects/paper/versions/" + url.PathEscape(version) + "/builds/" + strconv.Itoa(build) + "/downloads/" + path.Base(name)
-	return PaperJar{
-		Version: version,
-		Build:   build,
-		Name:    name,
-		URL:     downloadURL,
-		SHA256:  strings.TrimSpace(br.Downloads.Application.SHA256),
+	downloadURL := buildURL + "/downloads/" + path.Base(name)
+	return ServerJar{
+		Software: project,
+		Version:  version,
+		Build:    build,
+		Name:     name,
+		URL:      downloadURL,
+		SHA256:   strings.TrimSpace(br.Downloads.Application.SHA256),
 	}, nil
 }
 
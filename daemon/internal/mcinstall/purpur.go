@@ -0,0 +1,81 @@
+package mcinstall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type purpurVersionResp struct {
+	Builds struct {
+		Latest string   `json:"latest"`
+		All    []string `json:"all"`
+	} `json:"builds"`
+}
+
+type purpurBuildResp struct {
+	Build  string `json:"build"`
+	Result string `json:"result"`
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+// ResolvePurpurJar resolves a Purpur server jar from the Purpur API v2. build 0 selects
+// the latest build. Purpur publishes an md5 (and on newer builds a sha256) per build.
+func ResolvePurpurJar(ctx context.Context, apiBaseURL, version string, build int) (ServerJar, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return ServerJar{}, errors.New("version is required")
+	}
+
+	apiBase := strings.TrimRight(strings.TrimSpace(apiBaseURL), "/")
+	if apiBase == "" {
+		apiBase = "https://api.purpurmc.org"
+	}
+
+	versionBase := apiBase + "/v2/purpur/" + url.PathEscape(version)
+	if build == 0 {
+		var ver purpurVersionResp
+		if err := fetchJSONLenient(ctx, versionBase, &ver); err != nil {
+			return ServerJar{}, fmt.Errorf("fetch purpur versions: %w", err)
+		}
+		build, _ = strconv.Atoi(strings.TrimSpace(ver.Builds.Latest))
+		if build <= 0 {
+			for _, b := range ver.Builds.All {
+				if n, err := strconv.Atoi(strings.TrimSpace(b)); err == nil && n > build {
+					build = n
+				}
+			}
+		}
+		if build <= 0 {
+			return ServerJar{}, fmt.Errorf("no builds for purpur %s", version)
+		}
+	}
+
+	buildURL := versionBase + "/" + strconv.Itoa(build)
+	var br purpurBuildResp
+	if err := fetchJSONLenient(ctx, buildURL, &br); err != nil {
+		return ServerJar{}, fmt.Errorf("fetch purpur build: %w", err)
+	}
+	if r := strings.TrimSpace(br.Result); r != "" && !strings.EqualFold(r, "SUCCESS") {
+		return ServerJar{}, fmt.Errorf("purpur build %d is not a successful build (%s)", build, r)
+	}
+	sha256 := strings.ToLower(strings.TrimSpace(br.SHA256))
+	md5 := strings.ToLower(strings.TrimSpace(br.MD5))
+	if sha256 == "" && md5 == "" {
+		return ServerJar{}, errors.New("purpur build has no checksum")
+	}
+
+	return ServerJar{
+		Software: "purpur",
+		Version:  version,
+		Build:    build,
+		Name:     fmt.Sprintf("purpur-%s-%d.jar", version, build),
+		URL:      buildURL + "/download",
+		SHA256:   sha256,
+		MD5:      md5,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package mcinstall
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// ServerJar is a single runnable server (or proxy) jar with its published checksums.
+type ServerJar struct {
+	Software string
+	Version  string
+	Build    int
+	Name     string
+	URL      string
+	SHA256   string
+	MD5      string // only when the API publishes no sha256 (Purpur)
+}
+
+// JarProvider resolves jars from one download API.
+type JarProvider interface {
+	ResolveJar(ctx context.Context, project, version string, build int) (ServerJar, error)
+}
+
+// PaperMCProvider serves every project of the PaperMC downloads API.
+type PaperMCProvider struct {
+	APIBaseURL string
+}
+
+func (p PaperMCProvider) ResolveJar(ctx context.Context, project, version string, build int) (ServerJar, error) {
+	return ResolvePaperMCJar(ctx, p.APIBaseURL, project, version, build)
+}
+
+// PurpurProvider serves Purpur (the project argument is ignored).
+type PurpurProvider struct {
+	APIBaseURL string
+}
+
+func (p PurpurProvider) ResolveJar(ctx context.Context, _ string, version string, build int) (ServerJar, error) {
+	return ResolvePurpurJar(ctx, p.APIBaseURL, version, build)
+}
+
+const (
+	ProviderPaperMC = "papermc"
+	ProviderPurpur  = "purpur"
+)
+
+// Software is a server software that installs as a single jar.
+type Software struct {
+	ID       string // also the project name on the provider's API
+	Name     string
+	Provider string // ProviderPaperMC | ProviderPurpur
+	// Proxy is true for proxies (Velocity, Waterfall): no world and no EULA.
+	Proxy bool
+}
+
+var softwareRegistry = []Software{
+	{ID: "paper", Name: "Paper", Provider: ProviderPaperMC},
+	{ID: "folia", Name: "Folia", Provider: ProviderPaperMC},
+	{ID: "purpur", Name: "Purpur", Provider: ProviderPurpur},
+	{ID: "velocity", Name: "Velocity", Provider: ProviderPaperMC, Proxy: true},
+	{ID: "waterfall", Name: "Waterfall", Provider: ProviderPaperMC, Proxy: true},
+}
+
+var projectPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Softwares lists the jar-based server software in display order.
+func Softwares() []Software {
+	return append([]Software(nil), softwareRegistry...)
+}
+
+func LookupSoftware(id string) (Software, bool) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, s := range softwareRegistry {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Software{}, false
+}
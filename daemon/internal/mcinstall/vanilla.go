@@ -22,8 +22,10 @@ type VanillaServerJar struct {
 
 type vanillaManifest struct {
 	Versions []struct {
-		ID  string `json:"id"`
-		URL string `json:"url"`
+		ID          string `json:"id"`
+		Type        string `json:"type"`
+		URL         string `json:"url"`
+		ReleaseTime string `json:"releaseTime"`
 	} `json:"versions"`
 }
 
@@ -0,0 +1,177 @@
+package mods
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultClientDenylist lists client-only mods whose metadata often does not say so
+// (Forge mods without clientSideOnly, Fabric mods declaring "*").
+var defaultClientDenylist = []string{
+	"oculus",
+	"iris",
+	"embeddium",
+	"rubidium",
+	"sodium",
+	"sodiumextra",
+	"sodium-extra",
+	"reeses-sodium-options",
+	"optifine*.jar",
+	"optifabric",
+	"xaerominimap",
+	"xaerominimapfair",
+	"xaeroworldmap",
+	"voxelmap",
+	"betterf3",
+	"controlling",
+	"mousetweaks",
+	"legendarytooltips",
+	"fancymenu",
+	"drippyloadingscreen",
+	"dynamiclights",
+	"dynamiclightsreforged",
+	"lambdynlights",
+	"notenoughanimations",
+	"skinlayers3d",
+	"entity_model_features",
+	"entity_texture_features",
+	"citresewn",
+	"continuity",
+	"modmenu",
+	"zoomify",
+	"cullleaves",
+	"chat_heads",
+	"torohealth",
+	"presencefootsteps",
+	"sound_physics_remastered",
+	"betterthirdperson",
+	"itemphysiclite",
+	"toastcontrol",
+	"enhancedvisuals",
+}
+
+// Denylist names mods that must not be loaded on a dedicated server. Entries are mod
+// ids or jar file name globs ("optifine*.jar").
+type Denylist struct {
+	ids   map[string]bool
+	globs []string
+}
+
+// DefaultDenylist returns the built-in denylist.
+func DefaultDenylist() *Denylist {
+	d := &Denylist{ids: map[string]bool{}}
+	for _, entry := range defaultClientDenylist {
+		d.add(entry)
+	}
+	return d
+}
+
+// LoadDenylist returns the built-in denylist extended by the file at path: one entry
+// per line, "#" comments, "!entry" removes a built-in entry. A missing file is not an
+// error.
+func LoadDenylist(path string) (*Denylist, error) {
+	d := DefaultDenylist()
+	if strings.TrimSpace(path) == "" {
+		return d, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return d, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "!"):
+			d.remove(strings.TrimSpace(line[1:]))
+		default:
+			d.add(line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read denylist: %w", err)
+	}
+	return d, nil
+}
+
+func isFileEntry(entry string) bool {
+	return strings.ContainsAny(entry, "*?[") || strings.HasSuffix(entry, ".jar")
+}
+
+func (d *Denylist) add(entry string) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" {
+		return
+	}
+	if isFileEntry(entry) {
+		d.globs = append(d.globs, entry)
+		return
+	}
+	d.ids[entry] = true
+}
+
+func (d *Denylist) remove(entry string) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if !isFileEntry(entry) {
+		delete(d.ids, entry)
+		return
+	}
+	globs := d.globs[:0]
+	for _, g := range d.globs {
+		if g != entry {
+			globs = append(globs, g)
+		}
+	}
+	d.globs = globs
+}
+
+// Match returns the entry matching the jar file name or one of its mod ids.
+func (d *Denylist) Match(fileName string, mods []Mod) (string, bool) {
+	name := strings.ToLower(strings.TrimSuffix(path.Base(fileName), ".disabled"))
+	for _, g := range d.globs {
+		if ok, _ := path.Match(g, name); ok {
+			return g, true
+		}
+	}
+	for _, m := range mods {
+		if id := strings.ToLower(m.ID); d.ids[id] {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// ClientOnlyReason explains why a jar should not be loaded on a dedicated server, or
+// returns "" if it can be. A jar is client-only when it is on the denylist or when every
+// mod it declares says it only runs on the client.
+func ClientOnlyReason(fileName string, mods []Mod, deny *Denylist) string {
+	if deny != nil {
+		if entry, ok := deny.Match(fileName, mods); ok {
+			return "denylist: " + entry
+		}
+	}
+	if len(mods) == 0 {
+		return ""
+	}
+	var loaders []string
+	for _, m := range mods {
+		if m.Environment != EnvClient {
+			return ""
+		}
+		loaders = append(loaders, m.Loader)
+	}
+	return "client-only metadata (" + strings.Join(loaders, ", ") + ")"
+}
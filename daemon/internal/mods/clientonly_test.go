@@ -0,0 +1,45 @@
+package mods
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientOnlyReason(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("# local additions\nfancyhud\nclientstuff-*.jar  # by file name\n!modmenu\n"), 0o644); err != nil {
+		t.Fatalf("write denylist: %v", err)
+	}
+	deny, err := LoadDenylist(path)
+	if err != nil {
+		t.Fatalf("LoadDenylist: %v", err)
+	}
+
+	cases := []struct {
+		file string
+		mods []Mod
+		want string
+	}{
+		{"iris-1.6.jar", []Mod{{Loader: LoaderFabric, ID: "iris"}}, "denylist: iris"},
+		{"OptiFine_1.20.1_HD_U_I6.jar", nil, "denylist: optifine*.jar"},
+		{"clientstuff-2.0.jar.disabled", nil, "denylist: clientstuff-*.jar"},
+		{"hud.jar", []Mod{{Loader: LoaderForge, ID: "FancyHUD"}}, "denylist: fancyhud"},
+		{"modmenu.jar", []Mod{{Loader: LoaderFabric, ID: "modmenu"}}, ""},
+		{"zoom.jar", []Mod{{Loader: LoaderFabric, ID: "zoom", Environment: EnvClient}}, "client-only metadata (fabric)"},
+		{"mixed.jar", []Mod{{Loader: LoaderForge, ID: "a", Environment: EnvClient}, {Loader: LoaderForge, ID: "b"}}, ""},
+		{"lithium.jar", []Mod{{Loader: LoaderFabric, ID: "lithium", Environment: EnvBoth}}, ""},
+		{"spark-forge.jar", readZip(t, map[string]string{"META-INF/mods.toml": "[[mods]]\nmodId=\"spark\"\ndisplayTest=\"IGNORE_ALL_VERSION\"\n"}), ""},
+		{"clientonly-forge.jar", readZip(t, map[string]string{"META-INF/mods.toml": "clientSideOnly=true\n[[mods]]\nmodId=\"hud\"\ndisplayTest=\"IGNORE_ALL_VERSION\"\n"}), "client-only metadata (forge)"},
+		{"unknown.jar", nil, ""},
+	}
+	for _, tc := range cases {
+		if got := ClientOnlyReason(tc.file, tc.mods, deny); got != tc.want {
+			t.Errorf("ClientOnlyReason(%q) = %q, want %q", tc.file, got, tc.want)
+		}
+	}
+
+	if _, err := LoadDenylist(filepath.Join(t.TempDir(), "missing.txt")); err != nil {
+		t.Fatalf("missing denylist file should not be an error: %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+// Package mods reads the metadata of Minecraft mod and plugin jars.
+package mods
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Loaders (and plugin platforms) a jar can declare metadata for.
+const (
+	LoaderFabric   = "fabric"
+	LoaderQuilt    = "quilt"
+	LoaderForge    = "forge"
+	LoaderNeoForge = "neoforge"
+	LoaderBukkit   = "bukkit" // plugin.yml (Bukkit/Spigot/Paper)
+	LoaderPaper    = "paper"  // paper-plugin.yml
+)
+
+// Environments a mod declares it runs in.
+const (
+	EnvBoth   = "both"
+	EnvClient = "client"
+	EnvServer = "server"
+)
+
+const maxMetadataBytes = 1 << 20
+
+// Mod is the metadata of one mod or plugin declared by a jar.
+type Mod struct {
+	Loader      string   `json:"loader"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Authors     []string `json:"authors,omitempty"`
+	// Environment is "both", "client" or "server" ("" when the metadata does not say).
+	Environment  string       `json:"environment,omitempty"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is a declared dependency of a mod.
+type Dependency struct {
+	ID       string `json:"id"`
+	Version  string `json:"version,omitempty"`
+	Required bool   `json:"required"`
+	Side     string `json:"side,omitempty"`
+}
+
+// ReadJar returns the mods declared by a jar. A jar may carry metadata for several
+// loaders (multi-loader builds) or declare several mods (Forge); it returns an empty
+// slice when it has no known metadata.
+func ReadJar(path string) ([]Mod, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return Read(&zr.Reader)
+}
+
+// Read is ReadJar for an opened archive.
+func Read(zr *zip.Reader) ([]Mod, error) {
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, bool, error) {
+		f := files[name]
+		if f == nil {
+			return nil, false, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, true, err
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(io.LimitReader(rc, maxMetadataBytes))
+		return b, true, err
+	}
+
+	var out []Mod
+	parsers := []struct {
+		name  string
+		parse func([]byte, string) ([]Mod, error)
+	}{
+		{"fabric.mod.json", parseFabric},
+		{"quilt.mod.json", parseQuilt},
+		{"META-INF/mods.toml", func(b []byte, v string) ([]Mod, error) { return parseModsToml(b, LoaderForge, v) }},
+		{"META-INF/neoforge.mods.toml", func(b []byte, v string) ([]Mod, error) { return parseModsToml(b, LoaderNeoForge, v) }},
+		{"plugin.yml", func(b []byte, _ string) ([]Mod, error) { return parsePluginYAML(b, LoaderBukkit) }},
+		{"paper-plugin.yml", func(b []byte, _ string) ([]Mod, error) { return parsePluginYAML(b, LoaderPaper) }},
+	}
+	var jarVersion string
+	if b, found, err := read("META-INF/MANIFEST.MF"); found && err == nil {
+		jarVersion = manifestAttr(string(b), "Implementation-Version")
+	}
+	for _, p := range parsers {
+		b, found, err := read(p.name)
+		if !found {
+			continue
+		}
+		if err != nil {
+			return out, fmt.Errorf("read %s: %w", p.name, err)
+		}
+		mods, err := p.parse(b, jarVersion)
+		if err != nil {
+			return out, fmt.Errorf("parse %s: %w", p.name, err)
+		}
+		out = append(out, mods...)
+	}
+	return out, nil
+}
+
+// ---- Fabric / Quilt ----
+
+func parseFabric(b []byte, _ string) ([]Mod, error) {
+	var m struct {
+		ID          string                     `json:"id"`
+		Version     string                     `json:"version"`
+		Name        string                     `json:"name"`
+		Description string                     `json:"description"`
+		Authors     []json.RawMessage          `json:"authors"`
+		Environment string                     `json:"environment"`
+		Depends     map[string]json.RawMessage `json:"depends"`
+		Recommends  map[string]json.RawMessage `json:"recommends"`
+	}
+	if err := json.Unmarshal(sanitizeJSON(b), &m); err != nil {
+		return nil, err
+	}
+	mod := Mod{
+		Loader:      LoaderFabric,
+		ID:          m.ID,
+		Name:        m.Name,
+		Version:     m.Version,
+		Description: m.Description,
+	}
+	for _, a := range m.Authors {
+		if name := personName(a); name != "" {
+			mod.Authors = append(mod.Authors, name)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(m.Environment)) {
+	case "client":
+		mod.Environment = EnvClient
+	case "server":
+		mod.Environment = EnvServer
+	case "*", "":
+		mod.Environment = EnvBoth
+	}
+	mod.Dependencies = append(fabricDeps(m.Depends, true), fabricDeps(m.Recommends, false)...)
+	return []Mod{mod}, nil
+}
+
+func fabricDeps(deps map[string]json.RawMessage, required bool) []Dependency {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out := make([]Dependency, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, Dependency{ID: id, Version: versionRange(deps[id]), Required: required})
+	}
+	return out
+}
+
+func parseQuilt(b []byte, _ string) ([]Mod, error) {
+	var m struct {
+		QuiltLoader struct {
+			ID       string `json:"id"`
+			Version  string `json:"version"`
+			Metadata struct {
+				Name         string            `json:"name"`
+				Description  string            `json:"description"`
+				Contributors map[string]string `json:"contributors"`
+			} `json:"metadata"`
+			Depends []json.RawMessage `json:"depends"`
+		} `json:"quilt_loader"`
+		Minecraft struct {
+			Environment string `json:"environment"`
+		} `json:"minecraft"`
+	}
+	if err := json.Unmarshal(sanitizeJSON(b), &m); err != nil {
+		return nil, err
+	}
+	ql := m.QuiltLoader
+	mod := Mod{
+		Loader:      LoaderQuilt,
+		ID:          ql.ID,
+		Name:        ql.Metadata.Name,
+		Version:     ql.Version,
+		Description: ql.Metadata.Description,
+	}
+	for name := range ql.Metadata.Contributors {
+		mod.Authors = append(mod.Authors, name)
+	}
+	sort.Strings(mod.Authors)
+	switch strings.ToLower(strings.TrimSpace(m.Minecraft.Environment)) {
+	case "client":
+		mod.Environment = EnvClient
+	case "dedicated_server":
+		mod.Environment = EnvServer
+	case "*", "":
+		mod.Environment = EnvBoth
+	}
+	for _, raw := range ql.Depends {
+		var id string
+		if json.Unmarshal(raw, &id) == nil {
+			mod.Dependencies = append(mod.Dependencies, Dependency{ID: id, Required: true})
+			continue
+		}
+		var d struct {
+			ID       string          `json:"id"`
+			Versions json.RawMessage `json:"versions"`
+			Optional bool            `json:"optional"`
+		}
+		if json.Unmarshal(raw, &d) == nil && d.ID != "" {
+			mod.Dependencies = append(mod.Dependencies, Dependency{ID: d.ID, Version: versionRange(d.Versions), Required: !d.Optional})
+		}
+	}
+	return []Mod{mod}, nil
+}
+
+// personName accepts "name" or {"name": "..."}.
+func personName(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var p struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &p) == nil {
+		return strings.TrimSpace(p.Name)
+	}
+	return ""
+}
+
+// versionRange accepts "range", ["range", ...] or {"any": [...]}-like values.
+func versionRange(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return strings.Join(list, " || ")
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// sanitizeJSON replaces raw newlines and tabs, which Fabric's lenient parser accepts
+// inside strings, so encoding/json can read the file.
+func sanitizeJSON(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	inString, escaped := false, false
+	for _, c := range b {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				out = append(out, '\\', 'n')
+				continue
+			case c == '\r':
+				continue
+			case c == '\t':
+				out = append(out, '\\', 't')
+				continue
+			}
+		} else if c == '"' {
+			inString = true
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ---- Forge / NeoForge ----
+
+func parseModsToml(b []byte, loader, jarVersion string) ([]Mod, error) {
+	doc, err := parseToml(string(b))
+	if err != nil {
+		return nil, err
+	}
+	clientOnly, _ := doc.Root["clientSideOnly"].(bool)
+	var out []Mod
+	for _, t := range doc.Arrays["mods"] {
+		mod := Mod{
+			Loader:      loader,
+			ID:          tomlString(t, "modId"),
+			Name:        tomlString(t, "displayName"),
+			Version:     tomlString(t, "version"),
+			Description: strings.TrimSpace(tomlString(t, "description")),
+		}
+		if mod.Version == "${file.jarVersion}" {
+			mod.Version = jarVersion
+		}
+		for _, a := range strings.Split(tomlString(t, "authors"), ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				mod.Authors = append(mod.Authors, a)
+			}
+		}
+		if mod.Authors == nil {
+			if s := tomlString(doc.Root, "authors"); s != "" {
+				mod.Authors = []string{s}
+			}
+		}
+		// Only clientSideOnly declares a client-only mod. displayTest IGNORE_ALL_VERSION
+		// just means "not required on the other side" and is used by server-only and
+		// either-side mods too.
+		mod.Environment = EnvBoth
+		if clientOnly {
+			mod.Environment = EnvClient
+		}
+		for _, d := range doc.Arrays["dependencies."+mod.ID] {
+			dep := Dependency{
+				ID:      tomlString(d, "modId"),
+				Version: tomlString(d, "versionRange"),
+				Side:    strings.ToLower(tomlString(d, "side")),
+			}
+			if v, ok := d["mandatory"].(bool); ok {
+				dep.Required = v
+			} else {
+				// NeoForge: type = "required" | "optional" | "incompatible" | "discouraged".
+				dep.Required = strings.EqualFold(tomlString(d, "type"), "required") || tomlString(d, "type") == ""
+			}
+			if dep.ID != "" {
+				mod.Dependencies = append(mod.Dependencies, dep)
+			}
+		}
+		if mod.ID != "" {
+			out = append(out, mod)
+		}
+	}
+	return out, nil
+}
+
+func tomlString(t map[string]any, key string) string {
+	s, _ := t[key].(string)
+	return s
+}
+
+// ---- Bukkit / Paper plugins ----
+
+func parsePluginYAML(b []byte, loader string) ([]Mod, error) {
+	doc, err := parseYAML(string(b))
+	if err != nil {
+		return nil, err
+	}
+	mod := Mod{
+		Loader:      loader,
+		ID:          yamlString(doc["name"]),
+		Version:     yamlString(doc["version"]),
+		Description: yamlString(doc["description"]),
+		Environment: EnvServer,
+	}
+	mod.Name = mod.ID
+	if a := yamlString(doc["author"]); a != "" {
+		mod.Authors = append(mod.Authors, a)
+	}
+	mod.Authors = append(mod.Authors, yamlStrings(doc["authors"])...)
+
+	if loader == LoaderPaper {
+		// dependencies: { server: { Name: { required: true } }, bootstrap: {...} }
+		deps, _ := doc["dependencies"].(map[string]any)
+		server, _ := deps["server"].(map[string]any)
+		names := make([]string, 0, len(server))
+		for name := range server {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			required := true
+			if opts, ok := server[name].(map[string]any); ok {
+				if v := yamlString(opts["required"]); v != "" {
+					required = v == "true"
+				}
+			}
+			mod.Dependencies = append(mod.Dependencies, Dependency{ID: name, Required: required})
+		}
+	} else {
+		for _, d := range yamlStrings(doc["depend"]) {
+			mod.Dependencies = append(mod.Dependencies, Dependency{ID: d, Required: true})
+		}
+		for _, d := range yamlStrings(doc["softdepend"]) {
+			mod.Dependencies = append(mod.Dependencies, Dependency{ID: d})
+		}
+	}
+	if mod.ID == "" {
+		return nil, nil
+	}
+	return []Mod{mod}, nil
+}
+
+// manifestAttr reads a main-section attribute of a MANIFEST.MF.
+func manifestAttr(manifest, key string) string {
+	prefix := strings.ToLower(key) + ":"
+	for _, line := range strings.Split(strings.ReplaceAll(manifest, "\r\n", "\n"), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), prefix) {
+			return strings.TrimSpace(line[len(prefix):])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package mods
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func readZip(t *testing.T, files map[string]string) []Mod {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip open: %v", err)
+	}
+	mods, err := Read(zr)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return mods
+}
+
+func TestRead_Fabric(t *testing.T) {
+	mods := readZip(t, map[string]string{"fabric.mod.json": `{
+		"schemaVersion": 1,
+		"id": "sodium",
+		"version": "0.5.8",
+		"name": "Sodium",
+		"description": "A rendering
+engine",
+		"authors": ["jellysquid", {"name": "IMS"}],
+		"environment": "client",
+		"depends": {"fabricloader": ">=0.12.0", "minecraft": ["1.20.4", "1.20.5"]},
+		"recommends": {"modmenu": "*"}
+	}`})
+	want := []Mod{{
+		Loader:      LoaderFabric,
+		ID:          "sodium",
+		Name:        "Sodium",
+		Version:     "0.5.8",
+		Description: "A rendering\nengine",
+		Authors:     []string{"jellysquid", "IMS"},
+		Environment: EnvClient,
+		Dependencies: []Dependency{
+			{ID: "fabricloader", Version: ">=0.12.0", Required: true},
+			{ID: "minecraft", Version: "1.20.4 || 1.20.5", Required: true},
+			{ID: "modmenu", Version: "*"},
+		},
+	}}
+	if !reflect.DeepEqual(mods, want) {
+		t.Fatalf("got %#v\nwant %#v", mods, want)
+	}
+}
+
+func TestRead_Quilt(t *testing.T) {
+	mods := readZip(t, map[string]string{"quilt.mod.json": `{
+		"quilt_loader": {
+			"id": "qsl", "version": "7.0.0",
+			"metadata": {"name": "QSL", "contributors": {"Alice": "Owner"}},
+			"depends": ["quilt_loader", {"id": "minecraft", "versions": ">=1.20"}, {"id": "emi", "optional": true}]
+		},
+		"minecraft": {"environment": "dedicated_server"}
+	}`})
+	if len(mods) != 1 {
+		t.Fatalf("got %d mods", len(mods))
+	}
+	m := mods[0]
+	if m.ID != "qsl" || m.Environment != EnvServer || !reflect.DeepEqual(m.Authors, []string{"Alice"}) || len(m.Dependencies) != 3 {
+		t.Fatalf("unexpected mod: %#v", m)
+	}
+	if !m.Dependencies[0].Required || m.Dependencies[1].Version != ">=1.20" || m.Dependencies[2].Required {
+		t.Fatalf("unexpected dependencies: %#v", m.Dependencies)
+	}
+}
+
+func TestRead_ForgeAndNeoForge(t *testing.T) {
+	mods := readZip(t, map[string]string{
+		"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\r\nImplementation-Version: 2.3.4\r\n\r\n",
+		"META-INF/mods.toml": `
+modLoader="javafml" # comment
+loaderVersion="[47,)"
+license='MIT'
+
+[[mods]]
+modId="jei"
+version="${file.jarVersion}"
+displayName="Just Enough Items"
+authors="mezz, Alice"
+description='''
+Item and recipe viewer
+'''
+
+[[mods]]
+modId="jei_addon"
+displayTest="IGNORE_ALL_VERSION"
+
+[[dependencies.jei]]
+    modId="forge"
+    mandatory=true
+    versionRange="[47,)"
+    ordering="NONE"
+    side="BOTH"
+[[dependencies.jei]]
+    modId="jade"
+    mandatory=false
+    side="CLIENT"
+`,
+		"META-INF/neoforge.mods.toml": `
+clientSideOnly=true
+[[mods]]
+modId="jei"
+version="19.0.0"
+[[dependencies.jei]]
+modId="neoforge"
+type="required"
+versionRange="[20.4,)"
+[[dependencies.jei]]
+modId="rei"
+type="incompatible"
+`,
+	})
+	if len(mods) != 3 {
+		t.Fatalf("got %d mods: %#v", len(mods), mods)
+	}
+	jei := mods[0]
+	if jei.Loader != LoaderForge || jei.Version != "2.3.4" || jei.Name != "Just Enough Items" || jei.Description != "Item and recipe viewer" {
+		t.Fatalf("unexpected forge mod: %#v", jei)
+	}
+	if !reflect.DeepEqual(jei.Authors, []string{"mezz", "Alice"}) || jei.Environment != EnvBoth {
+		t.Fatalf("unexpected forge mod: %#v", jei)
+	}
+	wantDeps := []Dependency{
+		{ID: "forge", Version: "[47,)", Required: true, Side: "both"},
+		{ID: "jade", Required: false, Side: "client"},
+	}
+	if !reflect.DeepEqual(jei.Dependencies, wantDeps) {
+		t.Fatalf("got deps %#v", jei.Dependencies)
+	}
+	if mods[1].ID != "jei_addon" || mods[1].Environment != EnvBoth {
+		t.Fatalf("displayTest IGNORE_ALL_VERSION must not mark client-only: %#v", mods[1])
+	}
+	neo := mods[2]
+	if neo.Loader != LoaderNeoForge || neo.Environment != EnvClient || len(neo.Dependencies) != 2 || !neo.Dependencies[0].Required || neo.Dependencies[1].Required {
+		t.Fatalf("unexpected neoforge mod: %#v", neo)
+	}
+}
+
+func TestRead_Plugins(t *testing.T) {
+	mods := readZip(t, map[string]string{
+		"plugin.yml": `# comment
+name: EssentialsX
+version: '2.20.1'
+main: com.earth2me.essentials.Essentials
+author: Zenexer
+authors: [ementalo, "snowleo"]
+description: >
+  Essential commands
+  for servers.
+website: https://essentialsx.net
+depend:
+- Vault
+softdepend: [LuckPerms] # optional
+commands:
+  home:
+    description: Teleport home
+`,
+		"paper-plugin.yml": `name: EssentialsX
+version: 2.20.1
+dependencies:
+  server:
+    Vault:
+      load: BEFORE
+      required: false
+    LuckPerms:
+      required: true
+`,
+	})
+	if len(mods) != 2 {
+		t.Fatalf("got %d mods", len(mods))
+	}
+	bukkit := mods[0]
+	if bukkit.ID != "EssentialsX" || bukkit.Version != "2.20.1" || bukkit.Description != "Essential commands for servers." || bukkit.Environment != EnvServer {
+		t.Fatalf("unexpected plugin: %#v", bukkit)
+	}
+	if !reflect.DeepEqual(bukkit.Authors, []string{"Zenexer", "ementalo", "snowleo"}) {
+		t.Fatalf("unexpected authors: %#v", bukkit.Authors)
+	}
+	if !reflect.DeepEqual(bukkit.Dependencies, []Dependency{{ID: "Vault", Required: true}, {ID: "LuckPerms"}}) {
+		t.Fatalf("unexpected dependencies: %#v", bukkit.Dependencies)
+	}
+	paper := mods[1]
+	if paper.Loader != LoaderPaper || !reflect.DeepEqual(paper.Dependencies, []Dependency{{ID: "LuckPerms", Required: true}, {ID: "Vault"}}) {
+		t.Fatalf("unexpected paper plugin: %#v", paper)
+	}
+}
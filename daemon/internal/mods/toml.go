@@ -0,0 +1,299 @@
+package mods
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlDoc is the subset of TOML used by mods.toml: top-level keys, [table] and
+// [[array.of.tables]] headers, strings, booleans, numbers and arrays. Values of
+// [table] sections are kept in Tables; inline tables are skipped.
+type tomlDoc struct {
+	Root   map[string]any
+	Tables map[string]map[string]any
+	Arrays map[string][]map[string]any
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func parseToml(s string) (tomlDoc, error) {
+	doc := tomlDoc{Root: map[string]any{}, Tables: map[string]map[string]any{}, Arrays: map[string][]map[string]any{}}
+	p := &tomlParser{s: strings.TrimPrefix(s, "\ufeff"), line: 1}
+	cur := doc.Root
+	for {
+		p.skipSpaceAndComments(true)
+		if p.eof() {
+			return doc, nil
+		}
+		if p.peek() == '[' {
+			array := strings.HasPrefix(p.s[p.pos:], "[[")
+			end := "]"
+			if array {
+				end = "]]"
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+			i := strings.Index(p.s[p.pos:], end)
+			if i < 0 {
+				return doc, p.errorf("unterminated table header")
+			}
+			name := normalizeTomlKey(p.s[p.pos : p.pos+i])
+			p.pos += i + len(end)
+			if array {
+				cur = map[string]any{}
+				doc.Arrays[name] = append(doc.Arrays[name], cur)
+			} else {
+				if doc.Tables[name] == nil {
+					doc.Tables[name] = map[string]any{}
+				}
+				cur = doc.Tables[name]
+			}
+			continue
+		}
+		key, err := p.key()
+		if err != nil {
+			return doc, err
+		}
+		p.skipSpaceAndComments(false)
+		if p.eof() || p.peek() != '=' {
+			return doc, p.errorf("expected = after %q", key)
+		}
+		p.pos++
+		p.skipSpaceAndComments(false)
+		v, err := p.value()
+		if err != nil {
+			return doc, err
+		}
+		cur[key] = v
+	}
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.s) }
+func (p *tomlParser) peek() byte { return p.s[p.pos] }
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpaceAndComments skips blanks and comments, and newlines if newlines is set.
+func (p *tomlParser) skipSpaceAndComments(newlines bool) {
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) key() (string, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '=' || c == '\n' {
+			break
+		}
+		if c == '"' || c == '\'' {
+			if _, err := p.value(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		p.pos++
+	}
+	key := normalizeTomlKey(p.s[start:p.pos])
+	if key == "" {
+		return "", p.errorf("empty key")
+	}
+	return key, nil
+}
+
+// normalizeTomlKey trims a (possibly dotted or quoted) key: ` a . "b" ` -> `a.b`.
+func normalizeTomlKey(k string) string {
+	parts := strings.Split(k, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+func (p *tomlParser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("missing value")
+	}
+	rest := p.s[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, `'''`):
+		delim := rest[:3]
+		p.pos += 3
+		// A newline right after the opening delimiter is trimmed.
+		if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+			p.pos += 2
+			p.line++
+		} else if strings.HasPrefix(p.s[p.pos:], "\n") {
+			p.pos++
+			p.line++
+		}
+		i := strings.Index(p.s[p.pos:], delim)
+		if i < 0 {
+			return nil, p.errorf("unterminated multi-line string")
+		}
+		raw := p.s[p.pos : p.pos+i]
+		p.line += strings.Count(raw, "\n")
+		p.pos += i + 3
+		if delim == `'''` {
+			return raw, nil
+		}
+		return unescapeToml(raw), nil
+	case rest[0] == '"':
+		p.pos++
+		start := p.pos
+		for !p.eof() && p.peek() != '"' {
+			if p.peek() == '\\' {
+				p.pos++
+			}
+			if !p.eof() && p.peek() == '\n' {
+				return nil, p.errorf("unterminated string")
+			}
+			p.pos++
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated string")
+		}
+		raw := p.s[start:p.pos]
+		p.pos++
+		return unescapeToml(raw), nil
+	case rest[0] == '\'':
+		i := strings.IndexAny(rest[1:], "'\n")
+		if i < 0 || rest[1+i] != '\'' {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos += i + 2
+		return rest[1 : 1+i], nil
+	case rest[0] == '[':
+		p.pos++
+		var arr []any
+		for {
+			p.skipSpaceAndComments(true)
+			if p.eof() {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.peek() == ']' {
+				p.pos++
+				return arr, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+			p.skipSpaceAndComments(true)
+			if !p.eof() && p.peek() == ',' {
+				p.pos++
+			}
+		}
+	case rest[0] == '{':
+		depth := 0
+		for !p.eof() {
+			c := p.peek()
+			p.pos++
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				if depth--; depth == 0 {
+					return nil, nil
+				}
+			}
+		}
+		return nil, p.errorf("unterminated inline table")
+	}
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == ',' || c == ']' || c == '}' || c == '\n' || c == '#' {
+			break
+		}
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.s[start:p.pos])
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("missing value")
+	}
+	if n, err := strconv.ParseFloat(strings.ReplaceAll(raw, "_", ""), 64); err == nil {
+		return n, nil
+	}
+	// Dates and other bare values are kept as text.
+	return raw, nil
+}
+
+func unescapeToml(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+n < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += n
+					continue
+				}
+			}
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		case '\n':
+			// Line-ending backslash: trim the newline and leading whitespace.
+			for i+1 < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i+1])) {
+				i++
+			}
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
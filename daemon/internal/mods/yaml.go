@@ -0,0 +1,249 @@
+package mods
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseYAML reads the block-style YAML subset used by plugin.yml and paper-plugin.yml:
+// nested mappings, "- item" sequences, [a, b] flow sequences, quoted scalars and |/>
+// block scalars. Scalars are returned as strings.
+func parseYAML(s string) (map[string]any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(strings.TrimPrefix(s, "\ufeff"), "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimLeft(raw, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tab indentation", i+1)
+		}
+		text := strings.TrimLeft(raw, " ")
+		lines = append(lines, yamlLine{no: i + 1, indent: len(raw) - len(text), text: strings.TrimRight(text, " \t"), raw: raw})
+	}
+	p := &yamlParser{lines: lines}
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return map[string]any{}, nil
+	}
+	v, err := p.block(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top level is not a mapping")
+	}
+	return m, nil
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+	raw    string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) {
+		t := p.lines[p.pos].text
+		if t != "" && !strings.HasPrefix(t, "#") && t != "---" {
+			return
+		}
+		p.pos++
+	}
+}
+
+// block parses the mapping or sequence starting at the current line, whose entries are
+// indented by indent.
+func (p *yamlParser) block(indent int) (any, error) {
+	p.skipBlank()
+	if p.pos < len(p.lines) && isYAMLSeqItem(p.lines[p.pos].text) {
+		var seq []any
+		for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+			l := p.lines[p.pos]
+			if l.indent != indent || !isYAMLSeqItem(l.text) {
+				break
+			}
+			p.pos++
+			item := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+			if k, v, isMap := splitYAMLKey(item); isMap {
+				// "- key: value" starts a mapping item; only its inline pairs are kept.
+				m := map[string]any{k: yamlScalar(v)}
+				for p.skipBlank(); p.pos < len(p.lines) && p.lines[p.pos].indent > indent; p.skipBlank() {
+					if k2, v2, ok := splitYAMLKey(p.lines[p.pos].text); ok {
+						m[k2] = yamlScalar(v2)
+					}
+					p.pos++
+				}
+				seq = append(seq, m)
+				continue
+			}
+			seq = append(seq, yamlScalar(item))
+		}
+		return seq, nil
+	}
+
+	m := map[string]any{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.no)
+		}
+		k, v, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", l.no)
+		}
+		p.pos++
+		switch {
+		case v == "|" || v == ">" || strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">"):
+			m[k] = p.blockScalar(indent, v[0] == '>')
+		case v != "":
+			m[k] = yamlScalar(v)
+		default:
+			p.skipBlank()
+			if p.pos >= len(p.lines) {
+				m[k] = ""
+				continue
+			}
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLSeqItem(next.text)) {
+				child, err := p.block(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[k] = child
+			} else {
+				m[k] = ""
+			}
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) blockScalar(indent int, folded bool) string {
+	var parts []string
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.text != "" && l.indent <= indent {
+			break
+		}
+		parts = append(parts, strings.TrimSpace(l.raw))
+		p.pos++
+	}
+	sep := "\n"
+	if folded {
+		sep = " "
+	}
+	return strings.TrimSpace(strings.Join(parts, sep))
+}
+
+func isYAMLSeqItem(t string) bool {
+	return t == "-" || strings.HasPrefix(t, "- ")
+}
+
+// splitYAMLKey splits "key: value" (the value has its comment removed).
+func splitYAMLKey(t string) (string, string, bool) {
+	if strings.HasPrefix(t, "\"") || strings.HasPrefix(t, "'") {
+		end := strings.IndexByte(t[1:], t[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key := t[1 : 1+end]
+		rest := strings.TrimSpace(t[2+end:])
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, stripYAMLComment(strings.TrimSpace(rest[1:])), true
+	}
+	i := strings.Index(t, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(t, ":") {
+			return "", "", false
+		}
+		i = len(t) - 1
+	}
+	key := strings.TrimSpace(t[:i])
+	if key == "" || strings.ContainsAny(key, "[{") {
+		return "", "", false
+	}
+	return key, stripYAMLComment(strings.TrimSpace(t[i+1:])), true
+}
+
+func stripYAMLComment(v string) string {
+	if strings.HasPrefix(v, "#") {
+		return ""
+	}
+	quote := byte(0)
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || v[i-1] == ' ' || v[i-1] == '[' || v[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && i > 0 && v[i-1] == ' ':
+			return strings.TrimSpace(v[:i])
+		}
+	}
+	return v
+}
+
+// yamlScalar converts a plain, quoted or [flow, sequence] value.
+func yamlScalar(v string) any {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		var seq []any
+		for _, part := range strings.Split(v[1:len(v)-1], ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				seq = append(seq, yamlUnquote(part))
+			}
+		}
+		return seq
+	}
+	return yamlUnquote(v)
+}
+
+func yamlUnquote(v string) string {
+	if len(v) >= 2 {
+		switch {
+		case v[0] == '"' && v[len(v)-1] == '"':
+			return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n").Replace(v[1 : len(v)-1])
+		case v[0] == '\'' && v[len(v)-1] == '\'':
+			return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+		}
+	}
+	return v
+}
+
+func yamlString(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// yamlStrings accepts a sequence or a single scalar.
+func yamlStrings(v any) []string {
+	switch t := v.(type) {
+	case string:
+		if s := strings.TrimSpace(t); s != "" {
+			return []string{s}
+		}
+	case []any:
+		var out []string
+		for _, item := range t {
+			if s := yamlString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -22,8 +22,12 @@ type Hello struct {
 
 // HelloAck is sent by the panel after it receives Hello.
 // It can be used for panel-binding and feature negotiation.
+//
+// With feature "ack" the panel answers every command_result with a message of type
+// "ack" carrying the same id; the daemon re-sends unacknowledged results after reconnecting.
 type HelloAck struct {
-	PanelID string `json:"panel_id"`
+	PanelID  string   `json:"panel_id"`
+	Features []string `json:"features,omitempty"`
 }
 
 // Heartbeat is sent periodically by the daemon.
@@ -40,6 +44,16 @@ type Heartbeat struct {
 	Net        *NetInfo          `json:"net,omitempty"`
 	LastError  string            `json:"last_error,omitempty"`
 	ServerTime int64             `json:"server_time_unix,omitempty"`
+	Outbox     *OutboxStats      `json:"outbox,omitempty"`
+}
+
+// OutboxStats describes the daemon's outbound message queue.
+type OutboxStats struct {
+	PendingResults int    `json:"pending_results"`
+	QueuedLogs     int    `json:"queued_logs"`
+	QueuedLogBytes int    `json:"queued_log_bytes"`
+	DroppedLogs    uint64 `json:"dropped_logs"`
+	DroppedResults uint64 `json:"dropped_results"`
 }
 
 type CPUStat struct {
@@ -79,12 +93,63 @@ type MCInstance struct {
 	PID               int      `json:"pid,omitempty"`
 	CPUPercent        *float64 `json:"cpu_percent,omitempty"`
 	MemRSSBytes       *uint64  `json:"mem_rss_bytes,omitempty"`
+	MemLimitBytes     *uint64  `json:"mem_limit_bytes,omitempty"`
+	Cgroup            bool     `json:"cgroup,omitempty"`
 	Java              string   `json:"java,omitempty"`
 	JavaMajor         int      `json:"java_major,omitempty"`
 	RequiredJavaMajor int      `json:"required_java_major,omitempty"`
 	LastExitCode      *int     `json:"last_exit_code,omitempty"`
 	LastExitSignal    string   `json:"last_exit_signal,omitempty"`
 	LastExitUnix      int64    `json:"last_exit_unix,omitempty"`
+	RestartPolicy     string   `json:"restart_policy,omitempty"`
+	RestartCount      int      `json:"restart_count,omitempty"`
+	NextRestartUnix   int64    `json:"next_restart_unix,omitempty"`
+	RestartGaveUp     bool     `json:"restart_gave_up,omitempty"`
+	Adopted           bool     `json:"adopted,omitempty"`
+	JvmProfile        string   `json:"jvm_profile,omitempty"`
+	CommandLine       string   `json:"command_line,omitempty"`
+	Ready             bool     `json:"ready,omitempty"`
+	ReadyUnix         int64    `json:"ready_unix,omitempty"`
+	Ping              *MCPing  `json:"ping,omitempty"`
+}
+
+// Event is a typed occurrence parsed from server console output (message type "event").
+// Kinds: server_done, player_join, player_leave, chat, death, advancement, lag, exception.
+type Event struct {
+	Source   string `json:"source"`
+	Instance string `json:"instance,omitempty"`
+	Kind     string `json:"kind"`
+	Stream   string `json:"stream,omitempty"`
+	Line     string `json:"line,omitempty"`
+
+	Player  string `json:"player,omitempty"`
+	UUID    string `json:"uuid,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	StartupMs    int64 `json:"startup_ms,omitempty"`
+	BehindMs     int64 `json:"behind_ms,omitempty"`
+	SkippedTicks int   `json:"skipped_ticks,omitempty"`
+
+	Exception  string   `json:"exception,omitempty"`
+	StackTrace []string `json:"stack_trace,omitempty"`
+}
+
+// MCPing is the latest Server List Ping result of a running instance.
+type MCPing struct {
+	VersionName   string         `json:"version_name,omitempty"`
+	Protocol      int            `json:"protocol,omitempty"`
+	OnlinePlayers int            `json:"online_players"`
+	MaxPlayers    int            `json:"max_players"`
+	PlayersSample []MCPingPlayer `json:"players_sample,omitempty"`
+	MOTD          string         `json:"motd,omitempty"`
+	LatencyMs     int64          `json:"latency_ms"`
+	Legacy        bool           `json:"legacy,omitempty"`
+	CheckedUnix   int64          `json:"checked_unix"`
+}
+
+type MCPingPlayer struct {
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"`
 }
 
 // Command is sent by the panel to ask the daemon to do something.
@@ -104,8 +169,10 @@ type CommandResult struct {
 
 // LogLine streams process output (mc/frp) to the panel.
 type LogLine struct {
-	Source   string `json:"source"` // "mc" | "frp"
+	Source   string `json:"source"` // "mc" | "frp" | "install"
 	Stream   string `json:"stream"` // "stdout" | "stderr"
 	Instance string `json:"instance,omitempty"`
 	Line     string `json:"line"`
+	Seq      uint64 `json:"seq,omitempty"` // daemon-wide increasing sequence (see log_tail)
+	TSUnixMs int64  `json:"ts_unix_ms,omitempty"`
 }
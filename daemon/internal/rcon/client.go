@@ -0,0 +1,224 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packet types of the Source RCON protocol (as implemented by Minecraft).
+const (
+	TypeResponse = 0
+	TypeCommand  = 2
+	TypeAuthResp = 2
+	TypeAuth     = 3
+)
+
+const (
+	maxChunkSize  = 4096 // Minecraft splits responses at 4096 bytes of body.
+	maxPacketSize = maxChunkSize + 10
+	maxBodySize   = 1446 // Minecraft rejects longer requests.
+)
+
+var ErrAuthFailed = errors.New("rcon: authentication failed")
+
+type Client struct {
+	conn net.Conn
+	rd   *bufio.Reader
+
+	mu     sync.Mutex
+	nextID int32
+}
+
+// Dial connects to addr and authenticates with password.
+func Dial(ctx context.Context, addr string, password string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, rd: bufio.NewReader(conn), nextID: 1}
+	if err := c.auth(ctx, password); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) auth(ctx context.Context, password string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyDeadline(ctx)
+
+	id := c.allocID()
+	if err := c.writePacket(id, TypeAuth, password); err != nil {
+		return err
+	}
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		// Some servers send an empty RESPONSE_VALUE before the auth response.
+		if p.typ != TypeAuthResp {
+			continue
+		}
+		if p.id == -1 {
+			return ErrAuthFailed
+		}
+		if p.id != id {
+			return fmt.Errorf("rcon: unexpected auth response id=%d", p.id)
+		}
+		return nil
+	}
+}
+
+// Command runs a console command and returns the full (possibly multi-packet) response body.
+func (c *Client) Command(ctx context.Context, command string) (string, error) {
+	command = strings.TrimRight(command, "\r\n")
+	if command == "" {
+		return "", errors.New("rcon: command is empty")
+	}
+	if len(command) > maxBodySize {
+		return "", fmt.Errorf("rcon: command too long (max %d bytes)", maxBodySize)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyDeadline(ctx)
+
+	id := c.allocID()
+	if err := c.writePacket(id, TypeCommand, command); err != nil {
+		return "", err
+	}
+
+	// Vanilla reads each request with a single read() into a 1460-byte buffer and drops the
+	// connection if it holds more than one packet, so nothing else may be written until the
+	// command has been answered.
+	var first packet
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return "", err
+		}
+		if p.id == -1 {
+			return "", ErrAuthFailed
+		}
+		if p.id == id {
+			first = p
+			break
+		}
+	}
+	if len(first.body) < maxChunkSize {
+		return first.body, nil
+	}
+
+	// A full chunk may be followed by more. Send a request the server answers after the
+	// remaining output; its reply marks the end of the fragmented response.
+	sentinel := c.allocID()
+	if err := c.writePacket(sentinel, TypeResponse, ""); err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	out.WriteString(first.body)
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return "", err
+		}
+		switch p.id {
+		case id:
+			out.WriteString(p.body)
+		case sentinel:
+			return out.String(), nil
+		case -1:
+			return "", ErrAuthFailed
+		}
+	}
+}
+
+func (c *Client) applyDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	_ = c.conn.SetDeadline(deadline)
+}
+
+func (c *Client) allocID() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+	return id
+}
+
+type packet struct {
+	id   int32
+	typ  int32
+	body string
+}
+
+func (c *Client) writePacket(id int32, typ int32, body string) error {
+	return WritePacket(c.conn, id, typ, body)
+}
+
+func (c *Client) readPacket() (packet, error) {
+	id, typ, body, err := ReadPacket(c.rd)
+	return packet{id: id, typ: typ, body: body}, err
+}
+
+// WritePacket encodes one RCON packet: little-endian length, id, type, NUL-terminated body, NUL pad.
+func WritePacket(w io.Writer, id int32, typ int32, body string) error {
+	buf := make([]byte, 4+4+4+len(body)+2)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(buf)-4))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(id))
+	binary.LittleEndian.PutUint32(buf[8:12], uint32(typ))
+	copy(buf[12:], body)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadPacket decodes one RCON packet.
+func ReadPacket(r io.Reader) (id int32, typ int32, body string, err error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, 0, "", err
+	}
+	size := int32(binary.LittleEndian.Uint32(hdr[:]))
+	if size < 10 || size > maxPacketSize {
+		return 0, 0, "", fmt.Errorf("rcon: invalid packet size %d", size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, 0, "", err
+	}
+	id = int32(binary.LittleEndian.Uint32(b[0:4]))
+	typ = int32(binary.LittleEndian.Uint32(b[4:8]))
+	payload := b[8:]
+	if i := indexNUL(payload); i >= 0 {
+		payload = payload[:i]
+	}
+	return id, typ, string(payload), nil
+}
+
+func indexNUL(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,169 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer mimics Minecraft's RCON thread: each request is taken from a single read() into
+// a 1460-byte buffer and the connection is dropped unless it holds exactly one packet; commands
+// are answered in 4096-byte chunks and other packet types get "Unknown request".
+func fakeServer(t *testing.T, password string, handle func(cmd string) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				authed := false
+				buf := make([]byte, 1460)
+				for {
+					// Give pipelined writes time to coalesce, as they would on a busy server.
+					time.Sleep(20 * time.Millisecond)
+					n, err := conn.Read(buf)
+					if err != nil || n < 10 {
+						return
+					}
+					if int(binary.LittleEndian.Uint32(buf[0:4])) != n-4 {
+						return
+					}
+					id, typ, body, err := ReadPacket(bytes.NewReader(buf[:n]))
+					if err != nil {
+						return
+					}
+					switch {
+					case typ == TypeAuth:
+						if body == password {
+							authed = true
+							_ = WritePacket(conn, id, TypeAuthResp, "")
+						} else {
+							_ = WritePacket(conn, -1, TypeAuthResp, "")
+						}
+					case !authed:
+						return
+					case typ == TypeCommand:
+						resp := handle(body)
+						for {
+							chunk := resp
+							if len(chunk) > 4096 {
+								chunk = chunk[:4096]
+							}
+							_ = WritePacket(conn, id, TypeResponse, chunk)
+							resp = resp[len(chunk):]
+							if resp == "" {
+								break
+							}
+						}
+					default:
+						_ = WritePacket(conn, id, TypeResponse, fmt.Sprintf("Unknown request %x", typ))
+					}
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClient_Command(t *testing.T) {
+	addr := fakeServer(t, "secret", func(cmd string) string { return "ran: " + cmd })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(ctx, addr, "secret")
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	defer c.Close()
+
+	got, err := c.Command(ctx, "list")
+	if err != nil {
+		t.Fatalf("Command(): %v", err)
+	}
+	if got != "ran: list" {
+		t.Fatalf("got %q", got)
+	}
+	got, err = c.Command(ctx, "say hi")
+	if err != nil {
+		t.Fatalf("Command() second: %v", err)
+	}
+	if got != "ran: say hi" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestClient_FragmentedResponse(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	addr := fakeServer(t, "secret", func(cmd string) string { return long })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(ctx, addr, "secret")
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	defer c.Close()
+
+	got, err := c.Command(ctx, "help")
+	if err != nil {
+		t.Fatalf("Command(): %v", err)
+	}
+	if got != long {
+		t.Fatalf("got %d bytes, want %d", len(got), len(long))
+	}
+	// The connection must still be usable after the sentinel exchange.
+	got, err = c.Command(ctx, "list")
+	if err != nil {
+		t.Fatalf("Command() after fragmented response: %v", err)
+	}
+	if got != long {
+		t.Fatalf("got %d bytes after fragmented response", len(got))
+	}
+}
+
+func TestClient_ExactChunkResponse(t *testing.T) {
+	exact := strings.Repeat("y", 4096)
+	addr := fakeServer(t, "secret", func(cmd string) string { return exact })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := Dial(ctx, addr, "secret")
+	if err != nil {
+		t.Fatalf("Dial(): %v", err)
+	}
+	defer c.Close()
+
+	got, err := c.Command(ctx, "help")
+	if err != nil {
+		t.Fatalf("Command(): %v", err)
+	}
+	if got != exact {
+		t.Fatalf("got %d bytes, want %d", len(got), len(exact))
+	}
+}
+
+func TestClient_AuthFailed(t *testing.T) {
+	addr := fakeServer(t, "secret", func(cmd string) string { return "" })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Dial(ctx, addr, "wrong")
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected ErrAuthFailed, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"elegantmc/daemon/internal/backup"
@@ -27,11 +28,17 @@ type Deps struct {
 	ServersFS *sandbox.FS
 	MC        *mc.Manager
 	Log       *log.Logger
+	// Progress receives per-instance progress lines (the panel's install log); optional.
+	Progress func(instanceID string, line string)
 }
 
 type Manager struct {
 	cfg  Config
 	deps Deps
+
+	mu sync.Mutex
+	// baseCtx outlives individual task runs; servers started by restart tasks are bound to it.
+	baseCtx context.Context
 }
 
 type ScheduleFile struct {
@@ -49,12 +56,22 @@ type Task struct {
 	AtUnix   int64 `json:"at_unix,omitempty"`   // if set, run once at/after time
 
 	// backup options
-	KeepLast int   `json:"keep_last,omitempty"` // backup retention (backup) or log retention (prune_logs)
-	Stop     *bool `json:"stop,omitempty"`      // default true
+	KeepLast int    `json:"keep_last,omitempty"` // backup retention (backup) or log retention (prune_logs)
+	Stop     *bool  `json:"stop,omitempty"`      // default true
+	Format   string `json:"format,omitempty"`    // "zip" (default) | "repo" (deduplicated snapshots)
 
 	// announce options
 	Message string `json:"message,omitempty"`
 
+	// restart/stop options (same meaning as the mc_stop args)
+	WarnSec        int    `json:"warn_sec,omitempty"`         // countdown broadcast before stopping
+	WarnIntervals  []int  `json:"warn_intervals,omitempty"`   // remaining-seconds marks
+	WarnMessage    string `json:"warn_message,omitempty"`     // "{seconds}" is replaced
+	WarnTellraw    bool   `json:"warn_tellraw,omitempty"`     // tellraw instead of say
+	SaveAll        *bool  `json:"save_all,omitempty"`         // default false
+	StopTimeoutSec int    `json:"stop_timeout_sec,omitempty"` // wait after "stop" before SIGTERM (default 15)
+	TermTimeoutSec int    `json:"term_timeout_sec,omitempty"` // wait after SIGTERM before SIGKILL (default 5)
+
 	LastRunUnix int64  `json:"last_run_unix,omitempty"`
 	LastError   string `json:"last_error,omitempty"`
 }
@@ -82,6 +99,9 @@ func (m *Manager) Run(ctx context.Context) {
 	if !m.cfg.Enabled {
 		return
 	}
+	m.mu.Lock()
+	m.baseCtx = ctx
+	m.mu.Unlock()
 
 	ticker := time.NewTicker(m.cfg.PollEvery)
 	defer ticker.Stop()
@@ -175,17 +195,17 @@ func (m *Manager) runTask(ctx context.Context, t Task) error {
 	switch strings.ToLower(strings.TrimSpace(t.Type)) {
 	case "restart":
 		m.logf("scheduler: restart: instance=%s", t.InstanceID)
-		return m.restart(ctx, t.InstanceID)
+		return m.restart(ctx, t.InstanceID, m.stopOptions(t))
 	case "stop":
 		m.logf("scheduler: stop: instance=%s", t.InstanceID)
-		return m.stop(ctx, t.InstanceID)
+		return m.stop(ctx, t.InstanceID, m.stopOptions(t))
 	case "backup":
 		stop := true
 		if t.Stop != nil {
 			stop = *t.Stop
 		}
 		m.logf("scheduler: backup: instance=%s", t.InstanceID)
-		return m.backup(ctx, t.InstanceID, t.KeepLast, stop)
+		return m.backup(ctx, t.InstanceID, t.Format, t.KeepLast, stop)
 	case "announce":
 		m.logf("scheduler: announce: instance=%s", t.InstanceID)
 		return m.announce(ctx, t.InstanceID, t.Message)
@@ -197,7 +217,32 @@ func (m *Manager) runTask(ctx context.Context, t Task) error {
 	}
 }
 
-func (m *Manager) restart(ctx context.Context, instanceID string) error {
+func (m *Manager) stopOptions(t Task) mc.StopOptions {
+	opt := mc.DefaultStopOptions()
+	opt.WarnSec = t.WarnSec
+	opt.WarnIntervals = t.WarnIntervals
+	opt.WarnMessage = t.WarnMessage
+	opt.Tellraw = t.WarnTellraw
+	if t.SaveAll != nil {
+		opt.SaveAll = *t.SaveAll
+	}
+	if t.StopTimeoutSec > 0 {
+		opt.TimeoutSec = t.StopTimeoutSec
+	}
+	if t.TermTimeoutSec > 0 {
+		opt.TermTimeoutSec = t.TermTimeoutSec
+	}
+	instanceID := t.InstanceID
+	opt.Progress = func(line string) {
+		m.logf("scheduler: stop: instance=%s %s", instanceID, line)
+		if m.deps.Progress != nil {
+			m.deps.Progress(instanceID, "[stop] "+line)
+		}
+	}
+	return opt
+}
+
+func (m *Manager) restart(ctx context.Context, instanceID string, stopOpt mc.StopOptions) error {
 	if m.deps.ServersFS == nil || m.deps.MC == nil {
 		return errors.New("daemon misconfigured: scheduler deps missing")
 	}
@@ -211,8 +256,16 @@ func (m *Manager) restart(ctx context.Context, instanceID string) error {
 		jar = "server.jar"
 	}
 
-	_ = m.deps.MC.Stop(ctx, instanceID)
-	return m.deps.MC.Start(ctx, mc.StartOptions{
+	_ = m.deps.MC.StopWithOptions(ctx, instanceID, stopOpt)
+
+	// ctx is canceled when the task run ends; the server must outlive it.
+	m.mu.Lock()
+	startCtx := m.baseCtx
+	m.mu.Unlock()
+	if startCtx == nil {
+		startCtx = context.WithoutCancel(ctx)
+	}
+	return m.deps.MC.Start(startCtx, mc.StartOptions{
 		InstanceID: instanceID,
 		JarPath:    jar,
 		JavaPath:   strings.TrimSpace(cfg.JavaPath),
@@ -222,14 +275,14 @@ func (m *Manager) restart(ctx context.Context, instanceID string) error {
 	}, nil)
 }
 
-func (m *Manager) stop(ctx context.Context, instanceID string) error {
+func (m *Manager) stop(ctx context.Context, instanceID string, opt mc.StopOptions) error {
 	if m.deps.MC == nil {
 		return errors.New("daemon misconfigured: scheduler deps missing")
 	}
-	return m.deps.MC.Stop(ctx, instanceID)
+	return m.deps.MC.StopWithOptions(ctx, instanceID, opt)
 }
 
-func (m *Manager) backup(ctx context.Context, instanceID string, keepLast int, stop bool) error {
+func (m *Manager) backup(ctx context.Context, instanceID string, format string, keepLast int, stop bool) error {
 	if m.deps.ServersFS == nil || m.deps.MC == nil {
 		return errors.New("daemon misconfigured: scheduler deps missing")
 	}
@@ -245,7 +298,11 @@ func (m *Manager) backup(ctx context.Context, instanceID string, keepLast int, s
 		return err
 	}
 
-	name := fmt.Sprintf("%s-%d.zip", instanceID, time.Now().Unix())
+	ext := ".zip"
+	if format == "repo" {
+		ext = backup.SnapshotExt
+	}
+	name := fmt.Sprintf("%s-%d%s", instanceID, time.Now().Unix(), ext)
 	destRel := filepath.Join("_backups", instanceID, name)
 	destAbs, err := m.deps.ServersFS.Resolve(destRel)
 	if err != nil {
@@ -262,11 +319,23 @@ func (m *Manager) backup(ctx context.Context, instanceID string, keepLast int, s
 	default:
 	}
 
-	files, err := backup.ZipDir(srcAbs, destAbs)
-	if err != nil {
-		return err
+	if format == "repo" {
+		repo, err := backup.OpenRepo(filepath.Dir(destAbs))
+		if err != nil {
+			return err
+		}
+		st, err := repo.Backup(srcAbs, name, nil)
+		if err != nil {
+			return err
+		}
+		m.logf("scheduler: backup ok: instance=%s files=%d new_chunks=%d/%d stored=%d path=%s", instanceID, st.Files, st.NewChunks, st.Chunks, st.StoredBytes, destRel)
+	} else {
+		files, err := backup.ZipDir(srcAbs, destAbs)
+		if err != nil {
+			return err
+		}
+		m.logf("scheduler: backup ok: instance=%s files=%d path=%s", instanceID, files, destRel)
 	}
-	m.logf("scheduler: backup ok: instance=%s files=%d path=%s", instanceID, files, destRel)
 
 	if keepLast > 0 {
 		_ = pruneOldBackups(destAbs, keepLast)
@@ -343,11 +412,17 @@ func (m *Manager) pruneLogs(ctx context.Context, instanceID string, keepLast int
 	return nil
 }
 
-func pruneOldBackups(latestZipAbs string, keepLast int) error {
+// pruneOldBackups keeps the newest keepLast backups of the same kind as latestAbs
+// (zip archives or repository snapshots).
+func pruneOldBackups(latestAbs string, keepLast int) error {
 	if keepLast < 1 {
 		return nil
 	}
-	dir := filepath.Dir(latestZipAbs)
+	ext := ".zip"
+	if backup.IsSnapshotPath(latestAbs) {
+		ext = backup.SnapshotExt
+	}
+	dir := filepath.Dir(latestAbs)
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return err
@@ -362,7 +437,7 @@ func pruneOldBackups(latestZipAbs string, keepLast int) error {
 			continue
 		}
 		name := ent.Name()
-		if !strings.HasSuffix(strings.ToLower(name), ".zip") {
+		if !strings.HasSuffix(strings.ToLower(name), ext) {
 			continue
 		}
 		info, err := ent.Info()
@@ -372,6 +447,22 @@ func pruneOldBackups(latestZipAbs string, keepLast int) error {
 		files = append(files, item{path: filepath.Join(dir, name), ts: info.ModTime()})
 	}
 	sort.Slice(files, func(i, j int) bool { return files[i].ts.After(files[j].ts) })
+	if len(files) <= keepLast {
+		return nil
+	}
+	if ext == backup.SnapshotExt {
+		repo, err := backup.OpenRepo(dir)
+		if err != nil {
+			return err
+		}
+		for i := keepLast; i < len(files); i++ {
+			if _, _, err := repo.Forget(filepath.Base(files[i].path)); err != nil {
+				return err
+			}
+			_ = os.Remove(files[i].path + ".meta.json")
+		}
+		return nil
+	}
 	for i := keepLast; i < len(files); i++ {
 		_ = os.Remove(files[i].path)
 	}
@@ -0,0 +1,309 @@
+package slp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Status is the result of a Server List Ping.
+type Status struct {
+	VersionName string
+	Protocol    int
+	Online      int
+	Max         int
+	Sample      []Player
+	MOTD        string
+	Latency     time.Duration
+	Legacy      bool // answered only the pre-1.7 (1.6) ping
+}
+
+type Player struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+type statusJSON struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int      `json:"max"`
+		Online int      `json:"online"`
+		Sample []Player `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+}
+
+const maxStatusJSON = 1 << 20
+
+// Ping queries host:port with the modern (1.7+) handshake and falls back to the 1.6 legacy ping.
+func Ping(ctx context.Context, host string, port int) (Status, error) {
+	st, err := pingModern(ctx, host, port)
+	if err == nil {
+		return st, nil
+	}
+	if ctx.Err() != nil {
+		return Status{}, err
+	}
+	if legacy, lerr := pingLegacy(ctx, host, port); lerr == nil {
+		return legacy, nil
+	}
+	return Status{}, err
+}
+
+func dial(ctx context.Context, host string, port int) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	_ = conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+func pingModern(ctx context.Context, host string, port int) (Status, error) {
+	conn, err := dial(ctx, host, port)
+	if err != nil {
+		return Status{}, err
+	}
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+
+	// Handshake: protocol -1 (any), server address, port, next state 1 (status).
+	var hs bytes.Buffer
+	writeVarInt(&hs, 0x00)
+	writeVarInt(&hs, -1)
+	writeString(&hs, host)
+	_ = binary.Write(&hs, binary.BigEndian, uint16(port))
+	writeVarInt(&hs, 1)
+	if err := writePacket(conn, hs.Bytes()); err != nil {
+		return Status{}, err
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return Status{}, err
+	}
+
+	body, err := readPacket(rd)
+	if err != nil {
+		return Status{}, err
+	}
+	br := bytes.NewReader(body)
+	id, err := readVarInt(br)
+	if err != nil {
+		return Status{}, err
+	}
+	if id != 0x00 {
+		return Status{}, fmt.Errorf("slp: unexpected packet id 0x%02x", id)
+	}
+	raw, err := readString(br, maxStatusJSON)
+	if err != nil {
+		return Status{}, err
+	}
+	var sj statusJSON
+	if err := json.Unmarshal([]byte(raw), &sj); err != nil {
+		return Status{}, fmt.Errorf("slp: invalid status json: %w", err)
+	}
+	st := Status{
+		VersionName: sj.Version.Name,
+		Protocol:    sj.Version.Protocol,
+		Online:      sj.Players.Online,
+		Max:         sj.Players.Max,
+		Sample:      sj.Players.Sample,
+		MOTD:        flattenChat(sj.Description),
+	}
+
+	// Ping/pong for round-trip latency.
+	var ping bytes.Buffer
+	writeVarInt(&ping, 0x01)
+	payload := time.Now().UnixNano()
+	_ = binary.Write(&ping, binary.BigEndian, payload)
+	sent := time.Now()
+	if err := writePacket(conn, ping.Bytes()); err != nil {
+		return st, nil
+	}
+	if pong, err := readPacket(rd); err == nil && len(pong) >= 9 && pong[0] == 0x01 {
+		st.Latency = time.Since(sent)
+	}
+	return st, nil
+}
+
+func pingLegacy(ctx context.Context, host string, port int) (Status, error) {
+	conn, err := dial(ctx, host, port)
+	if err != nil {
+		return Status{}, err
+	}
+	defer conn.Close()
+
+	// 1.6 format: FE 01 FA "MC|PingHost" len(data) protocol(74) host port.
+	var data bytes.Buffer
+	data.WriteByte(74)
+	writeUTF16(&data, host)
+	_ = binary.Write(&data, binary.BigEndian, int32(port))
+
+	var req bytes.Buffer
+	req.Write([]byte{0xFE, 0x01, 0xFA})
+	writeUTF16(&req, "MC|PingHost")
+	_ = binary.Write(&req, binary.BigEndian, uint16(data.Len()))
+	req.Write(data.Bytes())
+	sent := time.Now()
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return Status{}, err
+	}
+
+	rd := bufio.NewReader(conn)
+	kick, err := rd.ReadByte()
+	if err != nil {
+		return Status{}, err
+	}
+	if kick != 0xFF {
+		return Status{}, errors.New("slp: invalid legacy response")
+	}
+	var n uint16
+	if err := binary.Read(rd, binary.BigEndian, &n); err != nil {
+		return Status{}, err
+	}
+	units := make([]uint16, n)
+	if err := binary.Read(rd, binary.BigEndian, units); err != nil {
+		return Status{}, err
+	}
+	latency := time.Since(sent)
+	text := string(utf16.Decode(units))
+
+	// "§1\x00protocol\x00version\x00motd\x00online\x00max"
+	parts := strings.Split(text, "\x00")
+	if len(parts) != 6 || parts[0] != "§1" {
+		return Status{}, errors.New("slp: unsupported legacy response")
+	}
+	proto, _ := strconv.Atoi(parts[1])
+	online, _ := strconv.Atoi(parts[4])
+	max, _ := strconv.Atoi(parts[5])
+	return Status{
+		VersionName: parts[2],
+		Protocol:    proto,
+		MOTD:        parts[3],
+		Online:      online,
+		Max:         max,
+		Latency:     latency,
+		Legacy:      true,
+	}, nil
+}
+
+// flattenChat turns a description (plain string or chat component) into plain text.
+func flattenChat(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var comp struct {
+		Text  string            `json:"text"`
+		Extra []json.RawMessage `json:"extra"`
+	}
+	if err := json.Unmarshal(raw, &comp); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(comp.Text)
+	for _, e := range comp.Extra {
+		b.WriteString(flattenChat(e))
+	}
+	return b.String()
+}
+
+func writePacket(w io.Writer, body []byte) error {
+	var buf bytes.Buffer
+	writeVarInt(&buf, int32(len(body)))
+	buf.Write(body)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readPacket(r io.ByteReader) ([]byte, error) {
+	n, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 || n > maxStatusJSON+16 {
+		return nil, fmt.Errorf("slp: invalid packet length %d", n)
+	}
+	b := make([]byte, n)
+	for i := range b {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		b[i] = c
+	}
+	return b, nil
+}
+
+func writeVarInt(buf *bytes.Buffer, v int32) {
+	u := uint32(v)
+	for {
+		if u&^0x7F == 0 {
+			buf.WriteByte(byte(u))
+			return
+		}
+		buf.WriteByte(byte(u&0x7F | 0x80))
+		u >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var out uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		out |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(out), nil
+		}
+	}
+	return 0, errors.New("slp: varint too long")
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeVarInt(buf, int32(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader, max int) (string, error) {
+	n, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || int(n) > max || int(n) > r.Len() {
+		return "", errors.New("slp: invalid string length")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func writeUTF16(buf *bytes.Buffer, s string) {
+	units := utf16.Encode([]rune(s))
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(units)))
+	_ = binary.Write(buf, binary.BigEndian, units)
+}
@@ -0,0 +1,103 @@
+package slp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func listen(t *testing.T, handle func(conn net.Conn)) (string, int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+func TestPing_Modern(t *testing.T) {
+	const status = `{"version":{"name":"1.21.1","protocol":767},"players":{"max":20,"online":2,"sample":[{"name":"Steve","id":"8667ba71-b85a-4004-af54-457a9734eed7"}]},"description":{"text":"Hello ","extra":[{"text":"World"}]}}`
+	host, port := listen(t, func(conn net.Conn) {
+		rd := bufio.NewReader(conn)
+		if _, err := readPacket(rd); err != nil { // handshake
+			return
+		}
+		if _, err := readPacket(rd); err != nil { // status request
+			return
+		}
+		var body bytes.Buffer
+		writeVarInt(&body, 0x00)
+		writeString(&body, status)
+		_ = writePacket(conn, body.Bytes())
+		ping, err := readPacket(rd)
+		if err != nil {
+			return
+		}
+		_ = writePacket(conn, ping)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := Ping(ctx, host, port)
+	if err != nil {
+		t.Fatalf("Ping(): %v", err)
+	}
+	if st.Legacy || st.VersionName != "1.21.1" || st.Protocol != 767 || st.Online != 2 || st.Max != 20 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if st.MOTD != "Hello World" {
+		t.Fatalf("motd=%q", st.MOTD)
+	}
+	if len(st.Sample) != 1 || st.Sample[0].Name != "Steve" {
+		t.Fatalf("sample=%+v", st.Sample)
+	}
+}
+
+func TestPing_LegacyFallback(t *testing.T) {
+	host, port := listen(t, func(conn net.Conn) {
+		b := make([]byte, 1)
+		if _, err := conn.Read(b); err != nil {
+			return
+		}
+		if b[0] != 0xFE {
+			return // modern handshake: drop the connection like a pre-1.7 server
+		}
+		units := utf16.Encode([]rune("§1\x0078\x001.6.4\x00A Legacy Server\x003\x0010"))
+		var resp bytes.Buffer
+		resp.WriteByte(0xFF)
+		_ = binary.Write(&resp, binary.BigEndian, uint16(len(units)))
+		_ = binary.Write(&resp, binary.BigEndian, units)
+		_, _ = conn.Write(resp.Bytes())
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := Ping(ctx, host, port)
+	if err != nil {
+		t.Fatalf("Ping(): %v", err)
+	}
+	if !st.Legacy || st.VersionName != "1.6.4" || st.Protocol != 78 || st.Online != 3 || st.Max != 10 || st.MOTD != "A Legacy Server" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadCgroupMemoryCurrent returns memory.current of a cgroup v2 directory.
+func ReadCgroupMemoryCurrent(dir string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// ReadCgroupMemoryMax returns memory.max of a cgroup v2 directory (0 when unlimited).
+func ReadCgroupMemoryMax(dir string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(dir, "memory.max"))
+	if err != nil {
+		return 0, err
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(v, 10, 64)
+}
+
+// ReadCgroupCPUUsageUsec returns usage_usec from cpu.stat of a cgroup v2 directory.
+func ReadCgroupCPUUsageUsec(dir string) (uint64, error) {
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, errors.New("usage_usec not found in cpu.stat")
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package sysinfo
+
+import "errors"
+
+func ReadCgroupMemoryCurrent(dir string) (uint64, error) {
+	return 0, errors.New("unsupported")
+}
+
+func ReadCgroupMemoryMax(dir string) (uint64, error) {
+	return 0, errors.New("unsupported")
+}
+
+func ReadCgroupCPUUsageUsec(dir string) (uint64, error) {
+	return 0, errors.New("unsupported")
+}
@@ -63,3 +63,29 @@ func ReadProcRSSBytes(pid int) (uint64, error) {
 	}
 	return rssPages * uint64(os.Getpagesize()), nil
 }
+
+// ReadProcStartTicks returns the process start time (clock ticks since boot).
+// Together with the pid it identifies a process across pid reuse.
+func ReadProcStartTicks(pid int) (uint64, error) {
+	if pid <= 0 {
+		return 0, errors.New("invalid pid")
+	}
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	s := string(b)
+	end := strings.LastIndexByte(s, ')')
+	if end < 0 {
+		return 0, errors.New("unexpected /proc/<pid>/stat format")
+	}
+	rest := strings.Fields(s[end+1:])
+	// rest[0] is state (field 3), starttime is field 22.
+	if len(rest) < 20 {
+		return 0, errors.New("unexpected /proc/<pid>/stat fields")
+	}
+	if rest[0] == "Z" || rest[0] == "X" {
+		return 0, errors.New("process is a zombie")
+	}
+	return strconv.ParseUint(rest[19], 10, 64)
+}
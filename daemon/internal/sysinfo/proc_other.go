@@ -15,3 +15,7 @@ func ReadProcCPUTicks(pid int) (uint64, error) {
 func ReadProcRSSBytes(pid int) (uint64, error) {
 	return 0, errors.New("unsupported")
 }
+
+func ReadProcStartTicks(pid int) (uint64, error) {
+	return 0, errors.New("unsupported")
+}
@@ -67,6 +67,8 @@ type Client struct {
 
 	bindMu       sync.Mutex
 	boundPanelID string
+
+	out *outbox
 }
 
 func New(cfg Config) *Client {
@@ -79,7 +81,7 @@ func New(cfg Config) *Client {
 	if cfg.ReconnectMax <= 0 {
 		cfg.ReconnectMax = 30 * time.Second
 	}
-	c := &Client{cfg: cfg, started: time.Now()}
+	c := &Client{cfg: cfg, started: time.Now(), out: newOutbox()}
 	if cfg.BindPanel && strings.TrimSpace(cfg.PanelBindingPath) != "" {
 		if id, err := loadPanelBinding(cfg.PanelBindingPath); err == nil {
 			c.boundPanelID = id
@@ -93,9 +95,8 @@ func (c *Client) Run(ctx context.Context) error {
 		return errors.New("CommandExecutor is nil")
 	}
 
-	c.cfg.CommandExecutor.BindSender(func(msg protocol.Message) {
-		_ = c.sendWithTimeout(ctx, msg, 5*time.Second)
-	})
+	// Messages are queued and written by the connection's writer; producers never block.
+	c.cfg.CommandExecutor.BindSender(c.out.push)
 
 	c.writeHealth()
 	go c.heartbeatLoop(ctx)
@@ -159,14 +160,34 @@ func (c *Client) runOnce(ctx context.Context) error {
 		c.cfg.Log.Printf("ws connected: %s", c.cfg.URL)
 	}
 
-	if err := c.sendHello(ctx); err != nil {
+	if err := c.sendHello(ctx, conn); err != nil {
 		return err
 	}
 
+	connCtx, cancel := context.WithCancel(ctx)
+	helloAck := make(chan protocol.HelloAck, 1)
+	writerDone := make(chan error, 1)
+	go func() {
+		writerDone <- c.writeLoop(connCtx, conn, helloAck)
+	}()
+	defer func() {
+		cancel()
+		<-writerDone
+	}()
+
 	// read loop
 	for {
-		_, data, err := conn.Read(ctx)
+		_, data, err := conn.Read(connCtx)
 		if err != nil {
+			select {
+			case werr := <-writerDone:
+				// Report the write error that tore the connection down; writer already exited.
+				writerDone <- werr
+				if werr != nil {
+					return werr
+				}
+			default:
+			}
 			return err
 		}
 		var msg protocol.Message
@@ -180,10 +201,19 @@ func (c *Client) runOnce(ctx context.Context) error {
 				if err := c.checkAndBindPanel(ack.PanelID); err != nil {
 					return err
 				}
+				select {
+				case helloAck <- ack:
+				default:
+				}
 			}
 			continue
 		}
 
+		if msg.Type == "ack" {
+			c.out.ack(msg.ID)
+			continue
+		}
+
 		if msg.Type == "command" {
 			var cmd protocol.Command
 			if err := json.Unmarshal(msg.Payload, &cmd); err != nil {
@@ -195,7 +225,7 @@ func (c *Client) runOnce(ctx context.Context) error {
 	}
 }
 
-func (c *Client) sendHello(ctx context.Context) error {
+func (c *Client) sendHello(ctx context.Context, conn *websocket.Conn) error {
 	version := strings.TrimSpace(os.Getenv("ELEGANTMC_VERSION"))
 	if version == "" {
 		version = "dev"
@@ -205,10 +235,10 @@ func (c *Client) sendHello(ctx context.Context) error {
 		Version:  version,
 		OS:       runtime.GOOS,
 		Arch:     runtime.GOARCH,
-		Features: []string{"fs", "fs_upload", "mc", "frp", "du", "backup_targz"},
+		Features: []string{"fs", "fs_upload", "mc", "frp", "du", "backup_targz", "rcon", "mc_events", "log_tail"},
 	}
 	payload, _ := json.Marshal(hello)
-	return c.send(ctx, protocol.Message{
+	return c.send(ctx, conn, protocol.Message{
 		Type:    "hello",
 		TSUnix:  time.Now().Unix(),
 		Payload: payload,
@@ -236,12 +266,14 @@ func (c *Client) sendHeartbeat(ctx context.Context) error {
 	hb.UptimeSec = int64(time.Since(c.started).Seconds())
 	hb.LastError = c.lastErr.String()
 	hb.ServerTime = time.Now().Unix()
+	hb.Outbox = c.out.stats()
 	payload, _ := json.Marshal(hb)
-	return c.sendWithTimeout(ctx, protocol.Message{
+	c.out.push(protocol.Message{
 		Type:    "heartbeat",
 		TSUnix:  time.Now().Unix(),
 		Payload: payload,
-	}, 5*time.Second)
+	})
+	return nil
 }
 
 func (c *Client) writeHealth() {
@@ -280,27 +312,60 @@ func boolToInt(v bool) int {
 func (c *Client) handleCommand(ctx context.Context, id string, cmd protocol.Command) {
 	res := c.cfg.CommandExecutor.Execute(ctx, cmd)
 	payload, _ := json.Marshal(res)
-	_ = c.sendWithTimeout(ctx, protocol.Message{
+	// Queued until the panel acknowledges it, across reconnects.
+	c.out.push(protocol.Message{
 		Type:    "command_result",
 		ID:      id,
 		TSUnix:  time.Now().Unix(),
 		Payload: payload,
-	}, 30*time.Second)
+	})
 }
 
-func (c *Client) sendWithTimeout(ctx context.Context, msg protocol.Message, timeout time.Duration) error {
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	return c.send(ctx, msg)
-}
+// writeLoop drains the outbox onto conn until ctx is canceled or a write fails.
+// Draining starts once the panel answered hello (or after a short grace period for
+// panels that never do), so the ack feature is known before results are written.
+func (c *Client) writeLoop(ctx context.Context, conn *websocket.Conn, helloAck <-chan protocol.HelloAck) error {
+	acks := false
+	select {
+	case <-ctx.Done():
+		return nil
+	case ack := <-helloAck:
+		for _, f := range ack.Features {
+			if f == "ack" {
+				acks = true
+			}
+		}
+	case <-time.After(5 * time.Second):
+	}
+	c.out.reset(acks)
 
-func (c *Client) send(ctx context.Context, msg protocol.Message) error {
-	c.connMu.RLock()
-	conn := c.conn
-	c.connMu.RUnlock()
-	if conn == nil {
-		return errors.New("not connected")
+	for {
+		msg, done, found := c.out.next()
+		if !found {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-c.out.notify:
+			}
+			continue
+		}
+		err := c.send(ctx, conn, msg)
+		done(err == nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// Unblock the read loop so the client reconnects.
+			_ = conn.Close(websocket.StatusGoingAway, "write failed")
+			return err
+		}
 	}
+}
+
+func (c *Client) send(ctx context.Context, conn *websocket.Conn, msg protocol.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
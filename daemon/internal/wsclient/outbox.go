@@ -0,0 +1,213 @@
+package wsclient
+
+import (
+	"sync"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+const (
+	outboxMaxResults     = 256             // unacknowledged command results
+	outboxLogBudgetBytes = 4 * 1024 * 1024 // queued log/event payload bytes
+	outboxMaxLogs        = 20000
+)
+
+// outbox is the outbound message queue shared by all connections. It survives
+// reconnects and is drained by a single writer per connection in priority order:
+// command results, then the latest heartbeat, then log/event messages.
+//
+//   - command_result messages are kept until the panel acknowledges them (type "ack");
+//     when the panel does not support acks they are dropped once written.
+//   - heartbeats are collapsed: only the most recent one is kept.
+//   - log/event messages are kept up to a byte budget; the oldest are dropped first, so
+//     a log storm never blocks producers or command results.
+type outbox struct {
+	mu     sync.Mutex
+	notify chan struct{}
+
+	acks      bool // panel acknowledges command results
+	results   []*outboxResult
+	heartbeat *protocol.Message
+	logs      []protocol.Message
+	logHead   int
+	logBytes  int
+
+	droppedLogs    uint64
+	droppedResults uint64
+}
+
+type outboxResult struct {
+	msg      protocol.Message
+	sent     bool // written on the current connection
+	inflight bool
+}
+
+func newOutbox() *outbox {
+	return &outbox{notify: make(chan struct{}, 1)}
+}
+
+func (o *outbox) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+func messageSize(msg protocol.Message) int {
+	return len(msg.Type) + len(msg.ID) + len(msg.Payload) + 64
+}
+
+// push enqueues msg without blocking.
+func (o *outbox) push(msg protocol.Message) {
+	o.mu.Lock()
+	switch msg.Type {
+	case "command_result":
+		if msg.ID != "" {
+			for _, r := range o.results {
+				if r.msg.ID == msg.ID {
+					r.msg, r.sent = msg, false
+					o.mu.Unlock()
+					o.wake()
+					return
+				}
+			}
+		}
+		if len(o.results) >= outboxMaxResults {
+			// Prefer evicting results that were already delivered once.
+			idx := 0
+			for i, r := range o.results {
+				if r.sent && !r.inflight {
+					idx = i
+					break
+				}
+			}
+			o.results = append(o.results[:idx], o.results[idx+1:]...)
+			o.droppedResults++
+		}
+		o.results = append(o.results, &outboxResult{msg: msg})
+	case "heartbeat":
+		m := msg
+		o.heartbeat = &m
+	default:
+		o.logs = append(o.logs, msg)
+		o.logBytes += messageSize(msg)
+		for o.logBytes > outboxLogBudgetBytes || len(o.logs)-o.logHead > outboxMaxLogs {
+			o.logBytes -= messageSize(o.logs[o.logHead])
+			o.logs[o.logHead] = protocol.Message{}
+			o.logHead++
+			o.droppedLogs++
+		}
+		o.compactLogsLocked()
+	}
+	o.mu.Unlock()
+	o.wake()
+}
+
+func (o *outbox) compactLogsLocked() {
+	if o.logHead > 0 && o.logHead*2 >= len(o.logs) {
+		n := copy(o.logs, o.logs[o.logHead:])
+		clear(o.logs[n:])
+		o.logs = o.logs[:n]
+		o.logHead = 0
+	}
+}
+
+// next returns the next message to write. done must be called with the write outcome.
+func (o *outbox) next() (msg protocol.Message, done func(ok bool), found bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, r := range o.results {
+		if r.sent || r.inflight {
+			continue
+		}
+		r.inflight = true
+		return r.msg, func(ok bool) { o.resultWritten(r, ok) }, true
+	}
+	if hb := o.heartbeat; hb != nil {
+		o.heartbeat = nil
+		return *hb, func(ok bool) {}, true
+	}
+	if o.logHead < len(o.logs) {
+		m := o.logs[o.logHead]
+		o.logs[o.logHead] = protocol.Message{}
+		o.logHead++
+		o.logBytes -= messageSize(m)
+		o.compactLogsLocked()
+		return m, func(ok bool) {
+			if !ok {
+				o.requeueLog(m)
+			}
+		}, true
+	}
+	return protocol.Message{}, nil, false
+}
+
+func (o *outbox) resultWritten(r *outboxResult, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	r.inflight = false
+	if !ok {
+		return
+	}
+	r.sent = true
+	if !o.acks {
+		o.removeResultLocked(r.msg.ID)
+	}
+}
+
+// requeueLog puts a log message that failed to write back at the head of the queue.
+func (o *outbox) requeueLog(m protocol.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.logBytes+messageSize(m) > outboxLogBudgetBytes {
+		o.droppedLogs++
+		return
+	}
+	if o.logHead > 0 {
+		o.logHead--
+		o.logs[o.logHead] = m
+	} else {
+		o.logs = append([]protocol.Message{m}, o.logs...)
+	}
+	o.logBytes += messageSize(m)
+}
+
+// ack removes an acknowledged command result.
+func (o *outbox) ack(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.removeResultLocked(id)
+}
+
+func (o *outbox) removeResultLocked(id string) {
+	for i, r := range o.results {
+		if r.msg.ID == id {
+			o.results = append(o.results[:i], o.results[i+1:]...)
+			return
+		}
+	}
+}
+
+// reset is called for every new connection: unacknowledged results are sent again.
+func (o *outbox) reset(acks bool) {
+	o.mu.Lock()
+	o.acks = acks
+	for _, r := range o.results {
+		r.sent = false
+	}
+	o.mu.Unlock()
+	o.wake()
+}
+
+func (o *outbox) stats() *protocol.OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &protocol.OutboxStats{
+		PendingResults: len(o.results),
+		QueuedLogs:     len(o.logs) - o.logHead,
+		QueuedLogBytes: o.logBytes,
+		DroppedLogs:    o.droppedLogs,
+		DroppedResults: o.droppedResults,
+	}
+}
@@ -0,0 +1,88 @@
+package wsclient
+
+import (
+	"strings"
+	"testing"
+
+	"elegantmc/daemon/internal/protocol"
+)
+
+func drain(o *outbox, ok bool) []protocol.Message {
+	var out []protocol.Message
+	for {
+		msg, done, found := o.next()
+		if !found {
+			return out
+		}
+		done(ok)
+		out = append(out, msg)
+	}
+}
+
+func TestOutbox_PriorityAndCollapse(t *testing.T) {
+	o := newOutbox()
+	o.reset(true)
+	o.push(protocol.Message{Type: "log", Payload: []byte(`"a"`)})
+	o.push(protocol.Message{Type: "heartbeat", Payload: []byte(`1`)})
+	o.push(protocol.Message{Type: "heartbeat", Payload: []byte(`2`)})
+	o.push(protocol.Message{Type: "command_result", ID: "c1"})
+
+	got := drain(o, true)
+	if len(got) != 3 {
+		t.Fatalf("got %d messages, want 3", len(got))
+	}
+	if got[0].Type != "command_result" || got[1].Type != "heartbeat" || string(got[1].Payload) != "2" || got[2].Type != "log" {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+}
+
+func TestOutbox_ResultsUntilAck(t *testing.T) {
+	o := newOutbox()
+	o.reset(true)
+	o.push(protocol.Message{Type: "command_result", ID: "c1"})
+	o.push(protocol.Message{Type: "command_result", ID: "c2"})
+	drain(o, true)
+
+	// Reconnect: both unacknowledged results are sent again.
+	o.ack("c1")
+	o.reset(true)
+	got := drain(o, true)
+	if len(got) != 1 || got[0].ID != "c2" {
+		t.Fatalf("resend after reconnect = %+v, want c2 only", got)
+	}
+
+	// Without ack support, written results are dropped.
+	o.reset(false)
+	drain(o, true)
+	o.reset(false)
+	if got := drain(o, true); len(got) != 0 {
+		t.Fatalf("results resent without ack support: %+v", got)
+	}
+}
+
+func TestOutbox_LogBudget(t *testing.T) {
+	o := newOutbox()
+	line := []byte(`"` + strings.Repeat("x", 1024) + `"`)
+	for i := 0; i < outboxLogBudgetBytes/1024+100; i++ {
+		o.push(protocol.Message{Type: "log", Payload: line})
+	}
+	o.push(protocol.Message{Type: "command_result", ID: "c1"})
+
+	st := o.stats()
+	if st.QueuedLogBytes > outboxLogBudgetBytes || st.DroppedLogs == 0 {
+		t.Fatalf("log budget not enforced: %+v", st)
+	}
+	msg, done, _ := o.next()
+	done(true)
+	if msg.ID != "c1" {
+		t.Fatalf("first message = %q, want command result", msg.Type)
+	}
+
+	// A failed write keeps the log line queued.
+	before := o.stats().QueuedLogs
+	_, done, _ = o.next()
+	done(false)
+	if after := o.stats().QueuedLogs; after != before {
+		t.Fatalf("queued logs after failed write = %d, want %d", after, before)
+	}
+}
@@ -113,6 +113,8 @@ type UiDensity = "comfortable" | "compact";
 type GameSettingsSnapshot = {
   jarPath: string;
   javaPath: string;
+  javaVendor: string;
+  jvmProfile: string;
   gamePort: number;
   xms: string;
   xmx: string;
@@ -863,6 +865,8 @@ export default function HomePage() {
   const [jarCandidates, setJarCandidates] = useState<string[]>([]);
   const [jarCandidatesStatus, setJarCandidatesStatus] = useState<string>("");
   const [javaPath, setJavaPath] = useState<string>("");
+  const [javaVendor, setJavaVendor] = useState<string>("");
+  const [jvmProfile, setJvmProfile] = useState<string>("");
   const [gamePort, setGamePort] = useState<number>(25565);
   const [xms, setXms] = useState<string>("1G");
   const [xmx, setXmx] = useState<string>("2G");
@@ -2517,6 +2521,8 @@ export default function HomePage() {
 
     const jar = normalizeJarPath(cleanInst, String(cfg?.jar_path ?? jarPath));
     const java = String(cfg?.java_path ?? javaPath).trim();
+    const vendor = String(cfg?.java_vendor ?? javaVendor).trim().toLowerCase();
+    const jvmProfileVal = String(cfg?.jvm_profile ?? jvmProfile).trim().toLowerCase();
     const gamePortRaw = Math.round(Number(cfg?.game_port ?? gamePort));
     const gamePortVal = Number.isFinite(gamePortRaw) && gamePortRaw >= 1 && gamePortRaw <= 65535 ? gamePortRaw : 25565;
     const frpRemoteRaw = Math.round(Number(cfg?.frp_remote_port ?? frpRemotePort));
@@ -2546,6 +2552,10 @@ export default function HomePage() {
     if (typeof cfg?.server_version === "string") payload.server_version = String(cfg.server_version).trim();
     if (cfg?.server_build != null && Number.isFinite(Number(cfg.server_build))) payload.server_build = Math.round(Number(cfg.server_build));
     if (!java) delete payload.java_path;
+    if (vendor) payload.java_vendor = vendor;
+    else delete payload.java_vendor;
+    if (jvmProfileVal) payload.jvm_profile = jvmProfileVal;
+    else delete payload.jvm_profile;
     await callOkCommand("fs_write", { path, b64: b64EncodeUtf8(JSON.stringify(payload, null, 2) + "\n") }, 10_000);
   }
 
@@ -3093,6 +3103,8 @@ export default function HomePage() {
         const c: any = cfg;
         if (typeof c.jar_path === "string" && c.jar_path.trim()) setJarPath(normalizeJarPath(inst, c.jar_path));
         if (typeof c.java_path === "string") setJavaPath(c.java_path);
+        if (typeof c.java_vendor === "string") setJavaVendor(c.java_vendor);
+        if (typeof c.jvm_profile === "string") setJvmProfile(c.jvm_profile);
         if (typeof c.xms === "string" && c.xms.trim()) setXms(c.xms);
         if (typeof c.xmx === "string" && c.xmx.trim()) setXmx(c.xmx);
         if (typeof c.jvm_args_preset === "string") setJvmArgsPreset(normalizeJvmPreset(c.jvm_args_preset));
@@ -3205,6 +3217,7 @@ export default function HomePage() {
     // Reset to defaults, then load per-instance config/props best-effort.
     setJarPath("server.jar");
     setJavaPath("");
+    setJavaVendor("");
     setGamePort(25565);
     setXms("1G");
     setXmx("2G");
@@ -5556,6 +5569,8 @@ export default function HomePage() {
 	    setSettingsSnapshot({
 	      jarPath,
 	      javaPath,
+      javaVendor,
+      jvmProfile,
       gamePort,
       xms,
       xmx,
@@ -5574,6 +5589,8 @@ export default function HomePage() {
 	    if (settingsSnapshot) {
 	      setJarPath(settingsSnapshot.jarPath);
 	      setJavaPath(settingsSnapshot.javaPath);
+      setJavaVendor(settingsSnapshot.javaVendor);
+      setJvmProfile(settingsSnapshot.jvmProfile);
       setGamePort(settingsSnapshot.gamePort);
       setXms(settingsSnapshot.xms);
       setXmx(settingsSnapshot.xmx);
@@ -5606,9 +5623,13 @@ export default function HomePage() {
         const changed: string[] = [];
         if (String(snap.jarPath || "") !== String(jarPath || "")) changed.push(`${t.tr("Jar", "Jar")}: ${snap.jarPath || "-"} → ${jarPath || "-"}`);
         if (String(snap.javaPath || "") !== String(javaPath || "")) changed.push(`${t.tr("Java", "Java")}: ${snap.javaPath || "-"} → ${javaPath || "-"}`);
+        if (String(snap.javaVendor || "") !== String(javaVendor || ""))
+          changed.push(`${t.tr("Java vendor", "Java 发行版")}: ${snap.javaVendor || t.tr("auto", "自动")} → ${javaVendor || t.tr("auto", "自动")}`);
         if (Number(snap.gamePort) !== Number(gamePort)) changed.push(`${t.tr("Port", "端口")}: ${snap.gamePort} → ${gamePort}`);
         if (String(snap.xms || "") !== String(xms || "")) changed.push(`Xms: ${snap.xms || "-"} → ${xms || "-"}`);
         if (String(snap.xmx || "") !== String(xmx || "")) changed.push(`Xmx: ${snap.xmx || "-"} → ${xmx || "-"}`);
+        if (String(snap.jvmProfile || "") !== String(jvmProfile || ""))
+          changed.push(`${t.tr("JVM profile", "JVM 档案")}: ${snap.jvmProfile || t.tr("none", "无")} → ${jvmProfile || t.tr("none", "无")}`);
         if (String(snap.jvmArgsPreset || "") !== String(jvmArgsPreset || "")) changed.push(`${t.tr("JVM preset", "JVM 预设")}: ${snap.jvmArgsPreset} → ${jvmArgsPreset}`);
         if (String(snap.jvmArgsExtra || "") !== String(jvmArgsExtra || "")) changed.push(`${t.tr("JVM args", "JVM 参数")}: ${t.tr("edited", "已修改")}`);
         if (Boolean(snap.enableFrp) !== Boolean(enableFrp)) changed.push(`FRP: ${snap.enableFrp ? t.tr("on", "开启") : t.tr("off", "关闭")} → ${enableFrp ? t.tr("on", "开启") : t.tr("off", "关闭")}`);
@@ -6311,7 +6332,8 @@ export default function HomePage() {
       const inst = String(instanceOverride ?? instanceId).trim();
       if (!inst) throw new Error(t.tr("instance_id is required", "instance_id 不能为空"));
       const formatRaw = String(opts?.format || "").trim().toLowerCase();
-      const format = formatRaw === "zip" ? "zip" : formatRaw === "tar.gz" || formatRaw === "tgz" ? "tar.gz" : "";
+      const format =
+        formatRaw === "zip" || formatRaw === "repo" ? formatRaw : formatRaw === "tar.gz" || formatRaw === "tgz" ? "tar.gz" : "";
       const stop = typeof opts?.stop === "boolean" ? !!opts.stop : true;
       const keepLast = Math.max(0, Math.min(1000, Math.round(Number(opts?.keep_last ?? opts?.keepLast ?? 0) || 0)));
       const comment = String(opts?.comment || "").trim();
@@ -6410,7 +6432,7 @@ export default function HomePage() {
         .filter((e: any) => {
           if (e?.isDir || !e?.name) return false;
           const lower = String(e.name).toLowerCase();
-          return lower.endsWith(".zip") || lower.endsWith(".tar.gz") || lower.endsWith(".tgz");
+          return lower.endsWith(".zip") || lower.endsWith(".tar.gz") || lower.endsWith(".tgz") || lower.endsWith(".snapshot");
         })
         .map((e: any) => joinRelPath(base, String(e.name)));
       list.sort((a: string, b: string) => b.localeCompare(a));
@@ -9952,6 +9974,40 @@ export default function HomePage() {
                       <div className="hint">{t.tr("Leave blank to let the daemon pick automatically (recommended).", "留空则由 Daemon 自动选择（推荐）")}</div>
                     </div>
                   ) : null}
+                  {showSettingsField("java", "vendor", "zulu", "temurin", "graalvm") ? (
+                    <div className="field">
+                      <label>{t.tr("Java vendor (optional)", "Java 发行版（可选）")}</label>
+                      <input value={javaVendor} onChange={(e) => setJavaVendor(e.target.value)} placeholder="temurin / zulu / graalvm" />
+                      <div className="hint">
+                        {t.tr(
+                          "Downloads and always uses this vendor's runtime (when Java is blank). Vendors besides temurin/zulu come from the daemon's Java manifest.",
+                          "Java 留空时下载并总是使用该发行版；temurin / zulu 以外的发行版来自 Daemon 的 Java 清单"
+                        )}
+                      </div>
+                    </div>
+                  ) : null}
+                  {showSettingsField("jvm", "profile", "gc", "zgc", "shenandoah", "memory") ? (
+                    <div className="field">
+                      <label>{t.tr("JVM profile (daemon)", "JVM 档案（Daemon）")}</label>
+                      <Select
+                        value={jvmProfile}
+                        onChange={(v) => setJvmProfile(String(v || ""))}
+                        options={[
+                          { value: "", label: t.tr("None", "无") },
+                          { value: "aikar", label: "Aikar (G1)" },
+                          { value: "zgc", label: t.tr("ZGC (generational on Java 21+)", "ZGC（Java 21+ 分代）") },
+                          { value: "shenandoah", label: "Shenandoah" },
+                          { value: "lowmem", label: t.tr("Low memory", "低内存") },
+                        ]}
+                      />
+                      <div className="hint">
+                        {t.tr(
+                          "Expanded by the daemon for the selected Java (flags the JVM rejects are skipped). Use the Default JVM preset below to avoid duplicate GC flags. Xms/Xmx accept \"auto\" to size the heap from host memory.",
+                          "由 Daemon 按所选 Java 展开（JVM 不支持的参数会跳过）。请将下方 JVM 预设设为默认，避免重复的 GC 参数。Xms/Xmx 可填 \"auto\"，按主机内存自动计算。"
+                        )}
+                      </div>
+                    </div>
+                  ) : null}
                   {showSettingsField("jvm", "args", "aikar", "gc") ? (
                     <div className="field" style={{ gridColumn: "1 / -1" }}>
                       <label>{t.tr("JVM args", "JVM 参数")}</label>
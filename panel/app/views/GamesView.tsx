@@ -269,7 +269,7 @@ export default function GamesView() {
   const [backupMetaByPath, setBackupMetaByPath] = useState<Record<string, any>>({});
   const [dangerRestorePath, setDangerRestorePath] = useState<string>("");
   const [backupNewOpen, setBackupNewOpen] = useState<boolean>(false);
-  const [backupNewFormat, setBackupNewFormat] = useState<"zip" | "tar.gz">("tar.gz");
+  const [backupNewFormat, setBackupNewFormat] = useState<"zip" | "tar.gz" | "repo">("tar.gz");
   const [backupNewStop, setBackupNewStop] = useState<boolean>(true);
   const [backupNewKeepLast, setBackupNewKeepLast] = useState<number>(0);
   const [backupNewComment, setBackupNewComment] = useState<string>("");
@@ -462,7 +462,7 @@ export default function GamesView() {
     const meta = backupMetaByPath[p];
     const metaUnix = Math.floor(Number(meta?.created_at_unix || 0));
     if (Number.isFinite(metaUnix) && metaUnix > 0) return { unix: metaUnix, file };
-    const m = file.match(/-(\d{9,12})\.(?:zip|tar\.gz|tgz)$/i);
+    const m = file.match(/-(\d{9,12})\.(?:zip|tar\.gz|tgz|snapshot)$/i);
     const unix = m ? Number(m[1]) : null;
     return { unix: Number.isFinite(Number(unix)) ? Number(unix) : null, file };
   }, [backupZips, backupMetaByPath]);
@@ -2157,7 +2157,7 @@ export default function GamesView() {
                     const file = path.split("/").pop() || path;
                     const meta = backupMetaByPath[path] || null;
                     const unixMeta = Math.floor(Number(meta?.created_at_unix || 0));
-                    const m = file.match(/-(\d{9,12})\.(?:zip|tar\.gz|tgz)$/i);
+                    const m = file.match(/-(\d{9,12})\.(?:zip|tar\.gz|tgz|snapshot)$/i);
                     const unixName = m ? Math.floor(Number(m[1])) : 0;
                     const unix =
                       (Number.isFinite(unixMeta) && unixMeta > 0 ? unixMeta : 0) ||
@@ -2165,12 +2165,16 @@ export default function GamesView() {
                       0;
                     const bytes = meta && Number.isFinite(Number(meta?.bytes)) ? Number(meta.bytes) : null;
                     const comment = meta ? String(meta?.comment || "").trim() : "";
+                    const storedBytes =
+                      meta?.repo && Number.isFinite(Number(meta.repo.stored_bytes)) ? Number(meta.repo.stored_bytes) : null;
                     const format =
                       meta && String(meta?.format || "").trim()
                         ? String(meta.format).trim()
                         : file.toLowerCase().endsWith(".zip")
                           ? "zip"
-                          : "tar.gz";
+                          : file.toLowerCase().endsWith(".snapshot")
+                            ? "repo"
+                            : "tar.gz";
                     return (
                       <tr key={path}>
                         <td className="muted">{unix ? <TimeAgo unix={unix} /> : "-"}</td>
@@ -2180,7 +2184,12 @@ export default function GamesView() {
                             <code style={{ minWidth: 0, overflow: "hidden", textOverflow: "ellipsis" }}>{file}</code>
                           </div>
                         </td>
-                        <td>{bytes == null ? "-" : fmtBytes(bytes)}</td>
+                        <td>
+                          {bytes == null ? "-" : fmtBytes(bytes)}
+                          {storedBytes != null ? (
+                            <div className="hint">{t.tr(`+${fmtBytes(storedBytes)} stored`, `新增 ${fmtBytes(storedBytes)}`)}</div>
+                          ) : null}
+                        </td>
                         <td className="muted" style={{ minWidth: 0 }}>
                           {comment || <span className="muted">-</span>}
                         </td>
@@ -3043,13 +3052,21 @@ export default function GamesView() {
                 <label>{t.tr("Format", "格式")}</label>
                 <Select
                   value={backupNewFormat}
-                  onChange={(v) => setBackupNewFormat((v as any) === "zip" ? "zip" : "tar.gz")}
+                  onChange={(v) => setBackupNewFormat((v as any) === "zip" || (v as any) === "repo" ? (v as any) : "tar.gz")}
                   options={[
                     { value: "tar.gz", label: "tar.gz" },
                     { value: "zip", label: "zip" },
+                    { value: "repo", label: t.tr("repo (deduplicated)", "repo（去重）") },
                   ]}
                 />
-                <div className="hint">{t.tr("tar.gz is smaller; zip is faster for small worlds.", "tar.gz 更小；zip 在小世界可能更快。")}</div>
+                <div className="hint">
+                  {backupNewFormat === "repo"
+                    ? t.tr(
+                        "Snapshot in the instance's backup repository: only changed data is stored, ideal for frequent backups of large worlds.",
+                        "存入实例的备份仓库：只保存变化的数据，适合大世界的频繁备份。"
+                      )
+                    : t.tr("tar.gz is smaller; zip is faster for small worlds.", "tar.gz 更小；zip 在小世界可能更快。")}
+                </div>
               </div>
               <div className="field">
                 <label>{t.tr("Keep last", "保留最近")}</label>
@@ -3092,7 +3109,7 @@ export default function GamesView() {
                   onClick={async () => {
                     const inst = instanceId.trim();
                     if (!inst) return;
-                    const format = backupNewFormat === "zip" ? "zip" : "tar.gz";
+                    const format = backupNewFormat === "zip" || backupNewFormat === "repo" ? backupNewFormat : "tar.gz";
                     const keepLast = Math.max(0, Math.min(1000, Math.round(Number(backupNewKeepLast || 0) || 0)));
                     const comment = String(backupNewComment || "").trim();
                     await backupServer(inst, { format, keep_last: keepLast, stop: backupNewStop, comment });
@@ -407,7 +407,7 @@ export function handleDaemonMessage(daemonId, raw) {
           JSON.stringify({
             type: "hello_ack",
             ts_unix: nowUnix(),
-            payload: { panel_id: state.panel_id || "" },
+            payload: { panel_id: state.panel_id || "", features: ["ack"] },
           })
         );
       }
@@ -468,6 +468,15 @@ export function handleDaemonMessage(daemonId, raw) {
   }
   if (type === "command_result") {
     const id = msg.id;
+    // Acknowledge every result (including re-sent duplicates) so the daemon can drop it from its queue.
+    if (id) {
+      try {
+        const ws = state.connections.get(daemonId);
+        if (ws) ws.send(JSON.stringify({ type: "ack", id, ts_unix: nowUnix() }));
+      } catch {
+        // ignore
+      }
+    }
     if (id && state.pending.has(id)) {
       const p = state.pending.get(id);
       state.pending.delete(id);